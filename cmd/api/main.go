@@ -2,29 +2,54 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
-	"github.com/chrisdamba/spacetrouble/internal/api"
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/events"
+	"github.com/chrisdamba/spacetrouble/internal/grpcserver"
+	"github.com/chrisdamba/spacetrouble/internal/httpserver"
+	"github.com/chrisdamba/spacetrouble/internal/mirror"
+	"github.com/chrisdamba/spacetrouble/internal/notify"
+	"github.com/chrisdamba/spacetrouble/internal/openapi"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
 	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/reconcile"
+	"github.com/chrisdamba/spacetrouble/internal/reminders"
 	"github.com/chrisdamba/spacetrouble/internal/repository"
 	"github.com/chrisdamba/spacetrouble/internal/service"
 	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/verify"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
 	"github.com/chrisdamba/spacetrouble/pkg/config"
 	"github.com/chrisdamba/spacetrouble/pkg/health"
 	"github.com/chrisdamba/spacetrouble/pkg/spacex"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"syscall"
 )
 
 type App struct {
-	config *config.Config
-	server *http.Server
-	db     *pgxpool.Pool
+	config        *config.Config
+	server        *http.Server
+	grpcServer    *grpc.Server
+	db            *pgxpool.Pool
+	eventListener *events.Listener
+	outboxRelay   *outbox.Relay
+	dispatcher    *webhooks.Dispatcher
+	notifier      *notify.Worker
+	reminders     *reminders.Scheduler
+	reconciler    *reconcile.Reconciler
+	verifier      *verify.Verifier
+	mirror        *mirror.Syncer
 }
 
 func NewApp(cfg *config.Config) *App {
@@ -42,6 +67,10 @@ func (a *App) Initialize(ctx context.Context) error {
 		return fmt.Errorf("server setup failed: %w", err)
 	}
 
+	if err := a.setupOutbox(); err != nil {
+		return fmt.Errorf("outbox setup failed: %w", err)
+	}
+
 	return nil
 }
 
@@ -66,8 +95,26 @@ func (a *App) setupDatabase(ctx context.Context) error {
 }
 
 func (a *App) setupServer() error {
-	services := a.setupServices()
-	router := a.setupRouter(services)
+	authenticator, err := auth.NewKeyStore(a.config.Auth.APIKeys)
+	if err != nil {
+		return fmt.Errorf("failed to load api keys: %w", err)
+	}
+
+	cursors, err := a.setupCursorSigner()
+	if err != nil {
+		return fmt.Errorf("failed to set up cursor signing: %w", err)
+	}
+
+	doc, err := openapi.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build openapi document: %w", err)
+	}
+
+	services, err := a.setupServices()
+	if err != nil {
+		return err
+	}
+	router := a.setupRouter(services, authenticator, cursors, doc)
 
 	a.server = &http.Server{
 		Addr:         a.config.Server.Address,
@@ -76,45 +123,243 @@ func (a *App) setupServer() error {
 		ReadTimeout:  a.config.Server.ReadTimeout,
 		IdleTimeout:  a.config.Server.IdleTimeout,
 	}
+	a.grpcServer = grpcserver.NewServer(services.BookingService, authenticator)
 
 	return nil
 }
 
-type Services struct {
-	BookingService ports.BookingService
-}
-
-func (a *App) setupServices() Services {
+func (a *App) setupServices() (httpserver.Services, error) {
 	repo := repository.NewBookingRepository(a.db)
+	spaceXCfg := a.config.SpaceX
 	spaceXClient := spacex.NewClient(
-		spacex.WithBaseURL(a.config.SpaceX.BaseURL),
+		spacex.WithBaseURL(spaceXCfg.BaseURL),
+		spacex.WithHTTPClient(&http.Client{Timeout: spaceXCfg.Timeout}),
+		spacex.WithRetries(spaceXCfg.MaxAttempts, spaceXCfg.RetryMinBackoff, spaceXCfg.RetryMaxBackoff),
+		spacex.WithCircuitBreaker(spaceXCfg.BreakerThreshold, spaceXCfg.BreakerCooldown),
+	)
+	// bookings and GraphQL look launchpads up through the cache; the
+	// reconciler wants SpaceX's latest and asks it directly
+	launchpads := spacex.NewCache(
+		spaceXClient,
+		spacex.WithCacheTTL(spaceXCfg.CacheTTL),
+		spacex.WithStaleWhileRevalidate(spaceXCfg.CacheStale),
+		// the snapshot policy answers from schedules this old
+		spacex.WithRetention(spaceXCfg.SnapshotMaxAge),
+	)
+	expvar.Publish("spacex_cache", expvar.Func(func() any { return launchpads.Stats() }))
+
+	// events are recorded in Postgres and reach every instance's broker
+	// through LISTEN/NOTIFY
+	eventRepo := repository.NewEventRepository(a.db)
+	broker := events.NewBroker()
+	a.eventListener = events.NewListener(a.notifyConn, eventRepo, broker)
+
+	riskRepo := repository.NewRiskRepository(a.db)
+	a.reconciler = reconcile.NewReconciler(
+		riskRepo,
+		spaceXClient,
+		repository.NewLeaseRepository(a.db),
+		reconcile.WithInterval(a.config.Reconcile.Interval),
+		reconcile.WithHolder(instanceName()),
+		reconcile.WithEventPublisher(eventRepo),
 	)
 
-	return Services{
-		BookingService: service.NewBookingService(repo, spaceXClient),
+	// bookings SpaceX cannot be asked about are decided by the degraded
+	// mode policy; pending ones are verified against SpaceX directly once
+	// it is back
+	policies, err := service.ParseDegradedPolicy(spaceXCfg.DegradedPolicy)
+	if err != nil {
+		return httpserver.Services{}, err
 	}
+	// bookings are checked against the Postgres mirror of the SpaceX
+	// schedule, and only go to SpaceX through the cache once it is stale
+	mirrorRepo := repository.NewMirrorRepository(a.db)
+	a.mirror = mirror.NewSyncer(
+		mirrorRepo,
+		spaceXClient,
+		repository.NewLeaseRepository(a.db),
+		mirror.WithInterval(spaceXCfg.MirrorInterval),
+		mirror.WithHolder(instanceName()),
+	)
+	launchpadRepo := repository.NewLaunchpadRepository(a.db)
+	bookingService := service.NewBookingService(repo, launchpads,
+		service.WithEventPublisher(eventRepo),
+		service.WithLaunchpadClosures(launchpadRepo),
+		service.WithLaunchMirror(mirrorRepo, spaceXCfg.MirrorMaxAge),
+		service.WithDegradedMode(policies, launchpads, spaceXCfg.SnapshotMaxAge),
+	)
+	a.verifier = verify.NewVerifier(
+		riskRepo,
+		spaceXClient,
+		repository.NewLeaseRepository(a.db),
+		verify.WithInterval(a.config.Reconcile.VerifyInterval),
+		verify.WithHolder(instanceName()),
+		verify.WithEventPublisher(eventRepo),
+	)
+
+	return httpserver.Services{
+		BookingService:     bookingService,
+		DestinationService: service.NewDestinationService(repo),
+		EventService:       service.NewEventService(broker, eventRepo),
+		WebhookService:     service.NewWebhookService(repository.NewWebhookRepository(a.db)),
+		RiskService:        service.NewRiskService(riskRepo),
+		LaunchpadService:   service.NewLaunchpadService(launchpadRepo),
+		Launchpads:         launchpads,
+		DegradedMode: func() health.DegradedMode {
+			return degradedMode(bookingService.DegradedMode(), !spaceXClient.CircuitOpen())
+		},
+	}, nil
 }
 
-func (a *App) setupRouter(services Services) http.Handler {
-	router := http.NewServeMux()
-	const versionPrefix = "/v1"
+// degradedMode describes the degraded mode policy for the health endpoint.
+func degradedMode(report models.DegradedModeReport, spaceXAvailable bool) health.DegradedMode {
+	policy := func(p models.DegradedPolicy) health.DegradedPolicy {
+		return health.DegradedPolicy{Name: string(p), Decisions: report.Decisions[p]}
+	}
+	mode := health.DegradedMode{
+		SpaceXAvailable: spaceXAvailable,
+		Policy:          policy(report.Policy),
+		Fallbacks:       make([]health.DegradedPolicy, 0, len(report.Fallbacks)),
+	}
+	for _, fallback := range report.Fallbacks {
+		mode.Fallbacks = append(mode.Fallbacks, policy(fallback))
+	}
+	return mode
+}
+
+// setupOutbox builds the relay for the sinks named in OUTBOX_SINKS, plus
+// the fan-out to webhook subscriptions and customer emails, and the
+// workers that deliver those.
+func (a *App) setupOutbox() error {
+	cfg := a.config.Outbox
+	webhookRepo := repository.NewWebhookRepository(a.db)
+	notificationRepo := repository.NewNotificationRepository(a.db)
+	sinks := []outbox.Sink{webhooks.NewFanout(webhookRepo), notify.NewSink(notificationRepo)}
+	for _, name := range strings.Split(cfg.Sinks, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "stdout":
+			sinks = append(sinks, outbox.NewStdoutSink())
+		case "file":
+			sink, err := outbox.NewFileSink(cfg.FilePath)
+			if err != nil {
+				return err
+			}
+			sinks = append(sinks, sink)
+		case "webhook":
+			if cfg.WebhookURL == "" {
+				return fmt.Errorf("OUTBOX_WEBHOOK_URL is required for the webhook sink")
+			}
+			sinks = append(sinks, outbox.NewWebhookSink(cfg.WebhookURL, nil))
+		case "nats":
+			sink, err := outbox.NewNATSSink(cfg.NATSURL, cfg.NATSSubject)
+			if err != nil {
+				return err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	a.outboxRelay = outbox.NewRelay(
+		repository.NewOutboxRepository(a.db),
+		sinks,
+		outbox.WithPollInterval(cfg.PollInterval),
+		outbox.WithBatchSize(cfg.BatchSize),
+	)
 
-	router.HandleFunc(versionPrefix+"/health", health.HealthGet())
+	webhookCfg := a.config.Webhooks
+	a.dispatcher = webhooks.NewDispatcher(
+		webhookRepo,
+		webhooks.WithHTTPClient(webhooks.NewHTTPClient(webhookCfg.Timeout)),
+		webhooks.WithRetries(webhookCfg.MaxAttempts, 10*time.Second, time.Hour),
+		webhooks.WithDisableAfter(webhookCfg.DisableAfter),
+	)
 
-	bookingHandler := utils.AllowedMethods(
-		utils.AllowedContentTypes(
-			api.BookingHandler(services.BookingService),
-			"application/json",
-		),
-		"POST", "GET", "DELETE",
+	mailer, err := a.setupMailer()
+	if err != nil {
+		return err
+	}
+	a.reminders = reminders.NewScheduler(
+		repository.NewReminderRepository(a.db),
+		notificationRepo,
+		repository.NewLeaseRepository(a.db),
+		reminders.WithInterval(a.config.Reminders.Interval),
+		reminders.WithHolder(instanceName()),
+	)
+	a.notifier = notify.NewWorker(
+		notificationRepo,
+		mailer,
+		notify.WithRetries(a.config.Mail.MaxAttempts, 30*time.Second, time.Hour),
+		notify.WithSentRecorder(a.reminders),
 	)
-	router.HandleFunc(versionPrefix+"/bookings", bookingHandler)
+	return nil
+}
+
+// instanceName tells instances apart in the leases of periodic jobs.
+func instanceName() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
 
-	return router
+func (a *App) setupMailer() (ports.Mailer, error) {
+	cfg := a.config.Mail
+	switch cfg.Transport {
+	case "smtp":
+		return notify.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		return notify.NewFileMailer(cfg.FileDir, cfg.From)
+	case "memory":
+		return notify.NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+}
+
+// notifyConn takes a connection out of the pool for LISTEN, since
+// notifications are only delivered to the session that asked for them.
+func (a *App) notifyConn(ctx context.Context) (events.NotifyConn, error) {
+	conn, err := a.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Hijack(), nil
+}
+
+func (a *App) setupCursorSigner() (*utils.CursorSigner, error) {
+	keys, err := utils.ParseSigningKeys(a.config.Pagination.CursorKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		log.Println("CURSOR_SIGNING_KEYS not set, using an ephemeral key; cursors will not survive restarts")
+		key, err := utils.RandomSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return utils.NewCursorSigner(keys, a.config.Pagination.CursorTTL)
+}
+
+func (a *App) setupRouter(services httpserver.Services, authenticator auth.Authenticator, cursors *utils.CursorSigner, doc *openapi.Document) http.Handler {
+	return httpserver.NewRouter(httpserver.Routes(services, authenticator, cursors, doc))
 }
 
 func (a *App) Run(ctx context.Context) error {
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 2)
+
+	listenCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
+	go a.eventListener.Run(listenCtx)
+	go a.outboxRelay.Run(listenCtx)
+	go a.dispatcher.Run(listenCtx)
+	go a.notifier.Run(listenCtx)
+	go a.reminders.Run(listenCtx)
+	go a.reconciler.Run(listenCtx)
+	go a.verifier.Run(listenCtx)
+	go a.mirror.Run(listenCtx)
 
 	go func() {
 		log.Printf("Starting server on %s", a.server.Addr)
@@ -123,6 +368,18 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
+	go func() {
+		lis, err := net.Listen("tcp", a.config.Server.GRPCAddress)
+		if err != nil {
+			serverErrors <- fmt.Errorf("grpc listen: %w", err)
+			return
+		}
+		log.Printf("Starting gRPC server on %s", lis.Addr())
+		if err := a.grpcServer.Serve(lis); err != nil {
+			serverErrors <- fmt.Errorf("grpc: %w", err)
+		}
+	}()
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
@@ -145,6 +402,17 @@ func (a *App) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		a.grpcServer.Stop()
+	}
+
 	if a.db != nil {
 		a.db.Close()
 	}
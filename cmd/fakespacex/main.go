@@ -0,0 +1,80 @@
+// Command fakespacex serves the parts of the SpaceX API the booking
+// service calls from a scenario fixture, with injected latency and
+// errors, so the stack runs without reaching SpaceX:
+//
+//	go run ./cmd/fakespacex -scenario cmd/fakespacex/scenarios/default.yaml
+//	SPACEX_URL=http://localhost:8090/v4 go run ./cmd/api
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/fakespacex"
+)
+
+func main() {
+	addr := flag.String("addr", getEnvOrDefault("FAKESPACEX_ADDRESS", ":8090"), "address to listen on")
+	path := flag.String("scenario", getEnvOrDefault("FAKESPACEX_SCENARIO", "cmd/fakespacex/scenarios/default.yaml"), "YAML or JSON scenario fixture")
+	flag.Parse()
+
+	scenario, err := fakespacex.LoadScenario(*path, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to load scenario: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           logRequests(fakespacex.NewServer(scenario)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving %d launchpads, %d launches and %d faults from %s on %s",
+		len(scenario.Launchpads), len(scenario.Launches), len(scenario.Faults), *path, *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// statusRecorder keeps the status a handler wrote, for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -1,16 +1,20 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	models "github.com/chrisdamba/spacetrouble/internal"
 	"github.com/chrisdamba/spacetrouble/internal/ports"
 	"github.com/chrisdamba/spacetrouble/internal/utils"
 	"github.com/chrisdamba/spacetrouble/internal/validator"
-	"github.com/google/uuid"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
-func BookingHandler(service ports.BookingService) http.HandlerFunc {
+// BookingHandler serves /bookings. Pagination cursors handed to clients are
+// signed by cursors and verified before being passed to the service.
+func BookingHandler(service ports.BookingService, cursors *utils.CursorSigner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -18,16 +22,18 @@ func BookingHandler(service ports.BookingService) http.HandlerFunc {
 		case http.MethodDelete:
 			deleteBooking(service, w, r)
 		case http.MethodGet:
-			list(service, w, r)
+			if r.URL.Query().Get("id") != "" {
+				get(service, w, r)
+				return
+			}
+			list(service, cursors, w, r)
 		}
 	}
 }
 
 func create(service ports.BookingService, w http.ResponseWriter, r *http.Request) {
 	var bookingRequest models.BookingRequest
-	if err := utils.JsonDecodeBody(r, &bookingRequest); err != nil {
-		ae := utils.NewBadRequest("error json decoding body")
-		utils.RenderResponse(r, w, ae.StatusCode, ae)
+	if !decodeRequest(w, r, &bookingRequest) {
 		return
 	}
 
@@ -48,8 +54,48 @@ func create(service ports.BookingService, w http.ResponseWriter, r *http.Request
 	utils.RenderResponse(r, w, http.StatusCreated, ans)
 }
 
-func list(service ports.BookingService, w http.ResponseWriter, r *http.Request) {
+// bodyFormats names the request formats in decoding error messages.
+var bodyFormats = map[utils.ContentType]string{
+	utils.ContentTypeXML:     "xml",
+	utils.ContentTypeTextXML: "xml",
+	utils.ContentTypeForm:    "form",
+}
+
+// decodeRequest decodes the request body into dst, writing the error
+// response and returning false when it cannot.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := utils.DecodeBody(r, dst)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, utils.ErrUnsupportedMediaType) {
+		utils.RenderResponse(r, w, http.StatusUnsupportedMediaType, nil)
+		return false
+	}
+	mediaType, _ := utils.MediaType(r)
+	format, ok := bodyFormats[mediaType]
+	if !ok {
+		format = "json"
+	}
+	ae := utils.NewBadRequest(fmt.Sprintf("error %s decoding body", format))
+	utils.RenderResponse(r, w, ae.StatusCode, ae)
+	return false
+}
+
+func get(service ports.BookingService, w http.ResponseWriter, r *http.Request) {
+	booking, err := service.GetBooking(r.Context(), r.URL.Query().Get("id"))
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	utils.RenderResponse(r, w, http.StatusOK, booking)
+}
+
+func list(service ports.BookingService, cursors *utils.CursorSigner, w http.ResponseWriter, r *http.Request) {
 	cursor := r.URL.Query().Get("cursor")
+	before := r.URL.Query().Get("before")
 	limitStr := r.URL.Query().Get("limit")
 
 	limit := 10 // default limit
@@ -63,19 +109,49 @@ func list(service ports.BookingService, w http.ResponseWriter, r *http.Request)
 		limit = parsedLimit
 	}
 
-	getReq := models.GetBookingsRequest{
-		Limit: limit,
+	if cursor != "" && before != "" {
+		ae := utils.NewBadRequest("cursor and before cannot be combined")
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
 	}
-	if cursor != "" {
+
+	var includeTotal bool
+	if v := r.URL.Query().Get("include_total"); v != "" {
 		var err error
-		var getReqUuid uuid.UUID
-		_, getReqUuid, err = utils.DecodeCursor(cursor)
+		if includeTotal, err = strconv.ParseBool(v); err != nil {
+			ae := utils.NewBadRequest("invalid include_total parameter")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+	}
+
+	filter, err := parseBookingFilter(r.URL.Query())
+	if err != nil {
+		ae := utils.NewBadRequest(err.Error())
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	fingerprint := FilterFingerprint(filter)
+	for _, c := range []*string{&cursor, &before} {
+		if *c == "" {
+			continue
+		}
+		inner, err := cursors.Verify(*c, fingerprint)
 		if err != nil {
-			ae := utils.NewBadRequest(err.Error())
+			ae := getCursorApiError(err)
 			utils.RenderResponse(r, w, ae.StatusCode, ae)
 			return
 		}
-		getReq.Uuid = getReqUuid.String()
+		*c = inner
+	}
+
+	getReq := models.GetBookingsRequest{
+		Limit:        limit,
+		Uuid:         cursor,
+		Before:       before,
+		Filter:       filter,
+		IncludeTotal: includeTotal,
 	}
 	bookings, err := service.AllBookings(r.Context(), getReq)
 	if err != nil {
@@ -84,9 +160,37 @@ func list(service ports.BookingService, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	bookings.Cursor = cursors.Sign(bookings.Cursor, fingerprint)
+	bookings.NextCursor = cursors.Sign(bookings.NextCursor, fingerprint)
+	bookings.PrevCursor = cursors.Sign(bookings.PrevCursor, fingerprint)
+
+	if link := paginationLinks(r, bookings); link != "" {
+		w.Header().Set("Link", link)
+	}
 	utils.RenderResponse(r, w, http.StatusOK, bookings)
 }
 
+// paginationLinks builds an RFC 8288 Link header pointing at the
+// neighbouring pages, keeping every other query parameter as requested.
+func paginationLinks(r *http.Request, res *models.AllBookingsResponse) string {
+	var links []string
+	add := func(rel, param, cursor string) {
+		if cursor == "" {
+			return
+		}
+		u := *r.URL
+		q := u.Query()
+		q.Del("cursor")
+		q.Del("before")
+		q.Set(param, cursor)
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+	add("next", "cursor", res.NextCursor)
+	add("prev", "before", res.PrevCursor)
+	return strings.Join(links, ", ")
+}
+
 func deleteBooking(service ports.BookingService, w http.ResponseWriter, r *http.Request) {
 	bookingID := r.URL.Query().Get("id")
 	if bookingID == "" {
@@ -104,19 +208,71 @@ func deleteBooking(service ports.BookingService, w http.ResponseWriter, r *http.
 	utils.RenderResponse(r, w, http.StatusNoContent, nil)
 }
 
-func getApiError(err error) utils.ApiError {
-	ae := utils.ApiError{Msg: err.Error()}
+func getCursorApiError(err error) utils.ApiError {
+	ae := utils.NewBadRequest(err.Error())
 	switch err {
-	case models.ErrInvalidUUID:
-		ae.StatusCode = http.StatusBadRequest
-	case models.ErrMissingDestination:
-		ae.StatusCode = http.StatusNotFound
-	case models.ErrBookingNotFound:
-		ae.StatusCode = http.StatusNotFound
-	case models.ErrLaunchPadUnavailable:
-		ae.StatusCode = http.StatusConflict
+	case utils.ErrCursorExpired:
+		ae.Code = "cursor_expired"
+	case utils.ErrCursorMismatch:
+		ae.Code = "cursor_filter_mismatch"
 	default:
-		ae.StatusCode = http.StatusInternalServerError
+		ae.Code = "invalid_cursor"
+	}
+	return ae
+}
+
+// apiErrors maps service errors to a status code and a stable error code.
+// Errors may be wrapped; the first match wins.
+var apiErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{models.ErrInvalidUUID, http.StatusBadRequest, "invalid_uuid"},
+	{models.ErrInvalidRequest, http.StatusBadRequest, "invalid_request"},
+	{models.ErrWebhookDestination, http.StatusBadRequest, "webhook_destination_not_allowed"},
+	{models.ErrMissingDestination, http.StatusNotFound, "destination_not_found"},
+	{models.ErrBookingNotFound, http.StatusNotFound, "booking_not_found"},
+	{models.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{models.ErrLaunchpadNotClosed, http.StatusNotFound, "launchpad_not_closed"},
+	{models.ErrBatchConflict, http.StatusConflict, "batch_conflict"},
+	{models.ErrLaunchPadUnavailable, http.StatusConflict, "launchpad_unavailable"},
+	{models.ErrBatchAborted, http.StatusConflict, "batch_aborted"},
+	{models.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated"},
+	{models.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{models.ErrSpaceXUnavailable, http.StatusServiceUnavailable, "spacex_unavailable"},
+}
+
+func getApiError(err error) utils.ApiError {
+	ae := utils.ApiError{Msg: err.Error()}
+	for _, e := range apiErrors {
+		if errors.Is(err, e.err) {
+			ae.StatusCode, ae.Code = e.status, e.code
+			return ae
+		}
 	}
+	ae.StatusCode = http.StatusInternalServerError
+	ae.Code = "internal_error"
 	return ae
 }
+
+// ErrorCodes lists every code an error response from the API can carry.
+func ErrorCodes() []string {
+	codes := make([]string, 0, len(apiErrors)+5)
+	for _, e := range apiErrors {
+		codes = append(codes, e.code)
+	}
+	return append(codes, "internal_error", "invalid_cursor", "cursor_expired", "cursor_filter_mismatch", "not_acceptable")
+}
+
+// MapError converts a service error to the status and code clients see,
+// so that other transports report failures the same way.
+func MapError(err error) utils.ApiError {
+	return getApiError(err)
+}
+
+// MapCursorError converts a cursor verification error the way the list
+// endpoint reports it.
+func MapCursorError(err error) utils.ApiError {
+	return getCursorApiError(err)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+// maxBatchRows caps how many bookings a single import may contain.
+const maxBatchRows = 1000
+
+const ndjsonContentType = "application/x-ndjson"
+
+// BookingBatchHandler serves /bookings:batch. The body is either a JSON
+// array of booking requests or NDJSON with one request per line; the mode
+// query parameter picks "atomic" (the default) or "best_effort".
+func BookingBatchHandler(service ports.BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mode := models.BatchMode(r.URL.Query().Get("mode"))
+		if mode == "" {
+			mode = models.BatchAtomic
+		}
+		if !mode.IsValid() {
+			ae := utils.NewBadRequest("mode must be atomic or best_effort")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		requests, err := decodeBatch(r)
+		if err != nil {
+			ae := utils.NewBadRequest(err.Error())
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		res, err := service.CreateBookingsBatch(r.Context(), requests, mode)
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		for i := range res.Results {
+			if err := res.Results[i].Err; err != nil {
+				ae := getApiError(err)
+				res.Results[i].Code, res.Results[i].Error = ae.Code, ae.Msg
+			}
+		}
+		utils.RenderResponse(r, w, batchStatus(res), res)
+	}
+}
+
+// batchStatus is 201 when every row was stored, 422 when none were and
+// 207 for a partial best-effort import.
+func batchStatus(res *models.BatchBookingResponse) int {
+	switch {
+	case res.Failed == 0:
+		return http.StatusCreated
+	case res.Created == 0:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+func decodeBatch(r *http.Request) ([]models.BookingRequest, error) {
+	dec := json.NewDecoder(r.Body)
+	var requests []models.BookingRequest
+	next := func() error {
+		if len(requests) == maxBatchRows {
+			return fmt.Errorf("batch exceeds %d rows", maxBatchRows)
+		}
+		var req models.BookingRequest
+		if err := dec.Decode(&req); err != nil {
+			return fmt.Errorf("row %d: %w", len(requests), err)
+		}
+		requests = append(requests, req)
+		return nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), ndjsonContentType) {
+		for dec.More() {
+			if err := next(); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil, errors.New("body must be a JSON array of bookings")
+		}
+		for dec.More() {
+			if err := next(); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("unterminated JSON array: %w", err)
+		}
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after batch")
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("batch is empty")
+	}
+	return requests, nil
+}
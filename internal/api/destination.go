@@ -0,0 +1,52 @@
+package api
+
+import (
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+	"net/http"
+)
+
+func DestinationHandler(service ports.DestinationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			createDestination(service, w, r)
+		case http.MethodGet:
+			listDestinations(service, w, r)
+		}
+	}
+}
+
+func createDestination(service ports.DestinationService, w http.ResponseWriter, r *http.Request) {
+	var destinationRequest models.DestinationRequest
+	if !decodeRequest(w, r, &destinationRequest) {
+		return
+	}
+
+	v := validator.NewCustomValidator()
+	if err := v.Validate(destinationRequest); err != nil {
+		ae := utils.NewBadRequest(err.Error())
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	destination, err := service.CreateDestination(r.Context(), &destinationRequest)
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusCreated, destination)
+}
+
+func listDestinations(service ports.DestinationService, w http.ResponseWriter, r *http.Request) {
+	destinations, err := service.AllDestinations(r.Context())
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusOK, models.DestinationList(destinations))
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+const (
+	// eventHeartbeat is how often an idle stream sends a comment so that
+	// proxies keep the connection open.
+	eventHeartbeat = 15 * time.Second
+	// eventWriteTimeout is extended on every write so the stream is not
+	// cut off by the server's write timeout.
+	eventWriteTimeout = 30 * time.Second
+	// eventRetry is the reconnect delay, in milliseconds, suggested to
+	// clients.
+	eventRetry = 3000
+)
+
+// BookingEventsHandler serves /bookings/events as a Server-Sent Events
+// stream. Each event's id can be sent back in a Last-Event-ID header to
+// resume after it.
+func BookingEventsHandler(service ports.EventService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var lastEventID int64
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id < 0 {
+				ae := utils.NewBadRequest("invalid Last-Event-ID header")
+				utils.RenderResponse(r, w, ae.StatusCode, ae)
+				return
+			}
+			lastEventID = id
+		}
+
+		events, err := service.Subscribe(r.Context(), lastEventID)
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		flush := func() error {
+			if err := rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+		if err := flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			case <-r.Context().Done():
+				return
+			}
+			if err := flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event models.BookingEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+const (
+	// exportFlushEvery is how many rows are buffered before flushing.
+	exportFlushEvery = 500
+	// exportWriteTimeout is extended on every flush so long exports are
+	// not cut off by the server's write timeout.
+	exportWriteTimeout = 30 * time.Second
+)
+
+// BookingExportHandler serves /bookings/export. It takes the same filters
+// as the bookings list plus format (csv or ndjson) and an optional comma
+// separated list of columns, and streams every matching booking.
+func BookingExportHandler(service ports.BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		newWriter, ok := exportWriters[format]
+		if !ok {
+			ae := utils.NewBadRequest("format must be csv or ndjson")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		columns, err := parseExportColumns(query.Get("columns"))
+		if err != nil {
+			ae := utils.NewBadRequest(err.Error())
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		filter, err := parseBookingFilter(query)
+		if err != nil {
+			ae := utils.NewBadRequest(err.Error())
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		// headers are only sent with the first row so that failures
+		// before then still get a proper error response
+		var out rowWriter
+		rc := http.NewResponseController(w)
+		rows := 0
+		start := func() error {
+			w.Header().Set("Content-Type", exportContentTypes[format])
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bookings.%s"`, format))
+			w.WriteHeader(http.StatusOK)
+			out = newWriter(w, columns)
+			return out.header()
+		}
+		flush := func() error {
+			if err := out.flush(); err != nil {
+				return err
+			}
+			if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		}
+
+		err = service.ExportBookings(r.Context(), filter, func(b models.Booking) error {
+			if out == nil {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			if err := out.row(b); err != nil {
+				return err
+			}
+			if rows++; rows%exportFlushEvery == 0 {
+				return flush()
+			}
+			return nil
+		})
+		if err != nil && out == nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		if err != nil {
+			// the status line is already sent; cut the stream short so
+			// the client sees an incomplete body rather than a clean end
+			log.Printf("booking export aborted after %d rows: %v", rows, err)
+			panic(http.ErrAbortHandler)
+		}
+		if out == nil {
+			if err := start(); err != nil {
+				return
+			}
+		}
+		_ = flush()
+	}
+}
+
+func parseExportColumns(spec string) ([]models.BookingColumn, error) {
+	if spec == "" {
+		return models.BookingColumns, nil
+	}
+	var columns []models.BookingColumn
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range models.BookingColumns {
+			if c.Name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown export column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+type rowWriter interface {
+	header() error
+	row(b models.Booking) error
+	flush() error
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+}
+
+var exportWriters = map[string]func(io.Writer, []models.BookingColumn) rowWriter{
+	"csv": func(w io.Writer, columns []models.BookingColumn) rowWriter {
+		return &csvRowWriter{w: csv.NewWriter(w), columns: columns}
+	},
+	"ndjson": func(w io.Writer, columns []models.BookingColumn) rowWriter {
+		return &ndjsonRowWriter{w: w, columns: columns}
+	},
+}
+
+type csvRowWriter struct {
+	w       *csv.Writer
+	columns []models.BookingColumn
+	record  []string
+}
+
+func (c *csvRowWriter) header() error {
+	c.record = make([]string, len(c.columns))
+	for i, col := range c.columns {
+		c.record[i] = col.Name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvRowWriter) row(b models.Booking) error {
+	for i, col := range c.columns {
+		c.record[i] = utils.CSVCell(col.Value(b))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvRowWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonRowWriter writes one JSON object per booking with the keys in
+// column order.
+type ndjsonRowWriter struct {
+	w       io.Writer
+	columns []models.BookingColumn
+	buf     []byte
+}
+
+func (n *ndjsonRowWriter) header() error { return nil }
+
+func (n *ndjsonRowWriter) row(b models.Booking) error {
+	n.buf = append(n.buf[:0], '{')
+	for i, col := range n.columns {
+		if i > 0 {
+			n.buf = append(n.buf, ',')
+		}
+		key, _ := json.Marshal(col.Name)
+		value, _ := json.Marshal(col.Value(b))
+		n.buf = append(n.buf, key...)
+		n.buf = append(n.buf, ':')
+		n.buf = append(n.buf, value...)
+	}
+	n.buf = append(n.buf, '}', '\n')
+	_, err := n.w.Write(n.buf)
+	return err
+}
+
+func (n *ndjsonRowWriter) flush() error { return nil }
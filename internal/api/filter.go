@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// parseBookingFilter reads the list filters and sort order from the query
+// string. Date bounds accept RFC 3339 timestamps or plain YYYY-MM-DD dates.
+func parseBookingFilter(q url.Values) (models.BookingFilter, error) {
+	var filter models.BookingFilter
+	var err error
+
+	if status := q.Get("status"); status != "" {
+		filter.Status = models.BookingStatus(status)
+		if !filter.Status.IsValid() {
+			return filter, fmt.Errorf("invalid status parameter")
+		}
+	}
+	if destinationID := q.Get("destination_id"); destinationID != "" {
+		if _, err := uuid.Parse(destinationID); err != nil {
+			return filter, fmt.Errorf("invalid destination_id parameter")
+		}
+		filter.DestinationID = destinationID
+	}
+	filter.LaunchpadID = q.Get("launchpad_id")
+	filter.LastName = q.Get("last_name")
+
+	dates := []struct {
+		param string
+		dst   *time.Time
+	}{
+		{"launch_date_from", &filter.LaunchFrom},
+		{"launch_date_to", &filter.LaunchTo},
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+	}
+	for _, d := range dates {
+		if *d.dst, err = parseDateParam(q.Get(d.param)); err != nil {
+			return filter, fmt.Errorf("invalid %s parameter", d.param)
+		}
+	}
+
+	switch sort := models.SortField(q.Get("sort")); sort {
+	case "", models.SortByCreatedAt, models.SortByLaunchDate:
+		filter.Sort.Field = sort
+	default:
+		return filter, fmt.Errorf("invalid sort parameter")
+	}
+	switch order := models.SortDirection(q.Get("order")); order {
+	case "", models.SortAsc, models.SortDesc:
+		filter.Sort.Direction = order
+	default:
+		return filter, fmt.Errorf("invalid order parameter")
+	}
+
+	return filter, nil
+}
+
+func parseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// FilterFingerprint identifies the filter and sort a cursor was issued for
+// so that a cursor cannot be replayed against a wider query.
+func FilterFingerprint(f models.BookingFilter) string {
+	sort := f.Sort.WithDefaults()
+	fields := []string{
+		string(f.Status), f.DestinationID, f.LaunchpadID, strings.ToLower(f.LastName),
+		formatBound(f.LaunchFrom), formatBound(f.LaunchTo),
+		formatBound(f.CreatedFrom), formatBound(f.CreatedTo),
+		string(sort.Field), string(sort.Direction),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
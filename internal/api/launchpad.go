@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+)
+
+// LaunchpadClosureHandler serves /launchpads/closures: closing a
+// launchpad to new bookings, listing the closed ones, and reopening one.
+func LaunchpadClosureHandler(service ports.LaunchpadService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			closeLaunchpad(service, w, r)
+		case http.MethodDelete:
+			reopenLaunchpad(service, w, r)
+		case http.MethodGet:
+			listLaunchpadClosures(service, w, r)
+		}
+	}
+}
+
+func closeLaunchpad(service ports.LaunchpadService, w http.ResponseWriter, r *http.Request) {
+	var closureRequest models.LaunchpadClosureRequest
+	if !decodeRequest(w, r, &closureRequest) {
+		return
+	}
+
+	v := validator.NewCustomValidator()
+	if err := v.Validate(closureRequest); err != nil {
+		ae := utils.NewBadRequest(err.Error())
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	closure, err := service.CloseLaunchpad(r.Context(), &closureRequest)
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusCreated, closure)
+}
+
+func reopenLaunchpad(service ports.LaunchpadService, w http.ResponseWriter, r *http.Request) {
+	launchpadID := r.URL.Query().Get("launchpad_id")
+	if launchpadID == "" {
+		ae := utils.NewBadRequest("launchpad ID is required")
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := service.ReopenLaunchpad(r.Context(), launchpadID); err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusNoContent, nil)
+}
+
+func listLaunchpadClosures(service ports.LaunchpadService, w http.ResponseWriter, r *http.Request) {
+	closures, err := service.LaunchpadClosures(r.Context())
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusOK, models.LaunchpadClosureList{Closures: closures})
+}
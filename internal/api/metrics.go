@@ -0,0 +1,23 @@
+package api
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+// MetricsHandler serves the expvar metrics, such as the SpaceX cache
+// counters and the Go runtime's memory statistics, to admins only.
+func MetricsHandler() http.HandlerFunc {
+	vars := expvar.Handler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.Require(r.Context(), auth.PermViewMetrics); err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		vars.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+)
+
+// BookingRescheduleHandler serves /bookings/reschedule, moving the booking
+// named by the id parameter to the launch date in the body.
+func BookingRescheduleHandler(service ports.BookingService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bookingID := r.URL.Query().Get("id")
+		if bookingID == "" {
+			ae := utils.NewBadRequest("booking ID is required")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		var rescheduleRequest models.RescheduleRequest
+		if !decodeRequest(w, r, &rescheduleRequest) {
+			return
+		}
+
+		v := validator.NewCustomValidator()
+		if err := v.Validate(rescheduleRequest); err != nil {
+			ae := utils.NewBadRequest(err.Error())
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		booking, err := service.RescheduleBooking(r.Context(), bookingID, &rescheduleRequest)
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		utils.RenderResponse(r, w, http.StatusOK, booking)
+	}
+}
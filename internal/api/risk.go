@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+// AtRiskBookingsHandler serves /bookings/at-risk: the bookings SpaceX has
+// since scheduled a launch against, why, and dates still free nearby.
+func AtRiskBookingsHandler(service ports.RiskService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := service.AtRiskBookings(r.Context())
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		utils.RenderResponse(r, w, http.StatusOK, report)
+	}
+}
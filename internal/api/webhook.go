@@ -0,0 +1,146 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// WebhookHandler serves /webhooks: subscribing, listing or fetching by
+// id, and unsubscribing.
+func WebhookHandler(service ports.WebhookService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			createWebhook(service, w, r)
+		case http.MethodDelete:
+			deleteWebhook(service, w, r)
+		case http.MethodGet:
+			if r.URL.Query().Get("id") != "" {
+				getWebhook(service, w, r)
+				return
+			}
+			listWebhooks(service, w, r)
+		}
+	}
+}
+
+func createWebhook(service ports.WebhookService, w http.ResponseWriter, r *http.Request) {
+	var webhookRequest models.WebhookRequest
+	if !decodeRequest(w, r, &webhookRequest) {
+		return
+	}
+
+	v := validator.NewCustomValidator()
+	if err := v.Validate(webhookRequest); err != nil {
+		ae := utils.NewBadRequest(err.Error())
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	webhook, err := service.CreateWebhook(r.Context(), &webhookRequest)
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusCreated, webhook)
+}
+
+func getWebhook(service ports.WebhookService, w http.ResponseWriter, r *http.Request) {
+	webhook, err := service.GetWebhook(r.Context(), r.URL.Query().Get("id"))
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusOK, webhook)
+}
+
+func listWebhooks(service ports.WebhookService, w http.ResponseWriter, r *http.Request) {
+	webhooks, err := service.ListWebhooks(r.Context())
+	if err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusOK, models.WebhookList{Webhooks: webhooks})
+}
+
+func deleteWebhook(service ports.WebhookService, w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		ae := utils.NewBadRequest("webhook ID is required")
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+
+	if err := service.DeleteWebhook(r.Context(), id); err != nil {
+		ae := getApiError(err)
+		utils.RenderResponse(r, w, ae.StatusCode, ae)
+		return
+	}
+	utils.RenderResponse(r, w, http.StatusNoContent, nil)
+}
+
+// WebhookDeliveriesHandler serves /webhooks/deliveries, the log of a
+// subscription's most recent deliveries.
+func WebhookDeliveriesHandler(service ports.WebhookService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			ae := utils.NewBadRequest("webhook ID is required")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		limit := defaultDeliveryLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed <= 0 || parsed > maxDeliveryLimit {
+				ae := utils.NewBadRequest("limit must be between 1 and " + strconv.Itoa(maxDeliveryLimit))
+				utils.RenderResponse(r, w, ae.StatusCode, ae)
+				return
+			}
+			limit = parsed
+		}
+
+		deliveries, err := service.WebhookDeliveries(r.Context(), id, limit)
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		utils.RenderResponse(r, w, http.StatusOK, models.WebhookDeliveryList{Deliveries: deliveries})
+	}
+}
+
+// WebhookEnableHandler serves /webhooks/enable, which turns a subscription
+// disabled after too many failed deliveries back on.
+func WebhookEnableHandler(service ports.WebhookService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			ae := utils.NewBadRequest("webhook ID is required")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		webhook, err := service.EnableWebhook(r.Context(), id)
+		if err != nil {
+			ae := getApiError(err)
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+		utils.RenderResponse(r, w, http.StatusOK, webhook)
+	}
+}
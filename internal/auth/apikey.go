@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/google/uuid"
+)
+
+type Authenticator interface {
+	Authenticate(token string) (Principal, error)
+}
+
+type apiKey struct {
+	key       string
+	principal Principal
+}
+
+type KeyStore struct {
+	keys []apiKey
+}
+
+// NewKeyStore parses a comma separated list of "key:role[:user-uuid]"
+// entries. Customer keys must carry the user they act as.
+func NewKeyStore(spec string) (*KeyStore, error) {
+	store := &KeyStore{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid api key entry %q", entry)
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		p := Principal{Role: role}
+		if len(parts) == 3 {
+			if p.UserID, err = uuid.Parse(parts[2]); err != nil {
+				return nil, fmt.Errorf("invalid user id for api key: %w", err)
+			}
+		}
+		if role == RoleCustomer && p.UserID == uuid.Nil {
+			return nil, fmt.Errorf("customer api key %q needs a user id", parts[0])
+		}
+		store.keys = append(store.keys, apiKey{key: parts[0], principal: p})
+	}
+	return store, nil
+}
+
+func (s *KeyStore) Authenticate(token string) (Principal, error) {
+	for _, k := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(k.key), []byte(token)) == 1 {
+			return k.principal, nil
+		}
+	}
+	return Principal{}, models.ErrUnauthenticated
+}
+
+// RequireAuth resolves the bearer token on the request and stores the
+// resulting principal in the request context.
+func RequireAuth(next http.HandlerFunc, authenticator Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			utils.RenderResponse(r, w, http.StatusUnauthorized, utils.NewUnauthorized(models.ErrUnauthenticated.Error()))
+			return
+		}
+		p, err := authenticator.Authenticate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			utils.RenderResponse(r, w, http.StatusUnauthorized, utils.NewUnauthorized(err.Error()))
+			return
+		}
+		next(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
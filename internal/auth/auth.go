@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+)
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleAgent    Role = "agent"
+	RoleAdmin    Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermBookOwn            Permission = "bookings:own"
+	PermBookOnBehalf       Permission = "bookings:any"
+	PermManageDestinations Permission = "destinations:manage"
+	PermManageLaunchpads   Permission = "launchpads:manage"
+	PermViewMetrics        Permission = "metrics:read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {PermBookOwn},
+	RoleAgent:    {PermBookOwn, PermBookOnBehalf},
+	RoleAdmin:    {PermBookOwn, PermBookOnBehalf, PermManageDestinations, PermManageLaunchpads, PermViewMetrics},
+}
+
+// Principal is the authenticated caller attached to a request context.
+// UserID is only meaningful for customers, whose bookings are scoped to it.
+type Principal struct {
+	UserID uuid.UUID
+	Role   Role
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+func (p Principal) Can(perm Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns reports whether the principal may see the given user's bookings.
+// Agents and admins act on behalf of any customer.
+func (p Principal) Owns(userID uuid.UUID) bool {
+	if p.Can(PermBookOnBehalf) {
+		return true
+	}
+	return p.UserID != uuid.Nil && p.UserID == userID
+}
+
+// Require fetches the principal from ctx and checks it holds perm.
+func Require(ctx context.Context, perm Permission) (Principal, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return Principal{}, models.ErrUnauthenticated
+	}
+	if !p.Can(perm) {
+		return p, models.ErrForbidden
+	}
+	return p, nil
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
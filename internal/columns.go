@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// BookingColumn is one flat field of a booking, used wherever bookings are
+// written out as rows, such as CSV.
+type BookingColumn struct {
+	Name  string
+	Value func(b Booking) string
+}
+
+// BookingColumns lists every column in its default order.
+var BookingColumns = []BookingColumn{
+	{"id", func(b Booking) string { return b.ID.String() }},
+	{"status", func(b Booking) string { return string(b.Status) }},
+	{"created_at", func(b Booking) string { return b.CreatedAt.UTC().Format(time.RFC3339) }},
+	{"user_id", func(b Booking) string { return b.User.ID.String() }},
+	{"first_name", func(b Booking) string { return b.User.FirstName }},
+	{"last_name", func(b Booking) string { return b.User.LastName }},
+	{"gender", func(b Booking) string { return b.User.Gender }},
+	{"birthday", func(b Booking) string { return b.User.Birthday.UTC().Format(time.DateOnly) }},
+	{"email", func(b Booking) string { return b.User.Email }},
+	{"flight_id", func(b Booking) string { return b.Flight.ID.String() }},
+	{"launchpad_id", func(b Booking) string { return b.Flight.LaunchpadID }},
+	{"launch_date", func(b Booking) string { return b.Flight.LaunchDate.UTC().Format(time.RFC3339) }},
+	{"destination_id", func(b Booking) string { return b.Flight.Destination.ID.String() }},
+	{"destination_name", func(b Booking) string { return b.Flight.Destination.Name }},
+}
+
+// Table flattens the page into a header and one record per booking.
+func (r AllBookingsResponse) Table() ([]string, [][]string) {
+	header := make([]string, len(BookingColumns))
+	for i, c := range BookingColumns {
+		header[i] = c.Name
+	}
+	records := make([][]string, len(r.Bookings))
+	for i, b := range r.Bookings {
+		records[i] = make([]string, len(BookingColumns))
+		for j, c := range BookingColumns {
+			records[i][j] = c.Value(b.Booking)
+		}
+	}
+	return header, records
+}
+
+func (d DestinationList) Table() ([]string, [][]string) {
+	records := make([][]string, len(d))
+	for i, dest := range d {
+		records[i] = []string{dest.ID.String(), dest.Name}
+	}
+	return []string{"id", "name"}, records
+}
@@ -0,0 +1,60 @@
+// Package events fans booking events out to subscribers within one API
+// instance, and feeds that fan-out from Postgres notifications so every
+// instance sees every event.
+package events
+
+import (
+	"sync"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped. A dropped subscriber's channel is closed so that its
+// client reconnects and resumes from the event store.
+const subscriberBuffer = 64
+
+// Broker delivers events to every current subscriber. Events are
+// numbered and recorded by the event store before they reach it, and a
+// reconnecting client catches up from that store, so the broker keeps
+// nothing once an event is delivered.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[chan models.BookingEvent]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan models.BookingEvent]struct{})}
+}
+
+// Subscribe returns a channel of events published from now on and a func
+// that ends the subscription.
+func (b *Broker) Subscribe() (<-chan models.BookingEvent, func()) {
+	ch := make(chan models.BookingEvent, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers an already recorded event without blocking.
+func (b *Broker) Publish(event models.BookingEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Channel is the Postgres notification channel booking events are sent on.
+const Channel = "booking_events"
+
+// catchUpBatch is how many events are read per query while catching up.
+const catchUpBatch = 100
+
+// NotifyConn is a dedicated connection that can LISTEN, such as a
+// *pgx.Conn hijacked from the pool.
+type NotifyConn interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Listener relays booking event notifications from Postgres to a Broker.
+type Listener struct {
+	connect func(ctx context.Context) (NotifyConn, error)
+	store   ports.EventStore
+	broker  *Broker
+	retry   time.Duration
+
+	lastID int64
+	// replayed holds the events published while catching up, whose
+	// notifications may still arrive.
+	replayed map[int64]bool
+}
+
+func NewListener(connect func(ctx context.Context) (NotifyConn, error), store ports.EventStore, broker *Broker) *Listener {
+	return &Listener{connect: connect, store: store, broker: broker, retry: time.Second}
+}
+
+// Run relays notifications until ctx is done, reconnecting after errors.
+// On reconnecting it first publishes whatever was recorded while it was
+// away, so a dropped connection loses nothing.
+func (l *Listener) Run(ctx context.Context) error {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("booking event listener: %v; reconnecting in %s", err, l.retry)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.retry):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return fmt.Errorf("listening: %w", err)
+	}
+	if err := l.catchUp(ctx); err != nil {
+		return fmt.Errorf("catching up: %w", err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+		var event models.BookingEvent
+		if err := json.Unmarshal([]byte(n.Payload), &event); err != nil {
+			log.Printf("booking event listener: skipping malformed notification: %v", err)
+			continue
+		}
+		if l.replayed[event.ID] {
+			delete(l.replayed, event.ID)
+			continue
+		}
+		l.deliver(event)
+	}
+}
+
+// catchUp publishes the events recorded after the last one seen. Nothing
+// is replayed on the first connection, since no subscriber can be waiting
+// for older events yet.
+func (l *Listener) catchUp(ctx context.Context) error {
+	l.replayed = make(map[int64]bool)
+	if l.lastID == 0 {
+		return nil
+	}
+	for {
+		events, err := l.store.EventsSince(ctx, l.lastID, catchUpBatch)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			l.replayed[event.ID] = true
+			l.deliver(event)
+		}
+		if len(events) < catchUpBatch {
+			return nil
+		}
+	}
+}
+
+func (l *Listener) deliver(event models.BookingEvent) {
+	if event.ID > l.lastID {
+		l.lastID = event.ID
+	}
+	l.broker.Publish(event)
+}
@@ -0,0 +1,11 @@
+// Package fakespacex serves the parts of the SpaceX v4 API the booking
+// service calls, from a Scenario rather than from SpaceX, so that the
+// stack and its tests run without network access.
+//
+// A scenario lists the launchpads and launches to serve, and the faults
+// to inject into responses. Each Fault applies to the requests whose path
+// starts with its Path: it delays them by Latency, then fails them with
+// Status, either each time, at a Rate, or only the first Times requests.
+// A 429 can carry a Retry-After header. Together these stand in for a slow,
+// failing or rate limited SpaceX.
+package fakespacex
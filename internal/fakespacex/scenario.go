@@ -0,0 +1,119 @@
+package fakespacex
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is what the fake SpaceX API serves: launchpads, launches and
+// the faults injected into responses.
+type Scenario struct {
+	Launchpads []Launchpad `yaml:"launchpads"`
+	Launches   []Launch    `yaml:"launches"`
+	Faults     []Fault     `yaml:"faults"`
+}
+
+// Launchpad is served with the fields of the SpaceX launchpad object that
+// the client and humans read.
+type Launchpad struct {
+	ID       string `yaml:"id" json:"id"`
+	Name     string `yaml:"name" json:"name,omitempty"`
+	FullName string `yaml:"full_name" json:"full_name,omitempty"`
+	Status   string `yaml:"status" json:"status"`
+	Locality string `yaml:"locality" json:"locality,omitempty"`
+	Region   string `yaml:"region" json:"region,omitempty"`
+}
+
+// Launch is dated either by DateUnix or by In, an offset from when the
+// scenario is loaded, so that a fixture does not run out of upcoming
+// launches. Upcoming defaults to whether the launch date is ahead.
+type Launch struct {
+	ID            string        `yaml:"id" json:"id,omitempty"`
+	Name          string        `yaml:"name" json:"name,omitempty"`
+	Launchpad     string        `yaml:"launchpad" json:"launchpad"`
+	DateUnix      int64         `yaml:"date_unix" json:"date_unix"`
+	In            time.Duration `yaml:"in" json:"-"`
+	DatePrecision string        `yaml:"date_precision" json:"date_precision"`
+	Upcoming      *bool         `yaml:"upcoming" json:"upcoming"`
+}
+
+// Fault fails or delays requests whose path, below the API version,
+// starts with Path; an empty Path matches every request. Each matching
+// request waits Latency, then with probability Rate, 1 when unset, is
+// answered with Status instead. Times caps how many requests are failed,
+// 0 meaning no cap. A 429 carries RetryAfter as its Retry-After header
+// when it is set.
+type Fault struct {
+	Path       string        `yaml:"path"`
+	Latency    time.Duration `yaml:"latency"`
+	Status     int           `yaml:"status"`
+	Rate       float64       `yaml:"rate"`
+	Times      int           `yaml:"times"`
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// LoadScenario reads a scenario from a YAML or JSON file, JSON being read
+// as the YAML it is a subset of. Launch dates given by In are fixed
+// relative to now.
+func LoadScenario(path string, now time.Time) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario: %w", err)
+	}
+	return ParseScenario(data, now)
+}
+
+// ParseScenario is LoadScenario for a fixture already read.
+func ParseScenario(data []byte, now time.Time) (*Scenario, error) {
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("parsing scenario: %w", err)
+	}
+	if err := scenario.resolve(now); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+func (s *Scenario) resolve(now time.Time) error {
+	launchpads := make(map[string]bool, len(s.Launchpads))
+	for _, launchpad := range s.Launchpads {
+		if launchpad.ID == "" {
+			return fmt.Errorf("launchpad without an id")
+		}
+		launchpads[launchpad.ID] = true
+	}
+
+	for i := range s.Launches {
+		launch := &s.Launches[i]
+		if !launchpads[launch.Launchpad] {
+			return fmt.Errorf("launch %d: unknown launchpad %q", i, launch.Launchpad)
+		}
+		if launch.In != 0 {
+			launch.DateUnix = now.Add(launch.In).Unix()
+		}
+		if launch.DatePrecision == "" {
+			launch.DatePrecision = "hour"
+		}
+		if launch.Upcoming == nil {
+			upcoming := launch.DateUnix > now.Unix()
+			launch.Upcoming = &upcoming
+		}
+		if launch.ID == "" {
+			launch.ID = fmt.Sprintf("launch-%d", i+1)
+		}
+	}
+
+	for i, fault := range s.Faults {
+		if fault.Status != 0 && (fault.Status < 400 || fault.Status > 599) {
+			return fmt.Errorf("fault %d: status %d is not an error", i, fault.Status)
+		}
+		if fault.Rate < 0 || fault.Rate > 1 {
+			return fmt.Errorf("fault %d: rate %v is not between 0 and 1", i, fault.Rate)
+		}
+	}
+	return nil
+}
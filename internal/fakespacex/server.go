@@ -0,0 +1,234 @@
+package fakespacex
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is the API version the fake serves under, so that the client
+// is pointed at it with SPACEX_URL=http://<host>/v4 as at SpaceX.
+const APIPrefix = "/v4"
+
+// Server answers the SpaceX API requests the client makes from a
+// Scenario:
+//
+//	GET  /v4/launchpads
+//	GET  /v4/launchpads/{id}
+//	POST /v4/launches/query
+type Server struct {
+	scenario *Scenario
+	mux      *http.ServeMux
+	rand     func() float64
+
+	mu     sync.Mutex
+	failed []int
+}
+
+type Option func(*Server)
+
+// WithRand replaces the source of the numbers, in [0, 1), that fault
+// rates are compared with.
+func WithRand(rand func() float64) Option {
+	return func(s *Server) { s.rand = rand }
+}
+
+func NewServer(scenario *Scenario, opts ...Option) *Server {
+	s := &Server{
+		scenario: scenario,
+		mux:      http.NewServeMux(),
+		rand:     rand.Float64,
+		failed:   make([]int, len(scenario.Faults)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("GET "+APIPrefix+"/launchpads", s.listLaunchpads)
+	s.mux.HandleFunc("GET "+APIPrefix+"/launchpads/{id}", s.getLaunchpad)
+	s.mux.HandleFunc("POST "+APIPrefix+"/launches/query", s.queryLaunches)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.injectFault(w, r) {
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// injectFault applies the faults matching the request in order, and
+// reports whether one of them answered it.
+func (s *Server) injectFault(w http.ResponseWriter, r *http.Request) bool {
+	path := strings.TrimPrefix(r.URL.Path, APIPrefix)
+	for i, fault := range s.scenario.Faults {
+		if !strings.HasPrefix(path, fault.Path) {
+			continue
+		}
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return true
+			}
+		}
+		if fault.Status == 0 || !s.fires(i, fault) {
+			continue
+		}
+		if fault.Status == http.StatusTooManyRequests && fault.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(fault.RetryAfter.Round(time.Second)/time.Second)))
+		}
+		http.Error(w, http.StatusText(fault.Status), fault.Status)
+		return true
+	}
+	return false
+}
+
+// fires decides whether fault i fails this request, counting it against
+// the fault's Times when it does.
+func (s *Server) fires(i int, fault Fault) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fault.Times > 0 && s.failed[i] >= fault.Times {
+		return false
+	}
+	if fault.Rate > 0 && s.rand() >= fault.Rate {
+		return false
+	}
+	s.failed[i]++
+	return true
+}
+
+func (s *Server) listLaunchpads(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.scenario.Launchpads)
+}
+
+func (s *Server) getLaunchpad(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	for _, launchpad := range s.scenario.Launchpads {
+		if launchpad.ID == id {
+			writeJSON(w, launchpad)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// launchQuery is the part of the SpaceX query language the fake
+// understands: equality on a launch's id, launchpad and upcoming flag,
+// sorting by date_unix, and pages. Select is ignored and every field is
+// returned.
+type launchQuery struct {
+	Query struct {
+		ID        *string `json:"id"`
+		Launchpad *string `json:"launchpad"`
+		Upcoming  *bool   `json:"upcoming"`
+	} `json:"query"`
+	Options struct {
+		Limit  int                    `json:"limit"`
+		Page   int                    `json:"page"`
+		Sort   map[string]interface{} `json:"sort"`
+		Select []string               `json:"select"`
+	} `json:"options"`
+}
+
+// launchPage is the paginated response of /launches/query.
+type launchPage struct {
+	Docs          []Launch `json:"docs"`
+	TotalDocs     int      `json:"totalDocs"`
+	Offset        int      `json:"offset"`
+	Limit         int      `json:"limit"`
+	TotalPages    int      `json:"totalPages"`
+	Page          int      `json:"page"`
+	PagingCounter int      `json:"pagingCounter"`
+	HasPrevPage   bool     `json:"hasPrevPage"`
+	HasNextPage   bool     `json:"hasNextPage"`
+	PrevPage      *int     `json:"prevPage"`
+	NextPage      *int     `json:"nextPage"`
+}
+
+func (s *Server) queryLaunches(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var q launchQuery
+	if err := decoder.Decode(&q); err != nil {
+		http.Error(w, fmt.Sprintf("unsupported query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	launches := []Launch{}
+	for _, launch := range s.scenario.Launches {
+		if (q.Query.ID == nil || *q.Query.ID == launch.ID) &&
+			(q.Query.Launchpad == nil || *q.Query.Launchpad == launch.Launchpad) &&
+			(q.Query.Upcoming == nil || *q.Query.Upcoming == *launch.Upcoming) {
+			launches = append(launches, launch)
+		}
+	}
+	descending := false
+	switch q.Options.Sort["date_unix"] {
+	case "desc", "descending", -1.0:
+		descending = true
+	}
+	sort.SliceStable(launches, func(i, j int) bool {
+		if descending {
+			return launches[i].DateUnix > launches[j].DateUnix
+		}
+		return launches[i].DateUnix < launches[j].DateUnix
+	})
+
+	writeJSON(w, paginate(launches, q.Options.Limit, q.Options.Page))
+}
+
+// paginate cuts out a page the way SpaceX does, 10 launches to a page
+// unless limit says otherwise.
+func paginate(launches []Launch, limit, page int) launchPage {
+	if limit <= 0 {
+		limit = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+	total := len(launches)
+	totalPages := (total + limit - 1) / limit
+	offset := (page - 1) * limit
+	end := offset + limit
+	if offset > total {
+		offset = total
+	}
+	if end > total {
+		end = total
+	}
+
+	result := launchPage{
+		Docs:          launches[offset:end],
+		TotalDocs:     total,
+		Offset:        offset,
+		Limit:         limit,
+		TotalPages:    totalPages,
+		Page:          page,
+		PagingCounter: offset + 1,
+		HasPrevPage:   page > 1,
+		HasNextPage:   page < totalPages,
+	}
+	if result.HasPrevPage {
+		prev := page - 1
+		result.PrevPage = &prev
+	}
+	if result.HasNextPage {
+		next := page + 1
+		result.NextPage = &next
+	}
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,37 @@
+package graphql
+
+import (
+	"errors"
+
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+)
+
+// apiError is a resolver error carrying the REST error code as the "code"
+// extension.
+type apiError struct {
+	utils.ApiError
+}
+
+func (e apiError) Error() string {
+	return e.Msg
+}
+
+func (e apiError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// resolverError maps a service error the way the REST API does.
+func resolverError(err error) error {
+	var ae apiError
+	if errors.As(err, &ae) {
+		return ae
+	}
+	return apiError{api.MapError(err)}
+}
+
+func badRequest(msg string) error {
+	ae := utils.NewBadRequest(msg)
+	ae.Code = "invalid_request"
+	return apiError{ae}
+}
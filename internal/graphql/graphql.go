@@ -0,0 +1,61 @@
+// Package graphql serves a GraphQL view of bookings, flights, destinations
+// and launchpads. Reads and writes go through the same services as the
+// REST API, and lookups made while resolving nested fields are batched per
+// request so that a page of N bookings does not cost N lookups.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth bounds how deeply a query may nest selections.
+const maxDepth = 10
+
+// LaunchpadSource looks up a launchpad and its upcoming launches. It is
+// satisfied by *spacex.Client.
+type LaunchpadSource interface {
+	LaunchSchedule(ctx context.Context, launchpadID string) (*spacex.Schedule, error)
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes POSTed GraphQL requests. Query errors are reported in
+// the response body with a code extension matching the REST error codes,
+// so the status is 200 for any request that could be parsed.
+func Handler(bookings ports.BookingService, destinations ports.DestinationService,
+	launchpads LaunchpadSource, cursors *utils.CursorSigner) http.HandlerFunc {
+	schema := gql.MustParseSchema(schemaSDL,
+		&resolver{bookings: bookings, destinations: destinations, cursors: cursors},
+		gql.MaxDepth(maxDepth),
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+			ae := utils.NewBadRequest("request body must be a JSON object with a query")
+			utils.RenderResponse(r, w, ae.StatusCode, ae)
+			return
+		}
+
+		ctx := withLoaders(r.Context(), destinations, launchpads)
+		res := schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batchWait is how long a loader collects keys before fetching them, so
+// that sibling fields resolved in parallel share one batch.
+const batchWait = 2 * time.Millisecond
+
+// batchFunc fetches many keys at once. Keys missing from the result are
+// reported as not found; an error fails every key in the batch.
+type batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// loader batches and caches lookups for the lifetime of one request, in
+// the style of a dataloader.
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch batchFunc[K, V]
+
+	mu        sync.Mutex
+	entries   map[K]*entry[V]
+	pending   []K
+	scheduled bool
+}
+
+// entry is filled in before done is closed and never written afterwards.
+type entry[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+func newLoader[K comparable, V any](ctx context.Context, fetch batchFunc[K, V]) *loader[K, V] {
+	return &loader[K, V]{ctx: ctx, fetch: fetch, entries: make(map[K]*entry[V])}
+}
+
+// Queue adds keys to the next batch without fetching anything, so a list
+// resolver can announce every key its children may ask for. Queued keys
+// are only fetched once some Load needs the batch.
+func (l *loader[K, V]) Queue(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		l.enqueue(key)
+	}
+}
+
+// Load returns the value for key, fetching it together with every other
+// key queued in the meantime. Each key is fetched at most once.
+func (l *loader[K, V]) Load(key K) (V, bool, error) {
+	l.mu.Lock()
+	e := l.enqueue(key)
+	if !l.scheduled && len(l.pending) > 0 && l.entries[l.pending[0]].done == e.done {
+		l.scheduled = true
+		time.AfterFunc(batchWait, l.dispatch)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.value, e.found, e.err
+	case <-l.ctx.Done():
+		var zero V
+		return zero, false, l.ctx.Err()
+	}
+}
+
+// enqueue returns the entry for key, adding key to the pending batch when
+// it has not been asked for yet. The caller must hold l.mu.
+func (l *loader[K, V]) enqueue(key K) *entry[V] {
+	if e, ok := l.entries[key]; ok {
+		return e
+	}
+	var done chan struct{}
+	if len(l.pending) == 0 {
+		done = make(chan struct{})
+	} else {
+		done = l.entries[l.pending[0]].done
+	}
+	e := &entry[V]{done: done}
+	l.entries[key] = e
+	l.pending = append(l.pending, key)
+	return e
+}
+
+func (l *loader[K, V]) dispatch() {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending, l.scheduled = nil, false
+	l.mu.Unlock()
+
+	values, err := l.fetch(l.ctx, keys)
+
+	l.mu.Lock()
+	done := l.entries[keys[0]].done
+	for _, key := range keys {
+		e := l.entries[key]
+		e.value, e.found = values[key]
+		e.err = err
+	}
+	l.mu.Unlock()
+	close(done)
+}
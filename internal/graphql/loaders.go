@@ -0,0 +1,80 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+)
+
+type loadersKey struct{}
+
+// loaders holds the per-request loaders shared by every resolver.
+type loaders struct {
+	destinations *loader[string, models.Destination]
+	launchpads   *loader[string, *spacex.Schedule]
+}
+
+func withLoaders(ctx context.Context, destinations ports.DestinationService, launchpads LaunchpadSource) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		destinations: newLoader(ctx, destinationBatch(destinations)),
+		launchpads:   newLoader(ctx, launchpadBatch(launchpads)),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func destinationBatch(service ports.DestinationService) batchFunc[string, models.Destination] {
+	return func(ctx context.Context, ids []string) (map[string]models.Destination, error) {
+		found, err := service.DestinationsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]models.Destination, len(found))
+		for _, d := range found {
+			byID[d.ID.String()] = d
+		}
+		return byID, nil
+	}
+}
+
+// launchpadBatch fetches each launchpad once, concurrently, since SpaceX
+// has no bulk lookup. Unknown launchpads are left out of the result.
+func launchpadBatch(source LaunchpadSource) batchFunc[string, *spacex.Schedule] {
+	return func(ctx context.Context, ids []string) (map[string]*spacex.Schedule, error) {
+		var (
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+			firstErr error
+		)
+		schedules := make(map[string]*spacex.Schedule, len(ids))
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				schedule, err := source.LaunchSchedule(ctx, id)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case errors.Is(err, spacex.ErrNotFound):
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+				default:
+					schedules[id] = schedule
+				}
+			}(id)
+		}
+		wg.Wait()
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return schedules, nil
+	}
+}
@@ -0,0 +1,421 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+type resolver struct {
+	bookings     ports.BookingService
+	destinations ports.DestinationService
+	cursors      *utils.CursorSigner
+}
+
+func (r *resolver) Booking(ctx context.Context, args struct{ ID gql.ID }) (*bookingResolver, error) {
+	booking, err := r.bookings.GetBooking(ctx, string(args.ID))
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return newBookingResolver(ctx, *booking), nil
+}
+
+type bookingFilterInput struct {
+	Status         *string
+	DestinationID  *gql.ID
+	LaunchpadID    *string
+	LastName       *string
+	LaunchDateFrom *gql.Time
+	LaunchDateTo   *gql.Time
+	CreatedFrom    *gql.Time
+	CreatedTo      *gql.Time
+	Sort           *string
+	Order          *string
+}
+
+// bookingsArgs holds limit and includeTotal by value since the schema
+// gives them defaults.
+type bookingsArgs struct {
+	Limit        int32
+	After        *string
+	Before       *string
+	Filter       *bookingFilterInput
+	IncludeTotal bool
+}
+
+// Bookings pages through bookings with the same signed cursors as the REST
+// listing, so a cursor is only accepted with the filter it was issued for.
+func (r *resolver) Bookings(ctx context.Context, args bookingsArgs) (*pageResolver, error) {
+	if args.Limit <= 0 {
+		return nil, badRequest("invalid limit argument")
+	}
+	after, before := deref(args.After), deref(args.Before)
+	if after != "" && before != "" {
+		return nil, badRequest("after and before cannot be combined")
+	}
+
+	filter, err := filterFromInput(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := api.FilterFingerprint(filter)
+	for _, c := range []*string{&after, &before} {
+		if *c == "" {
+			continue
+		}
+		inner, err := r.cursors.Verify(*c, fingerprint)
+		if err != nil {
+			return nil, apiError{api.MapCursorError(err)}
+		}
+		*c = inner
+	}
+
+	res, err := r.bookings.AllBookings(ctx, models.GetBookingsRequest{
+		Limit:        int(args.Limit),
+		Uuid:         after,
+		Before:       before,
+		Filter:       filter,
+		IncludeTotal: args.IncludeTotal,
+	})
+	if err != nil {
+		return nil, resolverError(err)
+	}
+
+	page := &pageResolver{
+		nextCursor: r.cursors.Sign(res.NextCursor, fingerprint),
+		prevCursor: r.cursors.Sign(res.PrevCursor, fingerprint),
+	}
+	if res.TotalCount != nil {
+		total := int32(*res.TotalCount)
+		page.totalCount = &total
+	}
+
+	l := loadersFrom(ctx)
+	for _, b := range res.Bookings {
+		l.launchpads.Queue(b.Flight.LaunchpadID)
+		if b.Flight.Destination.Name == "" {
+			l.destinations.Queue(b.Flight.Destination.ID.String())
+		}
+		page.bookings = append(page.bookings, &bookingResolver{b.Booking})
+	}
+	return page, nil
+}
+
+func filterFromInput(in *bookingFilterInput) (models.BookingFilter, error) {
+	var filter models.BookingFilter
+	if in == nil {
+		return filter, nil
+	}
+	if in.Status != nil {
+		filter.Status = models.BookingStatus(*in.Status)
+	}
+	if in.DestinationID != nil {
+		if _, err := uuid.Parse(string(*in.DestinationID)); err != nil {
+			return filter, badRequest("invalid destinationId argument")
+		}
+		filter.DestinationID = string(*in.DestinationID)
+	}
+	filter.LaunchpadID = deref(in.LaunchpadID)
+	filter.LastName = deref(in.LastName)
+	filter.LaunchFrom = timeOf(in.LaunchDateFrom)
+	filter.LaunchTo = timeOf(in.LaunchDateTo)
+	filter.CreatedFrom = timeOf(in.CreatedFrom)
+	filter.CreatedTo = timeOf(in.CreatedTo)
+	if in.Sort != nil {
+		filter.Sort.Field = sortFields[*in.Sort]
+	}
+	if in.Order != nil {
+		filter.Sort.Direction = sortOrders[*in.Order]
+	}
+	return filter, nil
+}
+
+var sortFields = map[string]models.SortField{
+	"CREATED_AT":  models.SortByCreatedAt,
+	"LAUNCH_DATE": models.SortByLaunchDate,
+}
+
+var sortOrders = map[string]models.SortDirection{
+	"ASC":  models.SortAsc,
+	"DESC": models.SortDesc,
+}
+
+func (r *resolver) Destinations(ctx context.Context) ([]*destinationResolver, error) {
+	destinations, err := r.destinations.AllDestinations(ctx)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	out := make([]*destinationResolver, len(destinations))
+	for i, d := range destinations {
+		out[i] = &destinationResolver{d}
+	}
+	return out, nil
+}
+
+// Destination returns null for an unknown id. Several destination fields
+// in one query are fetched together.
+func (r *resolver) Destination(ctx context.Context, args struct{ ID gql.ID }) (*destinationResolver, error) {
+	if _, err := uuid.Parse(string(args.ID)); err != nil {
+		return nil, resolverError(models.ErrInvalidUUID)
+	}
+	d, found, err := loadersFrom(ctx).destinations.Load(string(args.ID))
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &destinationResolver{d}, nil
+}
+
+func (r *resolver) Launchpad(ctx context.Context, args struct{ ID gql.ID }) (*launchpadResolver, error) {
+	return loadLaunchpad(ctx, string(args.ID))
+}
+
+type bookingInput struct {
+	ID            *gql.ID
+	UserID        *gql.ID
+	FirstName     string
+	LastName      string
+	Gender        string
+	Birthday      gql.Time
+	LaunchpadID   string
+	DestinationID gql.ID
+	LaunchDate    gql.Time
+}
+
+func (r *resolver) CreateBooking(ctx context.Context, args struct{ Input bookingInput }) (*bookingResolver, error) {
+	in := args.Input
+	request := models.BookingRequest{
+		FirstName:     in.FirstName,
+		LastName:      in.LastName,
+		Gender:        in.Gender,
+		Birthday:      in.Birthday.Time,
+		LaunchpadID:   in.LaunchpadID,
+		DestinationID: string(in.DestinationID),
+		LaunchDate:    in.LaunchDate.Time,
+	}
+	if in.ID != nil {
+		request.ID = string(*in.ID)
+	}
+	if in.UserID != nil {
+		request.UserID = string(*in.UserID)
+	}
+
+	v := validator.NewCustomValidator()
+	if err := v.Validate(request); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	booking, err := r.bookings.CreateBooking(ctx, &request)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	return newBookingResolver(ctx, *booking), nil
+}
+
+func (r *resolver) CancelBooking(ctx context.Context, args struct{ ID gql.ID }) (bool, error) {
+	if err := r.bookings.DeleteBooking(ctx, string(args.ID)); err != nil {
+		return false, resolverError(err)
+	}
+	return true, nil
+}
+
+type pageResolver struct {
+	bookings   []*bookingResolver
+	nextCursor string
+	prevCursor string
+	totalCount *int32
+}
+
+func (p *pageResolver) Bookings() []*bookingResolver {
+	return p.bookings
+}
+
+func (p *pageResolver) NextCursor() *string {
+	return optional(p.nextCursor)
+}
+
+func (p *pageResolver) PrevCursor() *string {
+	return optional(p.prevCursor)
+}
+
+func (p *pageResolver) TotalCount() *int32 {
+	return p.totalCount
+}
+
+type bookingResolver struct {
+	b models.Booking
+}
+
+// newBookingResolver queues the booking's launchpad so that a single
+// booking resolves the same way as a page of them.
+func newBookingResolver(ctx context.Context, b models.Booking) *bookingResolver {
+	loadersFrom(ctx).launchpads.Queue(b.Flight.LaunchpadID)
+	return &bookingResolver{b}
+}
+
+func (r *bookingResolver) ID() gql.ID {
+	return gql.ID(r.b.ID.String())
+}
+
+func (r *bookingResolver) Status() string {
+	return string(r.b.Status)
+}
+
+func (r *bookingResolver) CreatedAt() gql.Time {
+	return gql.Time{Time: r.b.CreatedAt}
+}
+
+func (r *bookingResolver) Passenger() *passengerResolver {
+	return &passengerResolver{r.b.User}
+}
+
+func (r *bookingResolver) Flight() *flightResolver {
+	return &flightResolver{r.b.Flight}
+}
+
+type passengerResolver struct {
+	u models.User
+}
+
+func (r *passengerResolver) ID() gql.ID {
+	return gql.ID(r.u.ID.String())
+}
+
+func (r *passengerResolver) FirstName() string {
+	return r.u.FirstName
+}
+
+func (r *passengerResolver) LastName() string {
+	return r.u.LastName
+}
+
+func (r *passengerResolver) Gender() string {
+	return r.u.Gender
+}
+
+func (r *passengerResolver) Birthday() gql.Time {
+	return gql.Time{Time: r.u.Birthday}
+}
+
+type flightResolver struct {
+	f models.Flight
+}
+
+func (r *flightResolver) ID() gql.ID {
+	return gql.ID(r.f.ID.String())
+}
+
+func (r *flightResolver) LaunchDate() gql.Time {
+	return gql.Time{Time: r.f.LaunchDate}
+}
+
+// Destination uses the destination loaded with the booking when there is
+// one, and otherwise batches the lookup with the rest of the page.
+func (r *flightResolver) Destination(ctx context.Context) (*destinationResolver, error) {
+	if r.f.Destination.Name != "" {
+		return &destinationResolver{r.f.Destination}, nil
+	}
+	d, found, err := loadersFrom(ctx).destinations.Load(r.f.Destination.ID.String())
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, resolverError(models.ErrMissingDestination)
+	}
+	return &destinationResolver{d}, nil
+}
+
+func (r *flightResolver) Launchpad(ctx context.Context) (*launchpadResolver, error) {
+	return loadLaunchpad(ctx, r.f.LaunchpadID)
+}
+
+type destinationResolver struct {
+	d models.Destination
+}
+
+func (r *destinationResolver) ID() gql.ID {
+	return gql.ID(r.d.ID.String())
+}
+
+func (r *destinationResolver) Name() string {
+	return r.d.Name
+}
+
+func loadLaunchpad(ctx context.Context, id string) (*launchpadResolver, error) {
+	schedule, found, err := loadersFrom(ctx).launchpads.Load(id)
+	if err != nil {
+		return nil, resolverError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &launchpadResolver{schedule}, nil
+}
+
+type launchpadResolver struct {
+	s *spacex.Schedule
+}
+
+func (r *launchpadResolver) ID() gql.ID {
+	return gql.ID(r.s.LaunchPad.Id)
+}
+
+func (r *launchpadResolver) Status() string {
+	return r.s.LaunchPad.Status
+}
+
+func (r *launchpadResolver) Active() bool {
+	return r.s.LaunchPad.IsActive()
+}
+
+func (r *launchpadResolver) UpcomingLaunches() []*launchResolver {
+	out := make([]*launchResolver, len(r.s.Launches))
+	for i, l := range r.s.Launches {
+		out[i] = &launchResolver{l}
+	}
+	return out
+}
+
+type launchResolver struct {
+	l spacex.Launch
+}
+
+func (r *launchResolver) Date() gql.Time {
+	return gql.Time{Time: time.Unix(r.l.Date, 0).UTC()}
+}
+
+func (r *launchResolver) DatePrecision() string {
+	return r.l.DatePrecision
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func timeOf(t *gql.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time
+}
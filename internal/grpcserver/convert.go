@@ -0,0 +1,110 @@
+package grpcserver
+
+import (
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	bookingv1 "github.com/chrisdamba/spacetrouble/pkg/pb/booking/v1"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var statusToProto = map[models.BookingStatus]bookingv1.BookingStatus{
+	models.StatusActive:              bookingv1.BookingStatus_BOOKING_STATUS_ACTIVE,
+	models.StatusConfirmed:           bookingv1.BookingStatus_BOOKING_STATUS_CONFIRMED,
+	models.StatusCancelled:           bookingv1.BookingStatus_BOOKING_STATUS_CANCELLED,
+	models.StatusAtRisk:              bookingv1.BookingStatus_BOOKING_STATUS_AT_RISK,
+	models.StatusPendingVerification: bookingv1.BookingStatus_BOOKING_STATUS_PENDING_VERIFICATION,
+}
+
+var statusFromProto = map[bookingv1.BookingStatus]models.BookingStatus{
+	bookingv1.BookingStatus_BOOKING_STATUS_ACTIVE:               models.StatusActive,
+	bookingv1.BookingStatus_BOOKING_STATUS_CONFIRMED:            models.StatusConfirmed,
+	bookingv1.BookingStatus_BOOKING_STATUS_CANCELLED:            models.StatusCancelled,
+	bookingv1.BookingStatus_BOOKING_STATUS_AT_RISK:              models.StatusAtRisk,
+	bookingv1.BookingStatus_BOOKING_STATUS_PENDING_VERIFICATION: models.StatusPendingVerification,
+}
+
+// timestamp converts an unset timestamp to the zero time, which the
+// validator and filters treat as missing.
+func timestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func bookingRequestFromProto(req *bookingv1.CreateBookingRequest) models.BookingRequest {
+	return models.BookingRequest{
+		UserID:        req.GetUserId(),
+		FirstName:     req.GetFirstName(),
+		LastName:      req.GetLastName(),
+		Gender:        req.GetGender(),
+		Birthday:      timestamp(req.GetBirthday()),
+		LaunchpadID:   req.GetLaunchpadId(),
+		DestinationID: req.GetDestinationId(),
+		LaunchDate:    timestamp(req.GetLaunchDate()),
+	}
+}
+
+func bookingToProto(b models.Booking) *bookingv1.Booking {
+	return &bookingv1.Booking{
+		Id: b.ID.String(),
+		User: &bookingv1.User{
+			Id:        b.User.ID.String(),
+			FirstName: b.User.FirstName,
+			LastName:  b.User.LastName,
+			Gender:    b.User.Gender,
+			Birthday:  timestamppb.New(b.User.Birthday),
+		},
+		Flight: &bookingv1.Flight{
+			Id:          b.Flight.ID.String(),
+			LaunchpadId: b.Flight.LaunchpadID,
+			Destination: &bookingv1.Destination{
+				Id:   b.Flight.Destination.ID.String(),
+				Name: b.Flight.Destination.Name,
+			},
+			LaunchDate: timestamppb.New(b.Flight.LaunchDate),
+		},
+		Status:    statusToProto[b.Status],
+		CreatedAt: timestamppb.New(b.CreatedAt),
+	}
+}
+
+func filterFromProto(req *bookingv1.ListBookingsRequest) (models.BookingFilter, error) {
+	filter := models.BookingFilter{
+		LaunchpadID: req.GetLaunchpadId(),
+		LastName:    req.GetLastName(),
+		LaunchFrom:  timestamp(req.GetLaunchDateFrom()),
+		LaunchTo:    timestamp(req.GetLaunchDateTo()),
+		CreatedFrom: timestamp(req.GetCreatedFrom()),
+		CreatedTo:   timestamp(req.GetCreatedTo()),
+	}
+	if st := req.GetStatus(); st != bookingv1.BookingStatus_BOOKING_STATUS_UNSPECIFIED {
+		status, ok := statusFromProto[st]
+		if !ok {
+			return filter, fmt.Errorf("invalid status")
+		}
+		filter.Status = status
+	}
+	if id := req.GetDestinationId(); id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			return filter, fmt.Errorf("invalid destination_id")
+		}
+		filter.DestinationID = id
+	}
+	switch req.GetSort() {
+	case bookingv1.SortField_SORT_FIELD_CREATED_AT:
+		filter.Sort.Field = models.SortByCreatedAt
+	case bookingv1.SortField_SORT_FIELD_LAUNCH_DATE:
+		filter.Sort.Field = models.SortByLaunchDate
+	}
+	switch req.GetOrder() {
+	case bookingv1.SortOrder_SORT_ORDER_ASC:
+		filter.Sort.Direction = models.SortAsc
+	case bookingv1.SortOrder_SORT_ORDER_DESC:
+		filter.Sort.Direction = models.SortDesc
+	}
+	return filter, nil
+}
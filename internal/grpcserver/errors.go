@@ -0,0 +1,51 @@
+package grpcserver
+
+import (
+	"net/http"
+
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies the API in ErrorInfo details.
+const errorDomain = "spacetrouble"
+
+// httpCodes translates the HTTP status api.MapError picks into a gRPC code.
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:   codes.InvalidArgument,
+	http.StatusUnauthorized: codes.Unauthenticated,
+	http.StatusForbidden:    codes.PermissionDenied,
+	http.StatusNotFound:     codes.NotFound,
+	http.StatusConflict:     codes.FailedPrecondition,
+	// SpaceX could not be asked and the degraded mode policy rejected the
+	// booking
+	http.StatusServiceUnavailable: codes.Unavailable,
+}
+
+// statusFromError maps a service error the way the REST API does. The
+// REST error code is sent as the reason of an ErrorInfo detail.
+func statusFromError(err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return err
+	}
+	ae := api.MapError(err)
+	code, ok := httpCodes[ae.StatusCode]
+	if !ok {
+		code = codes.Internal
+	}
+	return newStatus(code, ae.Code, ae.Msg)
+}
+
+func newStatus(code codes.Code, reason, msg string) error {
+	st := status.New(code, msg)
+	if reason == "" {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
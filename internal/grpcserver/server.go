@@ -0,0 +1,83 @@
+// Package grpcserver exposes ports.BookingService over gRPC using the
+// messages in proto/booking/v1.
+package grpcserver
+
+import (
+	"context"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+	bookingv1 "github.com/chrisdamba/spacetrouble/pkg/pb/booking/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type bookingServer struct {
+	bookingv1.UnimplementedBookingServiceServer
+	service ports.BookingService
+}
+
+// NewServer returns a gRPC server with the booking service registered and
+// every call authenticated against authenticator.
+func NewServer(service ports.BookingService, authenticator auth.Authenticator, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.UnaryInterceptor(unaryAuth(authenticator)),
+		grpc.StreamInterceptor(streamAuth(authenticator)),
+	)
+	s := grpc.NewServer(opts...)
+	bookingv1.RegisterBookingServiceServer(s, NewBookingServer(service))
+	return s
+}
+
+func NewBookingServer(service ports.BookingService) bookingv1.BookingServiceServer {
+	return &bookingServer{service: service}
+}
+
+func (s *bookingServer) CreateBooking(ctx context.Context, req *bookingv1.CreateBookingRequest) (*bookingv1.Booking, error) {
+	request := bookingRequestFromProto(req)
+	v := validator.NewCustomValidator()
+	if err := v.Validate(request); err != nil {
+		return nil, newStatus(codes.InvalidArgument, "invalid_request", err.Error())
+	}
+
+	booking, err := s.service.CreateBooking(ctx, &request)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return bookingToProto(*booking), nil
+}
+
+func (s *bookingServer) GetBooking(ctx context.Context, req *bookingv1.GetBookingRequest) (*bookingv1.Booking, error) {
+	booking, err := s.service.GetBooking(ctx, req.GetId())
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return bookingToProto(*booking), nil
+}
+
+func (s *bookingServer) ListBookings(req *bookingv1.ListBookingsRequest, stream grpc.ServerStreamingServer[bookingv1.Booking]) error {
+	filter, err := filterFromProto(req)
+	if err != nil {
+		return newStatus(codes.InvalidArgument, "invalid_request", err.Error())
+	}
+
+	err = s.service.ExportBookings(stream.Context(), filter, func(b models.Booking) error {
+		return stream.Send(bookingToProto(b))
+	})
+	if err != nil {
+		return statusFromError(err)
+	}
+	return nil
+}
+
+func (s *bookingServer) CancelBooking(ctx context.Context, req *bookingv1.CancelBookingRequest) (*bookingv1.CancelBookingResponse, error) {
+	if req.GetId() == "" {
+		return nil, newStatus(codes.InvalidArgument, "invalid_request", "booking ID is required")
+	}
+	if err := s.service.DeleteBooking(ctx, req.GetId()); err != nil {
+		return nil, statusFromError(err)
+	}
+	return &bookingv1.CancelBookingResponse{}, nil
+}
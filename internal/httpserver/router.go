@@ -0,0 +1,148 @@
+// Package httpserver routes the HTTP API to its handlers. The routes are
+// listed in one table so that cmd/api serves them and the OpenAPI tests
+// can check the document against them.
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/graphql"
+	"github.com/chrisdamba/spacetrouble/internal/openapi"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/pkg/health"
+)
+
+const versionPrefix = "/v1"
+
+// requestBodyTypes are the media types single-resource endpoints decode.
+var requestBodyTypes = []string{
+	string(utils.ContentTypeJSON),
+	string(utils.ContentTypeXML),
+	string(utils.ContentTypeTextXML),
+	string(utils.ContentTypeForm),
+}
+
+// Services are what the routes are served from.
+type Services struct {
+	BookingService     ports.BookingService
+	DestinationService ports.DestinationService
+	EventService       ports.EventService
+	WebhookService     ports.WebhookService
+	RiskService        ports.RiskService
+	LaunchpadService   ports.LaunchpadService
+	Launchpads         graphql.LaunchpadSource
+	DegradedMode       func() health.DegradedMode
+}
+
+// Route is a path, the methods it answers and its handler. Requests with
+// any other method get a 405.
+type Route struct {
+	Path    string
+	Methods []string
+	Handler http.HandlerFunc
+}
+
+// Routes lists every route the server registers.
+func Routes(services Services, authenticator auth.Authenticator, cursors *utils.CursorSigner, doc *openapi.Document) []Route {
+	authenticated := func(handler http.HandlerFunc, bodyTypes ...string) http.HandlerFunc {
+		handler = auth.RequireAuth(handler, authenticator)
+		if len(bodyTypes) > 0 {
+			handler = utils.AllowedContentTypes(handler, bodyTypes...)
+		}
+		return handler
+	}
+
+	return []Route{
+		{
+			Path:    versionPrefix + "/health",
+			Methods: []string{"GET"},
+			Handler: health.HealthGet(health.WithDegradedMode(services.DegradedMode)),
+		},
+		{
+			Path:    versionPrefix + "/openapi.json",
+			Methods: []string{"GET"},
+			Handler: openapi.Handler(doc),
+		},
+		{
+			// on the public port, so only admins may read it
+			Path:    "/debug/vars",
+			Methods: []string{"GET"},
+			Handler: authenticated(api.MetricsHandler()),
+		},
+		{
+			Path:    versionPrefix + "/bookings",
+			Methods: []string{"POST", "GET", "DELETE"},
+			Handler: authenticated(api.BookingHandler(services.BookingService, cursors), requestBodyTypes...),
+		},
+		{
+			Path:    versionPrefix + "/bookings:batch",
+			Methods: []string{"POST"},
+			Handler: authenticated(api.BookingBatchHandler(services.BookingService), "application/json", "application/x-ndjson"),
+		},
+		{
+			Path:    versionPrefix + "/bookings/export",
+			Methods: []string{"GET"},
+			Handler: authenticated(api.BookingExportHandler(services.BookingService)),
+		},
+		{
+			Path:    versionPrefix + "/bookings/events",
+			Methods: []string{"GET"},
+			Handler: authenticated(api.BookingEventsHandler(services.EventService)),
+		},
+		{
+			Path:    versionPrefix + "/bookings/reschedule",
+			Methods: []string{"POST"},
+			Handler: authenticated(api.BookingRescheduleHandler(services.BookingService), requestBodyTypes...),
+		},
+		{
+			Path:    versionPrefix + "/bookings/at-risk",
+			Methods: []string{"GET"},
+			Handler: authenticated(api.AtRiskBookingsHandler(services.RiskService)),
+		},
+		{
+			Path:    versionPrefix + "/destinations",
+			Methods: []string{"POST", "GET"},
+			Handler: authenticated(api.DestinationHandler(services.DestinationService), requestBodyTypes...),
+		},
+		{
+			Path:    versionPrefix + "/launchpads/closures",
+			Methods: []string{"POST", "GET", "DELETE"},
+			Handler: authenticated(api.LaunchpadClosureHandler(services.LaunchpadService), requestBodyTypes...),
+		},
+		{
+			Path:    versionPrefix + "/webhooks",
+			Methods: []string{"POST", "GET", "DELETE"},
+			Handler: authenticated(api.WebhookHandler(services.WebhookService), "application/json"),
+		},
+		{
+			Path:    versionPrefix + "/webhooks/deliveries",
+			Methods: []string{"GET"},
+			Handler: authenticated(api.WebhookDeliveriesHandler(services.WebhookService)),
+		},
+		{
+			Path:    versionPrefix + "/webhooks/enable",
+			Methods: []string{"POST"},
+			Handler: authenticated(api.WebhookEnableHandler(services.WebhookService)),
+		},
+		{
+			Path:    versionPrefix + "/graphql",
+			Methods: []string{"POST"},
+			Handler: authenticated(
+				graphql.Handler(services.BookingService, services.DestinationService, services.Launchpads, cursors),
+				"application/json",
+			),
+		},
+	}
+}
+
+// NewRouter registers routes on a new mux.
+func NewRouter(routes []Route) *http.ServeMux {
+	router := http.NewServeMux()
+	for _, route := range routes {
+		router.HandleFunc(route.Path, utils.AllowedMethods(route.Handler, route.Methods...))
+	}
+	return router
+}
@@ -0,0 +1,119 @@
+package mirror
+
+import (
+	"context"
+	"log"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+)
+
+// LeaseName is the lease an instance holds while it syncs the mirror.
+const LeaseName = "spacex-mirror"
+
+// ScheduleSource lists every launchpad with its upcoming launches.
+// spacex.Client is the source that calls SpaceX.
+type ScheduleSource interface {
+	Schedules(ctx context.Context) ([]spacex.Schedule, error)
+}
+
+// Store replaces the local copy of the SpaceX schedule.
+type Store interface {
+	ReplaceMirror(ctx context.Context, schedules []spacex.Schedule, syncedAt time.Time) error
+}
+
+// Syncer copies the SpaceX launchpads and their upcoming launches into
+// Postgres on a schedule, so that bookings are checked against the copy
+// rather than calling SpaceX each time.
+type Syncer struct {
+	store     Store
+	schedules ScheduleSource
+	leases    ports.LeaseStore
+
+	holder   string
+	interval time.Duration
+	leaseTTL time.Duration
+	now      func() time.Time
+}
+
+type Option func(*Syncer)
+
+// WithInterval sets how often the mirror is synced.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Syncer) { s.interval = interval }
+}
+
+// WithHolder names this instance in the lease; it defaults to a random id.
+func WithHolder(holder string) Option {
+	return func(s *Syncer) { s.holder = holder }
+}
+
+// WithClock replaces time.Now for stamping synced schedules.
+func WithClock(now func() time.Time) Option {
+	return func(s *Syncer) { s.now = now }
+}
+
+func NewSyncer(store Store, schedules ScheduleSource, leases ports.LeaseStore, opts ...Option) *Syncer {
+	s := &Syncer{
+		store:     store,
+		schedules: schedules,
+		leases:    leases,
+		holder:    uuid.NewString(),
+		interval:  15 * time.Minute,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.leaseTTL == 0 {
+		s.leaseTTL = 3 * s.interval
+	}
+	return s
+}
+
+// Run syncs the mirror until ctx is done, then gives up the lease.
+func (s *Syncer) Run(ctx context.Context) error {
+	defer func() {
+		if err := s.leases.ReleaseLease(context.Background(), LeaseName, s.holder); err != nil {
+			log.Printf("spacex mirror: %v", err)
+		}
+	}()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("spacex mirror: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// RunOnce replaces the mirror with what SpaceX lists now, if this
+// instance holds the lease. When SpaceX cannot be asked the mirror is
+// left as it was, to go stale.
+func (s *Syncer) RunOnce(ctx context.Context) (models.MirrorSyncResult, error) {
+	var result models.MirrorSyncResult
+	held, err := s.leases.AcquireLease(ctx, LeaseName, s.holder, s.leaseTTL)
+	if err != nil || !held {
+		return result, err
+	}
+
+	schedules, err := s.schedules.Schedules(ctx)
+	if err != nil {
+		return result, err
+	}
+	if err := s.store.ReplaceMirror(ctx, schedules, s.now().UTC()); err != nil {
+		return result, err
+	}
+
+	result.Launchpads = len(schedules)
+	for _, schedule := range schedules {
+		result.Launches += len(schedule.Launches)
+	}
+	return result, nil
+}
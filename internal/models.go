@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/xml"
 	"errors"
 	"time"
 
@@ -8,26 +9,121 @@ import (
 )
 
 type BookingRequest struct {
-	ID            string    `json:"id,omitempty" validate:"omitempty,valid_uuid"`
-	FirstName     string    `json:"first_name" validate:"required,name_length"`
-	LastName      string    `json:"last_name" validate:"required,name_length"`
-	Gender        string    `json:"gender" validate:"required,gender"`
-	Birthday      time.Time `json:"birthday" validate:"required,valid_age"`
-	LaunchpadID   string    `json:"launchpad_id" validate:"required,launchpad_id_length"`
-	DestinationID string    `json:"destination_id" validate:"required,valid_uuid"`
-	LaunchDate    time.Time `json:"launch_date" validate:"required,future_date"`
+	ID            string    `json:"id,omitempty" xml:"id,omitempty" validate:"omitempty,valid_uuid"`
+	UserID        string    `json:"user_id,omitempty" xml:"user_id,omitempty" validate:"omitempty,valid_uuid"`
+	FirstName     string    `json:"first_name" xml:"first_name" validate:"required,name_length"`
+	LastName      string    `json:"last_name" xml:"last_name" validate:"required,name_length"`
+	Email         string    `json:"email,omitempty" xml:"email,omitempty" validate:"omitempty,email,max=254"`
+	Gender        string    `json:"gender" xml:"gender" validate:"required,gender"`
+	Birthday      time.Time `json:"birthday" xml:"birthday" validate:"required,valid_age"`
+	LaunchpadID   string    `json:"launchpad_id" xml:"launchpad_id" validate:"required,launchpad_id_length"`
+	DestinationID string    `json:"destination_id" xml:"destination_id" validate:"required,valid_uuid"`
+	LaunchDate    time.Time `json:"launch_date" xml:"launch_date" validate:"required,future_date"`
+}
+
+// RescheduleRequest moves a booking's flight to a new launch date.
+type RescheduleRequest struct {
+	LaunchDate time.Time `json:"launch_date" xml:"launch_date" validate:"required,future_date"`
 }
 
 type AllBookingsResponse struct {
-	Bookings []BookingResponse `json:"bookings"`
-	Limit    int               `json:"limit"`
-	Cursor   string            `json:"cursor"`
+	Bookings   []BookingResponse `json:"bookings" xml:"bookings>booking"`
+	Limit      int               `json:"limit" xml:"limit"`
+	Cursor     string            `json:"cursor" xml:"cursor"` // same as NextCursor, kept for older clients
+	NextCursor string            `json:"next_cursor" xml:"next_cursor"`
+	PrevCursor string            `json:"prev_cursor" xml:"prev_cursor"`
+	TotalCount *int              `json:"total_count,omitempty" xml:"total_count,omitempty"`
 }
 
 type GetBookingsRequest struct {
-	Limit int
-	Uuid  string
-	Ts    time.Time
+	Limit        int
+	Uuid         string // cursor to page forward from
+	Before       string // cursor to page backward from
+	Ts           time.Time
+	Filter       BookingFilter
+	IncludeTotal bool
+}
+
+type BookingPage struct {
+	Bookings   []Booking
+	NextCursor string
+	PrevCursor string
+}
+
+type SortField string
+
+const (
+	SortByCreatedAt  SortField = "created_at"
+	SortByLaunchDate SortField = "launch_date"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+type BookingSort struct {
+	Field     SortField
+	Direction SortDirection
+}
+
+// BookingFilter narrows a bookings listing. Zero values are ignored; date
+// ranges include the From bound and exclude the To bound.
+type BookingFilter struct {
+	UserID        string
+	Status        BookingStatus
+	DestinationID string
+	LaunchpadID   string
+	LastName      string
+	LaunchFrom    time.Time
+	LaunchTo      time.Time
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	Sort          BookingSort
+}
+
+type BatchMode string
+
+const (
+	// BatchAtomic stores every row or none of them.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort stores every row that passes its checks.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchAtomic || m == BatchBestEffort
+}
+
+type BatchRowStatus string
+
+const (
+	RowCreated BatchRowStatus = "created"
+	RowFailed  BatchRowStatus = "failed"
+)
+
+// BatchRowResult reports the outcome of one row of a batch import. Err is
+// the underlying failure; Code and Error are its rendered form.
+type BatchRowResult struct {
+	Index   int            `json:"index" xml:"index"`
+	Status  BatchRowStatus `json:"status" xml:"status"`
+	Booking *Booking       `json:"booking,omitempty" xml:"booking,omitempty"`
+	Code    string         `json:"code,omitempty" xml:"code,omitempty"`
+	Error   string         `json:"error,omitempty" xml:"error,omitempty"`
+	Err     error          `json:"-" xml:"-"`
+}
+
+type BatchBookingResponse struct {
+	Mode    BatchMode        `json:"mode" xml:"mode"`
+	Created int              `json:"created" xml:"created"`
+	Failed  int              `json:"failed" xml:"failed"`
+	Results []BatchRowResult `json:"results" xml:"results>result"`
+}
+
+type DestinationRequest struct {
+	Name string `json:"name" xml:"name" validate:"required,name_length"`
 }
 
 type BookingStatus string
@@ -36,41 +132,313 @@ const (
 	StatusActive    BookingStatus = "ACTIVE"
 	StatusConfirmed BookingStatus = "CONFIRMED"
 	StatusCancelled BookingStatus = "CANCELLED"
+	// StatusAtRisk marks a booking whose launch SpaceX has since scheduled
+	// a launch against.
+	StatusAtRisk BookingStatus = "AT_RISK"
+	// StatusPendingVerification marks a booking accepted while SpaceX was
+	// unreachable, until its launch has been checked.
+	StatusPendingVerification BookingStatus = "PENDING_VERIFICATION"
+)
+
+func (s BookingStatus) IsValid() bool {
+	switch s {
+	case StatusActive, StatusConfirmed, StatusCancelled, StatusAtRisk, StatusPendingVerification:
+		return true
+	}
+	return false
+}
+
+// WithDefaults fills in the default ordering of oldest bookings first.
+func (s BookingSort) WithDefaults() BookingSort {
+	if s.Field == "" {
+		s.Field = SortByCreatedAt
+	}
+	if s.Direction == "" {
+		s.Direction = SortAsc
+	}
+	return s
+}
+
+type BookingEventType string
+
+const (
+	EventBookingCreated       BookingEventType = "booking.created"
+	EventBookingCancelled     BookingEventType = "booking.cancelled"
+	EventBookingStatusChanged BookingEventType = "booking.status_changed"
+	EventBookingRescheduled   BookingEventType = "booking.rescheduled"
+)
+
+// BookingEvent records a change to a booking. IDs grow in the order events
+// are recorded, so a subscriber can resume after the last one it saw.
+type BookingEvent struct {
+	ID             int64            `json:"id"`
+	Type           BookingEventType `json:"type"`
+	Booking        Booking          `json:"booking"`
+	PreviousStatus BookingStatus    `json:"previous_status,omitempty"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+}
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes, waiting to be relayed to other systems. Payload is
+// the booking as JSON.
+type OutboxEvent struct {
+	ID          int64
+	EventID     uuid.UUID
+	AggregateID uuid.UUID
+	Type        BookingEventType
+	Payload     []byte
+	OccurredAt  time.Time
+	Attempts    int
+}
+
+// WebhookRequest subscribes an endpoint to booking events. No event types
+// means every type.
+type WebhookRequest struct {
+	URL        string             `json:"url" xml:"url" validate:"required,http_url"`
+	EventTypes []BookingEventType `json:"event_types,omitempty" xml:"event_types>event_type,omitempty" validate:"dive,oneof=booking.created booking.cancelled booking.status_changed booking.rescheduled"`
+}
+
+// WebhookSubscription is an endpoint that booking events are pushed to.
+// The secret signs deliveries and is only shown when the subscription is
+// created. A subscription owned by a customer only receives events for
+// that customer's bookings.
+type WebhookSubscription struct {
+	ID                  uuid.UUID          `json:"id" xml:"id"`
+	OwnerID             uuid.UUID          `json:"owner_id,omitempty" xml:"owner_id,omitempty"`
+	URL                 string             `json:"url" xml:"url"`
+	EventTypes          []BookingEventType `json:"event_types" xml:"event_types>event_type"`
+	Secret              string             `json:"secret,omitempty" xml:"secret,omitempty"`
+	Active              bool               `json:"active" xml:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures" xml:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty" xml:"disabled_at,omitempty"`
+	CreatedAt           time.Time          `json:"created_at" xml:"created_at"`
+}
+
+// Accepts reports whether the subscription wants events of type t.
+func (w WebhookSubscription) Accepts(t BookingEventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, et := range w.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookList struct {
+	Webhooks []WebhookSubscription `json:"webhooks" xml:"webhook"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
 )
 
+// WebhookDelivery is one event on its way to one subscription, and the
+// log of how its attempts went. URL, Secret and Payload are only loaded
+// for the dispatcher.
+type WebhookDelivery struct {
+	ID             int64            `json:"id" xml:"id"`
+	SubscriptionID uuid.UUID        `json:"webhook_id" xml:"webhook_id"`
+	EventID        uuid.UUID        `json:"event_id" xml:"event_id"`
+	EventType      BookingEventType `json:"event_type" xml:"event_type"`
+	Status         DeliveryStatus   `json:"status" xml:"status"`
+	Attempts       int              `json:"attempts" xml:"attempts"`
+	ResponseStatus int              `json:"response_status,omitempty" xml:"response_status,omitempty"`
+	LastError      string           `json:"last_error,omitempty" xml:"last_error,omitempty"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty" xml:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty" xml:"delivered_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at" xml:"created_at"`
+
+	URL     string `json:"-" xml:"-"`
+	Secret  string `json:"-" xml:"-"`
+	Payload []byte `json:"-" xml:"-"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries" xml:"delivery"`
+}
+
+// BookingRisk explains why a booking is at risk and suggests launch dates
+// that are still free on the SpaceX schedule.
+type BookingRisk struct {
+	Booking          Booking       `json:"booking" xml:"booking"`
+	Reason           string        `json:"reason" xml:"reason"`
+	PreviousStatus   BookingStatus `json:"previous_status" xml:"previous_status"`
+	AlternativeDates []time.Time   `json:"alternative_dates" xml:"alternative_dates>date"`
+	FlaggedAt        time.Time     `json:"flagged_at" xml:"flagged_at"`
+}
+
+// AtRiskReport lists the bookings currently flagged AT_RISK, soonest launch
+// first.
+type AtRiskReport struct {
+	Bookings []BookingRisk `json:"bookings" xml:"booking"`
+}
+
+// ReconcileResult counts what one reconciliation run found.
+type ReconcileResult struct {
+	Checked int
+	Flagged int
+	Cleared int
+}
+
+// VerifyResult counts what one run of the verifier of pending bookings
+// found.
+type VerifyResult struct {
+	Checked   int
+	Confirmed int
+	Flagged   int
+}
+
+// LaunchpadDay is what the local mirror of the SpaceX schedule holds on a
+// launchpad for one day: its status, whether a launch may fall on the day
+// and when the mirror last synced it.
+type LaunchpadDay struct {
+	Status   string
+	Taken    bool
+	SyncedAt time.Time
+}
+
+// MirrorSyncResult counts what one sync of the SpaceX mirror stored.
+type MirrorSyncResult struct {
+	Launchpads int
+	Launches   int
+}
+
+// LaunchpadClosure keeps a launchpad closed to new bookings, whatever
+// SpaceX says of it, until an admin reopens it.
+type LaunchpadClosure struct {
+	LaunchpadID string    `json:"launchpad_id" xml:"launchpad_id"`
+	Reason      string    `json:"reason,omitempty" xml:"reason,omitempty"`
+	ClosedAt    time.Time `json:"closed_at" xml:"closed_at"`
+}
+
+type LaunchpadClosureRequest struct {
+	LaunchpadID string `json:"launchpad_id" xml:"launchpad_id" validate:"required,launchpad_id_length"`
+	Reason      string `json:"reason,omitempty" xml:"reason,omitempty" validate:"max=254"`
+}
+
+type LaunchpadClosureList struct {
+	Closures []LaunchpadClosure `json:"closures" xml:"closure"`
+}
+
+// DegradedPolicy says what becomes of a new booking while SpaceX cannot be
+// asked about its launch.
+type DegradedPolicy string
+
+const (
+	// DegradedReject turns the booking down.
+	DegradedReject DegradedPolicy = "reject"
+	// DegradedPending accepts it as PENDING_VERIFICATION.
+	DegradedPending DegradedPolicy = "pending"
+	// DegradedSnapshot checks it against the last schedule seen for the
+	// launchpad, when there is one recent enough.
+	DegradedSnapshot DegradedPolicy = "snapshot"
+)
+
+func (p DegradedPolicy) IsValid() bool {
+	switch p {
+	case DegradedReject, DegradedPending, DegradedSnapshot:
+		return true
+	}
+	return false
+}
+
+// DegradedModeReport describes the degraded mode policy: the policy tried
+// first, the ones tried after it in order, and how many bookings each has
+// decided since the service started.
+type DegradedModeReport struct {
+	Policy    DegradedPolicy
+	Fallbacks []DegradedPolicy
+	Decisions map[DegradedPolicy]int64
+}
+
+// Email is a rendered message. Text is always set; HTML may be empty.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type NotificationKind string
+
+const (
+	NotifyBookingCreated     NotificationKind = "booking_created"
+	NotifyBookingCancelled   NotificationKind = "booking_cancelled"
+	NotifyBookingRescheduled NotificationKind = "booking_rescheduled"
+	NotifyLaunchReminder     NotificationKind = "launch_reminder"
+)
+
+// Notification is an email queued for a customer. The dedupe key makes
+// queueing the same notification twice a no-op.
+type Notification struct {
+	ID        int64
+	DedupeKey string
+	BookingID uuid.UUID
+	Kind      NotificationKind
+	Email     Email
+	Attempts  int
+}
+
 var (
 	ErrInvalidUUID          = errors.New("invalid uuid")
 	ErrMissingDestination   = errors.New("destination does not exist")
 	ErrLaunchPadUnavailable = errors.New("launchpad is unavailable")
 	ErrBookingNotFound      = errors.New("booking not found")
+	ErrUnauthenticated      = errors.New("authentication required")
+	ErrForbidden            = errors.New("not permitted for this role")
+	ErrInvalidRequest       = errors.New("invalid request")
+	ErrBatchConflict        = errors.New("conflicts with an earlier row in the batch")
+	ErrBatchAborted         = errors.New("batch aborted because another row failed")
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrWebhookDestination   = errors.New("webhook url must point to a public address")
+	ErrSpaceXUnavailable    = errors.New("spacex is unavailable to check the launch")
+	ErrLaunchpadNotClosed   = errors.New("launchpad is not closed")
 )
 
 type Destination struct {
-	ID   uuid.UUID `json:"id"`
-	Name string    `json:"name"`
+	ID   uuid.UUID `json:"id" xml:"id"`
+	Name string    `json:"name" xml:"name"`
+}
+
+// DestinationList is the response of the destinations listing.
+type DestinationList []Destination
+
+// MarshalXML writes each destination as a <destination> element.
+func (d DestinationList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Destinations []Destination `xml:"destination"`
+	}{d}, start)
 }
 
 type Flight struct {
-	ID          uuid.UUID   `json:"id"`
-	LaunchpadID string      `json:"launchpad_id"`
-	Destination Destination `json:"destination"`
-	LaunchDate  time.Time   `json:"launch_date"`
+	ID          uuid.UUID   `json:"id" xml:"id"`
+	LaunchpadID string      `json:"launchpad_id" xml:"launchpad_id"`
+	Destination Destination `json:"destination" xml:"destination"`
+	LaunchDate  time.Time   `json:"launch_date" xml:"launch_date"`
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Gender    string    `json:"gender"`
-	Birthday  time.Time `json:"birthday"`
+	ID        uuid.UUID `json:"id" xml:"id"`
+	FirstName string    `json:"first_name" xml:"first_name"`
+	LastName  string    `json:"last_name" xml:"last_name"`
+	Gender    string    `json:"gender" xml:"gender"`
+	Birthday  time.Time `json:"birthday" xml:"birthday"`
+	Email     string    `json:"email,omitempty" xml:"email,omitempty"`
 }
 
 type Booking struct {
-	ID        uuid.UUID     `json:"id"`
-	User      User          `json:"user"`
-	Flight    Flight        `json:"flight"`
-	Status    BookingStatus `json:"status"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID        uuid.UUID     `json:"id" xml:"id"`
+	User      User          `json:"user" xml:"user"`
+	Flight    Flight        `json:"flight" xml:"flight"`
+	Status    BookingStatus `json:"status" xml:"status"`
+	CreatedAt time.Time     `json:"created_at" xml:"created_at"`
 }
 
 type BookingResponse struct {
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+// eventKinds maps the booking events that customers are emailed about to
+// their notification.
+var eventKinds = map[models.BookingEventType]models.NotificationKind{
+	models.EventBookingCreated:     models.NotifyBookingCreated,
+	models.EventBookingCancelled:   models.NotifyBookingCancelled,
+	models.EventBookingRescheduled: models.NotifyBookingRescheduled,
+}
+
+// Sink is an outbox sink that renders an email for each booking event a
+// customer is told about and queues it for the worker. Sending happens
+// later, so a mail server being down never holds up the outbox or fails a
+// booking. Bookings without an email address are skipped.
+type Sink struct {
+	store ports.NotificationStore
+}
+
+func NewSink(store ports.NotificationStore) *Sink {
+	return &Sink{store: store}
+}
+
+func (s *Sink) Name() string { return "notifications" }
+
+func (s *Sink) Send(ctx context.Context, event outbox.CloudEvent) error {
+	kind, ok := eventKinds[models.BookingEventType(strings.TrimPrefix(event.Type, outbox.TypePrefix))]
+	if !ok {
+		return nil
+	}
+	var booking models.Booking
+	if err := json.Unmarshal(event.Data, &booking); err != nil {
+		return fmt.Errorf("decoding booking: %w", err)
+	}
+	if booking.User.Email == "" {
+		return nil
+	}
+	email, err := Render(kind, Data{Booking: booking})
+	if err != nil {
+		return err
+	}
+	_, err = s.store.EnqueueNotification(ctx, &models.Notification{
+		DedupeKey: event.ID + ":" + string(kind),
+		BookingID: booking.ID,
+		Kind:      kind,
+		Email:     email,
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Data is what every template is rendered with. Lead and Checklist are
+// only set for reminders.
+type Data struct {
+	Booking   models.Booking
+	Lead      string
+	Checklist []string
+}
+
+// Kinds lists every notification that has templates.
+var Kinds = []models.NotificationKind{
+	models.NotifyBookingCreated,
+	models.NotifyBookingCancelled,
+	models.NotifyBookingRescheduled,
+	models.NotifyLaunchReminder,
+}
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.UTC().Format("Monday, 2 January 2006") },
+}
+
+type templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var parsed = mustParse()
+
+// mustParse parses the templates of every kind at start up, so a broken
+// template stops the service from starting rather than failing sends.
+func mustParse() map[models.NotificationKind]templates {
+	m := make(map[models.NotificationKind]templates, len(Kinds))
+	for _, kind := range Kinds {
+		text := texttemplate.Must(texttemplate.New(string(kind)).Funcs(funcs).ParseFS(templateFS,
+			"templates/layout.txt.tmpl", "templates/"+string(kind)+".txt.tmpl"))
+		html := htmltemplate.Must(htmltemplate.New(string(kind)).Funcs(funcs).ParseFS(templateFS,
+			"templates/layout.html.tmpl", "templates/"+string(kind)+".html.tmpl"))
+		m[kind] = templates{text: text, html: html}
+	}
+	return m
+}
+
+// Render renders the email of kind for the booking's passenger.
+func Render(kind models.NotificationKind, data Data) (models.Email, error) {
+	t, ok := parsed[kind]
+	if !ok {
+		return models.Email{}, fmt.Errorf("no templates for notification %q", kind)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return models.Email{}, fmt.Errorf("rendering %s subject: %w", kind, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, string(kind)+".txt.tmpl", data); err != nil {
+		return models.Email{}, fmt.Errorf("rendering %s text: %w", kind, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, string(kind)+".html.tmpl", data); err != nil {
+		return models.Email{}, fmt.Errorf("rendering %s html: %w", kind, err)
+	}
+	return models.Email{
+		To:      data.Booking.User.Email,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
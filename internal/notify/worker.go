@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+// SentRecorder is told about each notification once it has been sent,
+// for records kept outside the queue such as the reminders a booking has
+// had.
+type SentRecorder interface {
+	NotificationSent(ctx context.Context, n models.Notification) error
+}
+
+// Worker sends queued notifications through a mailer. Failed sends are
+// retried with exponential backoff until maxAttempts.
+type Worker struct {
+	store     ports.NotificationStore
+	mailer    ports.Mailer
+	recorders []SentRecorder
+
+	interval    time.Duration
+	batchSize   int
+	lease       time.Duration
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	now         func() time.Time
+}
+
+type Option func(*Worker)
+
+// WithPollInterval sets how long the worker waits when nothing is due.
+func WithPollInterval(interval time.Duration) Option {
+	return func(w *Worker) { w.interval = interval }
+}
+
+// WithRetries sets how many attempts a notification gets and the backoff
+// between them, which doubles from min up to max.
+func WithRetries(maxAttempts int, min, max time.Duration) Option {
+	return func(w *Worker) { w.maxAttempts, w.minBackoff, w.maxBackoff = maxAttempts, min, max }
+}
+
+// WithSentRecorder adds a recorder told about every notification sent.
+func WithSentRecorder(recorder SentRecorder) Option {
+	return func(w *Worker) { w.recorders = append(w.recorders, recorder) }
+}
+
+// WithClock replaces time.Now for scheduling retries.
+func WithClock(now func() time.Time) Option {
+	return func(w *Worker) { w.now = now }
+}
+
+func NewWorker(store ports.NotificationStore, mailer ports.Mailer, opts ...Option) *Worker {
+	w := &Worker{
+		store:       store,
+		mailer:      mailer,
+		interval:    time.Second,
+		batchSize:   20,
+		lease:       5 * time.Minute,
+		minBackoff:  30 * time.Second,
+		maxBackoff:  time.Hour,
+		maxAttempts: 5,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run sends notifications until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		n, err := w.SendOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("notification worker: %v", err)
+		}
+		if err == nil && n == w.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+// SendOnce claims one batch of due notifications and sends each,
+// returning how many were claimed.
+func (w *Worker) SendOnce(ctx context.Context) (int, error) {
+	notifications, err := w.store.ClaimNotifications(ctx, w.batchSize, w.lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range notifications {
+		if err := w.send(ctx, n); err != nil {
+			return len(notifications), err
+		}
+	}
+	return len(notifications), nil
+}
+
+// send sends one notification and records the outcome. Only failing to
+// record it is returned as an error.
+func (w *Worker) send(ctx context.Context, n models.Notification) error {
+	err := w.mailer.Send(ctx, n.Email)
+	if err == nil {
+		if err := w.store.MarkNotificationSent(ctx, n.ID); err != nil {
+			return err
+		}
+		for _, recorder := range w.recorders {
+			if err := recorder.NotificationSent(ctx, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		// shutting down; the lease runs out and the notification is retried
+		return ctx.Err()
+	}
+
+	var next *time.Time
+	if attempts := n.Attempts + 1; attempts < w.maxAttempts {
+		at := w.now().Add(w.backoff(n.Attempts))
+		next = &at
+	}
+	if err := w.store.MarkNotificationFailed(ctx, n.ID, err.Error(), next); err != nil {
+		return err
+	}
+	if next == nil {
+		log.Printf("%s notification %d for booking %s failed for good after %d attempts: %v", n.Kind, n.ID, n.BookingID, n.Attempts+1, err)
+	}
+	return nil
+}
+
+// backoff doubles minBackoff for every earlier attempt, up to maxBackoff.
+func (w *Worker) backoff(attempts int) time.Duration {
+	b := w.minBackoff
+	for i := 0; i < attempts && b < w.maxBackoff; i++ {
+		b *= 2
+	}
+	if b > w.maxBackoff {
+		b = w.maxBackoff
+	}
+	return b
+}
@@ -0,0 +1,618 @@
+// Package openapi describes the HTTP API as an OpenAPI 3.1 document. Models
+// are described by reflecting over their json tags and request constraints
+// come from their validate tags, so the document follows the code.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/chrisdamba/spacetrouble/pkg/health"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Webhooks   map[string]*PathItem `json:"webhooks,omitempty"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// requestBodyTypes are the media types single-resource endpoints decode.
+var requestBodyTypes = []string{"application/json", "application/xml", "text/xml", "application/x-www-form-urlencoded"}
+
+var bearerAuth = []map[string][]string{{"bearerAuth": {}}}
+
+// builder collects component schemas while operations are described.
+type builder struct {
+	gen *generator
+	err error
+}
+
+func (b *builder) schemaOf(v interface{}) *Schema {
+	if b.err != nil {
+		return nil
+	}
+	s, err := b.gen.schema(reflect.TypeOf(v))
+	b.err = err
+	return s
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+func requestBody(s *Schema, mediaTypes ...string) *RequestBody {
+	content := make(map[string]MediaType, len(mediaTypes))
+	for _, mt := range mediaTypes {
+		content[mt] = MediaType{Schema: s}
+	}
+	return &RequestBody{Required: true, Content: content}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:          "The request is malformed or fails validation.",
+	http.StatusUnauthorized:        "No valid API key was presented.",
+	http.StatusForbidden:           "The API key's role does not allow this operation.",
+	http.StatusNotFound:            "The resource named in the request does not exist.",
+	http.StatusConflict:            "The launchpad is unavailable for the requested date.",
+	http.StatusNotAcceptable:       "None of the media types in Accept can represent the response.",
+	http.StatusInternalServerError: "An unexpected error occurred.",
+	http.StatusServiceUnavailable:  "SpaceX could not be asked about the launch and the degraded mode policy rejected the booking.",
+}
+
+// withErrors adds the error responses for statuses to responses. 405 and
+// 415 responses have no body.
+func withErrors(responses map[string]*Response, statuses ...int) map[string]*Response {
+	for _, status := range statuses {
+		switch status {
+		case http.StatusMethodNotAllowed:
+			responses["405"] = &Response{Description: "The method is not supported on this path."}
+		case http.StatusUnsupportedMediaType:
+			responses["415"] = &Response{Description: "The request body's media type is not supported."}
+		default:
+			responses[strconv.Itoa(status)] = &Response{
+				Description: errorDescriptions[status],
+				Content:     jsonContent(ref("ApiError")),
+			}
+		}
+	}
+	return responses
+}
+
+// filterParameters are the bookings list and export filters.
+func filterParameters() []Parameter {
+	date := &Schema{Type: "string", Description: "RFC 3339 timestamp or YYYY-MM-DD date."}
+	return []Parameter{
+		{Name: "status", In: "query", Schema: &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.BookingStatus(""))]}},
+		{Name: "destination_id", In: "query", Schema: &Schema{Type: "string", Format: "uuid"}},
+		{Name: "launchpad_id", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "last_name", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "launch_date_from", In: "query", Description: "Inclusive lower bound.", Schema: date},
+		{Name: "launch_date_to", In: "query", Description: "Exclusive upper bound.", Schema: date},
+		{Name: "created_from", In: "query", Description: "Inclusive lower bound.", Schema: date},
+		{Name: "created_to", In: "query", Description: "Exclusive upper bound.", Schema: date},
+		{Name: "sort", In: "query", Schema: &Schema{Type: "string", Enum: []string{string(models.SortByCreatedAt), string(models.SortByLaunchDate)}}},
+		{Name: "order", In: "query", Schema: &Schema{Type: "string", Enum: []string{string(models.SortAsc), string(models.SortDesc)}}},
+	}
+}
+
+// Build generates the document. It fails when a model uses a type or a
+// validate rule the generator cannot describe.
+func Build() (*Document, error) {
+	b := &builder{gen: newGenerator()}
+
+	booking := b.schemaOf(models.Booking{})
+	bookingRequest := b.schemaOf(models.BookingRequest{})
+	rescheduleRequest := b.schemaOf(models.RescheduleRequest{})
+	bookingList := b.schemaOf(models.AllBookingsResponse{})
+	batchResponse := b.schemaOf(models.BatchBookingResponse{})
+	destination := b.schemaOf(models.Destination{})
+	destinationList := b.schemaOf(models.DestinationList{})
+	destinationRequest := b.schemaOf(models.DestinationRequest{})
+	closure := b.schemaOf(models.LaunchpadClosure{})
+	closureList := b.schemaOf(models.LaunchpadClosureList{})
+	closureRequest := b.schemaOf(models.LaunchpadClosureRequest{})
+	atRiskReport := b.schemaOf(models.AtRiskReport{})
+	webhook := b.schemaOf(models.WebhookSubscription{})
+	webhookList := b.schemaOf(models.WebhookList{})
+	webhookRequest := b.schemaOf(models.WebhookRequest{})
+	deliveryList := b.schemaOf(models.WebhookDeliveryList{})
+	// described for the data of each event in the stream
+	b.schemaOf(models.BookingEvent{})
+	healthResponse := b.schemaOf(health.HealthResponse{})
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	schemas := b.gen.components
+	schemas["ApiError"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": {Type: "string"},
+			"code":  {Type: "string", Enum: api.ErrorCodes()},
+		},
+		Required: []string{"error"},
+	}
+	eventTypes := make([]string, 0, len(enums[reflect.TypeOf(models.BookingEventType(""))]))
+	for _, t := range enums[reflect.TypeOf(models.BookingEventType(""))] {
+		eventTypes = append(eventTypes, outbox.TypePrefix+t)
+	}
+	schemas["WebhookEvent"] = &Schema{
+		Type:        "object",
+		Description: "A CloudEvents 1.0 envelope around the booking that changed.",
+		Properties: map[string]*Schema{
+			"specversion":     {Type: "string", Enum: []string{"1.0"}},
+			"id":              {Type: "string", Format: "uuid", Description: "The event id; retries and redeliveries repeat it."},
+			"source":          {Type: "string", Enum: []string{outbox.Source}},
+			"type":            {Type: "string", Enum: eventTypes},
+			"subject":         {Type: "string", Format: "uuid", Description: "The booking id."},
+			"time":            {Type: "string", Format: "date-time"},
+			"datacontenttype": {Type: "string", Enum: []string{"application/json"}},
+			"data":            booking,
+		},
+		Required: []string{"specversion", "id", "source", "type", "subject", "time", "datacontenttype", "data"},
+	}
+	schemas["GraphQLRequest"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"query":         {Type: "string", Description: "A query or mutation against the schema in internal/graphql/schema.graphql."},
+			"operationName": {Type: "string", Description: "Which operation to run when query holds several."},
+			"variables":     {Type: "object"},
+		},
+		Required: []string{"query"},
+	}
+	schemas["GraphQLResponse"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data": {Type: "object", Description: "The selected fields; absent when the request could not be executed."},
+			"errors": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"message": {Type: "string"},
+					"locations": {Type: "array", Items: &Schema{
+						Type:       "object",
+						Properties: map[string]*Schema{"line": {Type: "integer"}, "column": {Type: "integer"}},
+						Required:   []string{"line", "column"},
+					}},
+					"path": {Type: "array", Description: "Field names and list indexes leading to the failed field.", Items: &Schema{}},
+					"extensions": {
+						Type: "object",
+						Properties: map[string]*Schema{
+							"code": {Type: "string", Enum: api.ErrorCodes(), Description: "The code the REST API reports for the same error."},
+						},
+						Required: []string{"code"},
+					},
+				},
+				Required: []string{"message"},
+			}},
+		},
+	}
+	webhookID := []Parameter{{Name: "id", In: "query", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}}}
+
+	listParams := append([]Parameter{
+		{Name: "id", In: "query", Description: "Fetch a single booking instead of a page.", Schema: &Schema{Type: "string", Format: "uuid"}},
+		{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1)}},
+		{Name: "cursor", In: "query", Description: "next_cursor of the previous page.", Schema: &Schema{Type: "string"}},
+		{Name: "before", In: "query", Description: "prev_cursor of the next page; cannot be combined with cursor.", Schema: &Schema{Type: "string"}},
+		{Name: "include_total", In: "query", Schema: &Schema{Type: "boolean"}},
+	}, filterParameters()...)
+	exportParams := append([]Parameter{
+		{Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []string{"csv", "ndjson"}}},
+		{Name: "columns", In: "query", Description: "Comma separated column names.", Schema: &Schema{Type: "string"}},
+	}, filterParameters()...)
+
+	batchResult := jsonContent(batchResponse)
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   "Space Trouble API",
+			Version: "1.0.0",
+			Description: "Responses are JSON by default; XML, YAML and MessagePack are available through " +
+				"the Accept header, and CSV for list responses.",
+		},
+		Paths: map[string]*PathItem{
+			"/v1/health": {
+				"get": {
+					OperationID: "getHealth",
+					Summary:     "Report service health",
+					Tags:        []string{"health"},
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The service is up.", Content: jsonContent(healthResponse)},
+					}, 405),
+				},
+			},
+			"/v1/openapi.json": {
+				"get": {
+					OperationID: "getOpenAPI",
+					Summary:     "This document",
+					Tags:        []string{"meta"},
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The OpenAPI document.", Content: jsonContent(&Schema{Type: "object"})},
+					}, 405),
+				},
+			},
+			"/debug/vars": {
+				"get": {
+					OperationID: "getMetrics",
+					Summary:     "Runtime and SpaceX cache metrics",
+					Description: "The expvar variables: spacex_cache counters, memstats and cmdline. Admins only.",
+					Tags:        []string{"meta"},
+					Security:    bearerAuth,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "Every published variable by name.", Content: jsonContent(&Schema{Type: "object"})},
+					}, 401, 403, 405),
+				},
+			},
+			"/v1/bookings": {
+				"get": {
+					OperationID: "listBookings",
+					Summary:     "List bookings, or fetch one by id",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					Parameters:  listParams,
+					Responses: withErrors(map[string]*Response{
+						"200": {
+							Description: "A page of bookings, or the booking named by id.",
+							Content:     jsonContent(&Schema{OneOf: []*Schema{bookingList, booking}}),
+						},
+					}, 400, 401, 403, 404, 405, 406, 500),
+				},
+				"post": {
+					OperationID: "createBooking",
+					Summary:     "Book a ticket",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					RequestBody: requestBody(bookingRequest, requestBodyTypes...),
+					Responses: withErrors(map[string]*Response{
+						"201": {Description: "The booking was created.", Content: jsonContent(booking)},
+					}, 400, 401, 403, 404, 405, 406, 409, 415, 500, 503),
+				},
+				"delete": {
+					OperationID: "cancelBooking",
+					Summary:     "Cancel a booking",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					Parameters:  []Parameter{{Name: "id", In: "query", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}}},
+					Responses: withErrors(map[string]*Response{
+						"204": {Description: "The booking was cancelled."},
+					}, 400, 401, 403, 404, 405, 500),
+				},
+			},
+			"/v1/bookings:batch": {
+				"post": {
+					OperationID: "importBookings",
+					Summary:     "Import many bookings",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					Parameters: []Parameter{
+						{Name: "mode", In: "query", Schema: &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.BatchMode(""))]}},
+					},
+					RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+						"application/json":     {Schema: &Schema{Type: "array", Items: bookingRequest}},
+						"application/x-ndjson": {Schema: bookingRequest},
+					}},
+					Responses: withErrors(map[string]*Response{
+						"201": {Description: "Every row was created.", Content: batchResult},
+						"207": {Description: "Some rows were created.", Content: batchResult},
+						"422": {Description: "No row was created.", Content: batchResult},
+					}, 400, 401, 403, 405, 406, 415, 500),
+				},
+			},
+			"/v1/bookings/export": {
+				"get": {
+					OperationID: "exportBookings",
+					Summary:     "Stream every matching booking",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					Parameters:  exportParams,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The bookings, one per row or line.", Content: map[string]MediaType{
+							"text/csv":             {Schema: &Schema{Type: "string"}},
+							"application/x-ndjson": {Schema: &Schema{Type: "string"}},
+						}},
+					}, 400, 401, 403, 405, 500),
+				},
+			},
+			"/v1/bookings/events": {
+				"get": {
+					OperationID: "streamBookingEvents",
+					Summary:     "Stream booking changes",
+					Description: "A Server-Sent Events stream of booking changes. Each event carries its id, its type as the event name, " +
+						"and a BookingEvent as JSON data. Send the id of the last event received as Last-Event-ID, as EventSource " +
+						"does when it reconnects, to have the events recorded after it replayed, oldest first, before the live ones. " +
+						"Customers only receive events for their own bookings. An idle stream gets a comment every 15 seconds.",
+					Tags:     []string{"bookings"},
+					Security: bearerAuth,
+					Parameters: []Parameter{{
+						Name:        "Last-Event-ID",
+						In:          "header",
+						Description: "Resume after the event with this id.",
+						Schema:      &Schema{Type: "integer", Minimum: intPtr(0)},
+					}},
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The stream, open until the client goes away.", Content: map[string]MediaType{
+							"text/event-stream": {Schema: &Schema{Type: "string", Description: "Events whose data is a BookingEvent."}},
+						}},
+					}, 400, 401, 403, 405, 500),
+				},
+			},
+			"/v1/bookings/reschedule": {
+				"post": {
+					OperationID: "rescheduleBooking",
+					Summary:     "Move a booking to a new launch date",
+					Description: "Runs the same launchpad checks as a new booking against the new date, on the booking's " +
+						"launchpad. The booking takes the status those checks decide, and any at-risk flag is cleared. " +
+						"Customers may only move their own bookings.",
+					Tags:        []string{"bookings"},
+					Security:    bearerAuth,
+					Parameters:  []Parameter{{Name: "id", In: "query", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}}},
+					RequestBody: requestBody(rescheduleRequest, requestBodyTypes...),
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The booking was moved.", Content: jsonContent(booking)},
+					}, 400, 401, 403, 404, 405, 406, 409, 415, 500, 503),
+				},
+			},
+			"/v1/bookings/at-risk": {
+				"get": {
+					OperationID: "listAtRiskBookings",
+					Summary:     "List the bookings a SpaceX launch now conflicts with",
+					Description: "Reconciliation flags a booking AT_RISK when SpaceX schedules a launch from its launchpad on " +
+						"its launch date, or the launchpad stops being usable. Each entry gives the reason, the status the booking had before, and launch dates " +
+						"nearby that are still free. Customers only see their own bookings.",
+					Tags:     []string{"bookings"},
+					Security: bearerAuth,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The at-risk bookings, soonest launch first.", Content: jsonContent(atRiskReport)},
+					}, 401, 403, 405, 406, 500),
+				},
+			},
+			"/v1/destinations": {
+				"get": {
+					OperationID: "listDestinations",
+					Summary:     "List destinations",
+					Tags:        []string{"destinations"},
+					Security:    bearerAuth,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "Every destination.", Content: jsonContent(destinationList)},
+					}, 401, 403, 405, 406, 500),
+				},
+				"post": {
+					OperationID: "createDestination",
+					Summary:     "Add a destination",
+					Tags:        []string{"destinations"},
+					Security:    bearerAuth,
+					RequestBody: requestBody(destinationRequest, requestBodyTypes...),
+					Responses: withErrors(map[string]*Response{
+						"201": {Description: "The destination was created.", Content: jsonContent(destination)},
+					}, 400, 401, 403, 405, 406, 415, 500),
+				},
+			},
+			"/v1/launchpads/closures": {
+				"get": {
+					OperationID: "listLaunchpadClosures",
+					Summary:     "List the launchpads closed to bookings",
+					Tags:        []string{"launchpads"},
+					Security:    bearerAuth,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "Every closed launchpad.", Content: jsonContent(closureList)},
+					}, 401, 403, 405, 406, 500),
+				},
+				"post": {
+					OperationID: "closeLaunchpad",
+					Summary:     "Close a launchpad to new bookings",
+					Tags:        []string{"launchpads"},
+					Security:    bearerAuth,
+					RequestBody: requestBody(closureRequest, requestBodyTypes...),
+					Responses: withErrors(map[string]*Response{
+						"201": {Description: "The launchpad is closed.", Content: jsonContent(closure)},
+					}, 400, 401, 403, 405, 406, 415, 500),
+				},
+				"delete": {
+					OperationID: "reopenLaunchpad",
+					Summary:     "Reopen a closed launchpad",
+					Tags:        []string{"launchpads"},
+					Security:    bearerAuth,
+					Parameters:  []Parameter{{Name: "launchpad_id", In: "query", Required: true, Schema: &Schema{Type: "string"}}},
+					Responses: withErrors(map[string]*Response{
+						"204": {Description: "The launchpad is open again."},
+					}, 400, 401, 403, 404, 405, 500),
+				},
+			},
+			"/v1/graphql": {
+				"post": {
+					OperationID: "graphql",
+					Summary:     "Run a GraphQL query or mutation",
+					Description: "Errors met while resolving fields are reported in errors with the status still 200, so " +
+						"partial data can be returned. Queries may nest selections at most 10 deep.",
+					Tags:        []string{"graphql"},
+					Security:    bearerAuth,
+					RequestBody: requestBody(ref("GraphQLRequest"), "application/json"),
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The result of the operation.", Content: jsonContent(ref("GraphQLResponse"))},
+					}, 400, 401, 405, 415),
+				},
+			},
+			"/v1/webhooks": {
+				"get": {
+					OperationID: "listWebhooks",
+					Summary:     "List webhook subscriptions, or fetch one by id",
+					Description: "Customers see their own subscriptions; agents and admins see every one.",
+					Tags:        []string{"webhooks"},
+					Security:    bearerAuth,
+					Parameters:  []Parameter{{Name: "id", In: "query", Description: "Fetch a single subscription.", Schema: &Schema{Type: "string", Format: "uuid"}}},
+					Responses: withErrors(map[string]*Response{
+						"200": {
+							Description: "The subscriptions, or the one named by id.",
+							Content:     jsonContent(&Schema{OneOf: []*Schema{webhookList, webhook}}),
+						},
+					}, 400, 401, 403, 404, 405, 406, 500),
+				},
+				"post": {
+					OperationID: "createWebhook",
+					Summary:     "Subscribe a url to booking events",
+					Description: "No event_types means every type. The url must resolve to a public address.",
+					Tags:        []string{"webhooks"},
+					Security:    bearerAuth,
+					RequestBody: requestBody(webhookRequest, "application/json"),
+					Responses: withErrors(map[string]*Response{
+						"201": {
+							Description: "The subscription, with the secret that signs its deliveries. The secret is not shown again.",
+							Content:     jsonContent(webhook),
+						},
+					}, 400, 401, 403, 405, 406, 415, 500),
+				},
+				"delete": {
+					OperationID: "deleteWebhook",
+					Summary:     "Unsubscribe",
+					Tags:        []string{"webhooks"},
+					Security:    bearerAuth,
+					Parameters:  webhookID,
+					Responses: withErrors(map[string]*Response{
+						"204": {Description: "The subscription was removed."},
+					}, 400, 401, 403, 404, 405, 500),
+				},
+			},
+			"/v1/webhooks/deliveries": {
+				"get": {
+					OperationID: "listWebhookDeliveries",
+					Summary:     "A subscription's most recent deliveries",
+					Tags:        []string{"webhooks"},
+					Security:    bearerAuth,
+					Parameters: append(webhookID, Parameter{
+						Name: "limit", In: "query", Schema: &Schema{Type: "integer", Minimum: intPtr(1), Maximum: intPtr(500)},
+					}),
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The deliveries, newest first.", Content: jsonContent(deliveryList)},
+					}, 400, 401, 403, 404, 405, 406, 500),
+				},
+			},
+			"/v1/webhooks/enable": {
+				"post": {
+					OperationID: "enableWebhook",
+					Summary:     "Turn a disabled subscription back on",
+					Tags:        []string{"webhooks"},
+					Security:    bearerAuth,
+					Parameters:  webhookID,
+					Responses: withErrors(map[string]*Response{
+						"200": {Description: "The subscription, active again.", Content: jsonContent(webhook)},
+					}, 400, 401, 403, 404, 405, 406, 500),
+				},
+			},
+		},
+		Webhooks: map[string]*PathItem{
+			"bookingEvent": {
+				"post": {
+					OperationID: "deliverBookingEvent",
+					Summary:     "A booking event pushed to a subscribed url",
+					Description: "Deliveries that do not get a 2xx response are retried with backoff, so receivers should " +
+						"drop events whose id they have already handled. A subscription is disabled after too many " +
+						"consecutive failures and turned back on with enableWebhook.",
+					Tags: []string{"webhooks"},
+					Parameters: []Parameter{
+						{
+							Name: webhooks.DeliveryHeader, In: "header", Required: true,
+							Description: "The delivery id, the same on every retry.",
+							Schema:      &Schema{Type: "integer"},
+						},
+						{
+							Name: webhooks.SignatureHeader, In: "header", Required: true,
+							Description: "t=<unix seconds>,v1=<hex>, where v1 is the HMAC-SHA256 of \"<t>.<body>\" keyed with " +
+								"the subscription's secret. Recompute it over the raw body, compare in constant time and " +
+								"reject old timestamps so captured deliveries cannot be replayed.",
+							Schema: &Schema{Type: "string"},
+						},
+					},
+					RequestBody: requestBody(ref("WebhookEvent"), outbox.ContentType),
+					Responses: map[string]*Response{
+						"2XX": {Description: "The event was received."},
+					},
+				},
+			},
+		},
+		Components: Components{
+			Schemas:         schemas,
+			SecuritySchemes: map[string]*SecurityScheme{"bearerAuth": {Type: "http", Scheme: "bearer"}},
+		},
+	}
+	return doc, nil
+}
+
+// Handler serves doc as JSON whatever the Accept header asks for.
+func Handler(doc *Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to encode openapi document"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of JSON Schema 2020-12 used by the document.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *int               `json:"minimum,omitempty"`
+	Maximum     *int               `json:"maximum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// enums lists the allowed values of the string types that have them.
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(models.BookingStatus("")): {string(models.StatusActive), string(models.StatusConfirmed), string(models.StatusCancelled), string(models.StatusAtRisk), string(models.StatusPendingVerification)},
+	reflect.TypeOf(models.BatchMode("")):     {string(models.BatchAtomic), string(models.BatchBestEffort)},
+	reflect.TypeOf(models.BookingEventType("")): {
+		string(models.EventBookingCreated), string(models.EventBookingCancelled),
+		string(models.EventBookingStatusChanged), string(models.EventBookingRescheduled),
+	},
+	reflect.TypeOf(models.BatchRowStatus("")): {string(models.RowCreated), string(models.RowFailed)},
+}
+
+func intPtr(i int) *int { return &i }
+
+// validateRules turns a validate tag rule into schema constraints. Every
+// rule used on a request model must be listed here, so a new rule fails
+// document generation until it is described.
+var validateRules = map[string]func(s *Schema){
+	"required":  func(s *Schema) {},
+	"omitempty": func(s *Schema) {},
+	"name_length": func(s *Schema) {
+		s.MinLength, s.MaxLength = intPtr(1), intPtr(50)
+	},
+	"gender": func(s *Schema) {
+		s.Enum = []string{"female", "male", "other"}
+	},
+	"valid_age": func(s *Schema) {
+		s.Description = "Passenger must be between 18 and 75 years old."
+	},
+	"launchpad_id_length": func(s *Schema) {
+		s.MinLength, s.MaxLength = intPtr(24), intPtr(24)
+		s.Description = "SpaceX launchpad id."
+	},
+	"valid_uuid": func(s *Schema) {
+		s.Format = "uuid"
+	},
+	"future_date": func(s *Schema) {
+		s.Description = "Must be in the future."
+	},
+	"email": func(s *Schema) {
+		s.Format = "email"
+	},
+	"max=254": func(s *Schema) {
+		s.MaxLength = intPtr(254)
+	},
+	"http_url": func(s *Schema) {
+		s.Format = "uri"
+	},
+}
+
+// ruleFor looks rule up in validateRules. oneof rules list their values
+// in the tag, so any of them is known.
+func ruleFor(rule string) (func(s *Schema), bool) {
+	if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+		return func(s *Schema) { s.Enum = strings.Fields(values) }, true
+	}
+	apply, known := validateRules[rule]
+	return apply, known
+}
+
+// generator builds schemas from Go types, collecting named structs as
+// components referenced by $ref.
+type generator struct {
+	components map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{components: make(map[string]*Schema)}
+}
+
+func (g *generator) ref(t reflect.Type) (*Schema, error) {
+	name := t.Name()
+	if _, done := g.components[name]; !done {
+		g.components[name] = nil // guards against recursion
+		s, err := g.object(t)
+		if err != nil {
+			return nil, err
+		}
+		g.components[name] = s
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}, nil
+}
+
+func (g *generator) schema(t reflect.Type) (*Schema, error) {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case t == uuidType:
+		return &Schema{Type: "string", Format: "uuid"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string", Enum: enums[t]}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Slice:
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	}
+	return nil, fmt.Errorf("no schema for type %s", t)
+}
+
+// object describes a struct the way encoding/json writes it. Fields without
+// omitempty are required; fields with a validate tag take their
+// constraints, and their required flag, from it instead.
+func (g *generator) object(t reflect.Type) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded, err := g.object(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for name, p := range embedded.Properties {
+				s.Properties[name] = p
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := g.schema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		required := !strings.Contains(opts, "omitempty")
+		if rules, ok := field.Tag.Lookup("validate"); ok {
+			required = false
+			// rules after dive constrain the elements of a slice
+			target := prop
+			for _, rule := range strings.Split(rules, ",") {
+				if rule == "dive" && target.Items != nil {
+					target = target.Items
+					continue
+				}
+				apply, known := ruleFor(rule)
+				if !known {
+					return nil, fmt.Errorf("%s.%s: undocumented validate rule %q", t.Name(), field.Name, rule)
+				}
+				apply(target)
+				required = required || rule == "required"
+			}
+		}
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+const (
+	// Source identifies this service as the producer of every event.
+	Source = "/spacetrouble/bookings"
+	// TypePrefix namespaces booking event types, so booking.created is
+	// sent as com.spacetrouble.booking.created.
+	TypePrefix = "com.spacetrouble."
+)
+
+// CloudEvent is the structured-mode JSON envelope defined by the
+// CloudEvents 1.0 specification.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// NewCloudEvent wraps an outbox event. The id is the outbox event id, so
+// consumers can drop the duplicates at-least-once delivery produces.
+func NewCloudEvent(e models.OutboxEvent) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              e.EventID.String(),
+		Source:          Source,
+		Type:            TypePrefix + string(e.Type),
+		Subject:         e.AggregateID.String(),
+		Time:            e.OccurredAt.UTC(),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(e.Payload),
+	}
+}
@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+// Relay moves events from the outbox to its sinks. An event is only
+// marked published once every sink has accepted it and is otherwise
+// retried with exponential backoff until it is, so delivery is
+// at-least-once: a sink may see an event again when another sink failed.
+// The store hands out one event per booking at a time, which keeps each
+// booking's events in order.
+type Relay struct {
+	store ports.OutboxStore
+	sinks []Sink
+
+	interval   time.Duration
+	batchSize  int
+	lease      time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+}
+
+type RelayOption func(*Relay)
+
+// WithPollInterval sets how long the relay waits when the outbox is empty.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) { r.interval = d }
+}
+
+// WithBatchSize sets how many events are claimed at once.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) { r.batchSize = n }
+}
+
+// WithBackoff sets the delay before the first retry and the cap it
+// doubles up to.
+func WithBackoff(min, max time.Duration) RelayOption {
+	return func(r *Relay) { r.minBackoff, r.maxBackoff = min, max }
+}
+
+// WithClock replaces time.Now when scheduling retries.
+func WithClock(now func() time.Time) RelayOption {
+	return func(r *Relay) { r.now = now }
+}
+
+func NewRelay(store ports.OutboxStore, sinks []Sink, opts ...RelayOption) *Relay {
+	r := &Relay{
+		store:      store,
+		sinks:      sinks,
+		interval:   time.Second,
+		batchSize:  100,
+		lease:      time.Minute,
+		minBackoff: time.Second,
+		maxBackoff: 10 * time.Minute,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays events until ctx is done. A full batch is followed straight
+// away by the next one; otherwise the relay waits for the poll interval.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RelayOnce claims one batch and delivers it, returning how many events
+// were claimed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.ClaimOutbox(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := r.deliver(ctx, e); err != nil {
+			if ctx.Err() != nil {
+				return len(events), ctx.Err()
+			}
+			next := r.now().Add(r.backoff(e.Attempts))
+			log.Printf("outbox event %s for booking %s failed (attempt %d), retrying at %s: %v",
+				e.EventID, e.AggregateID, e.Attempts+1, next.Format(time.RFC3339), err)
+			if err := r.store.MarkFailed(ctx, e.ID, next, err.Error()); err != nil {
+				return len(events), err
+			}
+			continue
+		}
+		if err := r.store.MarkPublished(ctx, e.ID); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *Relay) deliver(ctx context.Context, e models.OutboxEvent) error {
+	event := NewCloudEvent(e)
+	var errs []string
+	for _, sink := range r.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backoff doubles minBackoff for every failed attempt, up to maxBackoff.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.minBackoff
+	for i := 0; i < attempts && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	return d
+}
@@ -3,26 +3,170 @@ package ports
 import (
 	"context"
 	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
 	"time"
 )
 
 type BookingRepository interface {
 	CreateBooking(ctx context.Context, booking *models.Booking) (*models.Booking, error)
+	CreateBookings(ctx context.Context, bookings []*models.Booking) error
 	GetBookingByID(ctx context.Context, id string) (*models.Booking, error)
-	GetBookingsPaginated(ctx context.Context, afterCursor string, limit int) ([]models.Booking, string, error)
+	GetBookingsPaginated(ctx context.Context, filter models.BookingFilter, afterCursor, beforeCursor string, limit int) (models.BookingPage, error)
+	CountBookings(ctx context.Context, filter models.BookingFilter) (int, error)
+	StreamBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error
 	GetDestinationById(ctx context.Context, id string) (*models.Destination, error)
+	GetDestinations(ctx context.Context) ([]models.Destination, error)
+	GetDestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error)
+	CreateDestination(ctx context.Context, destination *models.Destination) (*models.Destination, error)
 	GetFlights(ctx context.Context, filters map[string]interface{}) ([]models.Flight, error)
 	IsLaunchPadWeekAvailable(ctx context.Context, launchpadId, destinationId string,
 		t time.Time) (bool, error)
+	RescheduleBooking(ctx context.Context, id string, launchDate time.Time, status models.BookingStatus) (*models.Booking, error)
 	DeleteBooking(ctx context.Context, id string) error
 }
 
 type BookingService interface {
 	CreateBooking(ctx context.Context, request *models.BookingRequest) (*models.Booking, error)
+	CreateBookingsBatch(ctx context.Context, requests []models.BookingRequest, mode models.BatchMode) (*models.BatchBookingResponse, error)
+	GetBooking(ctx context.Context, id string) (*models.Booking, error)
 	AllBookings(ctx context.Context, req models.GetBookingsRequest) (*models.AllBookingsResponse, error)
+	ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error
+	RescheduleBooking(ctx context.Context, id string, request *models.RescheduleRequest) (*models.Booking, error)
 	DeleteBooking(ctx context.Context, id string) error
 }
 
+type DestinationService interface {
+	AllDestinations(ctx context.Context) ([]models.Destination, error)
+	DestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error)
+	CreateDestination(ctx context.Context, request *models.DestinationRequest) (*models.Destination, error)
+}
+
 type SpaceXClient interface {
 	CheckLaunchConflict(ctx context.Context, launchpadID string, ts time.Time) (bool, error)
 }
+
+// LaunchMirror answers launch checks from the local copy of the SpaceX
+// schedule.
+type LaunchMirror interface {
+	LaunchpadDay(ctx context.Context, launchpadID string, day time.Time) (*models.LaunchpadDay, error)
+	LastMirrorSync(ctx context.Context) (time.Time, error)
+}
+
+// LaunchpadClosures tells whether an admin has closed a launchpad to new
+// bookings.
+type LaunchpadClosures interface {
+	LaunchpadClosure(ctx context.Context, launchpadID string) (*models.LaunchpadClosure, error)
+}
+
+type LaunchpadRepository interface {
+	LaunchpadClosures
+	CloseLaunchpad(ctx context.Context, closure *models.LaunchpadClosure) (*models.LaunchpadClosure, error)
+	ReopenLaunchpad(ctx context.Context, launchpadID string) error
+	ListLaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error)
+}
+
+type LaunchpadService interface {
+	CloseLaunchpad(ctx context.Context, request *models.LaunchpadClosureRequest) (*models.LaunchpadClosure, error)
+	ReopenLaunchpad(ctx context.Context, launchpadID string) error
+	LaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error)
+}
+
+// EventPublisher records booking events and hands them to subscribers.
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, event *models.BookingEvent) error
+}
+
+// EventStore reads back recorded booking events, oldest first.
+type EventStore interface {
+	EventsSince(ctx context.Context, afterID int64, limit int) ([]models.BookingEvent, error)
+}
+
+type EventService interface {
+	Subscribe(ctx context.Context, lastEventID int64) (<-chan models.BookingEvent, error)
+}
+
+// OutboxStore leases outbox events to a relay and records the outcome of
+// each delivery.
+type OutboxStore interface {
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, nextAttempt time.Time, reason string) error
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, w *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context, ownerID uuid.UUID) ([]models.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	EnableWebhook(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error)
+}
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, request *models.WebhookRequest) (*models.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	EnableWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	WebhookDeliveries(ctx context.Context, id string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// WebhookDeliveryStore queues webhook deliveries and records the outcome
+// of each attempt.
+type WebhookDeliveryStore interface {
+	EnqueueDeliveries(ctx context.Context, eventID uuid.UUID, eventType models.BookingEventType, ownerID uuid.UUID, payload []byte) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, responseStatus int) error
+	MarkDeliveryFailed(ctx context.Context, id int64, responseStatus int, reason string, nextAttempt *time.Time, disableAfter int) (bool, error)
+}
+
+// Mailer sends one email.
+type Mailer interface {
+	Send(ctx context.Context, email models.Email) error
+}
+
+// NotificationStore queues customer emails and records the outcome of
+// each send.
+type NotificationStore interface {
+	EnqueueNotification(ctx context.Context, n *models.Notification) (bool, error)
+	ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error)
+	MarkNotificationSent(ctx context.Context, id int64) error
+	MarkNotificationFailed(ctx context.Context, id int64, reason string, nextAttempt *time.Time) error
+}
+
+// ReminderStore finds bookings due a launch reminder and records the ones
+// sent.
+type ReminderStore interface {
+	DueReminders(ctx context.Context, lead string, from, to time.Time, limit int) ([]models.Booking, error)
+	RecordReminder(ctx context.Context, bookingID uuid.UUID, lead, notificationKey string) error
+}
+
+// LeaseStore hands out named leases that expire unless renewed.
+type LeaseStore interface {
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// RiskStore lists future bookings for reconciliation against the SpaceX
+// schedule and flags or clears the ones at risk.
+type RiskStore interface {
+	FutureBookings(ctx context.Context, after time.Time) ([]models.Booking, error)
+	FlagAtRisk(ctx context.Context, risk *models.BookingRisk) (bool, error)
+	ClearRisk(ctx context.Context, bookingID uuid.UUID) (*models.Booking, error)
+}
+
+// VerificationStore lists bookings accepted while SpaceX was unreachable,
+// so their launches can be checked, and confirms or flags them.
+type VerificationStore interface {
+	PendingBookings(ctx context.Context, after time.Time) ([]models.Booking, error)
+	ConfirmBooking(ctx context.Context, bookingID uuid.UUID) (*models.Booking, error)
+	FlagAtRisk(ctx context.Context, risk *models.BookingRisk) (bool, error)
+}
+
+type RiskRepository interface {
+	AtRiskBookings(ctx context.Context, userID string) ([]models.BookingRisk, error)
+}
+
+type RiskService interface {
+	AtRiskBookings(ctx context.Context) (*models.AtRiskReport, error)
+}
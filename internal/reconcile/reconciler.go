@@ -0,0 +1,270 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+)
+
+// LeaseName is the lease an instance holds while it reconciles bookings.
+const LeaseName = "booking-reconciliation"
+
+// ScheduleSource looks up a launchpad and its upcoming launches, so every
+// booking on a launchpad is checked against one lookup.
+type ScheduleSource interface {
+	LaunchSchedule(ctx context.Context, launchpadID string) (*spacex.Schedule, error)
+}
+
+// Reconciler checks future bookings against the SpaceX schedule again,
+// since SpaceX keeps adding and moving launches after a booking is made.
+// A booking now clashing with a launch is flagged AT_RISK with the reason
+// and some free dates nearby; one whose clash has gone is restored.
+type Reconciler struct {
+	store     ports.RiskStore
+	schedules ScheduleSource
+	leases    ports.LeaseStore
+	events    ports.EventPublisher
+
+	holder       string
+	interval     time.Duration
+	leaseTTL     time.Duration
+	searchDays   int
+	alternatives int
+	now          func() time.Time
+}
+
+type Option func(*Reconciler)
+
+// WithInterval sets how often bookings are reconciled.
+func WithInterval(interval time.Duration) Option {
+	return func(r *Reconciler) { r.interval = interval }
+}
+
+// WithHolder names this instance in the lease; it defaults to a random id.
+func WithHolder(holder string) Option {
+	return func(r *Reconciler) { r.holder = holder }
+}
+
+// WithClock replaces time.Now for deciding which bookings are in the
+// future and which alternative dates are still ahead.
+func WithClock(now func() time.Time) Option {
+	return func(r *Reconciler) { r.now = now }
+}
+
+// WithEventPublisher records a status change event for every booking
+// flagged or restored.
+func WithEventPublisher(events ports.EventPublisher) Option {
+	return func(r *Reconciler) { r.events = events }
+}
+
+func NewReconciler(store ports.RiskStore, schedules ScheduleSource, leases ports.LeaseStore, opts ...Option) *Reconciler {
+	r := &Reconciler{
+		store:        store,
+		schedules:    schedules,
+		leases:       leases,
+		holder:       uuid.NewString(),
+		interval:     time.Hour,
+		searchDays:   14,
+		alternatives: 3,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.leaseTTL == 0 {
+		r.leaseTTL = 3 * r.interval
+	}
+	return r
+}
+
+// Run reconciles bookings until ctx is done, then gives up the lease.
+func (r *Reconciler) Run(ctx context.Context) error {
+	defer func() {
+		if err := r.leases.ReleaseLease(context.Background(), LeaseName, r.holder); err != nil {
+			log.Printf("booking reconciler: %v", err)
+		}
+	}()
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("booking reconciler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RunOnce reconciles every future booking, if this instance holds the
+// lease. A launchpad SpaceX cannot be asked about is skipped, leaving its
+// bookings as they are, and the first such error is returned once the
+// other launchpads are done.
+func (r *Reconciler) RunOnce(ctx context.Context) (models.ReconcileResult, error) {
+	var result models.ReconcileResult
+	held, err := r.leases.AcquireLease(ctx, LeaseName, r.holder, r.leaseTTL)
+	if err != nil || !held {
+		return result, err
+	}
+
+	now := r.now()
+	bookings, err := r.store.FutureBookings(ctx, now)
+	if err != nil {
+		return result, err
+	}
+
+	var firstErr error
+	for _, group := range byLaunchpad(bookings) {
+		if err := r.reconcileLaunchpad(ctx, now, group, &result); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return result, firstErr
+}
+
+// reconcileLaunchpad checks bookings, all on one launchpad, against a
+// single lookup of its schedule.
+func (r *Reconciler) reconcileLaunchpad(ctx context.Context, now time.Time, bookings []models.Booking, result *models.ReconcileResult) error {
+	launchpadID := bookings[0].Flight.LaunchpadID
+	schedule, err := r.schedules.LaunchSchedule(ctx, launchpadID)
+	if errors.Is(err, spacex.ErrNotFound) {
+		schedule = nil
+	} else if err != nil {
+		return fmt.Errorf("launchpad %s: %w", launchpadID, err)
+	}
+
+	for _, booking := range bookings {
+		reason, err := conflict(schedule, launchpadID, booking.Flight.LaunchDate)
+		if err != nil {
+			return fmt.Errorf("launchpad %s: %w", launchpadID, err)
+		}
+		result.Checked++
+
+		if reason == "" {
+			if booking.Status == models.StatusAtRisk {
+				if err := r.clear(ctx, booking, result); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		risk := &models.BookingRisk{
+			Booking:          booking,
+			Reason:           reason,
+			AlternativeDates: r.alternativeDates(schedule, booking.Flight.LaunchDate, now),
+			FlaggedAt:        now.UTC(),
+		}
+		flagged, err := r.store.FlagAtRisk(ctx, risk)
+		if err != nil {
+			return err
+		}
+		if flagged {
+			result.Flagged++
+			r.publish(ctx, risk.Booking, risk.PreviousStatus)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) clear(ctx context.Context, booking models.Booking, result *models.ReconcileResult) error {
+	restored, err := r.store.ClearRisk(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	if restored != nil {
+		result.Cleared++
+		r.publish(ctx, *restored, models.StatusAtRisk)
+	}
+	return nil
+}
+
+// publish records a status change. The change is already stored, so a
+// failure is logged rather than returned.
+func (r *Reconciler) publish(ctx context.Context, booking models.Booking, previous models.BookingStatus) {
+	if r.events == nil {
+		return
+	}
+	event := &models.BookingEvent{
+		Type:           models.EventBookingStatusChanged,
+		Booking:        booking,
+		PreviousStatus: previous,
+		OccurredAt:     r.now().UTC(),
+	}
+	if err := r.events.PublishEvent(ctx, event); err != nil {
+		log.Printf("failed to publish %s event for booking %s: %v", event.Type, booking.ID, err)
+	}
+}
+
+// conflict explains why a launch at ts is no longer possible, or returns
+// "" when it still is. A nil schedule means SpaceX no longer knows the
+// launchpad.
+func conflict(schedule *spacex.Schedule, launchpadID string, ts time.Time) (string, error) {
+	if schedule == nil {
+		return fmt.Sprintf("launchpad %s no longer exists", launchpadID), nil
+	}
+	if !schedule.LaunchPad.IsActive() {
+		return fmt.Sprintf("launchpad %s is %s", launchpadID, schedule.LaunchPad.Status), nil
+	}
+	for _, launch := range schedule.Launches {
+		available, err := launch.IsDayAvailable(ts)
+		if err != nil {
+			return "", err
+		}
+		if !available {
+			return fmt.Sprintf("SpaceX has scheduled a launch from launchpad %s on %s (%s precision)",
+				launchpadID, time.Unix(launch.Date, 0).UTC().Format("2006-01-02"), launch.DatePrecision), nil
+		}
+	}
+	return "", nil
+}
+
+// alternativeDates suggests the launch days nearest to ts, within
+// searchDays either side, that are still ahead and clear of every SpaceX
+// launch, earliest first. They keep the booking's time of day. Only the
+// SpaceX schedule is consulted, so a suggestion can still clash with
+// another of our bookings when it is taken up.
+func (r *Reconciler) alternativeDates(schedule *spacex.Schedule, ts, now time.Time) []time.Time {
+	dates := []time.Time{}
+	if schedule == nil || !schedule.LaunchPad.IsActive() {
+		return dates
+	}
+	for offset := 1; offset <= r.searchDays && len(dates) < r.alternatives; offset++ {
+		for _, candidate := range []time.Time{ts.AddDate(0, 0, -offset), ts.AddDate(0, 0, offset)} {
+			if len(dates) == r.alternatives || !candidate.After(now) {
+				continue
+			}
+			if reason, err := conflict(schedule, schedule.LaunchPad.Id, candidate); err == nil && reason == "" {
+				dates = append(dates, candidate)
+			}
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// byLaunchpad splits bookings, already ordered by launchpad, into one
+// group per launchpad.
+func byLaunchpad(bookings []models.Booking) [][]models.Booking {
+	var groups [][]models.Booking
+	for i, booking := range bookings {
+		if i == 0 || booking.Flight.LaunchpadID != bookings[i-1].Flight.LaunchpadID {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], booking)
+	}
+	return groups
+}
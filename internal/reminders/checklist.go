@@ -0,0 +1,56 @@
+package reminders
+
+import (
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+// Lead is a reminder sent Before a launch. A reminder is only due for
+// Window after that point, so a booking made or noticed late skips to the
+// next reminder instead of getting one that no longer fits.
+type Lead struct {
+	Name   string
+	Label  string
+	Before time.Duration
+	Window time.Duration
+}
+
+const day = 24 * time.Hour
+
+// Leads are the reminders every passenger gets, earliest first.
+var Leads = []Lead{
+	{Name: "30d", Label: "30 days", Before: 30 * day, Window: 2 * day},
+	{Name: "7d", Label: "7 days", Before: 7 * day, Window: day},
+	{Name: "24h", Label: "24 hours", Before: day, Window: 6 * time.Hour},
+}
+
+// Checklist lists what the passenger still has to do at lead. Items
+// depending on the booking come first.
+func Checklist(booking models.Booking, lead Lead) []string {
+	var items []string
+	if booking.Status != models.StatusConfirmed {
+		items = append(items, "Confirm your booking with our team")
+	}
+	switch lead.Name {
+	case "30d":
+		items = append(items,
+			"Book your pre-flight medical assessment",
+			fmt.Sprintf("Check your passport is in the name %s %s and valid for six months after launch",
+				booking.User.FirstName, booking.User.LastName),
+		)
+	case "7d":
+		items = append(items,
+			"Send us your medical clearance",
+			"Pack within the 5 kg personal allowance",
+			fmt.Sprintf("Arrange travel to launchpad %s", booking.Flight.LaunchpadID),
+		)
+	case "24h":
+		items = append(items,
+			"Bring the passport you booked with",
+			fmt.Sprintf("Arrive at launchpad %s at least three hours before launch", booking.Flight.LaunchpadID),
+		)
+	}
+	return items
+}
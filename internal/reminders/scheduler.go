@@ -0,0 +1,160 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/notify"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/google/uuid"
+)
+
+// LeaseName is the lease an instance holds while it looks for reminders.
+const LeaseName = "launch-reminders"
+
+// Scheduler queues launch reminders as they fall due. Only the instance
+// holding the lease looks for them; should two ever overlap, queueing is
+// idempotent per booking and reminder, so nobody is reminded twice. A
+// reminder is recorded against its booking once the notification worker
+// has sent it, through NotificationSent.
+type Scheduler struct {
+	reminders     ports.ReminderStore
+	notifications ports.NotificationStore
+	leases        ports.LeaseStore
+
+	holder    string
+	interval  time.Duration
+	leaseTTL  time.Duration
+	batchSize int
+	now       func() time.Time
+}
+
+type Option func(*Scheduler)
+
+// WithInterval sets how often the scheduler looks for due reminders.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) { s.interval = interval }
+}
+
+// WithHolder names this instance in the lease; it defaults to a random id.
+func WithHolder(holder string) Option {
+	return func(s *Scheduler) { s.holder = holder }
+}
+
+// WithClock replaces time.Now for deciding which reminders are due.
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) { s.now = now }
+}
+
+func NewScheduler(reminders ports.ReminderStore, notifications ports.NotificationStore, leases ports.LeaseStore, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		reminders:     reminders,
+		notifications: notifications,
+		leases:        leases,
+		holder:        uuid.NewString(),
+		interval:      time.Minute,
+		batchSize:     100,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.leaseTTL == 0 {
+		// long enough to outlive a round, short enough that another
+		// instance soon takes over from one that died
+		s.leaseTTL = 3 * s.interval
+	}
+	return s
+}
+
+// Run queues reminders until ctx is done, then gives up the lease.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer func() {
+		if err := s.leases.ReleaseLease(context.Background(), LeaseName, s.holder); err != nil {
+			log.Printf("reminder scheduler: %v", err)
+		}
+	}()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("reminder scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// RunOnce queues every reminder that is due, if this instance holds the
+// lease, and returns how many were queued.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	held, err := s.leases.AcquireLease(ctx, LeaseName, s.holder, s.leaseTTL)
+	if err != nil || !held {
+		return 0, err
+	}
+	queued := 0
+	for _, lead := range Leads {
+		for {
+			n, err := s.queueDue(ctx, lead)
+			queued += n
+			if err != nil {
+				return queued, err
+			}
+			if n < s.batchSize {
+				break
+			}
+		}
+	}
+	return queued, nil
+}
+
+// queueDue queues one batch of bookings due the reminder at lead.
+func (s *Scheduler) queueDue(ctx context.Context, lead Lead) (int, error) {
+	now := s.now()
+	to := now.Add(lead.Before)
+	bookings, err := s.reminders.DueReminders(ctx, lead.Name, to.Add(-lead.Window), to, s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, booking := range bookings {
+		email, err := notify.Render(models.NotifyLaunchReminder, notify.Data{
+			Booking:   booking,
+			Lead:      lead.Label,
+			Checklist: Checklist(booking, lead),
+		})
+		if err != nil {
+			return i, err
+		}
+		_, err = s.notifications.EnqueueNotification(ctx, &models.Notification{
+			DedupeKey: NotificationKey(booking.ID, lead.Name),
+			BookingID: booking.ID,
+			Kind:      models.NotifyLaunchReminder,
+			Email:     email,
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(bookings), nil
+}
+
+// NotificationSent records a launch reminder against its booking once it
+// has been sent; other notifications are ignored. The notification worker
+// calls it, so a reminder whose sends keep failing is never recorded.
+func (s *Scheduler) NotificationSent(ctx context.Context, n models.Notification) error {
+	prefix := NotificationKey(n.BookingID, "")
+	if n.Kind != models.NotifyLaunchReminder || !strings.HasPrefix(n.DedupeKey, prefix) {
+		return nil
+	}
+	return s.reminders.RecordReminder(ctx, n.BookingID, strings.TrimPrefix(n.DedupeKey, prefix), n.DedupeKey)
+}
+
+// NotificationKey is the dedupe key of the reminder named lead for a
+// booking.
+func NotificationKey(bookingID uuid.UUID, lead string) string {
+	return "reminder:" + bookingID.String() + ":" + lead
+}
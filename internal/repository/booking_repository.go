@@ -34,34 +34,62 @@ func (r *BookingRepository) CreateBooking(ctx context.Context, booking *models.B
 	}
 	defer tx.Rollback(ctx)
 
-	// create User if not exists
-	err = r.createUserTx(ctx, tx, &booking.User)
+	if err := r.insertBookingTx(ctx, tx, booking); err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return booking, nil
+}
+
+// CreateBookings stores all bookings in a single transaction; either every
+// booking is written or none are.
+func (r *BookingRepository) CreateBookings(ctx context.Context, bookings []*models.Booking) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, booking := range bookings {
+		if err := r.insertBookingTx(ctx, tx, booking); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *BookingRepository) insertBookingTx(ctx context.Context, tx pgx.Tx, booking *models.Booking) error {
+	// create User if not exists
+	err := r.createUserTx(ctx, tx, &booking.User)
+	if err != nil {
+		return err
+	}
 
 	// create Flight if not exists
 	err = r.createFlightTx(ctx, tx, &booking.Flight)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// create Booking
 	if booking.ID == uuid.Nil {
 		booking.ID = uuid.New()
 	}
-	booking.Status = models.StatusConfirmed
-	booking.CreatedAt = time.Now().UTC()
-	err = r.createBookingTx(ctx, tx, booking)
-	if err != nil {
-		return nil, err
+	// the service decides the status, PENDING_VERIFICATION when SpaceX
+	// could not be asked; bookings that come without one are confirmed
+	if booking.Status == "" {
+		booking.Status = models.StatusConfirmed
 	}
-
-	err = tx.Commit(ctx)
-	if err != nil {
-		return nil, err
+	booking.CreatedAt = time.Now().UTC()
+	if err := r.createBookingTx(ctx, tx, booking); err != nil {
+		return err
 	}
-	return booking, nil
+	return writeOutboxTx(ctx, tx, models.EventBookingCreated, booking)
 }
 
 func (r *BookingRepository) DeleteBooking(ctx context.Context, id string) error {
@@ -71,6 +99,15 @@ func (r *BookingRepository) DeleteBooking(ctx context.Context, id string) error
 	}
 	defer tx.Rollback(ctx)
 
+	// the booking is read first so the cancellation event can carry it
+	booking, err := scanBooking(tx.QueryRow(ctx, selectBookingsQuery+` WHERE B.id = $1 FOR UPDATE OF B`, id))
+	if err == pgx.ErrNoRows {
+		return models.ErrBookingNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get booking: %w", err)
+	}
+
 	query := `DELETE FROM bookings WHERE id = $1`
 	result, err := tx.Exec(ctx, query, id)
 	if err != nil {
@@ -81,14 +118,59 @@ func (r *BookingRepository) DeleteBooking(ctx context.Context, id string) error
 		return models.ErrBookingNotFound
 	}
 
+	booking.Status = models.StatusCancelled
+	if err := writeOutboxTx(ctx, tx, models.EventBookingCancelled, &booking); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
+// RescheduleBooking moves a booking's flight to launchDate and gives the
+// booking the status the launchpad checks decided. Any at-risk flag is
+// dropped, since it was raised against the old date.
+func (r *BookingRepository) RescheduleBooking(ctx context.Context, id string, launchDate time.Time, status models.BookingStatus) (*models.Booking, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := scanBooking(tx.QueryRow(ctx, selectBookingsQuery+` WHERE B.id = $1 FOR UPDATE OF B`, id))
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE flights SET launch_date = $2 WHERE id = $1`, booking.Flight.ID, launchDate); err != nil {
+		return nil, fmt.Errorf("failed to reschedule flight: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE bookings SET status = $2 WHERE id = $1`, id, status); err != nil {
+		return nil, fmt.Errorf("failed to update booking status: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM booking_risks WHERE booking_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to clear booking risk: %w", err)
+	}
+
+	booking.Flight.LaunchDate = launchDate
+	booking.Status = status
+	if err := writeOutboxTx(ctx, tx, models.EventBookingRescheduled, &booking); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
 func (r *BookingRepository) GetBookingByID(ctx context.Context, id string) (*models.Booking, error) {
 	query := `
         SELECT 
             B.id, B.status, B.created_at,
-            U.id, U.first_name, U.last_name, U.gender, U.birthday,
+            U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
             F.id, F.launchpad_id, F.launch_date,
             D.id, D.name
         FROM bookings B
@@ -104,7 +186,7 @@ func (r *BookingRepository) GetBookingByID(ctx context.Context, id string) (*mod
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&booking.ID, &booking.Status, &booking.CreatedAt,
-		&booking.User.ID, &booking.User.FirstName, &booking.User.LastName, &booking.User.Gender, &booking.User.Birthday,
+		&booking.User.ID, &booking.User.FirstName, &booking.User.LastName, &booking.User.Gender, &booking.User.Birthday, &booking.User.Email,
 		&booking.Flight.ID, &booking.Flight.LaunchpadID, &booking.Flight.LaunchDate,
 		&destinationID, &destinationName,
 	)
@@ -124,11 +206,10 @@ func (r *BookingRepository) GetBookingByID(ctx context.Context, id string) (*mod
 	return &booking, nil
 }
 
-func (r *BookingRepository) GetBookingsPaginated(ctx context.Context, afterCursor string, limit int) ([]models.Booking, string, error) {
-	query := `
+const selectBookingsQuery = `
         SELECT 
             B.id, B.status, B.created_at,
-            U.id, U.first_name, U.last_name, U.gender, U.birthday,
+            U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
             F.id, F.launchpad_id, F.launch_date,
             D.id, D.name
         FROM bookings B
@@ -136,65 +217,223 @@ func (r *BookingRepository) GetBookingsPaginated(ctx context.Context, afterCurso
         JOIN flights F ON F.id = B.flight_id
         JOIN destinations D ON D.id = F.destination_id
     `
+
+// GetBookingsPaginated returns the page after afterCursor, or the page
+// before beforeCursor when that is set. It reads one row past limit to
+// know whether another page follows in the direction of travel.
+func (r *BookingRepository) GetBookingsPaginated(ctx context.Context, filter models.BookingFilter,
+	afterCursor, beforeCursor string, limit int) (models.BookingPage, error) {
+	var page models.BookingPage
+	if afterCursor != "" && beforeCursor != "" {
+		return page, fmt.Errorf("only one of after and before cursors may be set")
+	}
+
+	sort := filter.Sort.WithDefaults()
+	sortColumn, ok := sortColumns[sort.Field]
+	if !ok {
+		return page, fmt.Errorf("unsupported sort field %q", sort.Field)
+	}
+	// paging backwards walks the index in the opposite direction and
+	// reverses the rows afterwards
+	backward := beforeCursor != ""
+	descending := (sort.Direction == models.SortDesc) != backward
+	cmp, order := ">", ""
+	if descending {
+		cmp, order = "<", " DESC"
+	}
+
 	var args []interface{}
 	var conditions []string
 
-	if afterCursor != "" {
-		afterTime, afterUUID, err := utils.DecodeCursor(afterCursor)
+	if encoded := afterCursor + beforeCursor; encoded != "" {
+		cursor, err := utils.DecodeCursor(encoded)
 		if err != nil {
-			return nil, "", err
+			return page, err
+		}
+		if cursor.SortKey != string(sort.Field) || cursor.Direction != string(sort.Direction) {
+			return page, fmt.Errorf("cursor was issued for a different sort order")
 		}
-		conditions = append(conditions, "(B.created_at, B.id) > ($1, $2)")
-		args = append(args, afterTime, afterUUID)
+		conditions = append(conditions, fmt.Sprintf("(%s, B.id) %s ($%d, $%d)", sortColumn, cmp, len(args)+1, len(args)+2))
+		args = append(args, cursor.Value, cursor.ID)
 	}
 
+	conditions, args = appendFilterConditions(filter, conditions, args)
+
+	query := selectBookingsQuery
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY B.created_at, B.id"
+	query += fmt.Sprintf(" ORDER BY %s%s, B.id%s", sortColumn, order, order)
 	query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
-	args = append(args, limit)
+	args = append(args, limit+1)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, "", err
+		return page, err
 	}
 	defer rows.Close()
 
 	var bookings []models.Booking
-	var lastBooking models.Booking
 	for rows.Next() {
-		var booking models.Booking
-		var destinationID uuid.UUID
-		var destinationName string
-
-		err := rows.Scan(
-			&booking.ID, &booking.Status, &booking.CreatedAt,
-			&booking.User.ID, &booking.User.FirstName, &booking.User.LastName, &booking.User.Gender, &booking.User.Birthday,
-			&booking.Flight.ID, &booking.Flight.LaunchpadID, &booking.Flight.LaunchDate,
-			&destinationID, &destinationName,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
-			return nil, "", err
-		}
-		booking.Flight.Destination = models.Destination{
-			ID:   destinationID,
-			Name: destinationName,
+			return page, err
 		}
 		bookings = append(bookings, booking)
-		lastBooking = booking
 	}
 	if err = rows.Err(); err != nil {
-		return nil, "", err
+		return page, err
+	}
+
+	hasMore := len(bookings) > limit
+	if hasMore {
+		bookings = bookings[:limit]
+	}
+	if backward {
+		for i, j := 0, len(bookings)-1; i < j; i, j = i+1, j-1 {
+			bookings[i], bookings[j] = bookings[j], bookings[i]
+		}
+	}
+	page.Bookings = bookings
+	if len(bookings) == 0 {
+		return page, nil
 	}
 
-	var nextCursor string
-	if len(bookings) == limit {
-		nextCursor = utils.EncodeCursor(lastBooking.CreatedAt, lastBooking.ID)
+	encode := func(b models.Booking) string {
+		return utils.EncodeCursor(utils.Cursor{
+			SortKey:   string(sort.Field),
+			Direction: string(sort.Direction),
+			Value:     sortValue(b, sort.Field),
+			ID:        b.ID,
+		})
+	}
+	// a cursor we were handed means rows exist on its far side
+	if hasMore || backward {
+		page.NextCursor = encode(bookings[len(bookings)-1])
+	}
+	if (backward && hasMore) || afterCursor != "" {
+		page.PrevCursor = encode(bookings[0])
 	}
 
-	return bookings, nextCursor, nil
+	return page, nil
+}
+
+// StreamBookings calls fn for every booking matching filter, in sort order,
+// while the rows are read from the database. It stops at the first error
+// returned by fn.
+func (r *BookingRepository) StreamBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error {
+	sort := filter.Sort.WithDefaults()
+	sortColumn, ok := sortColumns[sort.Field]
+	if !ok {
+		return fmt.Errorf("unsupported sort field %q", sort.Field)
+	}
+	order := ""
+	if sort.Direction == models.SortDesc {
+		order = " DESC"
+	}
+
+	conditions, args := appendFilterConditions(filter, nil, nil)
+	query := selectBookingsQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s%s, B.id%s", sortColumn, order, order)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(booking); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *BookingRepository) CountBookings(ctx context.Context, filter models.BookingFilter) (int, error) {
+	conditions, args := appendFilterConditions(filter, nil, nil)
+	query := `
+        SELECT COUNT(*)
+        FROM bookings B
+        JOIN users U ON U.id = B.user_id
+        JOIN flights F ON F.id = B.flight_id
+    `
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	return count, nil
+}
+
+func scanBooking(row pgx.Row) (models.Booking, error) {
+	var booking models.Booking
+	err := row.Scan(
+		&booking.ID, &booking.Status, &booking.CreatedAt,
+		&booking.User.ID, &booking.User.FirstName, &booking.User.LastName, &booking.User.Gender, &booking.User.Birthday, &booking.User.Email,
+		&booking.Flight.ID, &booking.Flight.LaunchpadID, &booking.Flight.LaunchDate,
+		&booking.Flight.Destination.ID, &booking.Flight.Destination.Name,
+	)
+	return booking, err
+}
+
+var sortColumns = map[models.SortField]string{
+	models.SortByCreatedAt:  "B.created_at",
+	models.SortByLaunchDate: "F.launch_date",
+}
+
+func sortValue(booking models.Booking, field models.SortField) time.Time {
+	if field == models.SortByLaunchDate {
+		return booking.Flight.LaunchDate
+	}
+	return booking.CreatedAt
+}
+
+func appendFilterConditions(filter models.BookingFilter, conditions []string, args []interface{}) ([]string, []interface{}) {
+	add := func(cond string, arg interface{}) {
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)+1))
+		args = append(args, arg)
+	}
+
+	if filter.UserID != "" {
+		add("B.user_id = $%d", filter.UserID)
+	}
+	if filter.Status != "" {
+		add("B.status = $%d", filter.Status)
+	}
+	if filter.DestinationID != "" {
+		add("F.destination_id = $%d", filter.DestinationID)
+	}
+	if filter.LaunchpadID != "" {
+		add("F.launchpad_id = $%d", filter.LaunchpadID)
+	}
+	if filter.LastName != "" {
+		add("LOWER(U.last_name) = LOWER($%d)", filter.LastName)
+	}
+	if !filter.LaunchFrom.IsZero() {
+		add("F.launch_date >= $%d", filter.LaunchFrom)
+	}
+	if !filter.LaunchTo.IsZero() {
+		add("F.launch_date < $%d", filter.LaunchTo)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("B.created_at >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("B.created_at < $%d", filter.CreatedTo)
+	}
+	return conditions, args
 }
 
 func (r *BookingRepository) GetDestinationById(ctx context.Context, id string) (*models.Destination, error) {
@@ -207,6 +446,55 @@ func (r *BookingRepository) GetDestinationById(ctx context.Context, id string) (
 
 }
 
+func (r *BookingRepository) GetDestinations(ctx context.Context) ([]models.Destination, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name FROM destinations ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var destinations []models.Destination
+	for rows.Next() {
+		var dest models.Destination
+		if err := rows.Scan(&dest.ID, &dest.Name); err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, dest)
+	}
+	return destinations, rows.Err()
+}
+
+// GetDestinationsByIDs fetches the destinations with the given ids in one
+// query. Unknown ids are skipped.
+func (r *BookingRepository) GetDestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name FROM destinations WHERE id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var destinations []models.Destination
+	for rows.Next() {
+		var dest models.Destination
+		if err := rows.Scan(&dest.ID, &dest.Name); err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, dest)
+	}
+	return destinations, rows.Err()
+}
+
+func (r *BookingRepository) CreateDestination(ctx context.Context, destination *models.Destination) (*models.Destination, error) {
+	if destination.ID == uuid.Nil {
+		destination.ID = uuid.New()
+	}
+	q := `INSERT INTO destinations (id, name) VALUES ($1, $2)`
+	if _, err := r.db.Exec(ctx, q, destination.ID, destination.Name); err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	return destination, nil
+}
+
 func (r *BookingRepository) GetFlights(ctx context.Context, filters map[string]interface{}) ([]models.Flight, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -246,12 +534,13 @@ func (r *BookingRepository) createUserTx(ctx context.Context, tx pgx.Tx, user *m
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
+	// a returning customer keeps their details, but may add an email
 	query := `
-        INSERT INTO users (id, first_name, last_name, gender, birthday)
-        VALUES ($1, $2, $3, $4, $5)
-        ON CONFLICT (id) DO NOTHING
+        INSERT INTO users (id, first_name, last_name, gender, birthday, email)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+        ON CONFLICT (id) DO UPDATE SET email = COALESCE(EXCLUDED.email, users.email)
     `
-	_, err := tx.Exec(ctx, query, user.ID, user.FirstName, user.LastName, user.Gender, user.Birthday)
+	_, err := tx.Exec(ctx, query, user.ID, user.FirstName, user.LastName, user.Gender, user.Birthday, user.Email)
 	return err
 }
 
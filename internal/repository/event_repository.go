@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+// EventRepository stores booking events in booking_events, whose insert
+// trigger notifies every listening API instance.
+type EventRepository struct {
+	db DBConn
+}
+
+func NewEventRepository(db DBConn) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// PublishEvent records event and sets its ID.
+func (r *EventRepository) PublishEvent(ctx context.Context, event *models.BookingEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	query := `INSERT INTO booking_events (type, booking_id, payload, occurred_at)
+        VALUES ($1, $2, $3, $4) RETURNING id`
+	err = r.db.QueryRow(ctx, query, event.Type, event.Booking.ID, payload, event.OccurredAt).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record booking event: %w", err)
+	}
+	return nil
+}
+
+// EventsSince returns up to limit events recorded after afterID, oldest
+// first.
+func (r *EventRepository) EventsSince(ctx context.Context, afterID int64, limit int) ([]models.BookingEvent, error) {
+	query := `SELECT id, payload, occurred_at FROM booking_events
+        WHERE id > $1 ORDER BY id LIMIT $2`
+	rows, err := r.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query booking events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.BookingEvent
+	for rows.Next() {
+		var (
+			event      models.BookingEvent
+			payload    []byte
+			id         int64
+			occurredAt time.Time
+		)
+		if err := rows.Scan(&id, &payload, &occurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan booking event: %w", err)
+		}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decoding booking event %d: %w", id, err)
+		}
+		event.ID, event.OccurredAt = id, occurredAt
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
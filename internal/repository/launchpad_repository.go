@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/jackc/pgx/v5"
+)
+
+// LaunchpadRepository stores the launchpads admins have closed to new
+// bookings.
+type LaunchpadRepository struct {
+	db DBConn
+}
+
+func NewLaunchpadRepository(db DBConn) *LaunchpadRepository {
+	return &LaunchpadRepository{db: db}
+}
+
+// CloseLaunchpad closes a launchpad. Closing one already closed replaces
+// the reason and keeps the time it was first closed.
+func (r *LaunchpadRepository) CloseLaunchpad(ctx context.Context, closure *models.LaunchpadClosure) (*models.LaunchpadClosure, error) {
+	query := `
+        INSERT INTO launchpad_closures (launchpad_id, reason, closed_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (launchpad_id) DO UPDATE SET reason = EXCLUDED.reason
+        RETURNING launchpad_id, reason, closed_at
+    `
+	var closed models.LaunchpadClosure
+	err := r.db.QueryRow(ctx, query, closure.LaunchpadID, closure.Reason, closure.ClosedAt).
+		Scan(&closed.LaunchpadID, &closed.Reason, &closed.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close launchpad %s: %w", closure.LaunchpadID, err)
+	}
+	return &closed, nil
+}
+
+func (r *LaunchpadRepository) ReopenLaunchpad(ctx context.Context, launchpadID string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM launchpad_closures WHERE launchpad_id = $1`, launchpadID)
+	if err != nil {
+		return fmt.Errorf("failed to reopen launchpad %s: %w", launchpadID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return models.ErrLaunchpadNotClosed
+	}
+	return nil
+}
+
+func (r *LaunchpadRepository) ListLaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error) {
+	rows, err := r.db.Query(ctx, `SELECT launchpad_id, reason, closed_at FROM launchpad_closures ORDER BY closed_at, launchpad_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list launchpad closures: %w", err)
+	}
+	defer rows.Close()
+
+	closures := []models.LaunchpadClosure{}
+	for rows.Next() {
+		var c models.LaunchpadClosure
+		if err := rows.Scan(&c.LaunchpadID, &c.Reason, &c.ClosedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan launchpad closure: %w", err)
+		}
+		closures = append(closures, c)
+	}
+	return closures, rows.Err()
+}
+
+// LaunchpadClosure returns the launchpad's closure, or nil when it is
+// open.
+func (r *LaunchpadRepository) LaunchpadClosure(ctx context.Context, launchpadID string) (*models.LaunchpadClosure, error) {
+	var c models.LaunchpadClosure
+	err := r.db.QueryRow(ctx, `SELECT launchpad_id, reason, closed_at FROM launchpad_closures WHERE launchpad_id = $1`, launchpadID).
+		Scan(&c.LaunchpadID, &c.Reason, &c.ClosedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check launchpad %s: %w", launchpadID, err)
+	}
+	return &c, nil
+}
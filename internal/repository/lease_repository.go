@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LeaseRepository hands out named, expiring leases, so that one of several
+// instances at a time runs a periodic job.
+type LeaseRepository struct {
+	db DBConn
+}
+
+func NewLeaseRepository(db DBConn) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// AcquireLease takes or renews the lease called name for holder, reporting
+// false while another holder's lease has not expired.
+func (r *LeaseRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	query := `
+        INSERT INTO leases (name, holder, expires_at)
+        VALUES ($1, $2, now() + $3::interval)
+        ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+        WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()
+        RETURNING holder
+    `
+	var got string
+	err := r.db.QueryRow(ctx, query, name, holder, ttl).Scan(&got)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up holder's lease early, so another instance can take
+// over without waiting for it to expire.
+func (r *LeaseRepository) ReleaseLease(ctx context.Context, name, holder string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM leases WHERE name = $1 AND holder = $2`, name, holder); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/jackc/pgx/v5"
+)
+
+// MirrorRepository keeps a local copy of the SpaceX launchpads and their
+// upcoming launches, so launches can be checked without calling SpaceX.
+type MirrorRepository struct {
+	db DBConn
+}
+
+func NewMirrorRepository(db DBConn) *MirrorRepository {
+	return &MirrorRepository{db: db}
+}
+
+// ReplaceMirror stores schedules as everything SpaceX lists, dropping the
+// launchpads and launches it no longer does. A launch with a date
+// precision the mirror cannot read fails the whole sync, leaving the
+// previous copy in place.
+func (r *MirrorRepository) ReplaceMirror(ctx context.Context, schedules []spacex.Schedule, syncedAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]string, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.LaunchPad.Id
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM spacex_launchpads WHERE NOT (id = ANY($1))`, ids); err != nil {
+		return fmt.Errorf("failed to drop launchpads: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM spacex_launches`); err != nil {
+		return fmt.Errorf("failed to drop launches: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		launchpad := schedule.LaunchPad
+		query := `
+            INSERT INTO spacex_launchpads (id, status, synced_at)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, synced_at = EXCLUDED.synced_at
+        `
+		if _, err := tx.Exec(ctx, query, launchpad.Id, launchpad.Status, syncedAt); err != nil {
+			return fmt.Errorf("failed to store launchpad %s: %w", launchpad.Id, err)
+		}
+
+		for _, launch := range schedule.Launches {
+			start, end, err := launch.Window()
+			if err != nil {
+				return fmt.Errorf("launch from launchpad %s: %w", launchpad.Id, err)
+			}
+			query := `
+                INSERT INTO spacex_launches (launchpad_id, date_unix, date_precision, window_start, window_end)
+                VALUES ($1, $2, $3, $4, $5)
+            `
+			if _, err := tx.Exec(ctx, query, launchpad.Id, launch.Date, launch.DatePrecision, start, end); err != nil {
+				return fmt.Errorf("failed to store launch from launchpad %s: %w", launchpad.Id, err)
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LaunchpadDay reports the launchpad's status and whether a launch may
+// fall on the UTC day starting at day, or nil when the mirror does not
+// hold the launchpad.
+func (r *MirrorRepository) LaunchpadDay(ctx context.Context, launchpadID string, day time.Time) (*models.LaunchpadDay, error) {
+	query := `
+        SELECT P.status, P.synced_at, EXISTS (
+            SELECT 1 FROM spacex_launches L
+            WHERE L.launchpad_id = P.id
+              AND L.window_start <= $2
+              AND ($2 = L.window_start OR $2 < L.window_end)
+        )
+        FROM spacex_launchpads P
+        WHERE P.id = $1
+    `
+	var result models.LaunchpadDay
+	err := r.db.QueryRow(ctx, query, launchpadID, day).Scan(&result.Status, &result.SyncedAt, &result.Taken)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check launchpad %s: %w", launchpadID, err)
+	}
+	return &result, nil
+}
+
+// LastMirrorSync returns when the mirror was last synced, or the zero
+// time when it never has been.
+func (r *MirrorRepository) LastMirrorSync(ctx context.Context) (time.Time, error) {
+	var syncedAt *time.Time
+	if err := r.db.QueryRow(ctx, `SELECT MAX(synced_at) FROM spacex_launchpads`).Scan(&syncedAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last mirror sync: %w", err)
+	}
+	if syncedAt == nil {
+		return time.Time{}, nil
+	}
+	return *syncedAt, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+// NotificationRepository queues customer emails for the notification
+// worker and records how sending them went.
+type NotificationRepository struct {
+	db DBConn
+}
+
+func NewNotificationRepository(db DBConn) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// EnqueueNotification queues n, reporting false when a notification with
+// the same dedupe key was already queued.
+func (r *NotificationRepository) EnqueueNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	query := `
+        INSERT INTO notifications (dedupe_key, booking_id, kind, recipient, subject, text_body, html_body)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (dedupe_key) DO NOTHING
+    `
+	result, err := r.db.Exec(ctx, query, n.DedupeKey, n.BookingID, string(n.Kind),
+		n.Email.To, n.Email.Subject, n.Email.Text, n.Email.HTML)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ClaimNotifications leases up to limit pending notifications that are
+// due, oldest first.
+func (r *NotificationRepository) ClaimNotifications(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error) {
+	query := `
+        UPDATE notifications SET locked_until = now() + $2::interval
+        WHERE id IN (
+            SELECT id FROM notifications
+            WHERE status = 'pending'
+              AND next_attempt_at <= now()
+              AND (locked_until IS NULL OR locked_until < now())
+            ORDER BY id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, dedupe_key, booking_id, kind, recipient, subject, text_body, html_body, attempts
+    `
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.DedupeKey, &n.BookingID, &n.Kind,
+			&n.Email.To, &n.Email.Subject, &n.Email.Text, &n.Email.HTML, &n.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the subquery's order
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID < notifications[j].ID })
+	return notifications, nil
+}
+
+func (r *NotificationRepository) MarkNotificationSent(ctx context.Context, id int64) error {
+	query := `
+        UPDATE notifications
+        SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = now(), locked_until = NULL
+        WHERE id = $1
+    `
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkNotificationFailed records a failed send. The notification is
+// retried at nextAttempt, or given up on when nextAttempt is nil.
+func (r *NotificationRepository) MarkNotificationFailed(ctx context.Context, id int64, reason string, nextAttempt *time.Time) error {
+	query := `
+        UPDATE notifications
+        SET attempts = attempts + 1,
+            status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+            next_attempt_at = COALESCE($3::timestamptz, next_attempt_at),
+            last_error = $2, locked_until = NULL
+        WHERE id = $1
+    `
+	if _, err := r.db.Exec(ctx, query, id, reason, nextAttempt); err != nil {
+		return fmt.Errorf("failed to record notification failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// writeOutboxTx records a domain event for booking in tx, so the event is
+// stored if and only if the change is.
+func writeOutboxTx(ctx context.Context, tx pgx.Tx, eventType models.BookingEventType, booking *models.Booking) error {
+	payload, err := json.Marshal(booking)
+	if err != nil {
+		return fmt.Errorf("encoding outbox payload: %w", err)
+	}
+	query := `
+        INSERT INTO outbox (event_id, aggregate_id, type, payload, occurred_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	_, err = tx.Exec(ctx, query, uuid.New(), booking.ID, eventType, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+// OutboxRepository hands outbox events to relays and records how their
+// delivery went.
+type OutboxRepository struct {
+	db DBConn
+}
+
+func NewOutboxRepository(db DBConn) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimOutbox leases up to limit events that are due for delivery. Only
+// the oldest unpublished event of each booking can be claimed, so events
+// for one booking are delivered in order, and a lease keeps other relays
+// off an event until it expires.
+func (r *OutboxRepository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	query := `
+        UPDATE outbox SET locked_until = now() + $2::interval
+        WHERE id IN (
+            SELECT o.id FROM outbox o
+            WHERE o.published_at IS NULL
+              AND o.next_attempt_at <= now()
+              AND (o.locked_until IS NULL OR o.locked_until < now())
+              AND NOT EXISTS (
+                  SELECT 1 FROM outbox p
+                  WHERE p.aggregate_id = o.aggregate_id AND p.published_at IS NULL AND p.id < o.id
+              )
+            ORDER BY o.id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, event_id, aggregate_id, type, payload, occurred_at, attempts
+    `
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventID, &e.AggregateID, &e.Type, &e.Payload, &e.OccurredAt, &e.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the subquery's order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	query := `UPDATE outbox SET published_at = now(), locked_until = NULL, last_error = NULL WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event published: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed releases the lease and schedules the next attempt.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, nextAttempt time.Time, reason string) error {
+	query := `
+        UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3, locked_until = NULL
+        WHERE id = $1
+    `
+	if _, err := r.db.Exec(ctx, query, id, nextAttempt, reason); err != nil {
+		return fmt.Errorf("failed to record outbox failure: %w", err)
+	}
+	return nil
+}
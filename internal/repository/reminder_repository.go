@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+)
+
+// ReminderRepository finds bookings due a launch reminder and records the
+// reminders each booking has been sent.
+type ReminderRepository struct {
+	db DBConn
+}
+
+func NewReminderRepository(db DBConn) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// DueReminders returns up to limit bookings with an email address that
+// launch after from and no later than to, and have neither had the
+// reminder named lead nor have it queued.
+func (r *ReminderRepository) DueReminders(ctx context.Context, lead string, from, to time.Time, limit int) ([]models.Booking, error) {
+	query := selectBookingsQuery + `
+        WHERE F.launch_date > $1 AND F.launch_date <= $2
+          AND U.email IS NOT NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM booking_reminders R WHERE R.booking_id = B.id AND R.lead = $3::text
+          )
+          AND NOT EXISTS (
+              SELECT 1 FROM notifications N WHERE N.dedupe_key = 'reminder:' || B.id::text || ':' || $3::text
+          )
+        ORDER BY F.launch_date, B.id
+        LIMIT $4
+    `
+	rows, err := r.db.Query(ctx, query, from, to, lead, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// RecordReminder notes that the reminder named lead was sent for a
+// booking as the notification with notificationKey. Recording it twice is
+// a no-op.
+func (r *ReminderRepository) RecordReminder(ctx context.Context, bookingID uuid.UUID, lead, notificationKey string) error {
+	query := `
+        INSERT INTO booking_reminders (booking_id, lead, notification_key)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (booking_id, lead) DO NOTHING
+    `
+	if _, err := r.db.Exec(ctx, query, bookingID, lead, notificationKey); err != nil {
+		return fmt.Errorf("failed to record reminder: %w", err)
+	}
+	return nil
+}
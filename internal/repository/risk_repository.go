@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// RiskRepository flags bookings that SpaceX has since scheduled a launch
+// against, and clears them again should the conflict go away.
+type RiskRepository struct {
+	db DBConn
+}
+
+func NewRiskRepository(db DBConn) *RiskRepository {
+	return &RiskRepository{db: db}
+}
+
+// FutureBookings returns every booking not cancelled that launches after
+// the given time, grouped by launchpad.
+func (r *RiskRepository) FutureBookings(ctx context.Context, after time.Time) ([]models.Booking, error) {
+	query := selectBookingsQuery + `
+        WHERE F.launch_date > $1 AND B.status <> $2
+        ORDER BY F.launchpad_id, F.launch_date, B.id
+    `
+	rows, err := r.db.Query(ctx, query, after, models.StatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get future bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// FlagAtRisk marks risk.Booking AT_RISK and records why. It reports true
+// and fills in the flagged booking and its previous status when the
+// booking was not at risk before; for a booking already at risk only the
+// reason and alternative dates are refreshed.
+func (r *RiskRepository) FlagAtRisk(ctx context.Context, risk *models.BookingRisk) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := scanBooking(tx.QueryRow(ctx, selectBookingsQuery+` WHERE B.id = $1 FOR UPDATE OF B`, risk.Booking.ID))
+	if err == pgx.ErrNoRows {
+		// cancelled since it was read; nothing left to flag
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	query := `
+        INSERT INTO booking_risks (booking_id, reason, previous_status, alternative_dates, flagged_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (booking_id) DO UPDATE SET reason = EXCLUDED.reason, alternative_dates = EXCLUDED.alternative_dates
+    `
+	if _, err := tx.Exec(ctx, query, booking.ID, risk.Reason, booking.Status, risk.AlternativeDates, risk.FlaggedAt); err != nil {
+		return false, fmt.Errorf("failed to record booking risk: %w", err)
+	}
+
+	flagged := booking.Status != models.StatusAtRisk
+	if flagged {
+		if _, err := tx.Exec(ctx, `UPDATE bookings SET status = $2 WHERE id = $1`, booking.ID, models.StatusAtRisk); err != nil {
+			return false, fmt.Errorf("failed to flag booking: %w", err)
+		}
+		risk.PreviousStatus = booking.Status
+		booking.Status = models.StatusAtRisk
+		if err := writeOutboxTx(ctx, tx, models.EventBookingStatusChanged, &booking); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	risk.Booking = booking
+	return flagged, nil
+}
+
+// ClearRisk puts an AT_RISK booking back to the status it had before it
+// was flagged, returning the restored booking, or nil when it was not at
+// risk.
+func (r *RiskRepository) ClearRisk(ctx context.Context, bookingID uuid.UUID) (*models.Booking, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous models.BookingStatus
+	err = tx.QueryRow(ctx, `DELETE FROM booking_risks WHERE booking_id = $1 RETURNING previous_status`, bookingID).Scan(&previous)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear booking risk: %w", err)
+	}
+
+	booking, err := scanBooking(tx.QueryRow(ctx, selectBookingsQuery+` WHERE B.id = $1 FOR UPDATE OF B`, bookingID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+	if booking.Status != models.StatusAtRisk {
+		return nil, tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE bookings SET status = $2 WHERE id = $1`, bookingID, previous); err != nil {
+		return nil, fmt.Errorf("failed to restore booking: %w", err)
+	}
+	booking.Status = previous
+	if err := writeOutboxTx(ctx, tx, models.EventBookingStatusChanged, &booking); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// PendingBookings returns the bookings still PENDING_VERIFICATION that
+// launch after the given time, soonest first.
+func (r *RiskRepository) PendingBookings(ctx context.Context, after time.Time) ([]models.Booking, error) {
+	query := selectBookingsQuery + `
+        WHERE F.launch_date > $1 AND B.status = $2
+        ORDER BY F.launch_date, B.id
+    `
+	rows, err := r.db.Query(ctx, query, after, models.StatusPendingVerification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.Booking
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// ConfirmBooking makes a PENDING_VERIFICATION booking CONFIRMED, returning
+// it, or nil when it is no longer pending.
+func (r *RiskRepository) ConfirmBooking(ctx context.Context, bookingID uuid.UUID) (*models.Booking, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := scanBooking(tx.QueryRow(ctx, selectBookingsQuery+` WHERE B.id = $1 FOR UPDATE OF B`, bookingID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %w", err)
+	}
+	if booking.Status != models.StatusPendingVerification {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE bookings SET status = $2 WHERE id = $1`, bookingID, models.StatusConfirmed); err != nil {
+		return nil, fmt.Errorf("failed to confirm booking: %w", err)
+	}
+	booking.Status = models.StatusConfirmed
+	if err := writeOutboxTx(ctx, tx, models.EventBookingStatusChanged, &booking); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// AtRiskBookings lists the bookings flagged AT_RISK with the reason and
+// suggested dates, soonest launch first. An empty userID lists everyone's.
+func (r *RiskRepository) AtRiskBookings(ctx context.Context, userID string) ([]models.BookingRisk, error) {
+	query := `
+        SELECT
+            B.id, B.status, B.created_at,
+            U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
+            F.id, F.launchpad_id, F.launch_date,
+            D.id, D.name,
+            R.reason, R.previous_status, R.alternative_dates, R.flagged_at
+        FROM bookings B
+        JOIN users U ON U.id = B.user_id
+        JOIN flights F ON F.id = B.flight_id
+        JOIN destinations D ON D.id = F.destination_id
+        JOIN booking_risks R ON R.booking_id = B.id
+        WHERE B.status = $1
+    `
+	args := []interface{}{models.StatusAtRisk}
+	if userID != "" {
+		query += ` AND B.user_id = $2`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY F.launch_date, B.id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get at-risk bookings: %w", err)
+	}
+	defer rows.Close()
+
+	risks := []models.BookingRisk{}
+	for rows.Next() {
+		var risk models.BookingRisk
+		b := &risk.Booking
+		err := rows.Scan(
+			&b.ID, &b.Status, &b.CreatedAt,
+			&b.User.ID, &b.User.FirstName, &b.User.LastName, &b.User.Gender, &b.User.Birthday, &b.User.Email,
+			&b.Flight.ID, &b.Flight.LaunchpadID, &b.Flight.LaunchDate,
+			&b.Flight.Destination.ID, &b.Flight.Destination.Name,
+			&risk.Reason, &risk.PreviousStatus, &risk.AlternativeDates, &risk.FlaggedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		risks = append(risks, risk)
+	}
+	return risks, rows.Err()
+}
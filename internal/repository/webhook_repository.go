@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// WebhookRepository stores webhook subscriptions and their delivery log.
+type WebhookRepository struct {
+	db DBConn
+}
+
+func NewWebhookRepository(db DBConn) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const selectWebhooksQuery = `
+        SELECT id, owner_id, url, event_types, active, consecutive_failures, disabled_at, created_at
+        FROM webhook_subscriptions
+    `
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, w *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	w.Active = true
+	w.CreatedAt = time.Now().UTC()
+	query := `
+        INSERT INTO webhook_subscriptions (id, owner_id, url, secret, event_types, active, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	_, err := r.db.Exec(ctx, query, w.ID, nullableUUID(w.OwnerID), w.URL, w.Secret, eventTypeStrings(w.EventTypes), w.Active, w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return w, nil
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	w, err := scanWebhook(r.db.QueryRow(ctx, selectWebhooksQuery+` WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &w, nil
+}
+
+// ListWebhooks returns the subscriptions owned by ownerID, or every
+// subscription when ownerID is uuid.Nil.
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, ownerID uuid.UUID) ([]models.WebhookSubscription, error) {
+	query := selectWebhooksQuery
+	var args []interface{}
+	if ownerID != uuid.Nil {
+		query += ` WHERE owner_id = $1`
+		args = append(args, ownerID)
+	}
+	rows, err := r.db.Query(ctx, query+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.WebhookSubscription{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes a subscription together with its delivery log.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnableWebhook turns a subscription back on and clears its run of
+// failures.
+func (r *WebhookRepository) EnableWebhook(ctx context.Context, id string) error {
+	query := `
+        UPDATE webhook_subscriptions
+        SET active = true, consecutive_failures = 0, disabled_at = NULL
+        WHERE id = $1
+    `
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to enable webhook: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns a subscription's most recent deliveries first.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+        SELECT id, subscription_id, event_id, event_type, status, attempts,
+               response_status, last_error, next_attempt_at, delivered_at, created_at
+        FROM webhook_deliveries
+        WHERE subscription_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    `
+	rows, err := r.db.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var responseStatus *int
+		var lastError *string
+		var nextAttempt time.Time
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+			&responseStatus, &lastError, &nextAttempt, &d.DeliveredAt, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if responseStatus != nil {
+			d.ResponseStatus = *responseStatus
+		}
+		if lastError != nil {
+			d.LastError = *lastError
+		}
+		if d.Status == models.DeliveryPending {
+			d.NextAttemptAt = &nextAttempt
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// EnqueueDeliveries queues an event for every active subscription that
+// wants its type and may see the booking of ownerID. Enqueueing the same
+// event twice is a no-op.
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, eventID uuid.UUID, eventType models.BookingEventType, ownerID uuid.UUID, payload []byte) (int, error) {
+	query := `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+        SELECT id, $1, $2::text, $3 FROM webhook_subscriptions
+        WHERE active
+          AND (cardinality(event_types) = 0 OR $2::text = ANY(event_types))
+          AND (owner_id IS NULL OR owner_id = $4)
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+    `
+	result, err := r.db.Exec(ctx, query, eventID, string(eventType), payload, ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// ClaimDeliveries leases up to limit pending deliveries of active
+// subscriptions that are due, oldest first.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `
+        WITH claimed AS (
+            UPDATE webhook_deliveries SET locked_until = now() + $2::interval
+            WHERE id IN (
+                SELECT d.id FROM webhook_deliveries d
+                JOIN webhook_subscriptions s ON s.id = d.subscription_id
+                WHERE d.status = 'pending'
+                  AND s.active
+                  AND d.next_attempt_at <= now()
+                  AND (d.locked_until IS NULL OR d.locked_until < now())
+                ORDER BY d.id
+                LIMIT $1
+                FOR UPDATE OF d SKIP LOCKED
+            )
+            RETURNING id, subscription_id, event_id, event_type, payload, attempts, created_at
+        )
+        SELECT c.id, c.subscription_id, c.event_id, c.event_type, c.payload, c.attempts, c.created_at, s.url, s.secret
+        FROM claimed c
+        JOIN webhook_subscriptions s ON s.id = c.subscription_id
+        ORDER BY c.id
+    `
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d := models.WebhookDelivery{Status: models.DeliveryPending}
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered records a successful attempt and resets the
+// subscription's run of failures.
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	query := `
+        WITH d AS (
+            UPDATE webhook_deliveries
+            SET status = 'delivered', attempts = attempts + 1, response_status = $2,
+                last_error = NULL, delivered_at = now(), locked_until = NULL
+            WHERE id = $1
+            RETURNING subscription_id
+        )
+        UPDATE webhook_subscriptions SET consecutive_failures = 0
+        WHERE id IN (SELECT subscription_id FROM d)
+    `
+	if _, err := r.db.Exec(ctx, query, id, responseStatus); err != nil {
+		return fmt.Errorf("failed to mark webhook delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed records a failed attempt. The delivery is retried at
+// nextAttempt, or given up on when nextAttempt is nil. The subscription is
+// disabled once disableAfter attempts in a row have failed; the result
+// reports whether that happened.
+func (r *WebhookRepository) MarkDeliveryFailed(ctx context.Context, id int64, responseStatus int, reason string, nextAttempt *time.Time, disableAfter int) (bool, error) {
+	query := `
+        WITH d AS (
+            UPDATE webhook_deliveries
+            SET attempts = attempts + 1,
+                status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+                next_attempt_at = COALESCE($4::timestamptz, next_attempt_at),
+                response_status = NULLIF($2, 0), last_error = $3, locked_until = NULL
+            WHERE id = $1
+            RETURNING subscription_id
+        )
+        UPDATE webhook_subscriptions
+        SET consecutive_failures = consecutive_failures + 1,
+            active = active AND consecutive_failures + 1 < $5,
+            disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $5 THEN now() ELSE disabled_at END
+        WHERE id IN (SELECT subscription_id FROM d)
+        RETURNING NOT active
+    `
+	var disabled bool
+	err := r.db.QueryRow(ctx, query, id, responseStatus, reason, nextAttempt, disableAfter).Scan(&disabled)
+	if err == pgx.ErrNoRows {
+		// the subscription was deleted while the attempt was in flight
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return disabled, nil
+}
+
+func scanWebhook(row pgx.Row) (models.WebhookSubscription, error) {
+	var w models.WebhookSubscription
+	var owner *uuid.UUID
+	var eventTypes []string
+	err := row.Scan(&w.ID, &owner, &w.URL, &eventTypes, &w.Active, &w.ConsecutiveFailures, &w.DisabledAt, &w.CreatedAt)
+	if owner != nil {
+		w.OwnerID = *owner
+	}
+	w.EventTypes = make([]models.BookingEventType, len(eventTypes))
+	for i, t := range eventTypes {
+		w.EventTypes[i] = models.BookingEventType(t)
+	}
+	return w, err
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func eventTypeStrings(types []models.BookingEventType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/validator"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+)
+
+// launchScheduler is implemented by SpaceX clients that can return a whole
+// launchpad schedule, letting a batch check many dates with one lookup.
+type launchScheduler interface {
+	LaunchSchedule(ctx context.Context, launchpadID string) (*spacex.Schedule, error)
+}
+
+// CreateBookingsBatch validates and stores many bookings at once. Every row
+// gets the same checks as CreateBooking and is also checked against the
+// rows before it. In atomic mode nothing is stored unless every row passes.
+func (s *bookingService) CreateBookingsBatch(ctx context.Context, requests []models.BookingRequest, mode models.BatchMode) (*models.BatchBookingResponse, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("%w: unknown batch mode %q", models.ErrInvalidRequest, mode)
+	}
+
+	v := validator.NewCustomValidator()
+	launches := newLaunchLookup(s.spaceX)
+	results := make([]models.BatchRowResult, len(requests))
+	var accepted []*models.Booking
+
+	for i := range requests {
+		row := &results[i]
+		row.Index = i
+
+		if err := v.Validate(requests[i]); err != nil {
+			row.Err = fmt.Errorf("%w: %s", models.ErrInvalidRequest, err)
+			continue
+		}
+		booking, err := s.prepareBooking(ctx, principal, &requests[i], launches.check)
+		if err == nil {
+			err = batchConflict(accepted, booking)
+		}
+		if err != nil {
+			row.Err = err
+			continue
+		}
+
+		if mode == models.BatchBestEffort {
+			if booking, err = s.repo.CreateBooking(ctx, booking); err != nil {
+				row.Err = fmt.Errorf("error creating booking: %w", err)
+				continue
+			}
+			s.publish(ctx, models.EventBookingCreated, *booking, "")
+		}
+		row.Booking = booking
+		accepted = append(accepted, booking)
+	}
+
+	if mode == models.BatchAtomic {
+		s.commitAtomic(ctx, results, accepted)
+	}
+
+	response := &models.BatchBookingResponse{Mode: mode, Results: results}
+	for i := range results {
+		if results[i].Err != nil {
+			results[i].Status = models.RowFailed
+			results[i].Booking = nil
+			response.Failed++
+		} else {
+			results[i].Status = models.RowCreated
+			response.Created++
+		}
+	}
+	return response, nil
+}
+
+// commitAtomic stores the accepted bookings in one transaction when every
+// row passed, and otherwise marks the passing rows as aborted.
+func (s *bookingService) commitAtomic(ctx context.Context, results []models.BatchRowResult, accepted []*models.Booking) {
+	abort := models.ErrBatchAborted
+	if len(accepted) == len(results) && len(accepted) > 0 {
+		err := s.repo.CreateBookings(ctx, accepted)
+		if err == nil {
+			for _, booking := range accepted {
+				s.publish(ctx, models.EventBookingCreated, *booking, "")
+			}
+			return
+		}
+		abort = fmt.Errorf("error creating bookings: %w", err)
+	}
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = abort
+		}
+	}
+}
+
+// batchConflict applies the launchpad rules to the rows already accepted in
+// this batch, which the database cannot see yet.
+func batchConflict(accepted []*models.Booking, booking *models.Booking) error {
+	year, week := booking.Flight.LaunchDate.UTC().ISOWeek()
+	for _, other := range accepted {
+		if other.Flight.LaunchpadID != booking.Flight.LaunchpadID {
+			continue
+		}
+		sameDestination := other.Flight.Destination.ID == booking.Flight.Destination.ID
+		if !sameDestination && sameDay(other.Flight.LaunchDate, booking.Flight.LaunchDate) {
+			return fmt.Errorf("%w: %w: launchpad already booked for different destination on this date",
+				models.ErrLaunchPadUnavailable, models.ErrBatchConflict)
+		}
+		otherYear, otherWeek := other.Flight.LaunchDate.UTC().ISOWeek()
+		if sameDestination && otherYear == year && otherWeek == week {
+			return fmt.Errorf("%w: %w: launchpad already scheduled for this destination this week",
+				models.ErrLaunchPadUnavailable, models.ErrBatchConflict)
+		}
+	}
+	return nil
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.UTC().Truncate(24 * time.Hour).Equal(b.UTC().Truncate(24 * time.Hour))
+}
+
+func sameWeek(a, b time.Time) bool {
+	year, week := a.UTC().ISOWeek()
+	otherYear, otherWeek := b.UTC().ISOWeek()
+	return year == otherYear && week == otherWeek
+}
+
+// launchLookup shares SpaceX lookups between the rows of a batch. Clients
+// that expose launch schedules are asked once per launchpad; others are
+// asked once per launchpad and day.
+type launchLookup struct {
+	client    ports.SpaceXClient
+	schedules map[string]*spacex.Schedule
+	answers   map[string]bool
+}
+
+func newLaunchLookup(client ports.SpaceXClient) *launchLookup {
+	return &launchLookup{
+		client:    client,
+		schedules: make(map[string]*spacex.Schedule),
+		answers:   make(map[string]bool),
+	}
+}
+
+func (l *launchLookup) check(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
+	if scheduler, ok := l.client.(launchScheduler); ok {
+		schedule, found := l.schedules[launchpadID]
+		if !found {
+			var err error
+			if schedule, err = scheduler.LaunchSchedule(ctx, launchpadID); err != nil {
+				return false, err
+			}
+			l.schedules[launchpadID] = schedule
+		}
+		return schedule.IsAvailable(ts)
+	}
+
+	key := launchpadID + "|" + ts.UTC().Format(time.DateOnly)
+	if available, found := l.answers[key]; found {
+		return available, nil
+	}
+	available, err := l.client.CheckLaunchConflict(ctx, launchpadID, ts)
+	if err != nil {
+		return false, err
+	}
+	l.answers[key] = available
+	return available, nil
+}
@@ -4,84 +4,106 @@ import (
 	"context"
 	"fmt"
 	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
 	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
 	"github.com/google/uuid"
+	"log"
 	"time"
 )
 
 type bookingService struct {
-	repo   ports.BookingRepository
-	spaceX ports.SpaceXClient
+	repo     ports.BookingRepository
+	spaceX   ports.SpaceXClient
+	events   ports.EventPublisher
+	closures ports.LaunchpadClosures
+	degraded *degradedMode
 }
 
-func NewBookingService(repo ports.BookingRepository, spaceX ports.SpaceXClient) *bookingService {
-	return &bookingService{
-		repo:   repo,
-		spaceX: spaceX,
+type BookingOption func(*bookingService)
+
+// WithEventPublisher records a booking event for every booking created or
+// cancelled.
+func WithEventPublisher(events ports.EventPublisher) BookingOption {
+	return func(s *bookingService) {
+		s.events = events
+	}
+}
+
+// WithLaunchpadClosures turns down bookings on launchpads an admin has
+// closed.
+func WithLaunchpadClosures(closures ports.LaunchpadClosures) BookingOption {
+	return func(s *bookingService) {
+		s.closures = closures
+	}
+}
+
+func NewBookingService(repo ports.BookingRepository, spaceX ports.SpaceXClient, opts ...BookingOption) *bookingService {
+	s := &bookingService{
+		repo:     repo,
+		spaceX:   spaceX,
+		degraded: newDegradedMode(nil, nil, 0),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *bookingService) CreateBooking(ctx context.Context, request *models.BookingRequest) (*models.Booking, error) {
-	// validate the destination exists
-	destinationID, err := uuid.Parse(request.DestinationID)
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
 	if err != nil {
-		return nil, fmt.Errorf("invalid destination id: %w", err)
+		return nil, err
 	}
-	destination, err := s.repo.GetDestinationById(ctx, request.DestinationID)
+	booking, err := s.prepareBooking(ctx, principal, request, s.spaceX.CheckLaunchConflict)
 	if err != nil {
-		return nil, fmt.Errorf("invalid destination: %w", err)
+		return nil, err
 	}
 
-	// check if launchpad is already booked for this date
-	flights, err := s.repo.GetFlights(ctx, map[string]interface{}{
-		"launchpad_id": request.LaunchpadID,
-		"launch_date":  request.LaunchDate,
-	})
+	// persist to db
+	savedBooking, err := s.repo.CreateBooking(ctx, booking)
 	if err != nil {
-		return nil, fmt.Errorf("error checking launchpad availability: %w", err)
+		return nil, fmt.Errorf("error creating booking: %w", err)
 	}
 
-	// if flights exist for this date but different destination, launchpad is unavailable
-	if len(flights) > 0 {
-		destinationUUID, err := uuid.Parse(request.DestinationID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid destination id: %w", err)
-		}
-		if flights[0].Destination.ID != destinationUUID {
-			return nil, fmt.Errorf("launchpad already booked for different destination on this date")
-		}
+	s.publish(ctx, models.EventBookingCreated, *savedBooking, "")
+	return savedBooking, nil
+}
+
+type launchCheck func(ctx context.Context, launchpadID string, ts time.Time) (bool, error)
+
+// prepareBooking runs every check a new booking must pass and builds the
+// booking to store. checkLaunch is consulted for SpaceX conflicts.
+func (s *bookingService) prepareBooking(ctx context.Context, principal auth.Principal, request *models.BookingRequest, checkLaunch launchCheck) (*models.Booking, error) {
+	userID, err := bookingUserID(principal, request.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	// check if launchpad is already used for this destination in the same week
-	exists, err := s.repo.IsLaunchPadWeekAvailable(ctx,
-		request.LaunchpadID,
-		destinationID.String(),
-		request.LaunchDate)
+	// validate the destination exists
+	destinationID, err := uuid.Parse(request.DestinationID)
 	if err != nil {
-		return nil, fmt.Errorf("error checking weekly availability: %w", err)
+		return nil, fmt.Errorf("invalid destination id: %w", err)
 	}
-	if exists { // Note: we're checking if it exists, not if it's available
-		return nil, fmt.Errorf("launchpad already scheduled for this destination this week")
+	destination, err := s.repo.GetDestinationById(ctx, request.DestinationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %w", err)
 	}
 
-	// check SpaceX launch conflict
-	spaceXAvailable, err := s.spaceX.CheckLaunchConflict(ctx, request.LaunchpadID, request.LaunchDate)
+	status, err := s.launchStatus(ctx, request.LaunchpadID, destinationID, request.LaunchDate, nil, checkLaunch)
 	if err != nil {
-		return nil, fmt.Errorf("error checking SpaceX availability: %w", err)
-	}
-	if !spaceXAvailable {
-		return nil, fmt.Errorf("launchpad reserved by SpaceX on this date")
+		return nil, err
 	}
 
-	// create the booking
-	booking := &models.Booking{
+	return &models.Booking{
 		ID: uuid.New(),
 		User: models.User{
-			ID:        uuid.New(),
+			ID:        userID,
 			FirstName: request.FirstName,
 			LastName:  request.LastName,
 			Gender:    request.Gender,
 			Birthday:  request.Birthday,
+			Email:     request.Email,
 		},
 		Flight: models.Flight{
 			ID:          uuid.New(),
@@ -89,57 +111,239 @@ func (s *bookingService) CreateBooking(ctx context.Context, request *models.Book
 			Destination: *destination,
 			LaunchDate:  request.LaunchDate,
 		},
-		Status:    models.StatusActive,
+		Status:    status,
 		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// launchStatus runs the launchpad checks for a flight to destinationID on
+// date and decides the status of the booking on it. own is the flight of a
+// booking being rescheduled, which does not count against the new date.
+func (s *bookingService) launchStatus(ctx context.Context, launchpadID string, destinationID uuid.UUID, date time.Time, own *models.Flight, checkLaunch launchCheck) (models.BookingStatus, error) {
+	if s.closures != nil {
+		closure, err := s.closures.LaunchpadClosure(ctx, launchpadID)
+		if err != nil {
+			return "", fmt.Errorf("error checking launchpad closures: %w", err)
+		}
+		if closure != nil {
+			return "", fmt.Errorf("%w: launchpad closed to bookings", models.ErrLaunchPadUnavailable)
+		}
 	}
 
-	// persist to db
-	savedBooking, err := s.repo.CreateBooking(ctx, booking)
+	// check if launchpad is already booked for this date
+	flights, err := s.repo.GetFlights(ctx, map[string]interface{}{
+		"launchpad_id": launchpadID,
+		"launch_date":  date,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating booking: %w", err)
+		return "", fmt.Errorf("error checking launchpad availability: %w", err)
 	}
 
-	return savedBooking, nil
+	// if flights exist for this date but different destination, launchpad is unavailable
+	if len(flights) > 0 && flights[0].Destination.ID != destinationID {
+		return "", fmt.Errorf("%w: launchpad already booked for different destination on this date", models.ErrLaunchPadUnavailable)
+	}
+
+	// check if launchpad is already used for this destination in the same week
+	// a booking moved within its week would otherwise clash with itself;
+	// there can be no other flight that week, or it could not have been
+	// booked
+	available := own != nil && sameWeek(own.LaunchDate, date)
+	if !available {
+		available, err = s.repo.IsLaunchPadWeekAvailable(ctx,
+			launchpadID,
+			destinationID.String(),
+			date)
+		if err != nil {
+			return "", fmt.Errorf("error checking weekly availability: %w", err)
+		}
+	}
+	if !available {
+		return "", fmt.Errorf("%w: launchpad already scheduled for this destination this week", models.ErrLaunchPadUnavailable)
+	}
+
+	// check SpaceX launch conflict, leaving it to the degraded mode
+	// policy when SpaceX cannot be asked
+	status := models.StatusConfirmed
+	spaceXAvailable, err := checkLaunch(ctx, launchpadID, date)
+	if spacex.IsUnavailable(err) {
+		return s.degraded.decide(launchpadID, date, err)
+	} else if err != nil {
+		return "", fmt.Errorf("error checking SpaceX availability: %w", err)
+	} else if !spaceXAvailable {
+		return "", fmt.Errorf("%w: launchpad reserved by SpaceX on this date", models.ErrLaunchPadUnavailable)
+	}
+
+	return status, nil
+}
+
+// DegradedMode reports the policy for bookings made while SpaceX is
+// unavailable and how it has decided them so far.
+func (s *bookingService) DegradedMode() models.DegradedModeReport {
+	return s.degraded.report()
+}
+
+func (s *bookingService) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, models.ErrInvalidUUID
+	}
+
+	booking, err := s.repo.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// report other customers' bookings as missing rather than forbidden
+	// so ids cannot be probed
+	if !principal.Owns(booking.User.ID) {
+		return nil, models.ErrBookingNotFound
+	}
+	return booking, nil
 }
 
 func (s *bookingService) AllBookings(ctx context.Context, req models.GetBookingsRequest) (*models.AllBookingsResponse, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := req.Limit
 	if limit <= 0 {
 		limit = 10
 	}
 
-	bookings, nextCursor, err := s.repo.GetBookingsPaginated(ctx, req.Uuid, limit)
+	filter := req.Filter
+	if !principal.Can(auth.PermBookOnBehalf) {
+		filter.UserID = principal.UserID.String()
+	}
+
+	page, err := s.repo.GetBookingsPaginated(ctx, filter, req.Uuid, req.Before, limit)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching bookings: %w", err)
 	}
 
 	response := &models.AllBookingsResponse{
-		Bookings: make([]models.BookingResponse, len(bookings)),
-		Limit:    limit,
-		Cursor:   nextCursor,
+		Bookings:   make([]models.BookingResponse, len(page.Bookings)),
+		Limit:      limit,
+		Cursor:     page.NextCursor,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
 	}
 
-	for i, booking := range bookings {
+	for i, booking := range page.Bookings {
 		response.Bookings[i] = models.BookingResponse{Booking: booking}
 	}
 
+	if req.IncludeTotal {
+		total, err := s.repo.CountBookings(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("error counting bookings: %w", err)
+		}
+		response.TotalCount = &total
+	}
+
 	return response, nil
 }
 
-func (s *bookingService) DeleteBooking(ctx context.Context, id string) error {
-	if _, err := uuid.Parse(id); err != nil {
-		return models.ErrInvalidUUID
+// ExportBookings streams every booking matching filter to fn. Customers only
+// ever see their own bookings.
+func (s *bookingService) ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return err
+	}
+	if !principal.Can(auth.PermBookOnBehalf) {
+		filter.UserID = principal.UserID.String()
+	}
+	return s.repo.StreamBookings(ctx, filter, fn)
+}
+
+// RescheduleBooking moves a booking to a new launch date on the same
+// launchpad, running the same launchpad checks as a new booking.
+func (s *bookingService) RescheduleBooking(ctx context.Context, id string, request *models.RescheduleRequest) (*models.Booking, error) {
+	booking, err := s.GetBooking(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status == models.StatusCancelled {
+		return nil, fmt.Errorf("%w: cannot reschedule booking with status %s", models.ErrInvalidRequest, booking.Status)
+	}
+	if booking.Flight.LaunchDate.Equal(request.LaunchDate) {
+		return nil, fmt.Errorf("%w: booking already launches on this date", models.ErrInvalidRequest)
 	}
 
-	booking, err := s.repo.GetBookingByID(ctx, id)
+	status, err := s.launchStatus(ctx, booking.Flight.LaunchpadID, booking.Flight.Destination.ID,
+		request.LaunchDate, &booking.Flight, s.spaceX.CheckLaunchConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	rescheduled, err := s.repo.RescheduleBooking(ctx, id, request.LaunchDate, status)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, models.EventBookingRescheduled, *rescheduled, booking.Status)
+	return rescheduled, nil
+}
+
+func (s *bookingService) DeleteBooking(ctx context.Context, id string) error {
+	booking, err := s.GetBooking(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	// only allow deletion of active or confirmed bookings
-	if booking.Status != models.StatusActive && booking.Status != models.StatusConfirmed {
+	// only allow deletion of active, confirmed, at-risk or pending bookings
+	if booking.Status != models.StatusActive && booking.Status != models.StatusConfirmed &&
+		booking.Status != models.StatusAtRisk && booking.Status != models.StatusPendingVerification {
 		return fmt.Errorf("cannot delete booking with status %s", booking.Status)
 	}
 
-	return s.repo.DeleteBooking(ctx, id)
+	if err := s.repo.DeleteBooking(ctx, id); err != nil {
+		return err
+	}
+
+	cancelled := *booking
+	cancelled.Status = models.StatusCancelled
+	s.publish(ctx, models.EventBookingCancelled, cancelled, booking.Status)
+	return nil
+}
+
+// publish records a booking event. The change it describes is already
+// stored, so a failure is logged rather than returned.
+func (s *bookingService) publish(ctx context.Context, eventType models.BookingEventType, booking models.Booking, previous models.BookingStatus) {
+	if s.events == nil {
+		return
+	}
+	event := &models.BookingEvent{
+		Type:           eventType,
+		Booking:        booking,
+		PreviousStatus: previous,
+		OccurredAt:     time.Now().UTC(),
+	}
+	if err := s.events.PublishEvent(ctx, event); err != nil {
+		log.Printf("failed to publish %s event for booking %s: %v", eventType, booking.ID, err)
+	}
+}
+
+// bookingUserID picks the user a new booking belongs to. Customers always
+// book for themselves; agents and admins may name an existing customer or
+// have a new one created from the request.
+func bookingUserID(principal auth.Principal, requested string) (uuid.UUID, error) {
+	if requested == "" {
+		if principal.Can(auth.PermBookOnBehalf) {
+			return uuid.New(), nil
+		}
+		return principal.UserID, nil
+	}
+	id, err := uuid.Parse(requested)
+	if err != nil {
+		return uuid.Nil, models.ErrInvalidUUID
+	}
+	if !principal.Owns(id) {
+		return uuid.Nil, models.ErrForbidden
+	}
+	return id, nil
 }
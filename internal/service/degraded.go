@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+)
+
+// ScheduleSnapshots hands out the schedule last seen for a launchpad, and
+// when it was seen, without calling SpaceX.
+type ScheduleSnapshots interface {
+	LastKnownSchedule(launchpadID string) (*spacex.Schedule, time.Time, bool)
+}
+
+// ParseDegradedPolicy reads a comma separated list of degraded mode
+// policies, tried in order. Reject ends the list, whether it is given or
+// not.
+func ParseDegradedPolicy(value string) ([]models.DegradedPolicy, error) {
+	var policies []models.DegradedPolicy
+	for _, name := range strings.Split(value, ",") {
+		policy := models.DegradedPolicy(strings.TrimSpace(name))
+		if policy == "" {
+			continue
+		}
+		if !policy.IsValid() {
+			return nil, fmt.Errorf("unknown degraded mode policy %q", policy)
+		}
+		if policy == models.DegradedReject {
+			break
+		}
+		policies = append(policies, policy)
+	}
+	return append(policies, models.DegradedReject), nil
+}
+
+// degradedMode decides new bookings while SpaceX cannot be asked about
+// their launch, trying each policy in turn until one applies.
+type degradedMode struct {
+	policies  []models.DegradedPolicy
+	snapshots ScheduleSnapshots
+	maxAge    time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	decisions map[models.DegradedPolicy]int64
+}
+
+// WithDegradedMode decides bookings by policies, in the order given, while
+// SpaceX is unavailable. The snapshot policy applies when snapshots has a
+// schedule for the launchpad no older than maxAge; the pending policy
+// always applies, and bookings are rejected when no policy does. Without
+// it every such booking is rejected.
+func WithDegradedMode(policies []models.DegradedPolicy, snapshots ScheduleSnapshots, maxAge time.Duration) BookingOption {
+	return func(s *bookingService) {
+		s.degraded = newDegradedMode(policies, snapshots, maxAge)
+	}
+}
+
+func newDegradedMode(policies []models.DegradedPolicy, snapshots ScheduleSnapshots, maxAge time.Duration) *degradedMode {
+	if len(policies) == 0 || policies[len(policies)-1] != models.DegradedReject {
+		policies = append(policies[:len(policies):len(policies)], models.DegradedReject)
+	}
+	return &degradedMode{
+		policies:  policies,
+		snapshots: snapshots,
+		maxAge:    maxAge,
+		now:       time.Now,
+		decisions: make(map[models.DegradedPolicy]int64),
+	}
+}
+
+// decide returns the status a booking for ts on launchpadID is created
+// with. cause is why SpaceX could not be asked.
+func (d *degradedMode) decide(launchpadID string, ts time.Time, cause error) (models.BookingStatus, error) {
+	for _, policy := range d.policies {
+		switch policy {
+		case models.DegradedSnapshot:
+			if d.snapshots == nil {
+				continue
+			}
+			schedule, seenAt, ok := d.snapshots.LastKnownSchedule(launchpadID)
+			if !ok || d.now().Sub(seenAt) > d.maxAge {
+				continue
+			}
+			available, err := schedule.IsAvailable(ts)
+			if err != nil {
+				return "", fmt.Errorf("error checking SpaceX availability: %w", err)
+			}
+			d.count(policy)
+			if !available {
+				return "", fmt.Errorf("%w: launchpad reserved by SpaceX on this date, as of %s",
+					models.ErrLaunchPadUnavailable, seenAt.UTC().Format(time.RFC3339))
+			}
+			return models.StatusConfirmed, nil
+		case models.DegradedPending:
+			d.count(policy)
+			return models.StatusPendingVerification, nil
+		}
+	}
+	d.count(models.DegradedReject)
+	return "", fmt.Errorf("%w: %v", models.ErrSpaceXUnavailable, cause)
+}
+
+func (d *degradedMode) count(policy models.DegradedPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.decisions[policy]++
+}
+
+func (d *degradedMode) report() models.DegradedModeReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	decisions := make(map[models.DegradedPolicy]int64, len(d.policies))
+	for _, policy := range d.policies {
+		decisions[policy] = d.decisions[policy]
+	}
+	return models.DegradedModeReport{
+		Policy:    d.policies[0],
+		Fallbacks: append([]models.DegradedPolicy{}, d.policies[1:]...),
+		Decisions: decisions,
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/google/uuid"
+)
+
+type destinationService struct {
+	repo ports.BookingRepository
+}
+
+func NewDestinationService(repo ports.BookingRepository) *destinationService {
+	return &destinationService{repo: repo}
+}
+
+func (s *destinationService) AllDestinations(ctx context.Context) ([]models.Destination, error) {
+	if _, err := auth.Require(ctx, auth.PermBookOwn); err != nil {
+		return nil, err
+	}
+	destinations, err := s.repo.GetDestinations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching destinations: %w", err)
+	}
+	return destinations, nil
+}
+
+// DestinationsByIDs looks up several destinations at once; ids that do not
+// exist are left out of the result.
+func (s *destinationService) DestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error) {
+	if _, err := auth.Require(ctx, auth.PermBookOwn); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("%w: destination id %q", models.ErrInvalidUUID, id)
+		}
+	}
+	destinations, err := s.repo.GetDestinationsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching destinations: %w", err)
+	}
+	return destinations, nil
+}
+
+func (s *destinationService) CreateDestination(ctx context.Context, request *models.DestinationRequest) (*models.Destination, error) {
+	if _, err := auth.Require(ctx, auth.PermManageDestinations); err != nil {
+		return nil, err
+	}
+	destination, err := s.repo.CreateDestination(ctx, &models.Destination{Name: request.Name})
+	if err != nil {
+		return nil, fmt.Errorf("error creating destination: %w", err)
+	}
+	return destination, nil
+}
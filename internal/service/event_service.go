@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+// replayBatch is how many past events are read per query when resuming.
+const replayBatch = 100
+
+// eventSource is the live side of the event stream.
+type eventSource interface {
+	Subscribe() (<-chan models.BookingEvent, func())
+}
+
+type eventService struct {
+	live  eventSource
+	store ports.EventStore
+}
+
+func NewEventService(live eventSource, store ports.EventStore) *eventService {
+	return &eventService{live: live, store: store}
+}
+
+// Subscribe streams booking events until ctx is done. With a lastEventID
+// the events recorded after it are replayed first. Customers only see
+// events for their own bookings. The channel is closed when the stream
+// ends, including when a slow subscriber is dropped.
+func (s *eventService) Subscribe(ctx context.Context, lastEventID int64) (<-chan models.BookingEvent, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	visible := func(e models.BookingEvent) bool {
+		return principal.Owns(e.Booking.User.ID)
+	}
+
+	// subscribe before replaying so nothing recorded in between is missed
+	live, cancel := s.live.Subscribe()
+	var backlog []models.BookingEvent
+	if lastEventID > 0 {
+		if backlog, err = s.store.EventsSince(ctx, lastEventID, replayBatch); err != nil {
+			cancel()
+			return nil, fmt.Errorf("error reading booking events: %w", err)
+		}
+	}
+	out := make(chan models.BookingEvent)
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		send := func(e models.BookingEvent) bool {
+			if !visible(e) {
+				return true
+			}
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed := lastEventID
+		for len(backlog) > 0 {
+			for _, e := range backlog {
+				if !send(e) {
+					return
+				}
+				replayed = e.ID
+			}
+			if len(backlog) < replayBatch {
+				break
+			}
+			next, err := s.store.EventsSince(ctx, replayed, replayBatch)
+			if err != nil {
+				return
+			}
+			backlog = next
+		}
+
+		for {
+			select {
+			case e, ok := <-live:
+				if !ok {
+					return
+				}
+				if e.ID <= replayed {
+					continue
+				}
+				if !send(e) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
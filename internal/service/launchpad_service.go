@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+// launchpadService lets admins close launchpads to new bookings and
+// reopen them. Bookings already made on a closed launchpad are kept.
+type launchpadService struct {
+	repo ports.LaunchpadRepository
+}
+
+func NewLaunchpadService(repo ports.LaunchpadRepository) *launchpadService {
+	return &launchpadService{repo: repo}
+}
+
+func (s *launchpadService) CloseLaunchpad(ctx context.Context, request *models.LaunchpadClosureRequest) (*models.LaunchpadClosure, error) {
+	if _, err := auth.Require(ctx, auth.PermManageLaunchpads); err != nil {
+		return nil, err
+	}
+	closure, err := s.repo.CloseLaunchpad(ctx, &models.LaunchpadClosure{
+		LaunchpadID: request.LaunchpadID,
+		Reason:      request.Reason,
+		ClosedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error closing launchpad: %w", err)
+	}
+	return closure, nil
+}
+
+func (s *launchpadService) ReopenLaunchpad(ctx context.Context, launchpadID string) error {
+	if _, err := auth.Require(ctx, auth.PermManageLaunchpads); err != nil {
+		return err
+	}
+	return s.repo.ReopenLaunchpad(ctx, launchpadID)
+}
+
+func (s *launchpadService) LaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error) {
+	if _, err := auth.Require(ctx, auth.PermManageLaunchpads); err != nil {
+		return nil, err
+	}
+	closures, err := s.repo.ListLaunchpadClosures(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing launchpad closures: %w", err)
+	}
+	return closures, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+)
+
+// WithLaunchMirror checks launches against the local mirror of the SpaceX
+// schedule, asking SpaceX itself only when the mirror was last synced
+// more than maxAge ago or cannot be read.
+func WithLaunchMirror(mirror ports.LaunchMirror, maxAge time.Duration) BookingOption {
+	return func(s *bookingService) {
+		s.spaceX = &mirrorChecker{mirror: mirror, live: s.spaceX, maxAge: maxAge, now: time.Now}
+	}
+}
+
+// mirrorChecker is a ports.SpaceXClient reading the mirror while it is
+// fresh.
+type mirrorChecker struct {
+	mirror ports.LaunchMirror
+	live   ports.SpaceXClient
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+func (m *mirrorChecker) CheckLaunchConflict(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
+	// the live check reports bad input the way callers expect
+	if launchpadID == "" || ts.Before(m.now()) {
+		return m.live.CheckLaunchConflict(ctx, launchpadID, ts)
+	}
+
+	day, err := m.mirror.LaunchpadDay(ctx, launchpadID, spacex.StartOfDay(ts))
+	if err != nil {
+		log.Printf("spacex mirror: %v; asking SpaceX", err)
+		return m.live.CheckLaunchConflict(ctx, launchpadID, ts)
+	}
+
+	if day == nil {
+		// unknown to a fresh mirror means unknown to SpaceX
+		syncedAt, err := m.mirror.LastMirrorSync(ctx)
+		if err != nil {
+			log.Printf("spacex mirror: %v; asking SpaceX", err)
+			return m.live.CheckLaunchConflict(ctx, launchpadID, ts)
+		}
+		if !m.fresh(syncedAt) {
+			return m.live.CheckLaunchConflict(ctx, launchpadID, ts)
+		}
+		return false, fmt.Errorf("checking launchpad: %w", spacex.ErrNotFound)
+	}
+
+	if !m.fresh(day.SyncedAt) {
+		return m.live.CheckLaunchConflict(ctx, launchpadID, ts)
+	}
+	launchpad := spacex.LaunchPad{Id: launchpadID, Status: day.Status}
+	return launchpad.IsActive() && !day.Taken, nil
+}
+
+func (m *mirrorChecker) fresh(syncedAt time.Time) bool {
+	return !syncedAt.IsZero() && m.now().Sub(syncedAt) <= m.maxAge
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+)
+
+type riskService struct {
+	repo ports.RiskRepository
+}
+
+func NewRiskService(repo ports.RiskRepository) *riskService {
+	return &riskService{repo: repo}
+}
+
+// AtRiskBookings reports the bookings flagged AT_RISK by reconciliation.
+// Customers only ever see their own.
+func (s *riskService) AtRiskBookings(ctx context.Context) (*models.AtRiskReport, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	userID := ""
+	if !principal.Can(auth.PermBookOnBehalf) {
+		userID = principal.UserID.String()
+	}
+	risks, err := s.repo.AtRiskBookings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching at-risk bookings: %w", err)
+	}
+	return &models.AtRiskReport{Bookings: risks}, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// secretPrefix marks webhook signing secrets so they are recognisable in
+// config files and logs.
+const secretPrefix = "whsec_"
+
+type webhookService struct {
+	repo     ports.WebhookRepository
+	resolver webhooks.Resolver
+}
+
+type WebhookOption func(*webhookService)
+
+// WithResolver replaces net.DefaultResolver for checking that webhook
+// hosts resolve to public addresses.
+func WithResolver(resolver webhooks.Resolver) WebhookOption {
+	return func(s *webhookService) { s.resolver = resolver }
+}
+
+func NewWebhookService(repo ports.WebhookRepository, opts ...WebhookOption) *webhookService {
+	s := &webhookService{repo: repo, resolver: net.DefaultResolver}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateWebhook subscribes an endpoint, which must be on the public
+// internet. Customers' subscriptions only receive events for their own
+// bookings; agents and admins receive all.
+func (s *webhookService) CreateWebhook(ctx context.Context, request *models.WebhookRequest) (*models.WebhookSubscription, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	if err := webhooks.CheckDestination(ctx, s.resolver, request.URL); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := &models.WebhookSubscription{
+		URL:        request.URL,
+		EventTypes: uniqueEventTypes(request.EventTypes),
+		Secret:     secret,
+	}
+	if !principal.Can(auth.PermBookOnBehalf) {
+		webhook.OwnerID = principal.UserID
+	}
+	created, err := s.repo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook: %w", err)
+	}
+	return created, nil
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	return s.visibleWebhook(ctx, principal, id)
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	owner := principal.UserID
+	if principal.Can(auth.PermBookOnBehalf) {
+		owner = uuid.Nil
+	} else if owner == uuid.Nil {
+		return []models.WebhookSubscription{}, nil
+	}
+	webhooks, err := s.repo.ListWebhooks(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return err
+	}
+	if _, err := s.visibleWebhook(ctx, principal, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteWebhook(ctx, id); err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	return nil
+}
+
+// EnableWebhook turns a subscription that was disabled after failing too
+// often back on, with a clean run of failures. Its pending deliveries are
+// sent again. The endpoint is checked again first, as its host may have
+// moved since it was subscribed.
+func (s *webhookService) EnableWebhook(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := s.visibleWebhook(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := webhooks.CheckDestination(ctx, s.resolver, webhook.URL); err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableWebhook(ctx, id); err != nil {
+		return nil, fmt.Errorf("error enabling webhook: %w", err)
+	}
+	webhook.Active = true
+	webhook.ConsecutiveFailures = 0
+	webhook.DisabledAt = nil
+	return webhook, nil
+}
+
+// WebhookDeliveries returns the most recent deliveries to a subscription.
+func (s *webhookService) WebhookDeliveries(ctx context.Context, id string, limit int) ([]models.WebhookDelivery, error) {
+	principal, err := auth.Require(ctx, auth.PermBookOwn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.visibleWebhook(ctx, principal, id); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.repo.ListDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// visibleWebhook loads a subscription the principal may see. Other
+// customers' subscriptions are reported as not found.
+func (s *webhookService) visibleWebhook(ctx context.Context, principal auth.Principal, id string) (*models.WebhookSubscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("%w: webhook id %q", models.ErrInvalidUUID, id)
+	}
+	webhook, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Owns(webhook.OwnerID) {
+		return nil, models.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating webhook secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+func uniqueEventTypes(types []models.BookingEventType) []models.BookingEventType {
+	seen := make(map[models.BookingEventType]bool, len(types))
+	out := []models.BookingEventType{}
+	for _, t := range types {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
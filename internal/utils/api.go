@@ -15,6 +15,7 @@ import (
 type ApiError struct {
 	StatusCode int    `json:"-"`
 	Msg        string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"`
 }
 type ContentType string
 
@@ -22,11 +23,15 @@ type XMLResponse struct {
 	XMLName xml.Name    `xml:"response"`
 	Data    interface{} `xml:"data,omitempty"`
 	Error   string      `xml:"error,omitempty"`
+	Code    string      `xml:"code,omitempty"`
 }
 
 const (
-	ContentTypeJSON ContentType = "application/json"
-	ContentTypeXML  ContentType = "application/xml"
+	ContentTypeJSON    ContentType = "application/json"
+	ContentTypeXML     ContentType = "application/xml"
+	ContentTypeYAML    ContentType = "application/yaml"
+	ContentTypeMsgPack ContentType = "application/msgpack"
+	ContentTypeCSV     ContentType = "text/csv"
 )
 
 func (o *ApiError) Error() string {
@@ -42,23 +47,19 @@ func JsonDecodeBody(r *http.Request, dst interface{}) error {
 }
 
 func NewInternalServerError(msg string) ApiError {
-	return ApiError{http.StatusInternalServerError, msg}
+	return ApiError{StatusCode: http.StatusInternalServerError, Msg: msg}
 }
 
 func NewBadRequest(msg string) ApiError {
-	return ApiError{http.StatusBadRequest, msg}
+	return ApiError{StatusCode: http.StatusBadRequest, Msg: msg}
 }
 
-func RenderResponse(r *http.Request, w http.ResponseWriter, statusCode int, res interface{}) {
-	contentType := getResponseContentType(r)
-	switch contentType {
-	case ContentTypeJSON:
-		renderJson(w, statusCode, res)
-	case ContentTypeXML:
-		renderXML(w, statusCode, res)
-	default:
-		renderJson(w, http.StatusUnsupportedMediaType, nil)
-	}
+func NewUnauthorized(msg string) ApiError {
+	return ApiError{StatusCode: http.StatusUnauthorized, Msg: msg}
+}
+
+func NewForbidden(msg string) ApiError {
+	return ApiError{StatusCode: http.StatusForbidden, Msg: msg}
 }
 
 func AllowedMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
@@ -72,10 +73,17 @@ func AllowedMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
 	}
 }
 
+// AllowedContentTypes rejects request bodies whose media type is not one of
+// mediaTypes. Parameters such as charset are ignored, and requests that
+// carry no body type on methods without a body are let through.
 func AllowedContentTypes(next http.HandlerFunc, mediaTypes ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		found := existsInSlice(mediaTypes, r.Header.Get("content-type"))
-		if found {
+		if r.Header.Get("Content-Type") == "" && !methodHasBody(r.Method) {
+			next(w, r)
+			return
+		}
+		mediaType, err := MediaType(r)
+		if err == nil && existsInSlice(mediaTypes, string(mediaType)) {
 			next(w, r)
 		} else {
 			RenderResponse(r, w, http.StatusUnsupportedMediaType, nil)
@@ -83,29 +91,51 @@ func AllowedContentTypes(next http.HandlerFunc, mediaTypes ...string) http.Handl
 	}
 }
 
-func EncodeCursor(t time.Time, id uuid.UUID) string {
-	cursor := fmt.Sprintf("%s,%s", t.Format(time.RFC3339Nano), id.String())
+func methodHasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// Cursor identifies the last row of a page by its sort key value and id.
+// SortKey and Direction record the ordering the cursor was issued for.
+type Cursor struct {
+	SortKey   string
+	Direction string
+	Value     time.Time
+	ID        uuid.UUID
+}
+
+func EncodeCursor(c Cursor) string {
+	cursor := fmt.Sprintf("%s,%s,%s,%s", c.SortKey, c.Direction, c.Value.Format(time.RFC3339Nano), c.ID.String())
 	return base64.StdEncoding.EncodeToString([]byte(cursor))
 }
 
-func DecodeCursor(encoded string) (time.Time, uuid.UUID, error) {
+// DecodeCursor also accepts the original "timestamp,uuid" form, which is
+// treated as created_at ascending.
+func DecodeCursor(encoded string) (Cursor, error) {
 	decodedBytes, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return time.Time{}, uuid.Nil, err
+		return Cursor{}, err
 	}
 	parts := strings.Split(string(decodedBytes), ",")
-	if len(parts) != 2 {
-		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor format")
+	var c Cursor
+	switch len(parts) {
+	case 2:
+		c.SortKey, c.Direction = "created_at", "asc"
+	case 4:
+		c.SortKey, c.Direction = parts[0], parts[1]
+		parts = parts[2:]
+	default:
+		return Cursor{}, fmt.Errorf("invalid cursor format")
 	}
-	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	c.Value, err = time.Parse(time.RFC3339Nano, parts[0])
 	if err != nil {
-		return time.Time{}, uuid.Nil, err
+		return Cursor{}, err
 	}
-	id, err := uuid.Parse(parts[1])
+	c.ID, err = uuid.Parse(parts[1])
 	if err != nil {
-		return time.Time{}, uuid.Nil, err
+		return Cursor{}, err
 	}
-	return t, id, nil
+	return c, nil
 }
 
 func existsInSlice(list []string, needle string) bool {
@@ -117,26 +147,6 @@ func existsInSlice(list []string, needle string) bool {
 	return false
 }
 
-func getResponseContentType(r *http.Request) ContentType {
-	accept := r.Header.Get("Accept")
-	if accept == "" {
-		return ContentTypeJSON // default to JSON if no Accept header
-	}
-
-	// parse Accept header and get the highest priority content type we support
-	types := strings.Split(accept, ",")
-	for _, t := range types {
-		mt := strings.TrimSpace(strings.Split(t, ";")[0]) // rmove quality values
-		switch mt {
-		case string(ContentTypeJSON):
-			return ContentTypeJSON
-		case string(ContentTypeXML):
-			return ContentTypeXML
-		}
-	}
-	return ContentTypeJSON // default to JSON if no supported type found
-}
-
 func renderJson(w http.ResponseWriter, statusCode int, res interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	var body []byte
@@ -167,7 +177,7 @@ func renderXML(w http.ResponseWriter, statusCode int, res interface{}) {
 	if res != nil {
 		switch v := res.(type) {
 		case ApiError:
-			xmlRes := XMLResponse{Error: v.Msg}
+			xmlRes := XMLResponse{Error: v.Msg, Code: v.Code}
 			body, err = xml.Marshal(xmlRes)
 		case error:
 			xmlRes := XMLResponse{Error: v.Error()}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeTextXML ContentType = "text/xml"
+	ContentTypeForm    ContentType = "application/x-www-form-urlencoded"
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// bodyDecoders decode a request body into dst by media type.
+var bodyDecoders = map[ContentType]func(body []byte, dst interface{}) error{
+	ContentTypeJSON:    json.Unmarshal,
+	ContentTypeXML:     xml.Unmarshal,
+	ContentTypeTextXML: xml.Unmarshal,
+	ContentTypeForm:    decodeForm,
+}
+
+// MediaType returns the lower-cased media type of the request body with
+// any parameters, such as charset, removed.
+func MediaType(r *http.Request) (ContentType, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return "", nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", err
+	}
+	return ContentType(mediaType), nil
+}
+
+// DecodeBody decodes the request body into dst according to its
+// Content-Type. Bodies without a Content-Type are read as JSON.
+func DecodeBody(r *http.Request, dst interface{}) error {
+	mediaType, err := MediaType(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedMediaType, err)
+	}
+	if mediaType == "" {
+		mediaType = ContentTypeJSON
+	}
+	decode, ok := bodyDecoders[mediaType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return decode(body, dst)
+}
+
+// decodeForm fills the struct dst points to from form fields named after
+// its json tags. Only string and time.Time fields are supported; times
+// are RFC 3339 or YYYY-MM-DD.
+func decodeForm(body []byte, dst interface{}) error {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form decoding needs a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !values.Has(name) {
+			continue
+		}
+		raw := values.Get(name)
+		switch fv := v.Field(i); {
+		case fv.Kind() == reflect.String:
+			fv.SetString(raw)
+		case field.Type == reflect.TypeOf(time.Time{}):
+			ts, err := parseFormTime(raw)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			fv.Set(reflect.ValueOf(ts))
+		default:
+			return fmt.Errorf("field %s: unsupported form field type %s", name, field.Type)
+		}
+	}
+	return nil
+}
+
+func parseFormTime(raw string) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339, raw); err == nil {
+		return ts, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Renderer writes a response body in one media type.
+type Renderer interface {
+	// CanRender reports whether res has a representation in this format.
+	CanRender(res interface{}) bool
+	Render(w http.ResponseWriter, statusCode int, res interface{})
+}
+
+// RendererFunc adapts a function able to render any value to a Renderer.
+type RendererFunc func(w http.ResponseWriter, statusCode int, res interface{})
+
+func (f RendererFunc) CanRender(interface{}) bool { return true }
+
+func (f RendererFunc) Render(w http.ResponseWriter, statusCode int, res interface{}) {
+	f(w, statusCode, res)
+}
+
+// Tabular is implemented by list responses that can be written as rows.
+type Tabular interface {
+	Table() (header []string, records [][]string)
+}
+
+type registeredRenderer struct {
+	mediaType string
+	renderer  Renderer
+}
+
+var (
+	renderersMu sync.RWMutex
+	// renderers is kept in server preference order; the first entry is
+	// used when the client sends no Accept header.
+	renderers []registeredRenderer
+)
+
+func init() {
+	RegisterRenderer(string(ContentTypeJSON), RendererFunc(renderJson))
+	RegisterRenderer(string(ContentTypeXML), RendererFunc(renderXML))
+	RegisterRenderer(string(ContentTypeYAML), RendererFunc(renderYAML))
+	RegisterRenderer(string(ContentTypeMsgPack), RendererFunc(renderMsgPack))
+	RegisterRenderer(string(ContentTypeCSV), csvRenderer{})
+}
+
+// RegisterRenderer makes renderer available for mediaType, replacing any
+// renderer already registered for it. New media types are least preferred.
+func RegisterRenderer(mediaType string, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	for i := range renderers {
+		if renderers[i].mediaType == mediaType {
+			renderers[i].renderer = renderer
+			return
+		}
+	}
+	renderers = append(renderers, registeredRenderer{mediaType: mediaType, renderer: renderer})
+}
+
+// RenderResponse writes res in the media type the request's Accept header
+// prefers. When no acceptable renderer exists the response is 406, except
+// for empty bodies and errors, which keep their status and fall back to
+// JSON so the original failure is not hidden.
+func RenderResponse(r *http.Request, w http.ResponseWriter, statusCode int, res interface{}) {
+	w.Header().Add("Vary", "Accept")
+	if renderer, ok := negotiateRenderer(r.Header.Get("Accept"), res); ok {
+		renderer.Render(w, statusCode, res)
+		return
+	}
+
+	switch res.(type) {
+	case nil:
+		w.WriteHeader(statusCode)
+	case ApiError, error:
+		renderJson(w, statusCode, res)
+	default:
+		renderJson(w, http.StatusNotAcceptable, ApiError{
+			StatusCode: http.StatusNotAcceptable,
+			Msg:        "none of the acceptable media types are available",
+			Code:       "not_acceptable",
+		})
+	}
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+	pos       int
+}
+
+// parseAccept reads the media ranges of an Accept header, skipping any
+// that are malformed.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for i, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q, pos: i})
+	}
+	return ranges
+}
+
+// matchRange finds the most specific range covering mediaType and returns
+// its specificity, or -1 when none does.
+func matchRange(ranges []mediaRange, mediaType string) (mediaRange, int) {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	best, specificity := mediaRange{}, -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.mediaType == mediaType:
+			s = 2
+		case mr.mediaType == typ+"/*":
+			s = 1
+		case mr.mediaType == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			best, specificity = mr, s
+		}
+	}
+	return best, specificity
+}
+
+// negotiateRenderer picks the renderer with the highest q-value that can
+// render res. Ties go to the range listed first by the client, then to
+// the server's preference order.
+func negotiateRenderer(accept string, res interface{}) (Renderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	candidates := make([]registeredRenderer, 0, len(renderers))
+	for _, rr := range renderers {
+		if res == nil || rr.renderer.CanRender(res) {
+			candidates = append(candidates, rr)
+		}
+	}
+	if strings.TrimSpace(accept) == "" {
+		if len(candidates) == 0 {
+			return nil, false
+		}
+		return candidates[0].renderer, true
+	}
+
+	ranges := parseAccept(accept)
+	var chosen Renderer
+	var chosenRange mediaRange
+	for _, rr := range candidates {
+		mr, specificity := matchRange(ranges, rr.mediaType)
+		if specificity < 0 || mr.q == 0 {
+			continue
+		}
+		if chosen == nil || mr.q > chosenRange.q || (mr.q == chosenRange.q && mr.pos < chosenRange.pos) {
+			chosen, chosenRange = rr.renderer, mr
+		}
+	}
+	return chosen, chosen != nil
+}
+
+// genericValue converts res to plain maps, slices and scalars through its
+// JSON encoding, so that other formats use the same field names.
+func genericValue(res interface{}) (interface{}, error) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(v), nil
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeNumbers(e)
+		}
+	}
+	return v
+}
+
+// renderEncoded renders res with marshal after converting it to a generic
+// value, reporting encoding failures as a 500 in the same format.
+func renderEncoded(w http.ResponseWriter, statusCode int, res interface{}, contentType ContentType, marshal func(interface{}) ([]byte, error)) {
+	w.Header().Set("Content-Type", string(contentType))
+	var body []byte
+	if res != nil {
+		v, err := genericValue(res)
+		if err == nil {
+			body, err = marshal(v)
+		}
+		if err != nil {
+			ae := NewInternalServerError(err.Error())
+			statusCode = ae.StatusCode
+			body, _ = marshal(map[string]interface{}{"error": ae.Msg})
+		}
+	}
+	w.WriteHeader(statusCode)
+	if len(body) > 0 {
+		w.Write(body)
+	}
+}
+
+func renderYAML(w http.ResponseWriter, statusCode int, res interface{}) {
+	renderEncoded(w, statusCode, res, ContentTypeYAML, yaml.Marshal)
+}
+
+func renderMsgPack(w http.ResponseWriter, statusCode int, res interface{}) {
+	renderEncoded(w, statusCode, res, ContentTypeMsgPack, msgpack.Marshal)
+}
+
+// CSVCell makes a value safe to open in a spreadsheet. Spreadsheets read
+// a cell starting with =, +, -, @, a tab or a carriage return as a
+// formula, so such a value is prefixed with a quote, which makes it text.
+func CSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvRenderer only renders list responses.
+type csvRenderer struct{}
+
+func (csvRenderer) CanRender(res interface{}) bool {
+	_, ok := res.(Tabular)
+	return ok
+}
+
+func (csvRenderer) Render(w http.ResponseWriter, statusCode int, res interface{}) {
+	w.Header().Set("Content-Type", string(ContentTypeCSV))
+	var buf bytes.Buffer
+	if table, ok := res.(Tabular); ok {
+		header, records := table.Table()
+		cw := csv.NewWriter(&buf)
+		cw.Write(header)
+		for _, record := range records {
+			for i := range record {
+				record[i] = CSVCell(record[i])
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+	}
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,195 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+)
+
+// LeaseName is the lease an instance holds while it verifies bookings.
+const LeaseName = "booking-verification"
+
+// Verifier checks the launches of bookings accepted as
+// PENDING_VERIFICATION while SpaceX was unreachable, once SpaceX can be
+// asked again. A booking whose launch is free becomes CONFIRMED; any
+// other is flagged AT_RISK, where reconciliation takes over.
+type Verifier struct {
+	store  ports.VerificationStore
+	spaceX ports.SpaceXClient
+	leases ports.LeaseStore
+	events ports.EventPublisher
+
+	holder   string
+	interval time.Duration
+	leaseTTL time.Duration
+	now      func() time.Time
+}
+
+type Option func(*Verifier)
+
+// WithInterval sets how often pending bookings are verified.
+func WithInterval(interval time.Duration) Option {
+	return func(v *Verifier) { v.interval = interval }
+}
+
+// WithHolder names this instance in the lease; it defaults to a random id.
+func WithHolder(holder string) Option {
+	return func(v *Verifier) { v.holder = holder }
+}
+
+// WithClock replaces time.Now for deciding which bookings are still ahead.
+func WithClock(now func() time.Time) Option {
+	return func(v *Verifier) { v.now = now }
+}
+
+// WithEventPublisher records a status change event for every booking
+// confirmed or flagged.
+func WithEventPublisher(events ports.EventPublisher) Option {
+	return func(v *Verifier) { v.events = events }
+}
+
+func NewVerifier(store ports.VerificationStore, spaceX ports.SpaceXClient, leases ports.LeaseStore, opts ...Option) *Verifier {
+	v := &Verifier{
+		store:    store,
+		spaceX:   spaceX,
+		leases:   leases,
+		holder:   uuid.NewString(),
+		interval: 5 * time.Minute,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.leaseTTL == 0 {
+		v.leaseTTL = 3 * v.interval
+	}
+	return v
+}
+
+// Run verifies pending bookings until ctx is done, then gives up the lease.
+func (v *Verifier) Run(ctx context.Context) error {
+	defer func() {
+		if err := v.leases.ReleaseLease(context.Background(), LeaseName, v.holder); err != nil {
+			log.Printf("booking verifier: %v", err)
+		}
+	}()
+	for {
+		if _, err := v.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("booking verifier: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(v.interval):
+		}
+	}
+}
+
+// RunOnce runs CheckLaunchConflict again for every pending booking still
+// ahead, if this instance holds the lease. Should SpaceX still be
+// unavailable the run stops there, leaving the remaining bookings pending
+// until the next one. Any other failure skips just its booking, and the
+// first is returned once the rest are done.
+func (v *Verifier) RunOnce(ctx context.Context) (models.VerifyResult, error) {
+	var result models.VerifyResult
+	held, err := v.leases.AcquireLease(ctx, LeaseName, v.holder, v.leaseTTL)
+	if err != nil || !held {
+		return result, err
+	}
+
+	now := v.now()
+	bookings, err := v.store.PendingBookings(ctx, now)
+	if err != nil {
+		return result, err
+	}
+
+	var firstErr error
+	for _, booking := range bookings {
+		launchpadID, launchDate := booking.Flight.LaunchpadID, booking.Flight.LaunchDate
+		available, err := v.spaceX.CheckLaunchConflict(ctx, launchpadID, launchDate)
+
+		var reason string
+		switch {
+		case errors.Is(err, spacex.ErrNotFound):
+			reason = fmt.Sprintf("launchpad %s no longer exists", launchpadID)
+		case spacex.IsUnavailable(err) || ctx.Err() != nil:
+			return result, err
+		case err != nil:
+			if firstErr == nil {
+				firstErr = fmt.Errorf("booking %s: %w", booking.ID, err)
+			}
+			continue
+		case !available:
+			reason = fmt.Sprintf("SpaceX reports launchpad %s unavailable on %s",
+				launchpadID, launchDate.UTC().Format("2006-01-02"))
+		}
+		result.Checked++
+
+		if reason == "" {
+			if err := v.confirm(ctx, booking, &result); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err := v.flag(ctx, booking, reason, now, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, firstErr
+}
+
+func (v *Verifier) confirm(ctx context.Context, booking models.Booking, result *models.VerifyResult) error {
+	confirmed, err := v.store.ConfirmBooking(ctx, booking.ID)
+	if err != nil {
+		return err
+	}
+	if confirmed != nil {
+		result.Confirmed++
+		v.publish(ctx, *confirmed, models.StatusPendingVerification)
+	}
+	return nil
+}
+
+// flag marks the booking AT_RISK. No alternative dates are suggested;
+// reconciliation fills them in on its next run.
+func (v *Verifier) flag(ctx context.Context, booking models.Booking, reason string, now time.Time, result *models.VerifyResult) error {
+	risk := &models.BookingRisk{
+		Booking:          booking,
+		Reason:           reason,
+		AlternativeDates: []time.Time{},
+		FlaggedAt:        now.UTC(),
+	}
+	flagged, err := v.store.FlagAtRisk(ctx, risk)
+	if err != nil {
+		return err
+	}
+	if flagged {
+		result.Flagged++
+		v.publish(ctx, risk.Booking, risk.PreviousStatus)
+	}
+	return nil
+}
+
+// publish records a status change. The change is already stored, so a
+// failure is logged rather than returned.
+func (v *Verifier) publish(ctx context.Context, booking models.Booking, previous models.BookingStatus) {
+	if v.events == nil {
+		return
+	}
+	event := &models.BookingEvent{
+		Type:           models.EventBookingStatusChanged,
+		Booking:        booking,
+		PreviousStatus: previous,
+		OccurredAt:     v.now().UTC(),
+	}
+	if err := v.events.PublishEvent(ctx, event); err != nil {
+		log.Printf("failed to publish %s event for booking %s: %v", event.Type, booking.ID, err)
+	}
+}
@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+)
+
+// Resolver looks up the addresses of a host; net.DefaultResolver is the
+// one used outside tests.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// nonPublic lists the ranges that are not covered by the netip.Addr
+// predicates and must not receive deliveries either: shared address space
+// used for carrier-grade NAT, IETF protocol assignments, benchmarking,
+// reserved, and IPv6 translation and documentation ranges.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// PublicAddress reports whether addr is on the public internet. Loopback,
+// private, link-local (which holds cloud metadata endpoints), multicast
+// and reserved addresses are not, so a subscription cannot make the
+// dispatcher reach into the network it runs in.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckDestination rejects a webhook URL that is not http or https, or
+// whose host is or resolves to an address that is not public.
+func CheckDestination(ctx context.Context, resolver Resolver, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: %q is not an http or https url", models.ErrWebhookDestination, rawURL)
+	}
+	host := u.Hostname()
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !PublicAddress(addr) {
+			return fmt.Errorf("%w: %s is not a public address", models.ErrWebhookDestination, host)
+		}
+		return nil
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: %s does not resolve", models.ErrWebhookDestination, host)
+	}
+	for _, addr := range addrs {
+		if !PublicAddress(addr) {
+			return fmt.Errorf("%w: %s resolves to %s, which is not a public address", models.ErrWebhookDestination, host, addr)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns the client deliveries are sent with. It refuses to
+// connect to an address that is not public, checking the address actually
+// dialed, so that a host which resolved to a public address when it was
+// subscribed cannot later be pointed inside. Proxies are not used, since
+// the address dialed would then be the proxy's, and redirects are not
+// followed: a 3xx is a failed delivery like any other non-2xx.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !PublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", models.ErrWebhookDestination, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
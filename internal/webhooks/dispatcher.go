@@ -0,0 +1,205 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/google/uuid"
+)
+
+const (
+	// DeliveryHeader identifies a delivery; it is the same on every retry.
+	DeliveryHeader = "X-SpaceTrouble-Delivery"
+	// drainLimit caps how much of a response is read, and discarded, so
+	// that the connection can be reused.
+	drainLimit = 64 << 10
+)
+
+// Dispatcher sends queued deliveries to their endpoints, signing each
+// attempt. Failed attempts are retried with exponential backoff until
+// maxAttempts, and a subscription whose endpoint fails disableAfter
+// attempts in a row is disabled.
+type Dispatcher struct {
+	store  ports.WebhookDeliveryStore
+	client outbox.HTTPDoer
+
+	interval     time.Duration
+	batchSize    int
+	lease        time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxAttempts  int
+	disableAfter int
+	now          func() time.Time
+}
+
+type Option func(*Dispatcher)
+
+// WithHTTPClient sets the client deliveries are sent with; its timeout
+// bounds every attempt. It defaults to NewHTTPClient, which only connects
+// to public addresses; a client replacing it should do the same.
+func WithHTTPClient(client outbox.HTTPDoer) Option {
+	return func(d *Dispatcher) { d.client = client }
+}
+
+// WithPollInterval sets how long the dispatcher waits when nothing is due.
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) { d.interval = interval }
+}
+
+// WithRetries sets how many attempts a delivery gets and the backoff
+// between them, which doubles from min up to max.
+func WithRetries(maxAttempts int, min, max time.Duration) Option {
+	return func(d *Dispatcher) { d.maxAttempts, d.minBackoff, d.maxBackoff = maxAttempts, min, max }
+}
+
+// WithDisableAfter sets how many failed attempts in a row disable a
+// subscription.
+func WithDisableAfter(n int) Option {
+	return func(d *Dispatcher) { d.disableAfter = n }
+}
+
+// WithClock replaces time.Now for signing and scheduling retries.
+func WithClock(now func() time.Time) Option {
+	return func(d *Dispatcher) { d.now = now }
+}
+
+func NewDispatcher(store ports.WebhookDeliveryStore, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		client:       NewHTTPClient(10 * time.Second),
+		interval:     time.Second,
+		batchSize:    50,
+		lease:        time.Minute,
+		minBackoff:   10 * time.Second,
+		maxBackoff:   time.Hour,
+		maxAttempts:  10,
+		disableAfter: 20,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		n, err := d.DispatchOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+		if err == nil && n == d.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.interval):
+		}
+	}
+}
+
+// DispatchOnce claims one batch of due deliveries and attempts each,
+// returning how many were claimed.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	deliveries, err := d.store.ClaimDeliveries(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+	// the rest of a batch is not sent to a subscription disabled midway;
+	// those deliveries stay pending and are not claimed while it is off
+	disabled := make(map[uuid.UUID]bool)
+	for _, delivery := range deliveries {
+		if disabled[delivery.SubscriptionID] {
+			continue
+		}
+		off, err := d.attempt(ctx, delivery)
+		if err != nil {
+			return len(deliveries), err
+		}
+		disabled[delivery.SubscriptionID] = off
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends one delivery and records the outcome, reporting whether
+// the subscription was disabled. Only failing to record the outcome is
+// returned as an error.
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) (bool, error) {
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		return false, d.store.MarkDelivered(ctx, delivery.ID, status)
+	}
+	if ctx.Err() != nil {
+		// shutting down; the lease runs out and the delivery is retried
+		return false, ctx.Err()
+	}
+
+	var next *time.Time
+	if attempts := delivery.Attempts + 1; attempts < d.maxAttempts {
+		at := d.now().Add(d.backoff(delivery.Attempts))
+		next = &at
+	}
+	disabled, markErr := d.store.MarkDeliveryFailed(ctx, delivery.ID, status, err.Error(), next, d.disableAfter)
+	if markErr != nil {
+		return false, markErr
+	}
+	if next == nil {
+		log.Printf("webhook delivery %d to %s failed for good after %d attempts: %v", delivery.ID, delivery.URL, delivery.Attempts+1, err)
+	}
+	if disabled {
+		log.Printf("webhook %s disabled after %d failed attempts in a row", delivery.SubscriptionID, d.disableAfter)
+	}
+	return disabled, nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", outbox.ContentType)
+	req.Header.Set("User-Agent", "SpaceTrouble-Webhooks/1.0")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, drainLimit))
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp.StatusCode, nil
+	}
+	// the response body is not kept: the delivery log is shown to the
+	// subscription's owner, who must not be able to read what an endpoint
+	// answered beyond its status
+	return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+}
+
+// backoff doubles minBackoff for every earlier attempt, up to maxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	b := d.minBackoff
+	for i := 0; i < attempts && b < d.maxBackoff; i++ {
+		b *= 2
+	}
+	if b > d.maxBackoff {
+		b = d.maxBackoff
+	}
+	return b
+}
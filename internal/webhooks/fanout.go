@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/google/uuid"
+)
+
+// Fanout is an outbox sink that queues each event for every subscription
+// that wants it. The outbox retries it like any other sink, and queueing
+// is idempotent, so no subscription misses an event or gets it twice.
+type Fanout struct {
+	store ports.WebhookDeliveryStore
+}
+
+func NewFanout(store ports.WebhookDeliveryStore) *Fanout {
+	return &Fanout{store: store}
+}
+
+func (f *Fanout) Name() string { return "webhooks" }
+
+func (f *Fanout) Send(ctx context.Context, event outbox.CloudEvent) error {
+	eventID, err := uuid.Parse(event.ID)
+	if err != nil {
+		return fmt.Errorf("event id: %w", err)
+	}
+	var booking models.Booking
+	if err := json.Unmarshal(event.Data, &booking); err != nil {
+		return fmt.Errorf("decoding booking: %w", err)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	eventType := models.BookingEventType(strings.TrimPrefix(event.Type, outbox.TypePrefix))
+	_, err = f.store.EnqueueDeliveries(ctx, eventID, eventType, booking.User.ID, payload)
+	return err
+}
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS booking_events_notify ON booking_events;
+DROP FUNCTION IF EXISTS notify_booking_event();
+DROP TABLE IF EXISTS booking_events;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS booking_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(40) NOT NULL,
+    booking_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS booking_events_booking_id_idx ON booking_events (booking_id);
+
+-- Every API instance LISTENs on booking_events. The notification carries
+-- the whole event, which is sent when the inserting transaction commits.
+CREATE OR REPLACE FUNCTION notify_booking_event() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('booking_events',
+        (NEW.payload || jsonb_build_object('id', NEW.id, 'occurred_at', NEW.occurred_at))::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER booking_events_notify
+    AFTER INSERT ON booking_events
+    FOR EACH ROW EXECUTE FUNCTION notify_booking_event();
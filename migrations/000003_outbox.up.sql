@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    aggregate_id UUID NOT NULL,
+    type VARCHAR(40) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    published_at TIMESTAMPTZ
+);
+
+-- The relay only ever looks at unpublished rows, oldest first per booking.
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (aggregate_id, id) WHERE published_at IS NULL;
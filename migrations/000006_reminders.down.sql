@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS leases;
+DROP TABLE IF EXISTS booking_reminders;
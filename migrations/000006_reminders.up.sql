@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS booking_reminders (
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    lead VARCHAR(10) NOT NULL,
+    notification_key TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (booking_id, lead)
+);
+
+-- a lease lets one of several instances run a periodic job at a time
+CREATE TABLE IF NOT EXISTS leases (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,9 @@
+-- a booking SpaceX has since scheduled a launch over is flagged AT_RISK;
+-- the row remembers why, and the status to restore should the conflict go
+CREATE TABLE IF NOT EXISTS booking_risks (
+    booking_id UUID PRIMARY KEY REFERENCES bookings(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    previous_status VARCHAR(20) NOT NULL,
+    alternative_dates TIMESTAMP[] NOT NULL DEFAULT '{}',
+    flagged_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
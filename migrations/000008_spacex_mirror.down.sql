@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS spacex_launches;
+DROP TABLE IF EXISTS spacex_launchpads;
@@ -0,0 +1,21 @@
+-- a local copy of the SpaceX launchpads and their upcoming launches, which
+-- the mirror sync worker replaces on every run
+CREATE TABLE IF NOT EXISTS spacex_launchpads (
+    id VARCHAR(24) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    synced_at TIMESTAMPTZ NOT NULL
+);
+
+-- window_start and window_end bound the days a launch may take, as
+-- spacex.Launch.Window reads its date precision, so that conflicts can be
+-- checked in SQL
+CREATE TABLE IF NOT EXISTS spacex_launches (
+    id BIGSERIAL PRIMARY KEY,
+    launchpad_id VARCHAR(24) NOT NULL REFERENCES spacex_launchpads(id) ON DELETE CASCADE,
+    date_unix BIGINT NOT NULL,
+    date_precision VARCHAR(10) NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_spacex_launches_launchpad_window ON spacex_launches (launchpad_id, window_start);
@@ -0,0 +1,2 @@
+-- the response bodies removed by the up migration are not kept anywhere
+-- to be restored
@@ -0,0 +1,6 @@
+-- failed deliveries used to keep up to 256 bytes of the endpoint's
+-- response, which the subscription's owner could read back; keep only
+-- the status
+UPDATE webhook_deliveries
+SET last_error = 'endpoint responded ' || response_status
+WHERE response_status IS NOT NULL AND last_error LIKE 'endpoint responded %';
@@ -0,0 +1,7 @@
+-- launchpads admins have closed to new bookings; a launchpad is open
+-- again once its row is deleted
+CREATE TABLE IF NOT EXISTS launchpad_closures (
+    launchpad_id VARCHAR(24) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMPTZ NOT NULL
+);
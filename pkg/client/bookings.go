@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type BookingStatus string
+
+const (
+	StatusActive              BookingStatus = "ACTIVE"
+	StatusConfirmed           BookingStatus = "CONFIRMED"
+	StatusCancelled           BookingStatus = "CANCELLED"
+	StatusAtRisk              BookingStatus = "AT_RISK"
+	StatusPendingVerification BookingStatus = "PENDING_VERIFICATION"
+)
+
+type Destination struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Flight struct {
+	ID          string      `json:"id"`
+	LaunchpadID string      `json:"launchpad_id"`
+	Destination Destination `json:"destination"`
+	LaunchDate  time.Time   `json:"launch_date"`
+}
+
+type User struct {
+	ID        string    `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Gender    string    `json:"gender"`
+	Birthday  time.Time `json:"birthday"`
+	Email     string    `json:"email,omitempty"`
+}
+
+type Booking struct {
+	ID        string        `json:"id"`
+	User      User          `json:"user"`
+	Flight    Flight        `json:"flight"`
+	Status    BookingStatus `json:"status"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// BookingRequest books a ticket. UserID is only honoured for agent and
+// admin keys booking on behalf of a customer. Bookings with an Email get
+// confirmation and cancellation emails.
+type BookingRequest struct {
+	UserID        string    `json:"user_id,omitempty"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Gender        string    `json:"gender"`
+	Birthday      time.Time `json:"birthday"`
+	Email         string    `json:"email,omitempty"`
+	LaunchpadID   string    `json:"launchpad_id"`
+	DestinationID string    `json:"destination_id"`
+	LaunchDate    time.Time `json:"launch_date"`
+}
+
+// ListOptions filters and orders a bookings listing. Zero values are left
+// out; date ranges include From and exclude To.
+type ListOptions struct {
+	// Limit is the page size used while iterating.
+	Limit         int
+	Status        BookingStatus
+	DestinationID string
+	LaunchpadID   string
+	LastName      string
+	LaunchFrom    time.Time
+	LaunchTo      time.Time
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	// Sort is "created_at" or "launch_date"; Order is "asc" or "desc".
+	Sort  string
+	Order string
+}
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	setTime := func(key string, t time.Time) {
+		if !t.IsZero() {
+			q.Set(key, t.UTC().Format(time.RFC3339))
+		}
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	set("status", string(o.Status))
+	set("destination_id", o.DestinationID)
+	set("launchpad_id", o.LaunchpadID)
+	set("last_name", o.LastName)
+	setTime("launch_date_from", o.LaunchFrom)
+	setTime("launch_date_to", o.LaunchTo)
+	setTime("created_from", o.CreatedFrom)
+	setTime("created_to", o.CreatedTo)
+	set("sort", o.Sort)
+	set("order", o.Order)
+	return q
+}
+
+type BookingPage struct {
+	Bookings   []Booking `json:"bookings"`
+	Limit      int       `json:"limit"`
+	NextCursor string    `json:"next_cursor"`
+	PrevCursor string    `json:"prev_cursor"`
+	TotalCount *int      `json:"total_count,omitempty"`
+}
+
+type Health struct {
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp"`
+	Version   string `json:"version"`
+	Uptime    string `json:"uptime"`
+	GoVersion string `json:"go_version"`
+}
+
+// CreateBooking is not retried, since a lost response could otherwise book
+// the same seat twice.
+func (c *Client) CreateBooking(ctx context.Context, req BookingRequest) (*Booking, error) {
+	var booking Booking
+	err := c.do(ctx, request{method: http.MethodPost, path: "/bookings", body: req}, &booking)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (c *Client) GetBooking(ctx context.Context, id string) (*Booking, error) {
+	var booking Booking
+	path := "/bookings?" + url.Values{"id": {id}}.Encode()
+	err := c.do(ctx, request{method: http.MethodGet, path: path, idempotent: true}, &booking)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// RescheduleBooking moves a booking to a new launch date. It is not
+// retried: the server rejects a move to the date a booking already has, so
+// a retry after a lost response would report a failed move that succeeded.
+func (c *Client) RescheduleBooking(ctx context.Context, id string, launchDate time.Time) (*Booking, error) {
+	var booking Booking
+	path := "/bookings/reschedule?" + url.Values{"id": {id}}.Encode()
+	body := struct {
+		LaunchDate time.Time `json:"launch_date"`
+	}{launchDate}
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: body}, &booking)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// CancelBooking is retried like other idempotent calls, so a retry after a
+// lost response can report ErrNotFound for a booking that was cancelled.
+func (c *Client) CancelBooking(ctx context.Context, id string) error {
+	path := "/bookings?" + url.Values{"id": {id}}.Encode()
+	return c.do(ctx, request{method: http.MethodDelete, path: path, idempotent: true}, nil)
+}
+
+// ListBookingsPage fetches the page following cursor; an empty cursor
+// fetches the first page.
+func (c *Client) ListBookingsPage(ctx context.Context, opts ListOptions, cursor string) (*BookingPage, error) {
+	q := opts.values()
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	path := "/bookings"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var page BookingPage
+	if err := c.do(ctx, request{method: http.MethodGet, path: path, idempotent: true}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListBookings returns an iterator over every booking matching opts,
+// fetching pages as it goes:
+//
+//	it := c.ListBookings(ctx, client.ListOptions{Status: client.StatusConfirmed})
+//	for it.Next() {
+//		b := it.Booking()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (c *Client) ListBookings(ctx context.Context, opts ListOptions) *BookingIterator {
+	return &BookingIterator{ctx: ctx, client: c, opts: opts}
+}
+
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/health", idempotent: true}, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+type BookingIterator struct {
+	ctx    context.Context
+	client *Client
+	opts   ListOptions
+
+	page    []Booking
+	cursor  string
+	current Booking
+	started bool
+	err     error
+}
+
+// Next advances to the next booking, fetching the next page when needed.
+// It returns false at the end of the listing or on error.
+func (it *BookingIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.cursor == "") {
+			return false
+		}
+		page, err := it.client.ListBookingsPage(it.ctx, it.opts, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.started = true
+		it.page, it.cursor = page.Bookings, page.NextCursor
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Booking returns the booking Next advanced to.
+func (it *BookingIterator) Booking() Booking {
+	return it.current
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *BookingIterator) Err() error {
+	return it.err
+}
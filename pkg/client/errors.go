@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Error codes sent by the API in the "code" field of error responses.
+const (
+	CodeInvalidUUID          = "invalid_uuid"
+	CodeInvalidRequest       = "invalid_request"
+	CodeWebhookDestination   = "webhook_destination_not_allowed"
+	CodeDestinationNotFound  = "destination_not_found"
+	CodeBookingNotFound      = "booking_not_found"
+	CodeWebhookNotFound      = "webhook_not_found"
+	CodeLaunchpadNotClosed   = "launchpad_not_closed"
+	CodeBatchConflict        = "batch_conflict"
+	CodeLaunchpadUnavailable = "launchpad_unavailable"
+	CodeBatchAborted         = "batch_aborted"
+	CodeUnauthenticated      = "unauthenticated"
+	CodeForbidden            = "forbidden"
+	CodeSpaceXUnavailable    = "spacex_unavailable"
+	CodeInternal             = "internal_error"
+	CodeInvalidCursor        = "invalid_cursor"
+	CodeCursorExpired        = "cursor_expired"
+	CodeCursorFilterMismatch = "cursor_filter_mismatch"
+	CodeNotAcceptable        = "not_acceptable"
+)
+
+// Sentinel errors matched by *APIError through errors.Is.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:      ErrBadRequest,
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusConflict:        ErrConflict,
+	http.StatusTooManyRequests: ErrRateLimited,
+}
+
+// APIError is an error response from the API. Use errors.Is with the
+// sentinel errors to branch on the kind of failure, or Code for the
+// specific reason.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// RetryAfter is the delay the server asked for, if any.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("spacetrouble: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("spacetrouble: %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	if target == ErrServer {
+		return e.StatusCode >= 500
+	}
+	return statusErrors[e.StatusCode] == target
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	var payload struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		apiErr.Message, apiErr.Code = payload.Error, payload.Code
+	} else {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
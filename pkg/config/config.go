@@ -8,13 +8,21 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	SpaceX   SpaceXConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	SpaceX     SpaceXConfig
+	Auth       AuthConfig
+	Pagination PaginationConfig
+	Outbox     OutboxConfig
+	Webhooks   WebhookConfig
+	Mail       MailConfig
+	Reminders  ReminderConfig
+	Reconcile  ReconcileConfig
 }
 
 type ServerConfig struct {
 	Address      string
+	GRPCAddress  string
 	WriteTimeout time.Duration
 	ReadTimeout  time.Duration
 	IdleTimeout  time.Duration
@@ -31,6 +39,88 @@ type DatabaseConfig struct {
 
 type SpaceXConfig struct {
 	BaseURL string
+	// CacheTTL is how long a launchpad's schedule is used without asking
+	// SpaceX again, and CacheStale how long after that it is still used
+	// while it is refreshed in the background
+	CacheTTL   time.Duration
+	CacheStale time.Duration
+	// Timeout bounds one request; failed requests are retried up to
+	// MaxAttempts times with a backoff doubling from RetryMinBackoff to
+	// RetryMaxBackoff
+	Timeout         time.Duration
+	MaxAttempts     int
+	RetryMinBackoff time.Duration
+	RetryMaxBackoff time.Duration
+	// BreakerThreshold failed requests in a row stop calls to SpaceX for
+	// BreakerCooldown; 0 turns the breaker off
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// DegradedPolicy is a comma separated list of snapshot, pending and
+	// reject, tried in order for bookings SpaceX cannot be asked about;
+	// SnapshotMaxAge is the oldest schedule the snapshot policy uses
+	DegradedPolicy string
+	SnapshotMaxAge time.Duration
+	// MirrorInterval is how often launchpads and upcoming launches are
+	// copied into Postgres; bookings ask SpaceX itself once the copy is
+	// older than MirrorMaxAge
+	MirrorInterval time.Duration
+	MirrorMaxAge   time.Duration
+}
+
+type PaginationConfig struct {
+	// CursorKeys is a comma separated list of "kid:secret" pairs; the first
+	// signs new cursors and the rest are still accepted during rotation
+	CursorKeys string
+	CursorTTL  time.Duration
+}
+
+type OutboxConfig struct {
+	// Sinks is a comma separated list of stdout, file, webhook and nats,
+	// in addition to webhook subscriptions, which always receive events
+	Sinks        string
+	WebhookURL   string
+	FilePath     string
+	NATSURL      string
+	NATSSubject  string
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+type WebhookConfig struct {
+	Timeout      time.Duration
+	MaxAttempts  int
+	DisableAfter int
+}
+
+type MailConfig struct {
+	// Transport is smtp, file or memory; file writes .eml files to FileDir
+	Transport    string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+	MaxAttempts  int
+}
+
+type ReminderConfig struct {
+	// Interval is how often the instance holding the lease looks for due
+	// launch reminders
+	Interval time.Duration
+}
+
+type ReconcileConfig struct {
+	// Interval is how often the instance holding the lease checks future
+	// bookings against the SpaceX schedule again, and VerifyInterval how
+	// often it checks bookings accepted while SpaceX was unreachable
+	Interval       time.Duration
+	VerifyInterval time.Duration
+}
+
+type AuthConfig struct {
+	// APIKeys is a comma separated list of "key:role[:user-uuid]" entries
+	APIKeys string
 }
 
 func (dc *DatabaseConfig) DSN() string {
@@ -56,12 +146,54 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("database config error: %w", err)
 	}
 
-	spaceXCfg := newSpaceXConfig()
+	spaceXCfg, err := newSpaceXConfig()
+	if err != nil {
+		return nil, fmt.Errorf("spacex config error: %w", err)
+	}
+
+	authCfg := newAuthConfig()
+
+	paginationCfg, err := newPaginationConfig()
+	if err != nil {
+		return nil, fmt.Errorf("pagination config error: %w", err)
+	}
+
+	outboxCfg, err := newOutboxConfig()
+	if err != nil {
+		return nil, fmt.Errorf("outbox config error: %w", err)
+	}
+
+	webhookCfg, err := newWebhookConfig()
+	if err != nil {
+		return nil, fmt.Errorf("webhook config error: %w", err)
+	}
+
+	mailCfg, err := newMailConfig()
+	if err != nil {
+		return nil, fmt.Errorf("mail config error: %w", err)
+	}
+
+	reminderCfg, err := newReminderConfig()
+	if err != nil {
+		return nil, fmt.Errorf("reminder config error: %w", err)
+	}
+
+	reconcileCfg, err := newReconcileConfig()
+	if err != nil {
+		return nil, fmt.Errorf("reconcile config error: %w", err)
+	}
 
 	return &Config{
-		Server:   serverCfg,
-		Database: dbCfg,
-		SpaceX:   spaceXCfg,
+		Server:     serverCfg,
+		Database:   dbCfg,
+		SpaceX:     spaceXCfg,
+		Auth:       authCfg,
+		Pagination: paginationCfg,
+		Outbox:     outboxCfg,
+		Webhooks:   webhookCfg,
+		Mail:       mailCfg,
+		Reminders:  reminderCfg,
+		Reconcile:  reconcileCfg,
 	}, nil
 }
 
@@ -83,6 +215,7 @@ func newServerConfig() (ServerConfig, error) {
 
 	return ServerConfig{
 		Address:      getEnvOrDefault("SERVER_ADDRESS", ":5000"),
+		GRPCAddress:  getEnvOrDefault("GRPC_ADDRESS", ":5001"),
 		WriteTimeout: writeTimeout,
 		ReadTimeout:  readTimeout,
 		IdleTimeout:  idleTimeout,
@@ -105,10 +238,174 @@ func newDatabaseConfig() (DatabaseConfig, error) {
 	}, nil
 }
 
-func newSpaceXConfig() SpaceXConfig {
+func newSpaceXConfig() (SpaceXConfig, error) {
+	cacheTTL, err := getDurationFromEnv("SPACEX_CACHE_TTL", "5m")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("cache ttl parse error: %w", err)
+	}
+
+	cacheStale, err := getDurationFromEnv("SPACEX_CACHE_STALE", "1h")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("cache stale parse error: %w", err)
+	}
+
+	timeout, err := getDurationFromEnv("SPACEX_TIMEOUT", "15s")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("timeout parse error: %w", err)
+	}
+
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("SPACEX_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("max attempts parse error: %w", err)
+	}
+
+	minBackoff, err := getDurationFromEnv("SPACEX_RETRY_MIN_BACKOFF", "200ms")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("retry min backoff parse error: %w", err)
+	}
+
+	maxBackoff, err := getDurationFromEnv("SPACEX_RETRY_MAX_BACKOFF", "5s")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("retry max backoff parse error: %w", err)
+	}
+
+	breakerThreshold, err := strconv.Atoi(getEnvOrDefault("SPACEX_BREAKER_THRESHOLD", "5"))
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("breaker threshold parse error: %w", err)
+	}
+
+	breakerCooldown, err := getDurationFromEnv("SPACEX_BREAKER_COOLDOWN", "30s")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("breaker cooldown parse error: %w", err)
+	}
+
+	snapshotMaxAge, err := getDurationFromEnv("SPACEX_SNAPSHOT_MAX_AGE", "24h")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("snapshot max age parse error: %w", err)
+	}
+
+	mirrorInterval, err := getDurationFromEnv("SPACEX_MIRROR_INTERVAL", "15m")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("mirror interval parse error: %w", err)
+	}
+
+	mirrorMaxAge, err := getDurationFromEnv("SPACEX_MIRROR_MAX_AGE", "1h")
+	if err != nil {
+		return SpaceXConfig{}, fmt.Errorf("mirror max age parse error: %w", err)
+	}
+
 	return SpaceXConfig{
-		BaseURL: getEnvOrDefault("SPACEX_URL", "https://api.spacexdata.com/v4"),
+		BaseURL:          getEnvOrDefault("SPACEX_URL", "https://api.spacexdata.com/v4"),
+		CacheTTL:         cacheTTL,
+		CacheStale:       cacheStale,
+		Timeout:          timeout,
+		MaxAttempts:      maxAttempts,
+		RetryMinBackoff:  minBackoff,
+		RetryMaxBackoff:  maxBackoff,
+		BreakerThreshold: breakerThreshold,
+		BreakerCooldown:  breakerCooldown,
+		DegradedPolicy:   getEnvOrDefault("SPACEX_DEGRADED_POLICY", "reject"),
+		SnapshotMaxAge:   snapshotMaxAge,
+		MirrorInterval:   mirrorInterval,
+		MirrorMaxAge:     mirrorMaxAge,
+	}, nil
+}
+
+func newAuthConfig() AuthConfig {
+	return AuthConfig{
+		APIKeys: getEnvOrDefault("AUTH_API_KEYS", ""),
+	}
+}
+
+func newPaginationConfig() (PaginationConfig, error) {
+	ttl, err := getDurationFromEnv("CURSOR_TTL", "24h")
+	if err != nil {
+		return PaginationConfig{}, fmt.Errorf("cursor ttl parse error: %w", err)
 	}
+
+	return PaginationConfig{
+		CursorKeys: getEnvOrDefault("CURSOR_SIGNING_KEYS", ""),
+		CursorTTL:  ttl,
+	}, nil
+}
+
+func newOutboxConfig() (OutboxConfig, error) {
+	interval, err := getDurationFromEnv("OUTBOX_POLL_INTERVAL", "1s")
+	if err != nil {
+		return OutboxConfig{}, fmt.Errorf("poll interval parse error: %w", err)
+	}
+
+	batchSize, err := strconv.Atoi(getEnvOrDefault("OUTBOX_BATCH_SIZE", "100"))
+	if err != nil {
+		return OutboxConfig{}, fmt.Errorf("batch size parse error: %w", err)
+	}
+
+	return OutboxConfig{
+		Sinks:        getEnvOrDefault("OUTBOX_SINKS", ""),
+		WebhookURL:   getEnvOrDefault("OUTBOX_WEBHOOK_URL", ""),
+		FilePath:     getEnvOrDefault("OUTBOX_FILE_PATH", "outbox.jsonl"),
+		NATSURL:      getEnvOrDefault("OUTBOX_NATS_URL", "nats://localhost:4222"),
+		NATSSubject:  getEnvOrDefault("OUTBOX_NATS_SUBJECT", "spacetrouble.bookings"),
+		PollInterval: interval,
+		BatchSize:    batchSize,
+	}, nil
+}
+
+func newWebhookConfig() (WebhookConfig, error) {
+	timeout, err := getDurationFromEnv("WEBHOOK_TIMEOUT", "10s")
+	if err != nil {
+		return WebhookConfig{}, fmt.Errorf("timeout parse error: %w", err)
+	}
+
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("WEBHOOK_MAX_ATTEMPTS", "10"))
+	if err != nil {
+		return WebhookConfig{}, fmt.Errorf("max attempts parse error: %w", err)
+	}
+
+	disableAfter, err := strconv.Atoi(getEnvOrDefault("WEBHOOK_DISABLE_AFTER", "20"))
+	if err != nil {
+		return WebhookConfig{}, fmt.Errorf("disable after parse error: %w", err)
+	}
+
+	return WebhookConfig{
+		Timeout:      timeout,
+		MaxAttempts:  maxAttempts,
+		DisableAfter: disableAfter,
+	}, nil
+}
+
+func newMailConfig() (MailConfig, error) {
+	port, err := strconv.Atoi(getEnvOrDefault("SMTP_PORT", "587"))
+	if err != nil {
+		return MailConfig{}, fmt.Errorf("smtp port parse error: %w", err)
+	}
+
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("MAIL_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return MailConfig{}, fmt.Errorf("max attempts parse error: %w", err)
+	}
+
+	return MailConfig{
+		Transport:    getEnvOrDefault("MAIL_TRANSPORT", "file"),
+		From:         getEnvOrDefault("MAIL_FROM", "SpaceTrouble <bookings@spacetrouble.local>"),
+		SMTPHost:     getEnvOrDefault("SMTP_HOST", "localhost"),
+		SMTPPort:     port,
+		SMTPUsername: getEnvOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword: getEnvOrDefault("SMTP_PASSWORD", ""),
+		FileDir:      getEnvOrDefault("MAIL_FILE_DIR", "mail"),
+		MaxAttempts:  maxAttempts,
+	}, nil
+}
+
+func newReminderConfig() (ReminderConfig, error) {
+	interval, err := getDurationFromEnv("REMINDER_INTERVAL", "1m")
+	if err != nil {
+		return ReminderConfig{}, fmt.Errorf("interval parse error: %w", err)
+	}
+
+	return ReminderConfig{
+		Interval: interval,
+	}, nil
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -121,3 +418,20 @@ func getEnvOrDefault(key, defaultValue string) string {
 func getDurationFromEnv(key, defaultValue string) (time.Duration, error) {
 	return time.ParseDuration(getEnvOrDefault(key, defaultValue))
 }
+
+func newReconcileConfig() (ReconcileConfig, error) {
+	interval, err := getDurationFromEnv("RECONCILE_INTERVAL", "1h")
+	if err != nil {
+		return ReconcileConfig{}, fmt.Errorf("interval parse error: %w", err)
+	}
+
+	verifyInterval, err := getDurationFromEnv("VERIFY_INTERVAL", "5m")
+	if err != nil {
+		return ReconcileConfig{}, fmt.Errorf("verify interval parse error: %w", err)
+	}
+
+	return ReconcileConfig{
+		Interval:       interval,
+		VerifyInterval: verifyInterval,
+	}, nil
+}
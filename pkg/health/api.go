@@ -19,11 +19,45 @@ type HealthResponse struct {
 		Sys        uint64 `json:"sys"`        // bytes obtained from system
 		NumGC      uint32 `json:"numGC"`      // number of garbage collections
 	} `json:"memory"`
+	DegradedMode *DegradedMode `json:"degraded_mode,omitempty"`
+}
+
+// DegradedMode reports what becomes of new bookings while SpaceX cannot be
+// asked about their launch: the policy tried first and the fallbacks tried
+// after it in order.
+type DegradedMode struct {
+	SpaceXAvailable bool             `json:"spacex_available"`
+	Policy          DegradedPolicy   `json:"policy"`
+	Fallbacks       []DegradedPolicy `json:"fallbacks"`
+}
+
+// DegradedPolicy is a degraded mode policy and how many bookings it has
+// decided since the service started.
+type DegradedPolicy struct {
+	Name      string `json:"name"`
+	Decisions int64  `json:"decisions"`
+}
+
+type Option func(*options)
+
+type options struct {
+	degradedMode func() DegradedMode
+}
+
+// WithDegradedMode adds the degraded mode report to the response. The
+// status is "degraded" while SpaceX is unavailable.
+func WithDegradedMode(report func() DegradedMode) Option {
+	return func(o *options) { o.degradedMode = report }
 }
 
 var startTime = time.Now()
 
-func HealthGet() http.HandlerFunc {
+func HealthGet(opts ...Option) http.HandlerFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -46,6 +80,14 @@ func HealthGet() http.HandlerFunc {
 		health.Memory.Sys = memStats.Sys
 		health.Memory.NumGC = memStats.NumGC
 
+		if o.degradedMode != nil {
+			degraded := o.degradedMode()
+			health.DegradedMode = &degraded
+			if !degraded.SpaceXAvailable {
+				health.Status = "degraded"
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
@@ -0,0 +1,232 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ScheduleSource looks up a launchpad together with its upcoming launches.
+// Client is the source that calls SpaceX.
+type ScheduleSource interface {
+	LaunchSchedule(ctx context.Context, launchpadID string) (*Schedule, error)
+}
+
+// Cache keeps launchpad schedules for a while so that bursts of bookings on
+// one launchpad do not each call SpaceX. A schedule is fresh for the TTL;
+// for a further stale period it is still served, while one background
+// lookup replaces it. Concurrent lookups of the same launchpad share a
+// single call. Unknown launchpads are cached like any other answer;
+// failed lookups are not.
+//
+// Entries past their stale period are dropped, at most once a TTL as new
+// lookups are stored, so that asking about ever more launchpad ids cannot
+// grow the cache without end. Schedules may be kept longer, for
+// LastKnownSchedule, with WithRetention.
+//
+// Cached schedules are shared between callers and must not be modified.
+type Cache struct {
+	source  ScheduleSource
+	ttl     time.Duration
+	stale   time.Duration
+	retain  time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	lastSweep time.Time
+	group     singleflight.Group
+
+	hits, staleHits, misses, fetches, fetchErrors atomic.Int64
+}
+
+type cacheEntry struct {
+	schedule  *Schedule
+	err       error
+	fetchedAt time.Time
+}
+
+// CacheStats counts how lookups were answered since the cache was made.
+// Misses that joined a lookup already under way are not in Fetches.
+// Entries is how many launchpads the cache holds now.
+type CacheStats struct {
+	Hits        int64 `json:"hits"`
+	StaleHits   int64 `json:"stale_hits"`
+	Misses      int64 `json:"misses"`
+	Fetches     int64 `json:"fetches"`
+	FetchErrors int64 `json:"fetch_errors"`
+	Entries     int   `json:"entries"`
+}
+
+type CacheOption func(*Cache)
+
+// WithCacheTTL sets how long a schedule is served without asking SpaceX.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) { c.ttl = ttl }
+}
+
+// WithStaleWhileRevalidate sets how long after the TTL an expired schedule
+// is still served while it is refreshed in the background. Zero turns
+// this off.
+func WithStaleWhileRevalidate(stale time.Duration) CacheOption {
+	return func(c *Cache) { c.stale = stale }
+}
+
+// WithRetention keeps schedules for LastKnownSchedule until they are d
+// old, when that is longer than the TTL and stale period after which they
+// are otherwise dropped. Unknown launchpads are not kept any longer.
+func WithRetention(d time.Duration) CacheOption {
+	return func(c *Cache) { c.retain = d }
+}
+
+// WithCacheClock replaces time.Now for deciding when schedules expire.
+func WithCacheClock(now func() time.Time) CacheOption {
+	return func(c *Cache) { c.now = now }
+}
+
+func NewCache(source ScheduleSource, opts ...CacheOption) *Cache {
+	c := &Cache{
+		source:  source,
+		ttl:     5 * time.Minute,
+		stale:   time.Hour,
+		timeout: 30 * time.Second,
+		now:     time.Now,
+		entries: make(map[string]*cacheEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CheckLaunchConflict reports whether ts is free on the launchpad, like
+// Client.CheckLaunchConflict, from the cached schedule.
+func (c *Cache) CheckLaunchConflict(ctx context.Context, launchpadID string, ts time.Time) (bool, error) {
+	if err := validateInputs(launchpadID, ts); err != nil {
+		return false, fmt.Errorf("invalid input: %w", err)
+	}
+
+	schedule, err := c.LaunchSchedule(ctx, launchpadID)
+	if err != nil {
+		return false, err
+	}
+
+	return schedule.IsAvailable(ts)
+}
+
+// LaunchSchedule returns the launchpad's schedule from the cache, looking
+// it up when it is missing or past its stale period.
+func (c *Cache) LaunchSchedule(ctx context.Context, launchpadID string) (*Schedule, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[launchpadID]
+	c.mu.Unlock()
+
+	if ok {
+		age := c.now().Sub(entry.fetchedAt)
+		switch {
+		case age < c.ttl:
+			c.hits.Add(1)
+			return entry.schedule, entry.err
+		case age < c.ttl+c.stale:
+			c.staleHits.Add(1)
+			c.refresh(context.WithoutCancel(ctx), launchpadID)
+			return entry.schedule, entry.err
+		}
+	}
+
+	c.misses.Add(1)
+	// the lookup is shared, so it must not end when this caller gives up
+	result := c.group.DoChan(launchpadID, func() (interface{}, error) {
+		return c.fetch(context.WithoutCancel(ctx), launchpadID)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-result:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		entry := r.Val.(*cacheEntry)
+		return entry.schedule, entry.err
+	}
+}
+
+// LastKnownSchedule returns the launchpad's schedule as last fetched, and
+// when, as long as the cache still holds it. It never calls SpaceX, so it
+// can stand in while SpaceX is unavailable.
+func (c *Cache) LastKnownSchedule(launchpadID string) (*Schedule, time.Time, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[launchpadID]
+	c.mu.Unlock()
+	if !ok || entry.schedule == nil {
+		return nil, time.Time{}, false
+	}
+	return entry.schedule, entry.fetchedAt, true
+}
+
+// Stats returns the cache's counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return CacheStats{
+		Entries:     entries,
+		Hits:        c.hits.Load(),
+		StaleHits:   c.staleHits.Load(),
+		Misses:      c.misses.Load(),
+		Fetches:     c.fetches.Load(),
+		FetchErrors: c.fetchErrors.Load(),
+	}
+}
+
+// refresh replaces a stale schedule in the background, unless a lookup of
+// it is already under way. A failed refresh keeps the stale schedule until
+// it runs out.
+func (c *Cache) refresh(ctx context.Context, launchpadID string) {
+	c.group.DoChan(launchpadID, func() (interface{}, error) {
+		return c.fetch(ctx, launchpadID)
+	})
+}
+
+// fetch looks the launchpad up and stores the answer. A launchpad SpaceX
+// does not know is stored as an answer; any other error is returned.
+func (c *Cache) fetch(ctx context.Context, launchpadID string) (*cacheEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.fetches.Add(1)
+	schedule, err := c.source.LaunchSchedule(ctx, launchpadID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		c.fetchErrors.Add(1)
+		return nil, err
+	}
+
+	entry := &cacheEntry{schedule: schedule, err: err, fetchedAt: c.now()}
+	c.mu.Lock()
+	c.entries[launchpadID] = entry
+	c.sweep(entry.fetchedAt)
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// sweep drops the entries past their stale period, which no lookup is
+// answered from any more, unless they are schedules still retained. It
+// only goes through the entries once a TTL. c.mu must be held.
+func (c *Cache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for id, entry := range c.entries {
+		age := now.Sub(entry.fetchedAt)
+		if age >= c.ttl+c.stale && (entry.schedule == nil || age >= c.retain) {
+			delete(c.entries, id)
+		}
+	}
+}
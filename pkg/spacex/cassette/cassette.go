@@ -0,0 +1,288 @@
+// Package cassette records the SpaceX API's responses to cassette files
+// and replays them, so that the client can be tested against real
+// response shapes without network access.
+//
+// A Recorder is a spacex.HTTPClient. In Record mode it passes requests on
+// to SpaceX and keeps every request and response; Save writes them out.
+// In Replay mode it answers each request from the cassette instead,
+// matching it on method, URL and body.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoInteraction is returned in Replay mode for a request the cassette
+// holds no response to.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Redacted replaces the values of redacted headers in a cassette.
+const Redacted = "REDACTED"
+
+type Mode int
+
+const (
+	// Replay answers requests from the cassette and never calls SpaceX.
+	Replay Mode = iota
+	// Record calls SpaceX and records the exchanges, replacing whatever
+	// the cassette held.
+	Record
+)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Interaction is one request and the response SpaceX gave to it.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+type Request struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int         `yaml:"status"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body"`
+}
+
+// Recorder is a spacex.HTTPClient recording to, or replaying from, the
+// cassette at one path.
+type Recorder struct {
+	path   string
+	mode   Mode
+	client spacex.HTTPClient
+	redact []string
+
+	mu       sync.Mutex
+	cassette Cassette
+	played   []bool
+}
+
+type Option func(*Recorder)
+
+// WithHTTPClient sets the client requests are sent with in Record mode;
+// it defaults to http.DefaultClient.
+func WithHTTPClient(client spacex.HTTPClient) Option {
+	return func(r *Recorder) { r.client = client }
+}
+
+// WithRedactedHeaders adds headers, request or response, whose values are
+// replaced by Redacted before they are written to the cassette.
+// Authorization, Cookie, Set-Cookie and X-Api-Key always are.
+func WithRedactedHeaders(names ...string) Option {
+	return func(r *Recorder) { r.redact = append(r.redact, names...) }
+}
+
+// New returns a Recorder for the cassette at path. In Replay mode the
+// cassette must exist.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:   path,
+		mode:   mode,
+		client: http.DefaultClient,
+		redact: []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+		}
+		r.played = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Do records or replays req, depending on the mode.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == Record {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+// replay answers with the first interaction matching the request that has
+// not been played yet, so that a cassette can hold a failure followed by
+// the retry that succeeds. Once all have been played, the last matching
+// one answers every further request.
+func (r *Recorder) replay(req *http.Request, body string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !matches(req, body, interaction.Request) {
+			continue
+		}
+		if !r.played[i] {
+			found = i
+			break
+		}
+		found = i
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s in %s", ErrNoInteraction, req.Method, req.URL, r.path)
+	}
+	r.played[found] = true
+	return r.cassette.Interactions[found].Response.toHTTP(req), nil
+}
+
+func (r *Recorder) record(req *http.Request, body string) (*http.Response, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: r.redacted(req.Header),
+			Body:    body,
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: r.redacted(resp.Header),
+			Body:    string(respBody),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Save writes what was recorded to the cassette, creating its directory
+// if need be. It does nothing in Replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	r.mu.Lock()
+	err := encoder.Encode(r.cassette)
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, data.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction{}, r.cassette.Interactions...)
+}
+
+func (r *Recorder) redacted(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	header = header.Clone()
+	for _, name := range r.redact {
+		if values := header.Values(name); len(values) > 0 {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = Redacted
+			}
+			header[http.CanonicalHeaderKey(name)] = redacted
+		}
+	}
+	return header
+}
+
+// matches compares method, URL and body. Bodies that are both JSON are
+// compared as values, so that key order and spacing do not matter.
+func matches(req *http.Request, body string, recorded Request) bool {
+	if req.Method != recorded.Method || req.URL.String() != recorded.URL {
+		return false
+	}
+	if body == recorded.Body {
+		return true
+	}
+	var got, want interface{}
+	if json.Unmarshal([]byte(body), &got) != nil || json.Unmarshal([]byte(recorded.Body), &want) != nil {
+		return false
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	return bytes.Equal(gotJSON, wantJSON)
+}
+
+// readBody reads the request body and puts it back for sending on.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("reading request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	header := r.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
 )
@@ -14,6 +15,12 @@ import (
 type Client struct {
 	httpClient HTTPClient
 	baseURL    string
+
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	breaker     *breaker
+	now         func() time.Time
 }
 
 type HTTPClient interface {
@@ -37,6 +44,13 @@ type LaunchesResponse struct {
 	Launches []Launch `json:"docs"`
 }
 
+// Schedule is a launchpad together with its upcoming launches, so that
+// several dates can be checked against one pair of API calls.
+type Schedule struct {
+	LaunchPad LaunchPad
+	Launches  []Launch
+}
+
 type SearchQuery struct {
 	Query   map[string]interface{} `json:"query"`
 	Options map[string]interface{} `json:"options"`
@@ -45,8 +59,41 @@ type SearchQuery struct {
 var (
 	ErrNotFound      error = errors.New("launchpad not found")
 	ErrBadStatusCode error = errors.New("invalid status code from spacex")
+	ErrCircuitOpen   error = errors.New("spacex is unavailable, not calling it until it recovers")
 )
 
+// statusError is an unexpected status code from SpaceX. It matches
+// ErrBadStatusCode.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%v: %d", ErrBadStatusCode, e.code)
+}
+
+func (e *statusError) Is(target error) bool {
+	return target == ErrBadStatusCode
+}
+
+// IsUnavailable reports whether err means SpaceX could not be asked, as
+// opposed to having answered: the circuit breaker is open, or the request
+// timed out, failed to connect or got a 5xx or 429 after every retry.
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return isRetryableStatus(status.code)
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
 func WithBaseURL(url string) Option {
 	return func(c *Client) {
 		c.baseURL = url
@@ -59,19 +106,59 @@ func WithHTTPClient(httpClient HTTPClient) Option {
 	}
 }
 
+// WithRetries sets how many attempts a request gets when SpaceX times out,
+// fails with a 5xx or rate limits it, and the backoff between them, which
+// doubles from minBackoff up to maxBackoff. A maxAttempts of 1 turns
+// retrying off.
+func WithRetries(maxAttempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithCircuitBreaker makes the client fail fast with ErrCircuitOpen once
+// threshold requests in a row have failed, trying SpaceX again with a
+// single request after cooldown. A threshold of 0 turns the breaker off.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker.threshold = threshold
+		c.breaker.cooldown = cooldown
+	}
+}
+
+// WithClock replaces time.Now for the circuit breaker and Retry-After
+// dates.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) { c.now = now }
+}
+
 func NewClient(opts ...Option) *Client {
 	client := &Client{
-		httpClient: &http.Client{Timeout: 15 * time.Second},
-		baseURL:    "https://api.spacexdata.com/v4",
+		httpClient:  &http.Client{Timeout: 15 * time.Second},
+		baseURL:     "https://api.spacexdata.com/v4",
+		maxAttempts: 3,
+		minBackoff:  200 * time.Millisecond,
+		maxBackoff:  5 * time.Second,
+		breaker:     &breaker{threshold: 5, cooldown: 30 * time.Second},
+		now:         time.Now,
 	}
 
 	for _, opt := range opts {
 		opt(client)
 	}
+	client.breaker.now = client.now
 
 	return client
 }
 
+// CircuitOpen reports whether the circuit breaker is turning requests
+// away.
+func (c *Client) CircuitOpen() bool {
+	return c.breaker.isOpen()
+}
+
 func (o *LaunchPad) IsActive() bool {
 	return o.Status == "active"
 }
@@ -81,32 +168,55 @@ func (c *Client) CheckLaunchConflict(ctx context.Context, launchpadID string, ts
 		return false, fmt.Errorf("invalid input: %w", err)
 	}
 
+	schedule, err := c.LaunchSchedule(ctx, launchpadID)
+	if err != nil {
+		return false, err
+	}
+
+	return schedule.IsAvailable(ts)
+}
+
+// LaunchSchedule fetches the launchpad and, when it is active, its
+// upcoming launches.
+func (c *Client) LaunchSchedule(ctx context.Context, launchpadID string) (*Schedule, error) {
 	launchpad, err := c.getLaunchpad(ctx, launchpadID)
 	if err != nil {
-		return false, fmt.Errorf("checking launchpad: %w", err)
+		return nil, fmt.Errorf("checking launchpad: %w", err)
 	}
 
+	schedule := &Schedule{LaunchPad: launchpad}
 	if !launchpad.IsActive() {
-		return false, nil
+		return schedule, nil
 	}
 
-	launches, err := c.getUpcomingLaunches(ctx, launchpadID)
+	schedule.Launches, err = c.getUpcomingLaunches(ctx, launchpadID)
 	if err != nil {
-		return false, fmt.Errorf("checking upcoming launches: %w", err)
+		return nil, fmt.Errorf("checking upcoming launches: %w", err)
 	}
+	return schedule, nil
+}
 
-	return c.isDateAvailable(launches, ts)
+func (s *Schedule) IsAvailable(ts time.Time) (bool, error) {
+	if err := validateInputs(s.LaunchPad.Id, ts); err != nil {
+		return false, fmt.Errorf("invalid input: %w", err)
+	}
+	if !s.LaunchPad.IsActive() {
+		return false, nil
+	}
+	return isDateAvailable(s.Launches, ts)
 }
 
 func (c *Client) GetLaunchPadById(ctx context.Context, launchpadID string) (LaunchPad, error) {
 	var ans LaunchPad
 	u := fmt.Sprintf("%s/%s/%s", c.baseURL, "launchpads", launchpadID)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return ans, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return ans, err
 	}
@@ -120,7 +230,7 @@ func (c *Client) GetLaunchPadById(ctx context.Context, launchpadID string) (Laun
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return ans, ErrBadStatusCode
+		return ans, &statusError{code: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -130,21 +240,84 @@ func (c *Client) GetLaunchPadById(ctx context.Context, launchpadID string) (Laun
 	return ans, json.Unmarshal(body, &ans)
 }
 
-func (c *Client) GetUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
-	u := fmt.Sprintf("%s/%s", c.baseURL, "launches/query")
-	q := c.generateUpcomingSearchQuery(launchpadID)
-	jsonBytes, err := json.Marshal(q)
-
+// GetLaunchPads lists every launchpad SpaceX knows.
+func (c *Client) GetLaunchPads(ctx context.Context) ([]LaunchPad, error) {
+	u := fmt.Sprintf("%s/%s", c.baseURL, "launchpads")
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewBuffer(jsonBytes))
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{code: resp.StatusCode}
+	}
+
+	var launchpads []LaunchPad
+	if err := json.NewDecoder(resp.Body).Decode(&launchpads); err != nil {
+		return nil, err
+	}
+	return launchpads, nil
+}
+
+// GetUpcomingLaunches lists the upcoming launches from every launchpad.
+func (c *Client) GetUpcomingLaunches(ctx context.Context) ([]Launch, error) {
+	return c.queryLaunches(ctx, c.generateUpcomingSearchQuery(""))
+}
+
+// Schedules fetches every launchpad together with its upcoming launches,
+// in two requests whatever the number of launchpads.
+func (c *Client) Schedules(ctx context.Context) ([]Schedule, error) {
+	launchpads, err := c.GetLaunchPads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching launchpads: %w", err)
+	}
+	launches, err := c.GetUpcomingLaunches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching upcoming launches: %w", err)
+	}
+
+	byLaunchpad := make(map[string][]Launch)
+	for _, launch := range launches {
+		byLaunchpad[launch.LaunchPadID] = append(byLaunchpad[launch.LaunchPadID], launch)
+	}
+	schedules := make([]Schedule, len(launchpads))
+	for i, launchpad := range launchpads {
+		schedules[i] = Schedule{LaunchPad: launchpad, Launches: byLaunchpad[launchpad.Id]}
+	}
+	return schedules, nil
+}
+
+func (c *Client) GetUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID string) ([]Launch, error) {
+	return c.queryLaunches(ctx, c.generateUpcomingSearchQuery(launchpadID))
+}
+
+func (c *Client) queryLaunches(ctx context.Context, q SearchQuery) ([]Launch, error) {
+	u := fmt.Sprintf("%s/%s", c.baseURL, "launches/query")
+	jsonBytes, err := json.Marshal(q)
 
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
+	// the body is rebuilt for every attempt, since a sent one is consumed
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(jsonBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/json")
+		return req, nil
+	})
 
 	if err != nil {
 		return nil, err
@@ -155,8 +328,7 @@ func (c *Client) GetUpcomingLaunchesLaunchPad(ctx context.Context, launchpadID s
 	}()
 
 	if resp.StatusCode != 200 {
-		fmt.Println(resp.StatusCode)
-		return nil, ErrBadStatusCode
+		return nil, &statusError{code: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -187,7 +359,7 @@ func (c *Client) getUpcomingLaunches(ctx context.Context, launchpadID string) ([
 	return launches, nil
 }
 
-func (c *Client) isDateAvailable(launches []Launch, ts time.Time) (bool, error) {
+func isDateAvailable(launches []Launch, ts time.Time) (bool, error) {
 	for _, launch := range launches {
 		available, err := launch.IsDayAvailable(ts)
 		if err != nil {
@@ -207,7 +379,9 @@ func (c *Client) generateUpcomingSearchQuery(launchpadID string) SearchQuery {
 	}
 
 	searchQuery.Query["upcoming"] = true
-	searchQuery.Query["launchpad"] = launchpadID
+	if launchpadID != "" {
+		searchQuery.Query["launchpad"] = launchpadID
+	}
 	searchQuery.Options["select"] = []string{"launchpad", "date_unix", "date_precision"}
 	searchQuery.Options["sort"] = map[string]string{"date_unix": "asc"}
 	searchQuery.Options["limit"] = 10000
@@ -216,13 +390,23 @@ func (c *Client) generateUpcomingSearchQuery(launchpadID string) SearchQuery {
 }
 
 func (l *Launch) IsDayAvailable(t time.Time) (bool, error) {
+	launchStart, end, err := l.Window()
+	if err != nil {
+		return false, err
+	}
+	requestStart := StartOfDay(t)
+
+	// date is unavailable if it falls within the launch window
+	return !(requestStart.Equal(launchStart) || (requestStart.After(launchStart) && requestStart.Before(end))), nil
+}
+
+// Window is the stretch of time the launch may happen in, given its date
+// precision: it starts at the beginning of the launch's UTC day. A day is
+// taken when its start is the window's start or falls inside the window.
+func (l *Launch) Window() (time.Time, time.Time, error) {
 	// get the launch date from Unix timestamp
 	launchDate := time.Unix(l.Date, 0).UTC()
-	requestDate := t.UTC()
-
-	// normalise both dates to start of day
-	launchStart := time.Date(launchDate.Year(), launchDate.Month(), launchDate.Day(), 0, 0, 0, 0, time.UTC)
-	requestStart := time.Date(requestDate.Year(), requestDate.Month(), requestDate.Day(), 0, 0, 0, 0, time.UTC)
+	launchStart := StartOfDay(launchDate)
 
 	// calculate end based on precision
 	var end time.Time
@@ -240,11 +424,16 @@ func (l *Launch) IsDayAvailable(t time.Time) (bool, error) {
 	case "day":
 		end = launchStart.AddDate(0, 0, 1).Add(-time.Second)
 	default:
-		return false, fmt.Errorf("invalid date precision: %s", l.DatePrecision)
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date precision: %s", l.DatePrecision)
 	}
+	return launchStart, end, nil
+}
 
-	// date is unavailable if it falls within the launch window
-	return !(requestStart.Equal(launchStart) || (requestStart.After(launchStart) && requestStart.Before(end))), nil
+// StartOfDay is the beginning of t's UTC day, which launch windows are
+// compared by.
+func StartOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
 
 func validateInputs(launchpadID string, ts time.Time) error {
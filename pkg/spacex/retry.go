@@ -0,0 +1,201 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// do sends the request newRequest builds, retrying it while SpaceX times
+// out, fails or rate limits, and records the outcome with the circuit
+// breaker. newRequest is called again for every attempt.
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doWithRetries(ctx, newRequest)
+	switch {
+	case ctx.Err() != nil:
+		// the caller gave up; that says nothing about SpaceX
+		c.breaker.record(outcomeIgnored)
+	case err != nil || isRetryableStatus(resp.StatusCode):
+		c.breaker.record(outcomeFailure)
+	default:
+		c.breaker.record(outcomeSuccess)
+	}
+	return resp, err
+}
+
+func (c *Client) doWithRetries(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(req)
+
+		wait, retry := c.retryWait(ctx, attempt, resp, err)
+		if !retry || attempt >= c.maxAttempts {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryWait reports whether an attempt is worth repeating and how long to
+// wait first: as long as a 429's Retry-After asks, otherwise a jittered
+// exponential backoff. A Retry-After longer than maxBackoff is not waited
+// for; the 429 is returned at once and counts as a failure.
+func (c *Client) retryWait(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+	if err != nil {
+		if !isTimeout(err) {
+			return 0, false
+		}
+		return c.backoff(attempt), true
+	}
+	if !isRetryableStatus(resp.StatusCode) {
+		return 0, false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := c.parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, wait <= c.maxBackoff
+		}
+	}
+	return c.backoff(attempt), true
+}
+
+// backoff doubles from minBackoff for every attempt made, up to
+// maxBackoff, and waits a random time between half of that and all of it
+// so that clients do not retry in step.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff
+	for i := 1; i < attempt && d < c.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or
+// as an HTTP date.
+func (c *Client) parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := at.Sub(c.now()); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+// breaker opens after threshold failed requests in a row. While open it
+// turns requests away until cooldown has passed, then lets a single trial
+// request through: success closes it, failure opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+func (b *breaker) isOpen() bool {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+func (b *breaker) record(o outcome) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch o {
+	case outcomeSuccess:
+		b.failures, b.open = 0, false
+	case outcomeFailure:
+		b.failures++
+		if b.trial || b.failures >= b.threshold {
+			b.open, b.openedAt = true, b.now()
+		}
+	}
+	b.trial = false
+}
@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	models "github.com/chrisdamba/spacetrouble/internal"
 	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,10 +31,60 @@ func (m *mockBookingService) CreateBooking(ctx context.Context, request *models.
 	return args.Get(0).(*models.Booking), args.Error(1)
 }
 
+func (m *mockBookingService) CreateBookingsBatch(ctx context.Context, requests []models.BookingRequest, mode models.BatchMode) (*models.BatchBookingResponse, error) {
+	args := m.Called(ctx, requests, mode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BatchBookingResponse), args.Error(1)
+}
+
+func (m *mockBookingService) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *mockBookingService) AllBookings(ctx context.Context, req models.GetBookingsRequest) (*models.AllBookingsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AllBookingsResponse), args.Error(1)
+}
+
+func (m *mockBookingService) ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error {
+	args := m.Called(ctx, filter, fn)
+	if bookings, ok := args.Get(0).([]models.Booking); ok {
+		for _, b := range bookings {
+			if err := fn(b); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *mockBookingService) RescheduleBooking(ctx context.Context, id string, request *models.RescheduleRequest) (*models.Booking, error) {
+	args := m.Called(ctx, id, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *mockBookingService) DeleteBooking(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func TestBookingHandler_Create(t *testing.T) {
 	tests := []struct {
 		name          string
 		request       *models.BookingRequest
+		rawBody       string
 		setupMock     func(*mockBookingService)
 		expectedCode  int
 		expectedError string
@@ -45,7 +99,7 @@ func TestBookingHandler_Create(t *testing.T) {
 				Gender:        "male",
 				Birthday:      time.Now().AddDate(-30, 0, 0),
 				LaunchpadID:   "123456789012345678901234",
-				DestinationID: uuid.New(),
+				DestinationID: uuid.New().String(),
 				LaunchDate:    time.Now().AddDate(0, 1, 0),
 			},
 			setupMock: func(m *mockBookingService) {
@@ -70,7 +124,7 @@ func TestBookingHandler_Create(t *testing.T) {
 				Gender:        "male",
 				Birthday:      time.Now().AddDate(-30, 0, 0),
 				LaunchpadID:   "123456789012345678901234",
-				DestinationID: uuid.New(),
+				DestinationID: uuid.New().String(),
 				LaunchDate:    time.Now().AddDate(0, 1, 0),
 			},
 			setupMock: func(m *mockBookingService) {
@@ -89,7 +143,7 @@ func TestBookingHandler_Create(t *testing.T) {
 		},
 		{
 			name:          "Invalid_JSON_Body",
-			request:       &models.BookingRequest{},
+			rawBody:       `{"first_name": `,
 			setupMock:     func(m *mockBookingService) {},
 			expectedCode:  http.StatusBadRequest,
 			expectedError: "error json decoding body",
@@ -115,7 +169,7 @@ func TestBookingHandler_Create(t *testing.T) {
 				Gender:        "male",
 				Birthday:      time.Now().AddDate(-30, 0, 0),
 				LaunchpadID:   "123456789012345678901234",
-				DestinationID: uuid.New(),
+				DestinationID: uuid.New().String(),
 				LaunchDate:    time.Now().AddDate(0, 1, 0),
 			},
 			setupMock: func(m *mockBookingService) {
@@ -134,7 +188,7 @@ func TestBookingHandler_Create(t *testing.T) {
 				Gender:        "male",
 				Birthday:      time.Now().AddDate(-30, 0, 0),
 				LaunchpadID:   "123456789012345678901234",
-				DestinationID: uuid.New(),
+				DestinationID: uuid.New().String(),
 				LaunchDate:    time.Now().AddDate(0, 1, 0),
 			},
 			setupMock: func(m *mockBookingService) {
@@ -163,9 +217,12 @@ func TestBookingHandler_Create(t *testing.T) {
 			mockService := new(mockBookingService)
 			tt.setupMock(mockService)
 
-			handler := api.BookingHandler(mockService)
+			handler := utils.AllowedContentTypes(api.BookingHandler(mockService, newTestCursorSigner(t)), "application/json")
 
 			body, _ := json.Marshal(tt.request)
+			if tt.rawBody != "" {
+				body = []byte(tt.rawBody)
+			}
 			req := httptest.NewRequest(http.MethodPost, "/bookings", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", tt.contentType)
 			req.Header.Set("Accept", tt.acceptHeader)
@@ -197,3 +254,325 @@ func TestBookingHandler_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestBookingHandler_List(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantFilter   models.BookingFilter
+		expectedCode int
+	}{
+		{
+			name:         "no filters",
+			query:        "",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "confirmed Mars bookings next month",
+			query: "?status=CONFIRMED&destination_id=a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11&launch_date_from=2030-11-01&launch_date_to=2030-12-01&sort=launch_date&order=desc",
+			wantFilter: models.BookingFilter{
+				Status:        models.StatusConfirmed,
+				DestinationID: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+				LaunchFrom:    time.Date(2030, 11, 1, 0, 0, 0, 0, time.UTC),
+				LaunchTo:      time.Date(2030, 12, 1, 0, 0, 0, 0, time.UTC),
+				Sort:          models.BookingSort{Field: models.SortByLaunchDate, Direction: models.SortDesc},
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid status",
+			query:        "?status=LOST",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid sort",
+			query:        "?sort=first_name",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid date",
+			query:        "?created_from=yesterday",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "unsigned cursor",
+			query: "?sort=launch_date&cursor=" + url.QueryEscape(utils.EncodeCursor(utils.Cursor{
+				SortKey: "launch_date", Direction: "asc", Value: time.Now(), ID: uuid.New(),
+			})),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockBookingService)
+			if tt.expectedCode == http.StatusOK {
+				mockService.On("AllBookings", mock.Anything, models.GetBookingsRequest{Limit: 10, Filter: tt.wantFilter}).
+					Return(&models.AllBookingsResponse{Limit: 10}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			api.BookingHandler(mockService, newTestCursorSigner(t)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBookingHandler_ListPaginationLinks(t *testing.T) {
+	cursors := newTestCursorSigner(t)
+
+	t.Run("link header for both directions", func(t *testing.T) {
+		mockService := new(mockBookingService)
+		mockService.On("AllBookings", mock.Anything, models.GetBookingsRequest{
+			Limit:        5,
+			Filter:       models.BookingFilter{Status: models.StatusConfirmed},
+			IncludeTotal: true,
+		}).Return(&models.AllBookingsResponse{Limit: 5, NextCursor: "n1", PrevCursor: "p1"}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings?limit=5&status=CONFIRMED&include_total=true", nil)
+		rr := httptest.NewRecorder()
+		api.BookingHandler(mockService, cursors).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		link := rr.Header().Get("Link")
+		assert.Regexp(t, `</v1/bookings\?cursor=[\w.-]+&include_total=true&limit=5&status=CONFIRMED>; rel="next"`, link)
+		assert.Regexp(t, `</v1/bookings\?before=[\w.-]+&include_total=true&limit=5&status=CONFIRMED>; rel="prev"`, link)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("no link header on a single page", func(t *testing.T) {
+		mockService := new(mockBookingService)
+		mockService.On("AllBookings", mock.Anything, models.GetBookingsRequest{Limit: 10}).
+			Return(&models.AllBookingsResponse{Limit: 10}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings", nil)
+		rr := httptest.NewRecorder()
+		api.BookingHandler(mockService, cursors).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Link"))
+	})
+
+	t.Run("cursor and before together", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings?cursor=a&before=b", nil)
+		rr := httptest.NewRecorder()
+		api.BookingHandler(new(mockBookingService), cursors).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestBookingHandler_SignedCursors(t *testing.T) {
+	oldKey := utils.SigningKey{Kid: "old", Secret: []byte("old-secret")}
+	newKey := utils.SigningKey{Kid: "new", Secret: []byte("new-secret")}
+	oldSigner, err := utils.NewCursorSigner([]utils.SigningKey{oldKey}, time.Hour)
+	require.NoError(t, err)
+
+	// issue a signed cursor for a CONFIRMED listing through the handler
+	issueCursor := func(t *testing.T, signer *utils.CursorSigner) string {
+		mockService := new(mockBookingService)
+		mockService.On("AllBookings", mock.Anything, mock.Anything).
+			Return(&models.AllBookingsResponse{Limit: 10, NextCursor: "raw-next"}, nil)
+
+		rr := httptest.NewRecorder()
+		api.BookingHandler(mockService, signer).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/bookings?status=CONFIRMED", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var res models.AllBookingsResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		require.NotEqual(t, "raw-next", res.NextCursor)
+		return res.NextCursor
+	}
+
+	tests := []struct {
+		name       string
+		signer     func(t *testing.T) *utils.CursorSigner
+		query      func(cursor string) string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "same filters",
+			query:      func(c string) string { return "?status=CONFIRMED&cursor=" + c },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "rotated key still verifies",
+			signer: func(t *testing.T) *utils.CursorSigner {
+				s, err := utils.NewCursorSigner([]utils.SigningKey{newKey, oldKey}, time.Hour)
+				require.NoError(t, err)
+				return s
+			},
+			query:      func(c string) string { return "?status=CONFIRMED&cursor=" + c },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "retired key",
+			signer: func(t *testing.T) *utils.CursorSigner {
+				s, err := utils.NewCursorSigner([]utils.SigningKey{newKey}, time.Hour)
+				require.NoError(t, err)
+				return s
+			},
+			query:      func(c string) string { return "?status=CONFIRMED&cursor=" + c },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_cursor",
+		},
+		{
+			name:       "filters stripped",
+			query:      func(c string) string { return "?cursor=" + c },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "cursor_filter_mismatch",
+		},
+		{
+			name:       "sort changed",
+			query:      func(c string) string { return "?status=CONFIRMED&sort=launch_date&before=" + c },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "cursor_filter_mismatch",
+		},
+		{
+			name:       "tampered payload",
+			query:      func(c string) string { return "?status=CONFIRMED&cursor=x" + c },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := issueCursor(t, oldSigner)
+			signer := oldSigner
+			if tt.signer != nil {
+				signer = tt.signer(t)
+			}
+
+			mockService := new(mockBookingService)
+			if tt.wantStatus == http.StatusOK {
+				mockService.On("AllBookings", mock.Anything, mock.MatchedBy(func(req models.GetBookingsRequest) bool {
+					return req.Uuid == "raw-next"
+				})).Return(&models.AllBookingsResponse{Limit: 10}, nil)
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/bookings"+tt.query(url.QueryEscape(cursor)), nil)
+			api.BookingHandler(mockService, signer).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantCode != "" {
+				var apiError utils.ApiError
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &apiError))
+				assert.Equal(t, tt.wantCode, apiError.Code)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("expired cursor", func(t *testing.T) {
+		expiring, err := utils.NewCursorSigner([]utils.SigningKey{oldKey}, -time.Second)
+		require.NoError(t, err)
+		cursor := issueCursor(t, expiring)
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings?status=CONFIRMED&cursor="+url.QueryEscape(cursor), nil)
+		api.BookingHandler(new(mockBookingService), expiring).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"code":"cursor_expired"`)
+	})
+}
+
+func newTestCursorSigner(t *testing.T) *utils.CursorSigner {
+	t.Helper()
+	signer, err := utils.NewCursorSigner([]utils.SigningKey{{Kid: "test", Secret: []byte("test-secret")}}, time.Hour)
+	require.NoError(t, err)
+	return signer
+}
+
+func TestBookingHandler_ListCSV(t *testing.T) {
+	mockService := new(mockBookingService)
+	bookingID := uuid.New()
+	mockService.On("AllBookings", mock.Anything, mock.Anything).Return(&models.AllBookingsResponse{
+		Bookings: []models.BookingResponse{{Booking: models.Booking{ID: bookingID, Status: models.StatusActive}}},
+		Limit:    10,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings", nil)
+	req.Header.Set("Accept", "text/csv")
+	rr := httptest.NewRecorder()
+	api.BookingHandler(mockService, newTestCursorSigner(t)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "id,status,created_at,"))
+	assert.True(t, strings.HasPrefix(lines[1], bookingID.String()+",ACTIVE,"))
+}
+
+func TestBookingHandler_CreateFromOtherFormats(t *testing.T) {
+	launch := time.Now().AddDate(0, 1, 0).UTC().Truncate(time.Second).Format(time.RFC3339)
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:        "xml body",
+			contentType: "application/xml; charset=utf-8",
+			body: `<booking><first_name>Ada</first_name><last_name>Lovelace</last_name><gender>female</gender>` +
+				`<birthday>1990-12-10T00:00:00Z</birthday><launchpad_id>5e9e4502f5090995de566f86</launchpad_id>` +
+				`<destination_id>a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11</destination_id><launch_date>` + launch + `</launch_date></booking>`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:        "form body",
+			contentType: "application/x-www-form-urlencoded",
+			body: url.Values{
+				"first_name":     {"Ada"},
+				"last_name":      {"Lovelace"},
+				"gender":         {"female"},
+				"birthday":       {"1990-12-10"},
+				"launchpad_id":   {"5e9e4502f5090995de566f86"},
+				"destination_id": {"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+				"launch_date":    {launch},
+			}.Encode(),
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "malformed xml",
+			contentType:  "application/xml",
+			body:         `<booking><first_name>Ada</booking>`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported media type",
+			contentType:  "text/plain",
+			body:         "Ada Lovelace",
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockBookingService)
+			if tt.expectedCode == http.StatusCreated {
+				mockService.On("CreateBooking", mock.Anything, mock.MatchedBy(func(r *models.BookingRequest) bool {
+					return r.FirstName == "Ada" && r.LastName == "Lovelace" && !r.Birthday.IsZero() && !r.LaunchDate.IsZero()
+				})).Return(&models.Booking{ID: uuid.New()}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", "application/json")
+			rr := httptest.NewRecorder()
+
+			api.BookingHandler(mockService, newTestCursorSigner(t)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingBatchHandler(t *testing.T) {
+	twoRows := func(reqs []models.BookingRequest) bool {
+		return len(reqs) == 2 && reqs[0].FirstName == "Ada" && reqs[1].FirstName == "Alan"
+	}
+	tests := []struct {
+		name         string
+		query        string
+		contentType  string
+		body         string
+		setupMock    func(*mockBookingService)
+		expectedCode int
+		wantCodes    []string
+	}{
+		{
+			name:        "JSON array, all rows created",
+			contentType: "application/json",
+			body:        `[{"first_name":"Ada"},{"first_name":"Alan"}]`,
+			setupMock: func(m *mockBookingService) {
+				m.On("CreateBookingsBatch", mock.Anything, mock.MatchedBy(twoRows), models.BatchAtomic).
+					Return(&models.BatchBookingResponse{
+						Mode:    models.BatchAtomic,
+						Created: 2,
+						Results: []models.BatchRowResult{
+							{Index: 0, Status: models.RowCreated},
+							{Index: 1, Status: models.RowCreated},
+						},
+					}, nil)
+			},
+			expectedCode: http.StatusCreated,
+			wantCodes:    []string{"", ""},
+		},
+		{
+			name:        "NDJSON, partial best effort import",
+			query:       "?mode=best_effort",
+			contentType: "application/x-ndjson",
+			body:        "{\"first_name\":\"Ada\"}\n{\"first_name\":\"Alan\"}\n",
+			setupMock: func(m *mockBookingService) {
+				m.On("CreateBookingsBatch", mock.Anything, mock.MatchedBy(twoRows), models.BatchBestEffort).
+					Return(&models.BatchBookingResponse{
+						Mode:    models.BatchBestEffort,
+						Created: 1,
+						Failed:  1,
+						Results: []models.BatchRowResult{
+							{Index: 0, Status: models.RowCreated},
+							{Index: 1, Status: models.RowFailed, Err: fmt.Errorf("%w: booked", models.ErrLaunchPadUnavailable)},
+						},
+					}, nil)
+			},
+			expectedCode: http.StatusMultiStatus,
+			wantCodes:    []string{"", "launchpad_unavailable"},
+		},
+		{
+			name:        "atomic batch rejected",
+			contentType: "application/json",
+			body:        `[{"first_name":"Ada"},{"first_name":"Alan"}]`,
+			setupMock: func(m *mockBookingService) {
+				m.On("CreateBookingsBatch", mock.Anything, mock.Anything, models.BatchAtomic).
+					Return(&models.BatchBookingResponse{
+						Mode:   models.BatchAtomic,
+						Failed: 2,
+						Results: []models.BatchRowResult{
+							{Index: 0, Status: models.RowFailed, Err: models.ErrBatchAborted},
+							{Index: 1, Status: models.RowFailed, Err: fmt.Errorf("%w: missing name", models.ErrInvalidRequest)},
+						},
+					}, nil)
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+			wantCodes:    []string{"batch_aborted", "invalid_request"},
+		},
+		{
+			name:         "unknown mode",
+			query:        "?mode=sometimes",
+			contentType:  "application/json",
+			body:         `[{}]`,
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "object instead of array",
+			contentType:  "application/json",
+			body:         `{"first_name":"Ada"}`,
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "empty batch",
+			contentType:  "application/json",
+			body:         `[]`,
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "malformed NDJSON row",
+			contentType:  "application/x-ndjson",
+			body:         "{\"first_name\":\"Ada\"}\n{oops\n",
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockBookingService)
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/bookings:batch"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", "application/json")
+			rr := httptest.NewRecorder()
+
+			api.BookingBatchHandler(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.wantCodes != nil {
+				var res models.BatchBookingResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				require.Len(t, res.Results, len(tt.wantCodes))
+				for i, code := range tt.wantCodes {
+					assert.Equal(t, code, res.Results[i].Code)
+					assert.Equal(t, code != "", res.Results[i].Error != "")
+				}
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
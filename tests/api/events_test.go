@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEventService streams the given events and then holds the stream
+// open until the client goes away.
+type stubEventService struct {
+	events      []models.BookingEvent
+	err         error
+	lastEventID int64
+}
+
+func (s *stubEventService) Subscribe(ctx context.Context, lastEventID int64) (<-chan models.BookingEvent, error) {
+	s.lastEventID = lastEventID
+	if s.err != nil {
+		return nil, s.err
+	}
+	ch := make(chan models.BookingEvent)
+	go func() {
+		defer close(ch)
+		for _, e := range s.events {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch, nil
+}
+
+// readFrames reads n SSE frames, skipping the retry preamble.
+func readFrames(t *testing.T, r *bufio.Reader, n int) []map[string]string {
+	t.Helper()
+	var frames []map[string]string
+	frame := map[string]string{}
+	for len(frames) < n {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if _, ok := frame["retry"]; !ok && len(frame) > 0 {
+				frames = append(frames, frame)
+			}
+			frame = map[string]string{}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		frame[field] = value
+	}
+	return frames
+}
+
+func TestBookingEventsHandler(t *testing.T) {
+	t.Run("streams events and resumes from Last-Event-ID", func(t *testing.T) {
+		booking := models.Booking{ID: uuid.New(), Status: models.StatusCancelled}
+		svc := &stubEventService{events: []models.BookingEvent{
+			{ID: 8, Type: models.EventBookingCreated, Booking: booking},
+			{ID: 9, Type: models.EventBookingCancelled, Booking: booking, PreviousStatus: models.StatusActive},
+		}}
+		server := httptest.NewServer(api.BookingEventsHandler(svc))
+		defer server.Close()
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "7")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, int64(7), svc.lastEventID)
+
+		frames := readFrames(t, bufio.NewReader(resp.Body), 2)
+		assert.Equal(t, "8", frames[0]["id"])
+		assert.Equal(t, "booking.created", frames[0]["event"])
+		assert.Equal(t, "9", frames[1]["id"])
+		assert.Equal(t, "booking.cancelled", frames[1]["event"])
+
+		var data models.BookingEvent
+		require.NoError(t, json.Unmarshal([]byte(frames[1]["data"]), &data))
+		assert.Equal(t, booking.ID, data.Booking.ID)
+		assert.Equal(t, models.StatusActive, data.PreviousStatus)
+	})
+
+	t.Run("rejects a malformed Last-Event-ID", func(t *testing.T) {
+		svc := &stubEventService{}
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings/events", nil)
+		req.Header.Set("Last-Event-ID", "abc")
+		w := httptest.NewRecorder()
+
+		api.BookingEventsHandler(svc)(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("maps subscribe errors", func(t *testing.T) {
+		svc := &stubEventService{err: models.ErrUnauthenticated}
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings/events", nil)
+		w := httptest.NewRecorder()
+
+		api.BookingEventsHandler(svc)(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"unauthenticated"`)
+	})
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func exportBookings() []models.Booking {
+	launch := time.Date(2031, 3, 4, 10, 0, 0, 0, time.UTC)
+	return []models.Booking{
+		{
+			ID:     uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+			Status: models.StatusConfirmed,
+			User:   models.User{FirstName: "Ada", LastName: "Lovelace, Countess", Email: "ada@example.com"},
+			Flight: models.Flight{LaunchpadID: "pad-1", LaunchDate: launch, Destination: models.Destination{Name: "Mars"}},
+		},
+		{
+			ID:     uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+			Status: models.StatusActive,
+			User:   models.User{FirstName: "Alan", LastName: "Turing"},
+			Flight: models.Flight{LaunchpadID: "pad-2", LaunchDate: launch, Destination: models.Destination{Name: "Moon"}},
+		},
+	}
+}
+
+func TestBookingExportHandler_CSV(t *testing.T) {
+	mockService := new(mockBookingService)
+	wantFilter := models.BookingFilter{Status: models.StatusConfirmed}
+	mockService.On("ExportBookings", mock.Anything, wantFilter, mock.Anything).Return(exportBookings(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings/export?format=csv&status=CONFIRMED&columns=id,last_name,destination_name", nil)
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "bookings.csv")
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "last_name", "destination_name"},
+		{"00000000-0000-0000-0000-000000000001", "Lovelace, Countess", "Mars"},
+		{"00000000-0000-0000-0000-000000000002", "Turing", "Moon"},
+	}, records)
+	mockService.AssertExpectations(t)
+}
+
+func TestBookingExportHandler_Email(t *testing.T) {
+	mockService := new(mockBookingService)
+	mockService.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return(exportBookings(), nil)
+
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/export?columns=last_name,email", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"last_name", "email"},
+		{"Lovelace, Countess", "ada@example.com"},
+		{"Turing", ""},
+	}, records)
+}
+
+func TestBookingExportHandler_CSVFormulas(t *testing.T) {
+	names := []string{"=HYPERLINK(\"http://evil.example\",\"x\")", "+1", "-1", "@SUM(A1)", "\tTab", "\rReturn", "O'Brien", "Smith-Jones"}
+	bookings := make([]models.Booking, len(names))
+	for i, name := range names {
+		bookings[i] = models.Booking{User: models.User{LastName: name}}
+	}
+	mockService := new(mockBookingService)
+	mockService.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return(bookings, nil)
+
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/export?columns=last_name", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"last_name"},
+		{"'=HYPERLINK(\"http://evil.example\",\"x\")"},
+		{"'+1"},
+		{"'-1"},
+		{"'@SUM(A1)"},
+		{"'\tTab"},
+		{"'\rReturn"},
+		{"O'Brien"},
+		{"Smith-Jones"},
+	}, records)
+}
+
+func TestBookingExportHandler_NDJSONKeepsValues(t *testing.T) {
+	mockService := new(mockBookingService)
+	mockService.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).
+		Return([]models.Booking{{User: models.User{LastName: "=1+1"}}}, nil)
+
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bookings/export?format=ndjson&columns=last_name", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"last_name":"=1+1"}`+"\n", rr.Body.String())
+}
+
+func TestBookingExportHandler_NDJSON(t *testing.T) {
+	mockService := new(mockBookingService)
+	mockService.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return(exportBookings(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings/export?format=ndjson&columns=id,launch_date", nil)
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"id":"00000000-0000-0000-0000-000000000001","launch_date":"2031-03-04T10:00:00Z"}`, lines[0])
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)))
+	}
+}
+
+func TestBookingExportHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*mockBookingService)
+		expectedCode int
+	}{
+		{
+			name:         "unknown format",
+			query:        "?format=xlsx",
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown column",
+			query:        "?columns=id,password",
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid filter",
+			query:        "?status=LOST",
+			setupMock:    func(m *mockBookingService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "failure before the first row",
+			query: "",
+			setupMock: func(m *mockBookingService) {
+				m.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockBookingService)
+			tt.setupMock(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/bookings/export"+tt.query, nil)
+			req.Header.Set("Accept", "application/json")
+			rr := httptest.NewRecorder()
+			api.BookingExportHandler(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBookingExportHandler_EmptyResult(t *testing.T) {
+	mockService := new(mockBookingService)
+	mockService.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/bookings/export?columns=id,status", nil)
+	rr := httptest.NewRecorder()
+	api.BookingExportHandler(mockService).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "id,status\n", rr.Body.String())
+}
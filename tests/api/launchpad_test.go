@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaunchpadClosureHandler(t *testing.T) {
+	const launchpadID = "5e9e4501f509094ba4566f84"
+
+	t.Run("closes a launchpad", func(t *testing.T) {
+		svc := new(mocks.MockLaunchpadService)
+		closedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		svc.On("CloseLaunchpad", mock.Anything, &models.LaunchpadClosureRequest{LaunchpadID: launchpadID, Reason: "storm damage"}).
+			Return(&models.LaunchpadClosure{LaunchpadID: launchpadID, Reason: "storm damage", ClosedAt: closedAt}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/launchpads/closures",
+			strings.NewReader(`{"launchpad_id":"`+launchpadID+`","reason":"storm damage"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		api.LaunchpadClosureHandler(svc).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var body models.LaunchpadClosure
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, launchpadID, body.LaunchpadID)
+		assert.Equal(t, closedAt, body.ClosedAt)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects a bad launchpad id", func(t *testing.T) {
+		svc := new(mocks.MockLaunchpadService)
+		req := httptest.NewRequest(http.MethodPost, "/v1/launchpads/closures", strings.NewReader(`{"launchpad_id":"pad"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		api.LaunchpadClosureHandler(svc).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "CloseLaunchpad", mock.Anything, mock.Anything)
+	})
+
+	t.Run("only admins manage launchpads", func(t *testing.T) {
+		svc := new(mocks.MockLaunchpadService)
+		svc.On("LaunchpadClosures", mock.Anything).Return(nil, models.ErrForbidden)
+
+		rr := httptest.NewRecorder()
+		api.LaunchpadClosureHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/launchpads/closures", nil))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("reopens a launchpad", func(t *testing.T) {
+		svc := new(mocks.MockLaunchpadService)
+		svc.On("ReopenLaunchpad", mock.Anything, launchpadID).Return(nil)
+
+		rr := httptest.NewRecorder()
+		api.LaunchpadClosureHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/launchpads/closures?launchpad_id="+launchpadID, nil))
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("reopening an open launchpad is not found", func(t *testing.T) {
+		svc := new(mocks.MockLaunchpadService)
+		svc.On("ReopenLaunchpad", mock.Anything, launchpadID).Return(models.ErrLaunchpadNotClosed)
+
+		rr := httptest.NewRecorder()
+		api.LaunchpadClosureHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/launchpads/closures?launchpad_id="+launchpadID, nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Contains(t, rr.Body.String(), "launchpad_not_closed")
+	})
+}
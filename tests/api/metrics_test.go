@@ -0,0 +1,45 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	get := func(ctx context.Context) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil).WithContext(ctx)
+		api.MetricsHandler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("serves admins", func(t *testing.T) {
+		rr := get(auth.WithPrincipal(context.Background(), auth.Principal{Role: auth.RoleAdmin}))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var vars map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &vars))
+		assert.Contains(t, vars, "memstats")
+	})
+
+	t.Run("forbids other roles", func(t *testing.T) {
+		for _, role := range []auth.Role{auth.RoleAgent, auth.RoleCustomer} {
+			rr := get(auth.WithPrincipal(context.Background(), auth.Principal{Role: role}))
+			assert.Equal(t, http.StatusForbidden, rr.Code, role)
+			assert.NotContains(t, rr.Body.String(), "memstats")
+		}
+	})
+
+	t.Run("requires a principal", func(t *testing.T) {
+		rr := get(context.Background())
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
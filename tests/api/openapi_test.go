@@ -0,0 +1,765 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/httpserver"
+	"github.com/chrisdamba/spacetrouble/internal/openapi"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	specAPIKey   = "spec-key"
+	specAgentKey = "spec-agent-key"
+)
+
+// specServices are the services behind the handlers in the spec tests.
+type specServices struct {
+	bookings     *mockBookingService
+	destinations *mockDestinationService
+	launchpads   *mocks.MockLaunchpadService
+	events       *stubEventService
+	webhooks     *mocks.MockWebhookService
+	risks        *mocks.MockRiskService
+	schedules    *mocks.MockSpaceXScheduler
+}
+
+func newSpecServices() *specServices {
+	return &specServices{
+		bookings:     new(mockBookingService),
+		destinations: new(mockDestinationService),
+		launchpads:   new(mocks.MockLaunchpadService),
+		events:       new(stubEventService),
+		webhooks:     new(mocks.MockWebhookService),
+		risks:        new(mocks.MockRiskService),
+		schedules:    new(mocks.MockSpaceXScheduler),
+	}
+}
+
+// specRoutes are the routes cmd/api serves, backed by s.
+func specRoutes(t *testing.T, s *specServices) []httpserver.Route {
+	t.Helper()
+	doc, err := openapi.Build()
+	require.NoError(t, err)
+	keys, err := auth.NewKeyStore(specAPIKey + ":admin," + specAgentKey + ":agent")
+	require.NoError(t, err)
+
+	return httpserver.Routes(httpserver.Services{
+		BookingService:     s.bookings,
+		DestinationService: s.destinations,
+		EventService:       s.events,
+		WebhookService:     s.webhooks,
+		RiskService:        s.risks,
+		LaunchpadService:   s.launchpads,
+		Launchpads:         s.schedules,
+	}, keys, newTestCursorSigner(t), doc)
+}
+
+func specRouter(t *testing.T, s *specServices) http.Handler {
+	t.Helper()
+	return httpserver.NewRouter(specRoutes(t, s))
+}
+
+// loadSpec fetches the served document, so the checks run against what
+// clients see rather than the Go values.
+func loadSpec(t *testing.T) map[string]interface{} {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	specRouter(t, newSpecServices()).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &spec))
+	require.Equal(t, openapi.Version, spec["openapi"])
+	return spec
+}
+
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// conforms checks value against a schema of the document. Objects are
+// treated as closed, so a field the handler adds without documenting it
+// is reported as drift.
+func conforms(spec, schema map[string]interface{}, value interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target := object(object(object(spec["components"])["schemas"])[name])
+		if target == nil {
+			return fmt.Errorf("%s: unresolved $ref %s", at, ref)
+		}
+		return conforms(spec, target, value, at)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, s := range oneOf {
+			if conforms(spec, object(s), value, at) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: matches %d of the oneOf schemas", at, matches)
+		}
+		return nil
+	}
+
+	switch schema["type"] {
+	case "object":
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", at, value)
+		}
+		props := object(schema["properties"])
+		if props == nil {
+			return nil
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := v[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %q", at, r)
+			}
+		}
+		for k, fv := range v {
+			ps := object(props[k])
+			if ps == nil {
+				return fmt.Errorf("%s: undocumented property %q", at, k)
+			}
+			if err := conforms(spec, ps, fv, at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		v, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", at, value)
+		}
+		for i, e := range v {
+			if err := conforms(spec, object(schema["items"]), e, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: want integer, got %v", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", at, value)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: want string, got %T", at, value)
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == s
+			}
+			if !found {
+				return fmt.Errorf("%s: %q is not in %v", at, s, enum)
+			}
+		}
+		switch schema["format"] {
+		case "uuid":
+			if !uuidPattern.MatchString(s) {
+				return fmt.Errorf("%s: %q is not a uuid", at, s)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, s)
+			}
+		}
+		if n, ok := schema["minLength"].(float64); ok && len(s) < int(n) {
+			return fmt.Errorf("%s: shorter than %v", at, n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && len(s) > int(n) {
+			return fmt.Errorf("%s: longer than %v", at, n)
+		}
+	}
+	return nil
+}
+
+type specCase struct {
+	name        string
+	method      string
+	path        string // as documented
+	target      string
+	body        string
+	contentType string
+	accept      string
+	headers     map[string]string
+	noAuth      bool
+	key         string // instead of the admin key
+	stream      bool   // the response stays open until the client goes away
+	setup       func(s *specServices)
+	status      int
+}
+
+func specCases() []specCase {
+	page := &models.AllBookingsResponse{
+		Bookings:   []models.BookingResponse{{Booking: goldenBooking}},
+		Limit:      10,
+		NextCursor: "next",
+	}
+	total := 1
+	counted := &models.AllBookingsResponse{Bookings: []models.BookingResponse{}, Limit: 10, TotalCount: &total}
+	batch := func(created, failed int) *models.BatchBookingResponse {
+		res := &models.BatchBookingResponse{Mode: models.BatchBestEffort, Created: created, Failed: failed}
+		for i := 0; i < created; i++ {
+			res.Results = append(res.Results, models.BatchRowResult{Index: len(res.Results), Status: models.RowCreated, Booking: &goldenBooking})
+		}
+		for i := 0; i < failed; i++ {
+			res.Results = append(res.Results, models.BatchRowResult{Index: len(res.Results), Status: models.RowFailed, Err: models.ErrBatchConflict})
+		}
+		return res
+	}
+	batchBody := "[" + goldenBookingBody + "," + goldenBookingBody + "]"
+	goldenClosure := models.LaunchpadClosure{
+		LaunchpadID: "5e9e4501f509094ba4566f84",
+		Reason:      "storm damage",
+		ClosedAt:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	disabledAt := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	goldenWebhook := models.WebhookSubscription{
+		ID:                  uuid.MustParse("6f1c2a3b-4d5e-4f60-8a7b-9c0d1e2f3a4b"),
+		URL:                 "https://hooks.example.com/spacetrouble",
+		EventTypes:          []models.BookingEventType{models.EventBookingCreated},
+		ConsecutiveFailures: 20,
+		DisabledAt:          &disabledAt,
+		CreatedAt:           time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	created := goldenWebhook
+	created.Secret, created.Active, created.ConsecutiveFailures, created.DisabledAt = "whsec_spec", true, 0, nil
+	delivered := time.Date(2030, 1, 1, 0, 0, 1, 0, time.UTC)
+	goldenDelivery := models.WebhookDelivery{
+		ID:             7,
+		SubscriptionID: goldenWebhook.ID,
+		EventID:        uuid.MustParse("0b6c1d2e-3f40-4a51-9b62-7c83d94ea5f6"),
+		EventType:      models.EventBookingCreated,
+		Status:         models.DeliveryDelivered,
+		Attempts:       1,
+		ResponseStatus: 200,
+		DeliveredAt:    &delivered,
+		CreatedAt:      delivered,
+	}
+	webhookBody := `{"url":"https://hooks.example.com/spacetrouble","event_types":["booking.created"]}`
+	webhookTarget := "/v1/webhooks?id=" + goldenWebhook.ID.String()
+	deliveriesTarget := "/v1/webhooks/deliveries?id=" + goldenWebhook.ID.String()
+	enableTarget := "/v1/webhooks/enable?id=" + goldenWebhook.ID.String()
+
+	return []specCase{
+		{name: "health", method: "GET", path: "/v1/health", target: "/v1/health", noAuth: true, status: 200},
+		{name: "health wrong method", method: "POST", path: "/v1/health", target: "/v1/health", noAuth: true, status: 405},
+		{name: "openapi", method: "GET", path: "/v1/openapi.json", target: "/v1/openapi.json", noAuth: true, status: 200},
+		{name: "openapi wrong method", method: "DELETE", path: "/v1/openapi.json", target: "/v1/openapi.json", noAuth: true, status: 405},
+
+		{name: "list bookings", method: "GET", path: "/v1/bookings", target: "/v1/bookings?limit=10",
+			setup: func(s *specServices) {
+				s.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(page, nil)
+			}, status: 200},
+		{name: "list bookings with total", method: "GET", path: "/v1/bookings", target: "/v1/bookings?include_total=true",
+			setup: func(s *specServices) {
+				s.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(counted, nil)
+			}, status: 200},
+		{name: "get booking", method: "GET", path: "/v1/bookings", target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			setup: func(s *specServices) {
+				s.bookings.On("GetBooking", mock.Anything, mock.Anything).Return(&goldenBooking, nil)
+			}, status: 200},
+		{name: "get booking invalid id", method: "GET", path: "/v1/bookings", target: "/v1/bookings?id=nope",
+			setup: func(s *specServices) {
+				s.bookings.On("GetBooking", mock.Anything, mock.Anything).Return(nil, models.ErrInvalidUUID)
+			}, status: 400},
+		{name: "get booking not found", method: "GET", path: "/v1/bookings", target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			setup: func(s *specServices) {
+				s.bookings.On("GetBooking", mock.Anything, mock.Anything).Return(nil, models.ErrBookingNotFound)
+			}, status: 404},
+		{name: "list bookings bad cursor", method: "GET", path: "/v1/bookings", target: "/v1/bookings?cursor=forged", status: 400},
+		{name: "list bookings forbidden", method: "GET", path: "/v1/bookings", target: "/v1/bookings",
+			setup: func(s *specServices) {
+				s.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(nil, models.ErrForbidden)
+			}, status: 403},
+		{name: "metrics", method: "GET", path: "/debug/vars", target: "/debug/vars", status: 200},
+		{name: "metrics unauthenticated", method: "GET", path: "/debug/vars", target: "/debug/vars", noAuth: true, status: 401},
+		{name: "metrics as agent", method: "GET", path: "/debug/vars", target: "/debug/vars", key: specAgentKey, status: 403},
+		{name: "metrics wrong method", method: "POST", path: "/debug/vars", target: "/debug/vars", status: 405},
+		{name: "list bookings unauthenticated", method: "GET", path: "/v1/bookings", target: "/v1/bookings", noAuth: true, status: 401},
+		{name: "list bookings not acceptable", method: "GET", path: "/v1/bookings", target: "/v1/bookings", accept: "text/html",
+			setup: func(s *specServices) {
+				s.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(page, nil)
+			}, status: 406},
+		{name: "list bookings failure", method: "GET", path: "/v1/bookings", target: "/v1/bookings",
+			setup: func(s *specServices) {
+				s.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("database down"))
+			}, status: 500},
+		{name: "bookings wrong method", method: "PUT", path: "/v1/bookings", target: "/v1/bookings", status: 405},
+
+		{name: "create booking", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: goldenBookingBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(&goldenBooking, nil)
+			}, status: 201},
+		{name: "create booking invalid", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: `{"first_name":"John"}`, contentType: "application/json", status: 400},
+		{name: "create booking unknown destination", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: goldenBookingBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(nil, models.ErrMissingDestination)
+			}, status: 404},
+		{name: "create booking conflict", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: goldenBookingBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(nil, models.ErrLaunchPadUnavailable)
+			}, status: 409},
+		{name: "create booking while spacex is unavailable", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: goldenBookingBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(nil, models.ErrSpaceXUnavailable)
+			}, status: 503},
+		{name: "create booking unsupported body", method: "POST", path: "/v1/bookings", target: "/v1/bookings", body: "x", contentType: "text/plain", status: 415},
+
+		{name: "cancel booking", method: "DELETE", path: "/v1/bookings", target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			setup: func(s *specServices) {
+				s.bookings.On("DeleteBooking", mock.Anything, mock.Anything).Return(nil)
+			}, status: 204},
+		{name: "cancel booking without id", method: "DELETE", path: "/v1/bookings", target: "/v1/bookings", status: 400},
+		{name: "cancel booking not found", method: "DELETE", path: "/v1/bookings", target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			setup: func(s *specServices) {
+				s.bookings.On("DeleteBooking", mock.Anything, mock.Anything).Return(models.ErrBookingNotFound)
+			}, status: 404},
+
+		{name: "reschedule booking", method: "POST", path: "/v1/bookings/reschedule", target: "/v1/bookings/reschedule?id=" + goldenBooking.ID.String(),
+			body: `{"launch_date":"2099-03-14T09:00:00Z"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("RescheduleBooking", mock.Anything, mock.Anything, mock.Anything).Return(&goldenBooking, nil)
+			}, status: 200},
+		{name: "reschedule booking without id", method: "POST", path: "/v1/bookings/reschedule", target: "/v1/bookings/reschedule",
+			body: `{"launch_date":"2099-03-14T09:00:00Z"}`, contentType: "application/json", status: 400},
+		{name: "reschedule booking conflict", method: "POST", path: "/v1/bookings/reschedule", target: "/v1/bookings/reschedule?id=" + goldenBooking.ID.String(),
+			body: `{"launch_date":"2099-03-14T09:00:00Z"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("RescheduleBooking", mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrLaunchPadUnavailable)
+			}, status: 409},
+		{name: "reschedule booking not found", method: "POST", path: "/v1/bookings/reschedule", target: "/v1/bookings/reschedule?id=" + goldenBooking.ID.String(),
+			body: `{"launch_date":"2099-03-14T09:00:00Z"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("RescheduleBooking", mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrBookingNotFound)
+			}, status: 404},
+		{name: "reschedule booking wrong method", method: "GET", path: "/v1/bookings/reschedule", target: "/v1/bookings/reschedule", status: 405},
+
+		{name: "batch all created", method: "POST", path: "/v1/bookings:batch", target: "/v1/bookings:batch?mode=best_effort", body: batchBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBookingsBatch", mock.Anything, mock.Anything, mock.Anything).Return(batch(2, 0), nil)
+			}, status: 201},
+		{name: "batch partial", method: "POST", path: "/v1/bookings:batch", target: "/v1/bookings:batch?mode=best_effort", body: batchBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBookingsBatch", mock.Anything, mock.Anything, mock.Anything).Return(batch(1, 1), nil)
+			}, status: 207},
+		{name: "batch none created", method: "POST", path: "/v1/bookings:batch", target: "/v1/bookings:batch", body: batchBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.bookings.On("CreateBookingsBatch", mock.Anything, mock.Anything, mock.Anything).Return(batch(0, 2), nil)
+			}, status: 422},
+		{name: "batch bad mode", method: "POST", path: "/v1/bookings:batch", target: "/v1/bookings:batch?mode=some", body: batchBody, contentType: "application/json", status: 400},
+
+		{name: "export csv", method: "GET", path: "/v1/bookings/export", target: "/v1/bookings/export",
+			setup: func(s *specServices) {
+				s.bookings.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return([]models.Booking{goldenBooking}, nil)
+			}, status: 200},
+		{name: "export ndjson", method: "GET", path: "/v1/bookings/export", target: "/v1/bookings/export?format=ndjson",
+			setup: func(s *specServices) {
+				s.bookings.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return([]models.Booking{goldenBooking}, nil)
+			}, status: 200},
+		{name: "export bad format", method: "GET", path: "/v1/bookings/export", target: "/v1/bookings/export?format=pdf", status: 400},
+
+		{name: "list destinations", method: "GET", path: "/v1/destinations", target: "/v1/destinations",
+			setup: func(s *specServices) {
+				s.destinations.On("AllDestinations", mock.Anything).Return([]models.Destination{goldenMars, goldenMoon}, nil)
+			}, status: 200},
+		{name: "create destination", method: "POST", path: "/v1/destinations", target: "/v1/destinations", body: `{"name":"Mars"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.destinations.On("CreateDestination", mock.Anything, mock.Anything).Return(&goldenMars, nil)
+			}, status: 201},
+		{name: "create destination forbidden", method: "POST", path: "/v1/destinations", target: "/v1/destinations", body: `{"name":"Mars"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.destinations.On("CreateDestination", mock.Anything, mock.Anything).Return(nil, models.ErrForbidden)
+			}, status: 403},
+		{name: "create destination invalid", method: "POST", path: "/v1/destinations", target: "/v1/destinations", body: `{}`, contentType: "application/json", status: 400},
+
+		{name: "list launchpad closures", method: "GET", path: "/v1/launchpads/closures", target: "/v1/launchpads/closures",
+			setup: func(s *specServices) {
+				s.launchpads.On("LaunchpadClosures", mock.Anything).Return([]models.LaunchpadClosure{goldenClosure}, nil)
+			}, status: 200},
+		{name: "close launchpad", method: "POST", path: "/v1/launchpads/closures", target: "/v1/launchpads/closures",
+			body: `{"launchpad_id":"5e9e4501f509094ba4566f84","reason":"storm damage"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.launchpads.On("CloseLaunchpad", mock.Anything, mock.Anything).Return(&goldenClosure, nil)
+			}, status: 201},
+		{name: "close launchpad forbidden", method: "POST", path: "/v1/launchpads/closures", target: "/v1/launchpads/closures",
+			body: `{"launchpad_id":"5e9e4501f509094ba4566f84"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.launchpads.On("CloseLaunchpad", mock.Anything, mock.Anything).Return(nil, models.ErrForbidden)
+			}, status: 403},
+		{name: "reopen launchpad", method: "DELETE", path: "/v1/launchpads/closures", target: "/v1/launchpads/closures?launchpad_id=5e9e4501f509094ba4566f84",
+			setup: func(s *specServices) {
+				s.launchpads.On("ReopenLaunchpad", mock.Anything, mock.Anything).Return(nil)
+			}, status: 204},
+		{name: "reopen open launchpad", method: "DELETE", path: "/v1/launchpads/closures", target: "/v1/launchpads/closures?launchpad_id=5e9e4501f509094ba4566f84",
+			setup: func(s *specServices) {
+				s.launchpads.On("ReopenLaunchpad", mock.Anything, mock.Anything).Return(models.ErrLaunchpadNotClosed)
+			}, status: 404},
+		{name: "booking events", method: "GET", path: "/v1/bookings/events", target: "/v1/bookings/events",
+			headers: map[string]string{"Last-Event-ID": "41"}, stream: true, status: 200},
+		{name: "booking events bad Last-Event-ID", method: "GET", path: "/v1/bookings/events", target: "/v1/bookings/events",
+			headers: map[string]string{"Last-Event-ID": "-1"}, status: 400},
+		{name: "booking events unauthenticated", method: "GET", path: "/v1/bookings/events", target: "/v1/bookings/events",
+			noAuth: true, status: 401},
+		{name: "booking events forbidden", method: "GET", path: "/v1/bookings/events", target: "/v1/bookings/events",
+			setup: func(s *specServices) {
+				s.events.err = models.ErrForbidden
+			}, status: 403},
+		{name: "at-risk bookings", method: "GET", path: "/v1/bookings/at-risk", target: "/v1/bookings/at-risk",
+			setup: func(s *specServices) {
+				s.risks.On("AtRiskBookings", mock.Anything).Return(goldenAtRiskReport(), nil)
+			}, status: 200},
+		{name: "at-risk bookings unauthenticated", method: "GET", path: "/v1/bookings/at-risk", target: "/v1/bookings/at-risk",
+			noAuth: true, status: 401},
+		{name: "at-risk bookings forbidden", method: "GET", path: "/v1/bookings/at-risk", target: "/v1/bookings/at-risk",
+			setup: func(s *specServices) {
+				s.risks.On("AtRiskBookings", mock.Anything).Return(nil, models.ErrForbidden)
+			}, status: 403},
+		{name: "at-risk bookings not acceptable", method: "GET", path: "/v1/bookings/at-risk", target: "/v1/bookings/at-risk",
+			accept: "text/html",
+			setup: func(s *specServices) {
+				s.risks.On("AtRiskBookings", mock.Anything).Return(goldenAtRiskReport(), nil)
+			}, status: 406},
+		{name: "at-risk bookings wrong method", method: "POST", path: "/v1/bookings/at-risk", target: "/v1/bookings/at-risk", status: 405},
+		{name: "graphql", method: "POST", path: "/v1/graphql", target: "/v1/graphql",
+			body:        `{"query":"query Destinations { destinations { id name } }","operationName":"Destinations","variables":{}}`,
+			contentType: "application/json",
+			setup: func(s *specServices) {
+				s.destinations.On("AllDestinations", mock.Anything).Return([]models.Destination{goldenMars}, nil)
+			}, status: 200},
+		{name: "graphql query error", method: "POST", path: "/v1/graphql", target: "/v1/graphql",
+			body: `{"query":"{ destinations { id"}`, contentType: "application/json", status: 200},
+		{name: "graphql without query", method: "POST", path: "/v1/graphql", target: "/v1/graphql",
+			body: `{}`, contentType: "application/json", status: 400},
+		{name: "graphql unauthenticated", method: "POST", path: "/v1/graphql", target: "/v1/graphql",
+			body: `{"query":"{ destinations { id } }"}`, contentType: "application/json", noAuth: true, status: 401},
+		{name: "graphql as text", method: "POST", path: "/v1/graphql", target: "/v1/graphql",
+			body: "{ destinations { id } }", contentType: "application/graphql", status: 415},
+		{name: "graphql wrong method", method: "GET", path: "/v1/graphql", target: "/v1/graphql", status: 405},
+		{name: "list webhooks", method: "GET", path: "/v1/webhooks", target: "/v1/webhooks",
+			setup: func(s *specServices) {
+				s.webhooks.On("ListWebhooks", mock.Anything).Return([]models.WebhookSubscription{goldenWebhook}, nil)
+			}, status: 200},
+		{name: "get webhook", method: "GET", path: "/v1/webhooks", target: webhookTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("GetWebhook", mock.Anything, goldenWebhook.ID.String()).Return(&goldenWebhook, nil)
+			}, status: 200},
+		{name: "get webhook bad id", method: "GET", path: "/v1/webhooks", target: "/v1/webhooks?id=nope",
+			setup: func(s *specServices) {
+				s.webhooks.On("GetWebhook", mock.Anything, "nope").Return(nil, models.ErrInvalidUUID)
+			}, status: 400},
+		{name: "get webhook not found", method: "GET", path: "/v1/webhooks", target: webhookTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("GetWebhook", mock.Anything, mock.Anything).Return(nil, models.ErrWebhookNotFound)
+			}, status: 404},
+		{name: "list webhooks unauthenticated", method: "GET", path: "/v1/webhooks", target: "/v1/webhooks", noAuth: true, status: 401},
+		{name: "create webhook", method: "POST", path: "/v1/webhooks", target: "/v1/webhooks",
+			body: webhookBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.webhooks.On("CreateWebhook", mock.Anything, mock.Anything).Return(&created, nil)
+			}, status: 201},
+		{name: "create webhook private url", method: "POST", path: "/v1/webhooks", target: "/v1/webhooks",
+			body: `{"url":"http://10.0.0.1/hook"}`, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.webhooks.On("CreateWebhook", mock.Anything, mock.Anything).Return(nil, models.ErrWebhookDestination)
+			}, status: 400},
+		{name: "create webhook unknown event type", method: "POST", path: "/v1/webhooks", target: "/v1/webhooks",
+			body: `{"url":"https://hooks.example.com","event_types":["booking.deleted"]}`, contentType: "application/json", status: 400},
+		{name: "create webhook forbidden", method: "POST", path: "/v1/webhooks", target: "/v1/webhooks",
+			body: webhookBody, contentType: "application/json",
+			setup: func(s *specServices) {
+				s.webhooks.On("CreateWebhook", mock.Anything, mock.Anything).Return(nil, models.ErrForbidden)
+			}, status: 403},
+		{name: "create webhook as xml", method: "POST", path: "/v1/webhooks", target: "/v1/webhooks",
+			body: "<webhook/>", contentType: "application/xml", status: 415},
+		{name: "delete webhook", method: "DELETE", path: "/v1/webhooks", target: webhookTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("DeleteWebhook", mock.Anything, goldenWebhook.ID.String()).Return(nil)
+			}, status: 204},
+		{name: "delete webhook without id", method: "DELETE", path: "/v1/webhooks", target: "/v1/webhooks", status: 400},
+		{name: "delete webhook not found", method: "DELETE", path: "/v1/webhooks", target: webhookTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("DeleteWebhook", mock.Anything, mock.Anything).Return(models.ErrWebhookNotFound)
+			}, status: 404},
+		{name: "webhooks wrong method", method: "PUT", path: "/v1/webhooks", target: "/v1/webhooks", status: 405},
+		{name: "webhook deliveries", method: "GET", path: "/v1/webhooks/deliveries", target: deliveriesTarget + "&limit=10",
+			setup: func(s *specServices) {
+				s.webhooks.On("WebhookDeliveries", mock.Anything, goldenWebhook.ID.String(), 10).
+					Return([]models.WebhookDelivery{goldenDelivery}, nil)
+			}, status: 200},
+		{name: "webhook deliveries limit too high", method: "GET", path: "/v1/webhooks/deliveries", target: deliveriesTarget + "&limit=501", status: 400},
+		{name: "webhook deliveries not found", method: "GET", path: "/v1/webhooks/deliveries", target: deliveriesTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("WebhookDeliveries", mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrWebhookNotFound)
+			}, status: 404},
+		{name: "enable webhook", method: "POST", path: "/v1/webhooks/enable", target: enableTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("EnableWebhook", mock.Anything, goldenWebhook.ID.String()).Return(&created, nil)
+			}, status: 200},
+		{name: "enable webhook not found", method: "POST", path: "/v1/webhooks/enable", target: enableTarget,
+			setup: func(s *specServices) {
+				s.webhooks.On("EnableWebhook", mock.Anything, mock.Anything).Return(nil, models.ErrWebhookNotFound)
+			}, status: 404},
+		{name: "enable webhook wrong method", method: "GET", path: "/v1/webhooks/enable", target: enableTarget, status: 405},
+		{name: "booking events wrong method", method: "POST", path: "/v1/bookings/events", target: "/v1/bookings/events", status: 405},
+	}
+}
+
+// TestOpenAPI_HandlersMatchSpec runs every documented operation through
+// the router and fails when a status, media type or body is not what the
+// document says.
+func TestOpenAPI_HandlersMatchSpec(t *testing.T) {
+	spec := loadSpec(t)
+	paths := object(spec["paths"])
+	exercised := make(map[string]bool)
+
+	for _, tc := range specCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			services := newSpecServices()
+			if tc.setup != nil {
+				tc.setup(services)
+			}
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if !tc.noAuth {
+				key := specAPIKey
+				if tc.key != "" {
+					key = tc.key
+				}
+				req.Header.Set("Authorization", "Bearer "+key)
+			}
+			if tc.stream {
+				ctx, cancel := context.WithCancel(req.Context())
+				cancel()
+				req = req.WithContext(ctx)
+			}
+			rr := httptest.NewRecorder()
+			specRouter(t, services).ServeHTTP(rr, req)
+			require.Equal(t, tc.status, rr.Code, rr.Body.String())
+
+			op := object(object(paths[tc.path])[strings.ToLower(tc.method)])
+			if op == nil {
+				require.Equal(t, 405, rr.Code, "undocumented operation %s %s", tc.method, tc.path)
+				return
+			}
+			status := strconv.Itoa(rr.Code)
+			exercised[tc.method+" "+tc.path+" "+status] = true
+			res := object(object(op["responses"])[status])
+			require.NotNil(t, res, "%s %s: status %s is not documented", tc.method, tc.path, status)
+
+			content := object(res["content"])
+			if content == nil {
+				assert.Empty(t, rr.Body.String(), "response without documented content has a body")
+				return
+			}
+			mediaType, _, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+			require.NoError(t, err)
+			media := object(content[mediaType])
+			require.NotNil(t, media, "media type %s is not documented", mediaType)
+			if mediaType != "application/json" {
+				return
+			}
+			var body interface{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.NoError(t, conforms(spec, object(media["schema"]), body, "body"))
+		})
+	}
+
+	// every documented success response must be exercised above
+	for path, item := range paths {
+		for method, op := range object(item) {
+			for status := range object(object(op)["responses"]) {
+				if strings.HasPrefix(status, "2") {
+					key := strings.ToUpper(method) + " " + path + " " + status
+					assert.True(t, exercised[key], "no case covers %s", key)
+				}
+			}
+		}
+	}
+}
+
+// TestOpenAPI_DocumentsEveryRoute compares the operations in the document
+// with the routes the server registers, so a route added without being
+// documented, or documented but never registered, is reported.
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	spec := loadSpec(t)
+	var documented []string
+	for path, item := range object(spec["paths"]) {
+		for method := range object(item) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	var registered []string
+	for _, route := range specRoutes(t, newSpecServices()) {
+		for _, method := range route.Methods {
+			registered = append(registered, method+" "+route.Path)
+		}
+	}
+	assert.ElementsMatch(t, registered, documented)
+}
+
+func validBookingRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"first_name":     "John",
+		"last_name":      "Doe",
+		"gender":         "male",
+		"birthday":       time.Now().AddDate(-30, 0, 0).UTC().Format(time.RFC3339),
+		"launchpad_id":   "5e9e4502f5090995de566f86",
+		"destination_id": uuid.NewString(),
+		"launch_date":    time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+	}
+}
+
+// TestOpenAPI_BookingRequestConstraints checks that the documented request
+// constraints are the ones the handler enforces: every required field and
+// every length, enum and format rule is broken in turn and must be
+// rejected, while dropping an optional field must not be.
+func TestOpenAPI_BookingRequestConstraints(t *testing.T) {
+	spec := loadSpec(t)
+	schema := object(object(object(spec["components"])["schemas"])["BookingRequest"])
+	require.NotNil(t, schema)
+	require.NoError(t, conforms(spec, schema, validBookingRequest(), "request"))
+
+	required := make(map[string]bool)
+	for _, r := range schema["required"].([]interface{}) {
+		required[r.(string)] = true
+	}
+
+	type variant struct {
+		name   string
+		mutate func(body map[string]interface{})
+		status int
+	}
+	var variants []variant
+	props := object(schema["properties"])
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		name, prop := name, object(props[name])
+		status := http.StatusCreated
+		if required[name] {
+			status = http.StatusBadRequest
+		}
+		variants = append(variants, variant{"without " + name, func(b map[string]interface{}) { delete(b, name) }, status})
+
+		if n, ok := prop["maxLength"].(float64); ok {
+			variants = append(variants, variant{name + " too long", func(b map[string]interface{}) {
+				b[name] = strings.Repeat("a", int(n)+1)
+			}, http.StatusBadRequest})
+		}
+		if n, ok := prop["minLength"].(float64); ok && n > 0 {
+			variants = append(variants, variant{name + " too short", func(b map[string]interface{}) {
+				b[name] = strings.Repeat("a", int(n)-1)
+			}, http.StatusBadRequest})
+		}
+		if _, ok := prop["enum"]; ok {
+			variants = append(variants, variant{name + " outside enum", func(b map[string]interface{}) {
+				b[name] = "not-listed"
+			}, http.StatusBadRequest})
+		}
+		if prop["format"] == "uuid" {
+			variants = append(variants, variant{name + " not a uuid", func(b map[string]interface{}) {
+				b[name] = "not-a-uuid"
+			}, http.StatusBadRequest})
+		}
+		if prop["format"] == "email" {
+			variants = append(variants, variant{name + " not an email", func(b map[string]interface{}) {
+				b[name] = "not-an-email"
+			}, http.StatusBadRequest})
+		}
+	}
+	variants = append(variants, variant{"valid", func(map[string]interface{}) {}, http.StatusCreated})
+
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			body := validBookingRequest()
+			v.mutate(body)
+			raw, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			services := newSpecServices()
+			services.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(&goldenBooking, nil).Maybe()
+			req := httptest.NewRequest(http.MethodPost, "/v1/bookings", strings.NewReader(string(raw)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+specAPIKey)
+			rr := httptest.NewRecorder()
+			specRouter(t, services).ServeHTTP(rr, req)
+
+			assert.Equal(t, v.status, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+// TestOpenAPI_WebhookEvent checks the documented webhook payload and
+// headers against what the dispatcher sends.
+func TestOpenAPI_WebhookEvent(t *testing.T) {
+	spec := loadSpec(t)
+	op := object(object(object(spec["webhooks"])["bookingEvent"])["post"])
+	require.NotNil(t, op)
+
+	headers := make(map[string]bool)
+	for _, p := range op["parameters"].([]interface{}) {
+		headers[object(p)["name"].(string)] = object(p)["in"] == "header"
+	}
+	assert.True(t, headers[webhooks.DeliveryHeader])
+	assert.True(t, headers[webhooks.SignatureHeader])
+
+	content := object(object(op["requestBody"])["content"])
+	media := object(content[outbox.ContentType])
+	require.NotNil(t, media, "%s is not documented", outbox.ContentType)
+
+	payload, err := json.Marshal(goldenBooking)
+	require.NoError(t, err)
+	event, err := json.Marshal(outbox.NewCloudEvent(models.OutboxEvent{
+		EventID:     uuid.New(),
+		AggregateID: goldenBooking.ID,
+		Type:        models.EventBookingStatusChanged,
+		Payload:     payload,
+		OccurredAt:  time.Now(),
+	}))
+	require.NoError(t, err)
+	var body interface{}
+	require.NoError(t, json.Unmarshal(event, &body))
+	assert.NoError(t, conforms(spec, object(media["schema"]), body, "event"))
+}
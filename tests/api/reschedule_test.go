@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookingRescheduleHandler(t *testing.T) {
+	bookingID := uuid.NewString()
+	launchDate := time.Now().AddDate(0, 2, 0).UTC().Truncate(time.Second)
+	body := `{"launch_date":"` + launchDate.Format(time.RFC3339) + `"}`
+
+	reschedule := func(svc *mocks.MockBookingService, target, body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		api.BookingRescheduleHandler(svc).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("returns the moved booking", func(t *testing.T) {
+		svc := new(mocks.MockBookingService)
+		moved := &models.Booking{ID: uuid.MustParse(bookingID), Status: models.StatusConfirmed, Flight: models.Flight{LaunchDate: launchDate}}
+		svc.On("RescheduleBooking", mock.Anything, bookingID, &models.RescheduleRequest{LaunchDate: launchDate}).Return(moved, nil)
+
+		rr := reschedule(svc, "/v1/bookings/reschedule?id="+bookingID, body, "application/json")
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var got models.Booking
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.True(t, launchDate.Equal(got.Flight.LaunchDate))
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("accepts a form body", func(t *testing.T) {
+		svc := new(mocks.MockBookingService)
+		svc.On("RescheduleBooking", mock.Anything, bookingID, mock.Anything).Return(&models.Booking{}, nil)
+
+		rr := reschedule(svc, "/v1/bookings/reschedule?id="+bookingID,
+			"launch_date="+launchDate.Format(time.RFC3339), "application/x-www-form-urlencoded")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("requires the booking id", func(t *testing.T) {
+		svc := new(mocks.MockBookingService)
+
+		rr := reschedule(svc, "/v1/bookings/reschedule", body, "application/json")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "RescheduleBooking", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a date in the past", func(t *testing.T) {
+		svc := new(mocks.MockBookingService)
+
+		rr := reschedule(svc, "/v1/bookings/reschedule?id="+bookingID, `{"launch_date":"2001-01-01T00:00:00Z"}`, "application/json")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "RescheduleBooking", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("maps service errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			err  error
+			code int
+		}{
+			{models.ErrBookingNotFound, http.StatusNotFound},
+			{models.ErrLaunchPadUnavailable, http.StatusConflict},
+			{models.ErrInvalidRequest, http.StatusBadRequest},
+			{models.ErrSpaceXUnavailable, http.StatusServiceUnavailable},
+		} {
+			svc := new(mocks.MockBookingService)
+			svc.On("RescheduleBooking", mock.Anything, bookingID, mock.Anything).Return(nil, tc.err)
+
+			rr := reschedule(svc, "/v1/bookings/reschedule?id="+bookingID, body, "application/json")
+
+			assert.Equal(t, tc.code, rr.Code, tc.err.Error())
+		}
+	})
+}
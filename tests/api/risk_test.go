@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtRiskBookingsHandler(t *testing.T) {
+	alternative := time.Date(2030, 6, 14, 9, 0, 0, 0, time.UTC)
+	report := &models.AtRiskReport{Bookings: []models.BookingRisk{{
+		Booking:          models.Booking{ID: uuid.New(), Status: models.StatusAtRisk},
+		Reason:           "SpaceX has scheduled a launch from launchpad 5e9e4501f509094ba4566f84 on 2030-06-15 (day precision)",
+		PreviousStatus:   models.StatusConfirmed,
+		AlternativeDates: []time.Time{alternative},
+	}}}
+
+	t.Run("lists at-risk bookings", func(t *testing.T) {
+		svc := new(mocks.MockRiskService)
+		svc.On("AtRiskBookings", mock.Anything).Return(report, nil)
+
+		rr := httptest.NewRecorder()
+		api.AtRiskBookingsHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/bookings/at-risk", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body models.AtRiskReport
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body.Bookings, 1)
+		assert.Equal(t, models.StatusAtRisk, body.Bookings[0].Booking.Status)
+		assert.Equal(t, models.StatusConfirmed, body.Bookings[0].PreviousStatus)
+		assert.Equal(t, report.Bookings[0].Reason, body.Bookings[0].Reason)
+		assert.Equal(t, []time.Time{alternative}, body.Bookings[0].AlternativeDates)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("renders xml", func(t *testing.T) {
+		svc := new(mocks.MockRiskService)
+		svc.On("AtRiskBookings", mock.Anything).Return(report, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/bookings/at-risk", nil)
+		req.Header.Set("Accept", "application/xml")
+		rr := httptest.NewRecorder()
+		api.AtRiskBookingsHandler(svc).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<alternative_dates><date>2030-06-14T09:00:00Z</date></alternative_dates>")
+	})
+
+	t.Run("maps service errors", func(t *testing.T) {
+		svc := new(mocks.MockRiskService)
+		svc.On("AtRiskBookings", mock.Anything).Return(nil, models.ErrUnauthenticated)
+
+		rr := httptest.NewRecorder()
+		api.AtRiskBookingsHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/bookings/at-risk", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
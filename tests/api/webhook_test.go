@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	t.Run("subscribes", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		created := &models.WebhookSubscription{ID: uuid.New(), URL: "https://partner.example.com/hooks", Secret: "whsec_abc", Active: true,
+			EventTypes: []models.BookingEventType{models.EventBookingCancelled}}
+		svc.On("CreateWebhook", mock.Anything, &models.WebhookRequest{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []models.BookingEventType{models.EventBookingCancelled},
+		}).Return(created, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/webhooks",
+			strings.NewReader(`{"url":"https://partner.example.com/hooks","event_types":["booking.cancelled"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		api.WebhookHandler(svc).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "whsec_abc", body["secret"])
+		assert.Equal(t, []interface{}{"booking.cancelled"}, body["event_types"])
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects bad subscriptions", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"missing url":        `{}`,
+			"not http":           `{"url":"ftp://partner.example.com"}`,
+			"unknown event type": `{"url":"https://partner.example.com","event_types":["booking.deleted"]}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				svc := new(mocks.MockWebhookService)
+				req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				rr := httptest.NewRecorder()
+				api.WebhookHandler(svc).ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+				svc.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		svc.On("ListWebhooks", mock.Anything).Return([]models.WebhookSubscription{{ID: uuid.New(), URL: "https://a.example.com", Active: true}}, nil)
+
+		rr := httptest.NewRecorder()
+		api.WebhookHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body models.WebhookList
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Len(t, body.Webhooks, 1)
+	})
+
+	t.Run("unsubscribes", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		id := uuid.NewString()
+		svc.On("DeleteWebhook", mock.Anything, id).Return(nil)
+
+		rr := httptest.NewRecorder()
+		api.WebhookHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/webhooks?id="+id, nil))
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("unknown subscription", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		id := uuid.NewString()
+		svc.On("GetWebhook", mock.Anything, id).Return(nil, models.ErrWebhookNotFound)
+
+		rr := httptest.NewRecorder()
+		api.WebhookHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/webhooks?id="+id, nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"webhook_not_found"`)
+	})
+}
+
+func TestWebhookDeliveriesHandler(t *testing.T) {
+	t.Run("returns the delivery log", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		id := uuid.NewString()
+		svc.On("WebhookDeliveries", mock.Anything, id, 50).Return([]models.WebhookDelivery{
+			{ID: 2, Status: models.DeliveryPending, Attempts: 1, ResponseStatus: 503, LastError: "endpoint responded 503"},
+			{ID: 1, Status: models.DeliveryDelivered, Attempts: 1, ResponseStatus: 200},
+		}, nil)
+
+		rr := httptest.NewRecorder()
+		api.WebhookDeliveriesHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/webhooks/deliveries?id="+id, nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body map[string][]map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body["deliveries"], 2)
+		assert.Equal(t, "endpoint responded 503", body["deliveries"][0]["last_error"])
+		assert.NotContains(t, body["deliveries"][0], "secret")
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("validates parameters", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		for _, target := range []string{"/v1/webhooks/deliveries", "/v1/webhooks/deliveries?id=" + uuid.NewString() + "&limit=0"} {
+			rr := httptest.NewRecorder()
+			api.WebhookDeliveriesHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		}
+	})
+}
+
+func TestWebhookEnableHandler(t *testing.T) {
+	t.Run("re-enables", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		id := uuid.New()
+		svc.On("EnableWebhook", mock.Anything, id.String()).Return(&models.WebhookSubscription{ID: id, URL: "https://a.example.com", Active: true}, nil)
+
+		rr := httptest.NewRecorder()
+		api.WebhookEnableHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/webhooks/enable?id="+id.String(), nil))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var body models.WebhookSubscription
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.True(t, body.Active)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("refuses an endpoint that is not public", func(t *testing.T) {
+		svc := new(mocks.MockWebhookService)
+		id := uuid.NewString()
+		svc.On("EnableWebhook", mock.Anything, id).Return(nil, models.ErrWebhookDestination)
+
+		rr := httptest.NewRecorder()
+		api.WebhookEnableHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/webhooks/enable?id="+id, nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"webhook_destination_not_allowed"`)
+	})
+
+	t.Run("requires an id", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		api.WebhookEnableHandler(new(mocks.MockWebhookService)).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/webhooks/enable", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
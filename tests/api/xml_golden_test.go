@@ -0,0 +1,294 @@
+package api_test
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+type mockDestinationService struct {
+	mock.Mock
+}
+
+func (m *mockDestinationService) AllDestinations(ctx context.Context) ([]models.Destination, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *mockDestinationService) DestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *mockDestinationService) CreateDestination(ctx context.Context, request *models.DestinationRequest) (*models.Destination, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Destination), args.Error(1)
+}
+
+var (
+	goldenMars = models.Destination{ID: uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), Name: "Mars"}
+	goldenMoon = models.Destination{ID: uuid.MustParse("b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a22"), Name: "Moon"}
+
+	goldenBooking = models.Booking{
+		ID: uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		User: models.User{
+			ID:        uuid.MustParse("123e4567-e89b-12d3-a456-426614174001"),
+			FirstName: "John",
+			LastName:  "Doe",
+			Gender:    "male",
+			Birthday:  time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Flight: models.Flight{
+			ID:          uuid.MustParse("123e4567-e89b-12d3-a456-426614174002"),
+			LaunchpadID: "5e9e4502f5090995de566f86",
+			Destination: goldenMars,
+			LaunchDate:  time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Status:    models.StatusConfirmed,
+		CreatedAt: time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+)
+
+// goldenAtRiskReport flags goldenBooking, which was confirmed before a
+// launch was scheduled on its date.
+func goldenAtRiskReport() *models.AtRiskReport {
+	booking := goldenBooking
+	booking.Status = models.StatusAtRisk
+	return &models.AtRiskReport{Bookings: []models.BookingRisk{{
+		Booking:          booking,
+		Reason:           "SpaceX has scheduled a launch from launchpad 5e9e4502f5090995de566f86 on 2031-01-01 (day precision)",
+		PreviousStatus:   models.StatusConfirmed,
+		AlternativeDates: []time.Time{time.Date(2031, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2031, 1, 3, 0, 0, 0, 0, time.UTC)},
+		FlaggedAt:        time.Date(2030, 12, 1, 6, 0, 0, 0, time.UTC),
+	}}}
+}
+
+const goldenBookingBody = `{"first_name":"John","last_name":"Doe","gender":"male","birthday":"1990-01-01T00:00:00Z",` +
+	`"launchpad_id":"5e9e4502f5090995de566f86","destination_id":"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11","launch_date":"2031-01-01T00:00:00Z"}`
+
+// TestXMLGolden checks the XML body of every endpoint that renders XML
+// against testdata/xml. Run with -update to rewrite the files.
+func TestXMLGolden(t *testing.T) {
+	total := 1
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		handler func(t *testing.T) http.Handler
+		status  int
+	}{
+		{
+			name:   "create_booking",
+			method: http.MethodPost,
+			target: "/v1/bookings",
+			body:   goldenBookingBody,
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("CreateBooking", mock.Anything, mock.Anything).Return(&goldenBooking, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "get_booking",
+			method: http.MethodGet,
+			target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("GetBooking", mock.Anything, goldenBooking.ID.String()).Return(&goldenBooking, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "get_booking_with_email",
+			method: http.MethodGet,
+			target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			handler: func(t *testing.T) http.Handler {
+				booking := goldenBooking
+				booking.User.Email = "john.doe@example.com"
+				m := new(mockBookingService)
+				m.On("GetBooking", mock.Anything, goldenBooking.ID.String()).Return(&booking, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "list_bookings",
+			method: http.MethodGet,
+			target: "/v1/bookings?include_total=true",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("AllBookings", mock.Anything, mock.Anything).Return(&models.AllBookingsResponse{
+					Bookings:   []models.BookingResponse{{Booking: goldenBooking}},
+					Limit:      10,
+					TotalCount: &total,
+				}, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "list_bookings_empty",
+			method: http.MethodGet,
+			target: "/v1/bookings",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("AllBookings", mock.Anything, mock.Anything).Return(&models.AllBookingsResponse{Limit: 10}, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "create_booking_pending",
+			method: http.MethodPost,
+			target: "/v1/bookings",
+			body:   goldenBookingBody,
+			handler: func(t *testing.T) http.Handler {
+				pending := goldenBooking
+				pending.Status = models.StatusPendingVerification
+				m := new(mockBookingService)
+				m.On("CreateBooking", mock.Anything, mock.Anything).Return(&pending, nil)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "get_booking_not_found",
+			method: http.MethodGet,
+			target: "/v1/bookings?id=" + goldenBooking.ID.String(),
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("GetBooking", mock.Anything, mock.Anything).Return(nil, models.ErrBookingNotFound)
+				return api.BookingHandler(m, newTestCursorSigner(t))
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "create_booking_invalid",
+			method: http.MethodPost,
+			target: "/v1/bookings",
+			body:   `{"first_name":"John"}`,
+			handler: func(t *testing.T) http.Handler {
+				return api.BookingHandler(new(mockBookingService), newTestCursorSigner(t))
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "batch_bookings",
+			method: http.MethodPost,
+			target: "/v1/bookings:batch?mode=best_effort",
+			body:   "[" + goldenBookingBody + "," + goldenBookingBody + "]",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockBookingService)
+				m.On("CreateBookingsBatch", mock.Anything, mock.Anything, models.BatchBestEffort).Return(&models.BatchBookingResponse{
+					Mode:    models.BatchBestEffort,
+					Created: 1,
+					Failed:  1,
+					Results: []models.BatchRowResult{
+						{Index: 0, Status: models.RowCreated, Booking: &goldenBooking},
+						{Index: 1, Status: models.RowFailed, Err: models.ErrBatchConflict},
+					},
+				}, nil)
+				return api.BookingBatchHandler(m)
+			},
+			status: http.StatusMultiStatus,
+		},
+		{
+			name:   "at_risk_bookings",
+			method: http.MethodGet,
+			target: "/v1/bookings/at-risk",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mocks.MockRiskService)
+				m.On("AtRiskBookings", mock.Anything).Return(goldenAtRiskReport(), nil)
+				return api.AtRiskBookingsHandler(m)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "create_destination",
+			method: http.MethodPost,
+			target: "/v1/destinations",
+			body:   `{"name":"Mars"}`,
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockDestinationService)
+				m.On("CreateDestination", mock.Anything, mock.Anything).Return(&goldenMars, nil)
+				return api.DestinationHandler(m)
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "list_destinations",
+			method: http.MethodGet,
+			target: "/v1/destinations",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockDestinationService)
+				m.On("AllDestinations", mock.Anything).Return([]models.Destination{goldenMars, goldenMoon}, nil)
+				return api.DestinationHandler(m)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "list_destinations_empty",
+			method: http.MethodGet,
+			target: "/v1/destinations",
+			handler: func(t *testing.T) http.Handler {
+				m := new(mockDestinationService)
+				m.On("AllDestinations", mock.Anything).Return([]models.Destination{}, nil)
+				return api.DestinationHandler(m)
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set("Accept", "application/xml")
+			rr := httptest.NewRecorder()
+
+			tt.handler(t).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, "application/xml", rr.Header().Get("Content-Type"))
+
+			golden := filepath.Join("testdata", "xml", tt.name+".xml")
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+				require.NoError(t, os.WriteFile(golden, append(rr.Body.Bytes(), '\n'), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, strings.TrimSuffix(string(want), "\n"), rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyStore(t *testing.T) {
+	customerID := uuid.New()
+
+	tests := []struct {
+		name    string
+		spec    string
+		token   string
+		want    auth.Principal
+		wantErr bool
+	}{
+		{
+			name:  "admin key",
+			spec:  "adm:admin",
+			token: "adm",
+			want:  auth.Principal{Role: auth.RoleAdmin},
+		},
+		{
+			name:  "customer key with user",
+			spec:  "adm:admin, cust:customer:" + customerID.String(),
+			token: "cust",
+			want:  auth.Principal{Role: auth.RoleCustomer, UserID: customerID},
+		},
+		{
+			name:    "customer key without user",
+			spec:    "cust:customer",
+			wantErr: true,
+		},
+		{
+			name:    "unknown role",
+			spec:    "key:pilot",
+			wantErr: true,
+		},
+		{
+			name:    "malformed entry",
+			spec:    "justakey",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := auth.NewKeyStore(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			p, err := store.Authenticate(tt.token)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+
+			_, err = store.Authenticate("unknown")
+			assert.Equal(t, models.ErrUnauthenticated, err)
+		})
+	}
+}
+
+func TestPrincipalPermissions(t *testing.T) {
+	userID := uuid.New()
+	customer := auth.Principal{Role: auth.RoleCustomer, UserID: userID}
+	agent := auth.Principal{Role: auth.RoleAgent}
+	admin := auth.Principal{Role: auth.RoleAdmin}
+
+	assert.True(t, customer.Owns(userID))
+	assert.False(t, customer.Owns(uuid.New()))
+	assert.True(t, agent.Owns(uuid.New()))
+
+	assert.False(t, customer.Can(auth.PermBookOnBehalf))
+	assert.True(t, agent.Can(auth.PermBookOnBehalf))
+	assert.False(t, agent.Can(auth.PermManageDestinations))
+	assert.True(t, admin.Can(auth.PermManageDestinations))
+	assert.False(t, agent.Can(auth.PermManageLaunchpads))
+	assert.True(t, admin.Can(auth.PermManageLaunchpads))
+	assert.False(t, agent.Can(auth.PermViewMetrics))
+	assert.True(t, admin.Can(auth.PermViewMetrics))
+}
+
+func TestRequire(t *testing.T) {
+	_, err := auth.Require(context.Background(), auth.PermBookOwn)
+	assert.Equal(t, models.ErrUnauthenticated, err)
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Role: auth.RoleAgent})
+	_, err = auth.Require(ctx, auth.PermManageDestinations)
+	assert.Equal(t, models.ErrForbidden, err)
+
+	p, err := auth.Require(ctx, auth.PermBookOnBehalf)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleAgent, p.Role)
+}
+
+func TestRequireAuth(t *testing.T) {
+	store, err := auth.NewKeyStore("secret:agent")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "valid token", authorization: "Bearer secret", wantStatus: http.StatusOK},
+		{name: "missing header", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", authorization: "Basic secret", wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", authorization: "Bearer nope", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got auth.Principal
+			handler := auth.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = auth.PrincipalFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/bookings", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, auth.RoleAgent, got.Role)
+			} else {
+				assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package events_test
+
+import (
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_DeliversToEverySubscriber(t *testing.T) {
+	broker := events.NewBroker()
+	first, cancelFirst := broker.Subscribe()
+	defer cancelFirst()
+	second, cancelSecond := broker.Subscribe()
+	defer cancelSecond()
+
+	broker.Publish(models.BookingEvent{ID: 7, Type: models.EventBookingCreated})
+
+	assert.Equal(t, int64(7), (<-first).ID)
+	assert.Equal(t, int64(7), (<-second).ID)
+}
+
+func TestBroker_CancelClosesChannel(t *testing.T) {
+	broker := events.NewBroker()
+	ch, cancel := broker.Subscribe()
+
+	cancel()
+	cancel()
+	broker.Publish(models.BookingEvent{ID: 1})
+
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	broker := events.NewBroker()
+	slow, cancel := broker.Subscribe()
+	defer cancel()
+
+	for i := 1; i <= 100; i++ {
+		broker.Publish(models.BookingEvent{ID: int64(i)})
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Less(t, received, 100, "the channel is closed once the subscriber falls behind")
+}
@@ -0,0 +1,119 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/events"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn serves queued notifications and then fails, as a dropped
+// connection would.
+type fakeConn struct {
+	payloads []string
+	execs    []string
+	closed   bool
+}
+
+func (c *fakeConn) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	return pgconn.NewCommandTag("LISTEN"), nil
+}
+
+func (c *fakeConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	if len(c.payloads) == 0 {
+		return nil, errors.New("connection lost")
+	}
+	p := c.payloads[0]
+	c.payloads = c.payloads[1:]
+	return &pgconn.Notification{Channel: events.Channel, Payload: p}, nil
+}
+
+func (c *fakeConn) Close(ctx context.Context) error {
+	c.closed = true
+	return nil
+}
+
+type fakeStore struct {
+	events []models.BookingEvent
+	asked  []int64
+}
+
+func (s *fakeStore) EventsSince(ctx context.Context, afterID int64, limit int) ([]models.BookingEvent, error) {
+	s.asked = append(s.asked, afterID)
+	var out []models.BookingEvent
+	for _, e := range s.events {
+		if e.ID > afterID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestListener_RelaysAndCatchesUpAfterReconnect(t *testing.T) {
+	conns := []*fakeConn{
+		{payloads: []string{
+			`{"id":1,"type":"booking.created","booking":{"status":"ACTIVE"}}`,
+			`not json`,
+			`{"id":2,"type":"booking.cancelled","booking":{"status":"CANCELLED"},"previous_status":"ACTIVE"}`,
+		}},
+		// event 4 is notified after the catch-up already returned it
+		{payloads: []string{`{"id":4,"type":"booking.created"}`, `{"id":5,"type":"booking.created"}`}},
+	}
+	// events 3 and 4 were recorded while the listener was reconnecting
+	store := &fakeStore{events: []models.BookingEvent{
+		{ID: 1}, {ID: 2}, {ID: 3, Type: models.EventBookingCreated}, {ID: 4, Type: models.EventBookingCreated},
+	}}
+	broker := events.NewBroker()
+	received, cancel := broker.Subscribe()
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	connects := 0
+	listener := events.NewListener(func(ctx context.Context) (events.NotifyConn, error) {
+		if connects == len(conns) {
+			stop()
+			return nil, ctx.Err()
+		}
+		connects++
+		return conns[connects-1], nil
+	}, store, broker)
+
+	done := make(chan error)
+	go func() { done <- listener.Run(ctx) }()
+
+	var got []models.BookingEvent
+	for len(got) < 5 {
+		select {
+		case e := <-received:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received only %d events", len(got))
+		}
+	}
+	stop()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids(got))
+	assert.Equal(t, models.StatusActive, got[1].PreviousStatus)
+	assert.Equal(t, []int64{2}, store.asked, "the first connection has nothing to catch up on")
+	for _, c := range conns {
+		require.Equal(t, []string{"LISTEN booking_events"}, c.execs)
+		assert.True(t, c.closed)
+	}
+}
+
+func ids(events []models.BookingEvent) []int64 {
+	out := []int64{}
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
@@ -0,0 +1,226 @@
+package fakespacex_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/fakespacex"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Now()
+
+const fixture = `
+launchpads:
+  - id: pad1
+    status: active
+  - id: pad2
+    status: retired
+launches:
+  - launchpad: pad1
+    in: 48h
+    date_precision: day
+  - launchpad: pad1
+    date_unix: 946684800
+    date_precision: hour
+`
+
+func newServer(t *testing.T, data string, opts ...fakespacex.Option) (*httptest.Server, *spacex.Client) {
+	t.Helper()
+	scenario, err := fakespacex.ParseScenario([]byte(data), now)
+	require.NoError(t, err)
+	server := httptest.NewServer(fakespacex.NewServer(scenario, opts...))
+	t.Cleanup(server.Close)
+	client := spacex.NewClient(
+		spacex.WithBaseURL(server.URL+fakespacex.APIPrefix),
+		spacex.WithRetries(3, time.Millisecond, 5*time.Millisecond),
+	)
+	return server, client
+}
+
+func TestServerAnswersTheClient(t *testing.T) {
+	_, client := newServer(t, fixture)
+	ctx := context.Background()
+
+	launchpad, err := client.GetLaunchPadById(ctx, "pad1")
+	require.NoError(t, err)
+	assert.Equal(t, spacex.LaunchPad{Id: "pad1", Status: "active"}, launchpad)
+
+	_, err = client.GetLaunchPadById(ctx, "nope")
+	assert.ErrorIs(t, err, spacex.ErrNotFound)
+
+	launches, err := client.GetUpcomingLaunchesLaunchPad(ctx, "pad1")
+	require.NoError(t, err)
+	require.Len(t, launches, 1, "past launches are not upcoming")
+	assert.Equal(t, now.Add(48*time.Hour).Unix(), launches[0].Date)
+
+	available, err := client.CheckLaunchConflict(ctx, "pad1", now.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, available)
+	available, err = client.CheckLaunchConflict(ctx, "pad1", now.Add(96*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	schedules, err := client.Schedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Len(t, schedules[0].Launches, 1)
+	assert.Empty(t, schedules[1].Launches)
+}
+
+func TestServerPaginatesQueries(t *testing.T) {
+	server, _ := newServer(t, fixture)
+
+	resp, err := http.Post(server.URL+"/v4/launches/query", "application/json",
+		strings.NewReader(`{"query":{"launchpad":"pad1"},"options":{"limit":1,"page":2,"sort":{"date_unix":"desc"}}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var page struct {
+		Docs []struct {
+			DateUnix int64 `json:"date_unix"`
+		} `json:"docs"`
+		TotalDocs   int  `json:"totalDocs"`
+		HasNextPage bool `json:"hasNextPage"`
+		PrevPage    *int `json:"prevPage"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Len(t, page.Docs, 1)
+	assert.EqualValues(t, 946684800, page.Docs[0].DateUnix)
+	assert.Equal(t, 2, page.TotalDocs)
+	assert.False(t, page.HasNextPage)
+	require.NotNil(t, page.PrevPage)
+	assert.Equal(t, 1, *page.PrevPage)
+}
+
+func TestServerRejectsQueriesItCannotAnswer(t *testing.T) {
+	server, _ := newServer(t, fixture)
+
+	resp, err := http.Post(server.URL+"/v4/launches/query", "application/json",
+		strings.NewReader(`{"query":{"date_utc":{"$gte":"2030-01-01"}}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerInjectsFaults(t *testing.T) {
+	t.Run("5xx until the retries run out", func(t *testing.T) {
+		_, client := newServer(t, fixture+`
+faults:
+  - path: /launchpads
+    status: 503
+`)
+		_, err := client.GetLaunchPadById(context.Background(), "pad1")
+
+		assert.ErrorIs(t, err, spacex.ErrBadStatusCode)
+		assert.True(t, spacex.IsUnavailable(err))
+	})
+
+	t.Run("a limited number of failures is retried through", func(t *testing.T) {
+		_, client := newServer(t, fixture+`
+faults:
+  - path: /launches/query
+    status: 500
+    times: 2
+`)
+		launches, err := client.GetUpcomingLaunchesLaunchPad(context.Background(), "pad1")
+
+		require.NoError(t, err)
+		assert.Len(t, launches, 1)
+	})
+
+	t.Run("429 with Retry-After", func(t *testing.T) {
+		server, _ := newServer(t, fixture+`
+faults:
+  - status: 429
+    retry_after: 2s
+`)
+		resp, err := http.Get(server.URL + "/v4/launchpads/pad1")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		rolls := []float64{0.1, 0.9}
+		server, _ := newServer(t, fixture+`
+faults:
+  - status: 502
+    rate: 0.5
+`, fakespacex.WithRand(func() float64 {
+			roll := rolls[0]
+			rolls = rolls[1:]
+			return roll
+		}))
+
+		for _, want := range []int{http.StatusBadGateway, http.StatusOK} {
+			resp, err := http.Get(server.URL + "/v4/launchpads/pad1")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, want, resp.StatusCode)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		_, client := newServer(t, fixture+`
+faults:
+  - latency: 50ms
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.GetLaunchPadById(ctx, "pad1")
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestParseScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "json", data: `{"launchpads":[{"id":"pad1","status":"active"}],"faults":[{"latency":"1s","status":503}]}`},
+		{name: "launch from an unknown launchpad", data: "launches:\n  - launchpad: nope\n", wantErr: `unknown launchpad "nope"`},
+		{name: "fault that is not an error", data: "faults:\n  - status: 200\n", wantErr: "is not an error"},
+		{name: "fault rate above 1", data: "faults:\n  - status: 500\n    rate: 2\n", wantErr: "is not between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fakespacex.ParseScenario([]byte(tt.data), now)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBundledScenariosLoad(t *testing.T) {
+	paths, err := filepath.Glob("../../cmd/fakespacex/scenarios/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			scenario, err := fakespacex.LoadScenario(path, now)
+			require.NoError(t, err)
+			assert.NotEmpty(t, scenario.Launchpads)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package graphql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/internal/graphql"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mars = models.Destination{ID: uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), Name: "Mars"}
+	moon = models.Destination{ID: uuid.MustParse("b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a22"), Name: "Moon"}
+
+	padA = "5e9e4502f5090995de566f86"
+	padB = "5e9e4501f509094ba4566f84"
+)
+
+type fixture struct {
+	bookings     *mocks.MockBookingService
+	destinations *mocks.MockDestinationService
+	launchpads   *mocks.MockSpaceXScheduler
+	cursors      *utils.CursorSigner
+	handler      http.HandlerFunc
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	cursors, err := utils.NewCursorSigner([]utils.SigningKey{{Kid: "k1", Secret: []byte("secret")}}, time.Hour)
+	require.NoError(t, err)
+
+	f := &fixture{
+		bookings:     new(mocks.MockBookingService),
+		destinations: new(mocks.MockDestinationService),
+		launchpads:   new(mocks.MockSpaceXScheduler),
+		cursors:      cursors,
+	}
+	f.handler = graphql.Handler(f.bookings, f.destinations, f.launchpads, cursors)
+	return f
+}
+
+type gqlError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+func (f *fixture) exec(t *testing.T, query string, variables map[string]interface{}) gqlResponse {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	f.handler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res gqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res
+}
+
+func booking(dest models.Destination, pad string) models.BookingResponse {
+	return models.BookingResponse{Booking: models.Booking{
+		ID:     uuid.New(),
+		User:   models.User{ID: uuid.New(), FirstName: "John", LastName: "Doe", Gender: "male"},
+		Flight: models.Flight{ID: uuid.New(), LaunchpadID: pad, Destination: dest, LaunchDate: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Status: models.StatusActive,
+	}}
+}
+
+func schedule(id string) *spacex.Schedule {
+	return &spacex.Schedule{
+		LaunchPad: spacex.LaunchPad{Id: id, Status: "active"},
+		Launches:  []spacex.Launch{{LaunchPadID: id, Date: 1924992000, DatePrecision: "day"}},
+	}
+}
+
+const pageQuery = `{
+	bookings(limit: 4) {
+		nextCursor
+		bookings {
+			id
+			passenger { firstName }
+			flight {
+				destination { id name }
+				launchpad { id active upcomingLaunches { date datePrecision } }
+			}
+		}
+	}
+}`
+
+func TestBookings_BatchesNestedLookups(t *testing.T) {
+	f := newFixture(t)
+	// Bookings whose destination was not joined in carry only its id.
+	unjoined := models.Destination{ID: moon.ID}
+	page := &models.AllBookingsResponse{
+		Bookings: []models.BookingResponse{
+			booking(mars, padA), booking(unjoined, padA), booking(unjoined, padB), booking(mars, padB),
+		},
+		NextCursor: "inner-next",
+	}
+	f.bookings.On("AllBookings", mock.Anything, mock.MatchedBy(func(req models.GetBookingsRequest) bool {
+		return req.Limit == 4 && req.Uuid == ""
+	})).Return(page, nil).Once()
+	f.destinations.On("DestinationsByIDs", mock.Anything, []string{moon.ID.String()}).
+		Return([]models.Destination{moon}, nil).Once()
+	f.launchpads.On("LaunchSchedule", mock.Anything, padA).Return(schedule(padA), nil).Once()
+	f.launchpads.On("LaunchSchedule", mock.Anything, padB).Return(schedule(padB), nil).Once()
+
+	res := f.exec(t, pageQuery, nil)
+
+	require.Empty(t, res.Errors)
+	var data struct {
+		Bookings struct {
+			NextCursor string
+			Bookings   []struct {
+				Passenger struct{ FirstName string }
+				Flight    struct {
+					Destination struct{ ID, Name string }
+					Launchpad   struct {
+						ID               string
+						Active           bool
+						UpcomingLaunches []struct{ Date, DatePrecision string }
+					}
+				}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(res.Data, &data))
+	require.Len(t, data.Bookings.Bookings, 4)
+	names := []string{}
+	for _, b := range data.Bookings.Bookings {
+		names = append(names, b.Flight.Destination.Name)
+		assert.Equal(t, "John", b.Passenger.FirstName)
+		assert.True(t, b.Flight.Launchpad.Active)
+		assert.Equal(t, []struct{ Date, DatePrecision string }{{"2031-01-01T00:00:00Z", "day"}}, b.Flight.Launchpad.UpcomingLaunches)
+	}
+	assert.Equal(t, []string{"Mars", "Moon", "Moon", "Mars"}, names)
+	assert.Equal(t, padB, data.Bookings.Bookings[2].Flight.Launchpad.ID)
+
+	inner, err := f.cursors.Verify(data.Bookings.NextCursor, api.FilterFingerprint(models.BookingFilter{}))
+	require.NoError(t, err)
+	assert.Equal(t, "inner-next", inner)
+
+	f.bookings.AssertExpectations(t)
+	f.destinations.AssertExpectations(t)
+	f.launchpads.AssertExpectations(t)
+}
+
+func TestBookings_SkipsUnselectedLookups(t *testing.T) {
+	f := newFixture(t)
+	page := &models.AllBookingsResponse{Bookings: []models.BookingResponse{booking(mars, padA), booking(moon, padB)}}
+	f.bookings.On("AllBookings", mock.Anything, mock.Anything).Return(page, nil)
+
+	res := f.exec(t, `{ bookings { bookings { flight { destination { name } } } } }`, nil)
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"bookings":{"bookings":[
+		{"flight":{"destination":{"name":"Mars"}}},
+		{"flight":{"destination":{"name":"Moon"}}}]}}`, string(res.Data))
+	f.destinations.AssertNotCalled(t, "DestinationsByIDs")
+	f.launchpads.AssertNotCalled(t, "LaunchSchedule")
+}
+
+func TestBookings_Cursors(t *testing.T) {
+	f := newFixture(t)
+	f.bookings.On("AllBookings", mock.Anything, mock.MatchedBy(func(req models.GetBookingsRequest) bool {
+		return req.Uuid == ""
+	})).Return(&models.AllBookingsResponse{NextCursor: "inner-next"}, nil).Once()
+	f.bookings.On("AllBookings", mock.Anything, mock.MatchedBy(func(req models.GetBookingsRequest) bool {
+		return req.Uuid == "inner-next" && req.Filter.Status == models.StatusActive
+	})).Return(&models.AllBookingsResponse{}, nil).Once()
+
+	const query = `query($after: String, $status: BookingStatus) {
+		bookings(after: $after, filter: {status: $status}) { nextCursor }
+	}`
+	res := f.exec(t, query, map[string]interface{}{"status": "ACTIVE"})
+	require.Empty(t, res.Errors)
+	var data struct{ Bookings struct{ NextCursor string } }
+	require.NoError(t, json.Unmarshal(res.Data, &data))
+	require.NotEmpty(t, data.Bookings.NextCursor)
+	assert.NotEqual(t, "inner-next", data.Bookings.NextCursor)
+
+	res = f.exec(t, query, map[string]interface{}{"status": "ACTIVE", "after": data.Bookings.NextCursor})
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"bookings":{"nextCursor":null}}`, string(res.Data))
+
+	res = f.exec(t, query, map[string]interface{}{"status": "CANCELLED", "after": data.Bookings.NextCursor})
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "cursor_filter_mismatch", res.Errors[0].Extensions["code"])
+	f.bookings.AssertExpectations(t)
+}
+
+func TestBookings_InvalidArguments(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"limit", `{ bookings(limit: 0) { nextCursor } }`, "invalid_request"},
+		{"after and before", `{ bookings(after: "a", before: "b") { nextCursor } }`, "invalid_request"},
+		{"destination id", `{ bookings(filter: {destinationId: "mars"}) { nextCursor } }`, "invalid_request"},
+		{"cursor", `{ bookings(after: "tampered") { nextCursor } }`, "invalid_cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			res := f.exec(t, tt.query, nil)
+
+			require.Len(t, res.Errors, 1)
+			assert.Equal(t, tt.code, res.Errors[0].Extensions["code"])
+			f.bookings.AssertNotCalled(t, "AllBookings")
+		})
+	}
+}
+
+func TestDestination_BatchesSiblingFields(t *testing.T) {
+	f := newFixture(t)
+	unknown := uuid.NewString()
+	f.destinations.On("DestinationsByIDs", mock.Anything, mock.MatchedBy(func(ids []string) bool {
+		got := append([]string(nil), ids...)
+		want := []string{mars.ID.String(), moon.ID.String(), unknown}
+		sort.Strings(got)
+		sort.Strings(want)
+		return assert.ObjectsAreEqual(want, got)
+	})).Return([]models.Destination{mars, moon}, nil).Once()
+
+	res := f.exec(t, `query($a: ID!, $b: ID!, $c: ID!) {
+		a: destination(id: $a) { name }
+		b: destination(id: $b) { name }
+		c: destination(id: $c) { name }
+	}`, map[string]interface{}{"a": mars.ID.String(), "b": moon.ID.String(), "c": unknown})
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"a":{"name":"Mars"},"b":{"name":"Moon"},"c":null}`, string(res.Data))
+	f.destinations.AssertExpectations(t)
+}
+
+func TestLaunchpad(t *testing.T) {
+	f := newFixture(t)
+	f.launchpads.On("LaunchSchedule", mock.Anything, padA).Return(schedule(padA), nil).Once()
+	f.launchpads.On("LaunchSchedule", mock.Anything, "unknown").Return(nil, spacex.ErrNotFound).Once()
+
+	res := f.exec(t, `{
+		known: launchpad(id: "`+padA+`") { status }
+		again: launchpad(id: "`+padA+`") { active }
+		missing: launchpad(id: "unknown") { status }
+	}`, nil)
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"known":{"status":"active"},"again":{"active":true},"missing":null}`, string(res.Data))
+	f.launchpads.AssertExpectations(t)
+}
+
+func TestCreateBooking(t *testing.T) {
+	const mutation = `mutation($input: BookingInput!) {
+		createBooking(input: $input) { id status flight { destination { name } } }
+	}`
+	input := func() map[string]interface{} {
+		return map[string]interface{}{
+			"firstName":     "John",
+			"lastName":      "Doe",
+			"gender":        "male",
+			"birthday":      "1990-01-01T00:00:00Z",
+			"launchpadId":   padA,
+			"destinationId": mars.ID.String(),
+			"launchDate":    time.Now().AddDate(1, 0, 0).UTC().Format(time.RFC3339),
+		}
+	}
+
+	t.Run("creates a booking", func(t *testing.T) {
+		f := newFixture(t)
+		created := booking(mars, padA).Booking
+		f.bookings.On("CreateBooking", mock.Anything, mock.MatchedBy(func(req *models.BookingRequest) bool {
+			return req.FirstName == "John" && req.DestinationID == mars.ID.String() && req.UserID == ""
+		})).Return(&created, nil)
+
+		res := f.exec(t, mutation, map[string]interface{}{"input": input()})
+
+		require.Empty(t, res.Errors)
+		assert.JSONEq(t, `{"createBooking":{"id":"`+created.ID.String()+`","status":"ACTIVE","flight":{"destination":{"name":"Mars"}}}}`, string(res.Data))
+		f.launchpads.AssertNotCalled(t, "LaunchSchedule")
+	})
+
+	t.Run("rejects invalid input", func(t *testing.T) {
+		f := newFixture(t)
+		in := input()
+		in["gender"] = "robot"
+
+		res := f.exec(t, mutation, map[string]interface{}{"input": in})
+
+		require.Len(t, res.Errors, 1)
+		assert.Equal(t, "invalid_request", res.Errors[0].Extensions["code"])
+		f.bookings.AssertNotCalled(t, "CreateBooking")
+	})
+
+	t.Run("maps service errors", func(t *testing.T) {
+		f := newFixture(t)
+		f.bookings.On("CreateBooking", mock.Anything, mock.Anything).Return(nil, models.ErrLaunchPadUnavailable)
+
+		res := f.exec(t, mutation, map[string]interface{}{"input": input()})
+
+		require.Len(t, res.Errors, 1)
+		assert.Equal(t, "launchpad_unavailable", res.Errors[0].Extensions["code"])
+		assert.Equal(t, "launchpad is unavailable", res.Errors[0].Message)
+	})
+}
+
+func TestCancelBooking(t *testing.T) {
+	f := newFixture(t)
+	id := uuid.NewString()
+	f.bookings.On("DeleteBooking", mock.Anything, id).Return(nil).Once()
+	f.bookings.On("DeleteBooking", mock.Anything, "missing").Return(models.ErrBookingNotFound).Once()
+
+	res := f.exec(t, `mutation($id: ID!) { cancelBooking(id: $id) }`, map[string]interface{}{"id": id})
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"cancelBooking":true}`, string(res.Data))
+
+	res = f.exec(t, `mutation { cancelBooking(id: "missing") }`, nil)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "booking_not_found", res.Errors[0].Extensions["code"])
+	f.bookings.AssertExpectations(t)
+}
+
+func TestHandler_RejectsMalformedBody(t *testing.T) {
+	f := newFixture(t)
+	req := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewBufferString(`{"variables":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	f.handler(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,275 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/grpcserver"
+	bookingv1 "github.com/chrisdamba/spacetrouble/pkg/pb/booking/v1"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	adminKey    = "admin-key"
+	customerKey = "customer-key"
+	customerID  = "123e4567-e89b-12d3-a456-426614174001"
+)
+
+var testBooking = models.Booking{
+	ID: uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+	User: models.User{
+		ID:        uuid.MustParse(customerID),
+		FirstName: "John",
+		LastName:  "Doe",
+		Gender:    "male",
+		Birthday:  time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+	Flight: models.Flight{
+		ID:          uuid.MustParse("123e4567-e89b-12d3-a456-426614174002"),
+		LaunchpadID: "5e9e4502f5090995de566f86",
+		Destination: models.Destination{ID: uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), Name: "Mars"},
+		LaunchDate:  time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+	Status:    models.StatusConfirmed,
+	CreatedAt: time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC),
+}
+
+// newClient serves the booking service over an in-memory listener.
+func newClient(t *testing.T, service *mocks.MockBookingService) bookingv1.BookingServiceClient {
+	t.Helper()
+	keys, err := auth.NewKeyStore(adminKey + ":admin," + customerKey + ":customer:" + customerID)
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpcserver.NewServer(service, keys)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return bookingv1.NewBookingServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+func validRequest() *bookingv1.CreateBookingRequest {
+	return &bookingv1.CreateBookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "male",
+		Birthday:      timestamppb.New(time.Now().AddDate(-30, 0, 0)),
+		LaunchpadId:   "5e9e4502f5090995de566f86",
+		DestinationId: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		LaunchDate:    timestamppb.New(time.Now().AddDate(1, 0, 0)),
+	}
+}
+
+// requireStatus checks the gRPC code and the REST error code carried in
+// the ErrorInfo detail.
+func requireStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status error: %v", err)
+	assert.Equal(t, code, st.Code())
+	var got string
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			got = info.Reason
+		}
+	}
+	assert.Equal(t, reason, got)
+}
+
+func TestCreateBooking(t *testing.T) {
+	service := new(mocks.MockBookingService)
+	service.On("CreateBooking", mock.Anything, mock.MatchedBy(func(r *models.BookingRequest) bool {
+		return r.FirstName == "John" && r.DestinationID == "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11" && !r.LaunchDate.IsZero()
+	})).Return(&testBooking, nil)
+	client := newClient(t, service)
+
+	booking, err := client.CreateBooking(withKey(adminKey), validRequest())
+
+	require.NoError(t, err)
+	assert.Equal(t, testBooking.ID.String(), booking.GetId())
+	assert.Equal(t, bookingv1.BookingStatus_BOOKING_STATUS_CONFIRMED, booking.GetStatus())
+	assert.Equal(t, "Mars", booking.GetFlight().GetDestination().GetName())
+	assert.True(t, booking.GetFlight().GetLaunchDate().AsTime().Equal(testBooking.Flight.LaunchDate))
+}
+
+func TestCreateBooking_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(r *bookingv1.CreateBookingRequest)
+		serviceErr error
+		code       codes.Code
+		reason     string
+	}{
+		{"missing birthday", func(r *bookingv1.CreateBookingRequest) { r.Birthday = nil }, nil, codes.InvalidArgument, "invalid_request"},
+		{"bad gender", func(r *bookingv1.CreateBookingRequest) { r.Gender = "robot" }, nil, codes.InvalidArgument, "invalid_request"},
+		{"unknown destination", nil, models.ErrMissingDestination, codes.NotFound, "destination_not_found"},
+		{"launchpad taken", nil, models.ErrLaunchPadUnavailable, codes.FailedPrecondition, "launchpad_unavailable"},
+		{"spacex unavailable", nil, models.ErrSpaceXUnavailable, codes.Unavailable, "spacex_unavailable"},
+		{"forbidden", nil, models.ErrForbidden, codes.PermissionDenied, "forbidden"},
+		{"unexpected", nil, errors.New("database down"), codes.Internal, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(mocks.MockBookingService)
+			service.On("CreateBooking", mock.Anything, mock.Anything).Return(nil, tt.serviceErr).Maybe()
+			client := newClient(t, service)
+			req := validRequest()
+			if tt.mutate != nil {
+				tt.mutate(req)
+			}
+
+			_, err := client.CreateBooking(withKey(adminKey), req)
+
+			requireStatus(t, err, tt.code, tt.reason)
+			if tt.mutate != nil {
+				service.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	client := newClient(t, new(mocks.MockBookingService))
+
+	_, err := client.GetBooking(context.Background(), &bookingv1.GetBookingRequest{Id: "x"})
+	requireStatus(t, err, codes.Unauthenticated, "unauthenticated")
+
+	_, err = client.GetBooking(withKey("wrong"), &bookingv1.GetBookingRequest{Id: "x"})
+	requireStatus(t, err, codes.Unauthenticated, "unauthenticated")
+
+	stream, err := client.ListBookings(context.Background(), &bookingv1.ListBookingsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireStatus(t, err, codes.Unauthenticated, "unauthenticated")
+}
+
+func TestGetBooking(t *testing.T) {
+	service := new(mocks.MockBookingService)
+	service.On("GetBooking", mock.Anything, testBooking.ID.String()).Return(&testBooking, nil)
+	service.On("GetBooking", mock.Anything, "missing").Return(nil, models.ErrBookingNotFound)
+	client := newClient(t, service)
+
+	booking, err := client.GetBooking(withKey(customerKey), &bookingv1.GetBookingRequest{Id: testBooking.ID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, "John", booking.GetUser().GetFirstName())
+
+	_, err = client.GetBooking(withKey(customerKey), &bookingv1.GetBookingRequest{Id: "missing"})
+	requireStatus(t, err, codes.NotFound, "booking_not_found")
+}
+
+func TestGetBooking_PassesPrincipal(t *testing.T) {
+	service := new(mocks.MockBookingService)
+	service.On("GetBooking", mock.MatchedBy(func(ctx context.Context) bool {
+		p, ok := auth.PrincipalFromContext(ctx)
+		return ok && p.Role == auth.RoleCustomer && p.UserID.String() == customerID
+	}), mock.Anything).Return(&testBooking, nil)
+	client := newClient(t, service)
+
+	_, err := client.GetBooking(withKey(customerKey), &bookingv1.GetBookingRequest{Id: testBooking.ID.String()})
+
+	require.NoError(t, err)
+}
+
+func TestListBookings(t *testing.T) {
+	second := testBooking
+	second.ID = uuid.New()
+	second.Status = models.StatusCancelled
+	from := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	service := new(mocks.MockBookingService)
+	service.On("ExportBookings", mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := auth.PrincipalFromContext(ctx)
+		return ok
+	}), models.BookingFilter{
+		Status:     models.StatusConfirmed,
+		LaunchFrom: from,
+		Sort:       models.BookingSort{Field: models.SortByLaunchDate, Direction: models.SortDesc},
+	}, mock.Anything).Return([]models.Booking{testBooking, second}, nil)
+	client := newClient(t, service)
+
+	stream, err := client.ListBookings(withKey(adminKey), &bookingv1.ListBookingsRequest{
+		Status:         bookingv1.BookingStatus_BOOKING_STATUS_CONFIRMED,
+		LaunchDateFrom: timestamppb.New(from),
+		Sort:           bookingv1.SortField_SORT_FIELD_LAUNCH_DATE,
+		Order:          bookingv1.SortOrder_SORT_ORDER_DESC,
+	})
+	require.NoError(t, err)
+
+	var got []*bookingv1.Booking
+	for {
+		b, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, b)
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, testBooking.ID.String(), got[0].GetId())
+	assert.Equal(t, bookingv1.BookingStatus_BOOKING_STATUS_CANCELLED, got[1].GetStatus())
+}
+
+func TestListBookings_Errors(t *testing.T) {
+	service := new(mocks.MockBookingService)
+	service.On("ExportBookings", mock.Anything, mock.Anything, mock.Anything).Return([]models.Booking{testBooking}, errors.New("connection reset"))
+	client := newClient(t, service)
+
+	stream, err := client.ListBookings(withKey(adminKey), &bookingv1.ListBookingsRequest{DestinationId: "nope"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+
+	stream, err = client.ListBookings(withKey(adminKey), &bookingv1.ListBookingsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireStatus(t, err, codes.Internal, "internal_error")
+}
+
+func TestCancelBooking(t *testing.T) {
+	service := new(mocks.MockBookingService)
+	service.On("DeleteBooking", mock.Anything, testBooking.ID.String()).Return(nil)
+	service.On("DeleteBooking", mock.Anything, "gone").Return(models.ErrBookingNotFound)
+	client := newClient(t, service)
+
+	_, err := client.CancelBooking(withKey(adminKey), &bookingv1.CancelBookingRequest{Id: testBooking.ID.String()})
+	require.NoError(t, err)
+
+	_, err = client.CancelBooking(withKey(adminKey), &bookingv1.CancelBookingRequest{Id: "gone"})
+	requireStatus(t, err, codes.NotFound, "booking_not_found")
+
+	_, err = client.CancelBooking(withKey(adminKey), &bookingv1.CancelBookingRequest{})
+	requireStatus(t, err, codes.InvalidArgument, "invalid_request")
+}
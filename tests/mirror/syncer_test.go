@@ -0,0 +1,108 @@
+package mirror_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/mirror"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	schedules []spacex.Schedule
+	err       error
+	calls     int
+}
+
+func (f *fakeSource) Schedules(_ context.Context) ([]spacex.Schedule, error) {
+	f.calls++
+	return f.schedules, f.err
+}
+
+type fakeStore struct {
+	schedules []spacex.Schedule
+	syncedAt  time.Time
+	replaced  int
+}
+
+func (f *fakeStore) ReplaceMirror(_ context.Context, schedules []spacex.Schedule, syncedAt time.Time) error {
+	f.schedules, f.syncedAt = schedules, syncedAt
+	f.replaced++
+	return nil
+}
+
+type fakeLeases struct {
+	holder string
+}
+
+func (f *fakeLeases) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if f.holder != "" && f.holder != holder {
+		return false, nil
+	}
+	f.holder = holder
+	return true, nil
+}
+
+func (f *fakeLeases) ReleaseLease(_ context.Context, name, holder string) error {
+	if f.holder == holder {
+		f.holder = ""
+	}
+	return nil
+}
+
+var now = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newSyncer(store *fakeStore, source *fakeSource, leases *fakeLeases) *mirror.Syncer {
+	return mirror.NewSyncer(store, source, leases,
+		mirror.WithHolder("a"),
+		mirror.WithClock(func() time.Time { return now }),
+	)
+}
+
+func TestSyncerReplacesMirror(t *testing.T) {
+	source := &fakeSource{schedules: []spacex.Schedule{
+		{
+			LaunchPad: spacex.LaunchPad{Id: "pad1", Status: "active"},
+			Launches: []spacex.Launch{
+				{LaunchPadID: "pad1", Date: now.AddDate(0, 1, 0).Unix(), DatePrecision: "day"},
+				{LaunchPadID: "pad1", Date: now.AddDate(0, 2, 0).Unix(), DatePrecision: "month"},
+			},
+		},
+		{LaunchPad: spacex.LaunchPad{Id: "pad2", Status: "retired"}},
+	}}
+	store := &fakeStore{}
+
+	result, err := newSyncer(store, source, &fakeLeases{}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, models.MirrorSyncResult{Launchpads: 2, Launches: 2}, result)
+	assert.Equal(t, source.schedules, store.schedules)
+	assert.Equal(t, now, store.syncedAt)
+}
+
+func TestSyncerLeavesMirrorWhenSpaceXFails(t *testing.T) {
+	source := &fakeSource{err: spacex.ErrCircuitOpen}
+	store := &fakeStore{}
+
+	result, err := newSyncer(store, source, &fakeLeases{}).RunOnce(context.Background())
+
+	assert.ErrorIs(t, err, spacex.ErrCircuitOpen)
+	assert.Zero(t, result)
+	assert.Zero(t, store.replaced)
+}
+
+func TestSyncerOnlyLeaseHolderRuns(t *testing.T) {
+	source := &fakeSource{}
+	store := &fakeStore{}
+
+	result, err := newSyncer(store, source, &fakeLeases{holder: "b"}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, result)
+	assert.Zero(t, source.calls)
+	assert.Zero(t, store.replaced)
+}
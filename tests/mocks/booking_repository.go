@@ -19,6 +19,11 @@ func (m *MockBookingRepository) CreateBooking(ctx context.Context, booking *mode
 	return args.Get(0).(*models.Booking), args.Error(1)
 }
 
+func (m *MockBookingRepository) CreateBookings(ctx context.Context, bookings []*models.Booking) error {
+	args := m.Called(ctx, bookings)
+	return args.Error(0)
+}
+
 func (m *MockBookingRepository) GetDestinationById(ctx context.Context, id string) (*models.Destination, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -27,6 +32,30 @@ func (m *MockBookingRepository) GetDestinationById(ctx context.Context, id strin
 	return args.Get(0).(*models.Destination), args.Error(1)
 }
 
+func (m *MockBookingRepository) GetDestinations(ctx context.Context) ([]models.Destination, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *MockBookingRepository) GetDestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *MockBookingRepository) CreateDestination(ctx context.Context, destination *models.Destination) (*models.Destination, error) {
+	args := m.Called(ctx, destination)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Destination), args.Error(1)
+}
+
 func (m *MockBookingRepository) GetFlights(ctx context.Context, filters map[string]interface{}) ([]models.Flight, error) {
 	args := m.Called(ctx, filters)
 	if args.Get(0) == nil {
@@ -40,9 +69,22 @@ func (m *MockBookingRepository) IsLaunchPadWeekAvailable(ctx context.Context, la
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockBookingRepository) GetBookingsPaginated(ctx context.Context, afterCursor string, limit int) ([]models.Booking, string, error) {
-	args := m.Called(ctx, afterCursor, limit)
-	return args.Get(0).([]models.Booking), args.String(1), args.Error(2)
+func (m *MockBookingRepository) GetBookingsPaginated(ctx context.Context, filter models.BookingFilter, afterCursor, beforeCursor string, limit int) (models.BookingPage, error) {
+	args := m.Called(ctx, filter, afterCursor, beforeCursor, limit)
+	return args.Get(0).(models.BookingPage), args.Error(1)
+}
+
+func (m *MockBookingRepository) CountBookings(ctx context.Context, filter models.BookingFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBookingRepository) RescheduleBooking(ctx context.Context, id string, launchDate time.Time, status models.BookingStatus) (*models.Booking, error) {
+	args := m.Called(ctx, id, launchDate, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
 }
 
 func (m *MockBookingRepository) DeleteBooking(ctx context.Context, id string) error {
@@ -57,3 +99,16 @@ func (m *MockBookingRepository) GetBookingByID(ctx context.Context, id string) (
 	}
 	return args.Get(0).(*models.Booking), args.Error(1)
 }
+
+// StreamBookings hands each booking given to Return to fn in turn.
+func (m *MockBookingRepository) StreamBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error {
+	args := m.Called(ctx, filter, fn)
+	if bookings, ok := args.Get(0).([]models.Booking); ok {
+		for _, b := range bookings {
+			if err := fn(b); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"context"
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBookingService struct {
+	mock.Mock
+}
+
+func (m *MockBookingService) CreateBooking(ctx context.Context, request *models.BookingRequest) (*models.Booking, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *MockBookingService) CreateBookingsBatch(ctx context.Context, requests []models.BookingRequest, mode models.BatchMode) (*models.BatchBookingResponse, error) {
+	args := m.Called(ctx, requests, mode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BatchBookingResponse), args.Error(1)
+}
+
+func (m *MockBookingService) GetBooking(ctx context.Context, id string) (*models.Booking, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *MockBookingService) AllBookings(ctx context.Context, req models.GetBookingsRequest) (*models.AllBookingsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AllBookingsResponse), args.Error(1)
+}
+
+// ExportBookings calls fn for each booking in the first return value
+// before returning the error in the second.
+func (m *MockBookingService) ExportBookings(ctx context.Context, filter models.BookingFilter, fn func(models.Booking) error) error {
+	args := m.Called(ctx, filter, fn)
+	if bookings, ok := args.Get(0).([]models.Booking); ok {
+		for _, b := range bookings {
+			if err := fn(b); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockBookingService) RescheduleBooking(ctx context.Context, id string, request *models.RescheduleRequest) (*models.Booking, error) {
+	args := m.Called(ctx, id, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Booking), args.Error(1)
+}
+
+func (m *MockBookingService) DeleteBooking(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
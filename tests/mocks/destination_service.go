@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDestinationService struct {
+	mock.Mock
+}
+
+func (m *MockDestinationService) AllDestinations(ctx context.Context) ([]models.Destination, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *MockDestinationService) DestinationsByIDs(ctx context.Context, ids []string) ([]models.Destination, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Destination), args.Error(1)
+}
+
+func (m *MockDestinationService) CreateDestination(ctx context.Context, request *models.DestinationRequest) (*models.Destination, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Destination), args.Error(1)
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLaunchpadService struct {
+	mock.Mock
+}
+
+func (m *MockLaunchpadService) CloseLaunchpad(ctx context.Context, request *models.LaunchpadClosureRequest) (*models.LaunchpadClosure, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LaunchpadClosure), args.Error(1)
+}
+
+func (m *MockLaunchpadService) ReopenLaunchpad(ctx context.Context, launchpadID string) error {
+	args := m.Called(ctx, launchpadID)
+	return args.Error(0)
+}
+
+func (m *MockLaunchpadService) LaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LaunchpadClosure), args.Error(1)
+}
+
+type MockLaunchpadRepository struct {
+	mock.Mock
+}
+
+func (m *MockLaunchpadRepository) CloseLaunchpad(ctx context.Context, closure *models.LaunchpadClosure) (*models.LaunchpadClosure, error) {
+	args := m.Called(ctx, closure)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LaunchpadClosure), args.Error(1)
+}
+
+func (m *MockLaunchpadRepository) ReopenLaunchpad(ctx context.Context, launchpadID string) error {
+	args := m.Called(ctx, launchpadID)
+	return args.Error(0)
+}
+
+func (m *MockLaunchpadRepository) ListLaunchpadClosures(ctx context.Context) ([]models.LaunchpadClosure, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LaunchpadClosure), args.Error(1)
+}
+
+func (m *MockLaunchpadRepository) LaunchpadClosure(ctx context.Context, launchpadID string) (*models.LaunchpadClosure, error) {
+	args := m.Called(ctx, launchpadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LaunchpadClosure), args.Error(1)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"context"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRiskService struct {
+	mock.Mock
+}
+
+func (m *MockRiskService) AtRiskBookings(ctx context.Context) (*models.AtRiskReport, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AtRiskReport), args.Error(1)
+}
+
+type MockRiskRepository struct {
+	mock.Mock
+}
+
+func (m *MockRiskRepository) AtRiskBookings(ctx context.Context, userID string) ([]models.BookingRisk, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.BookingRisk), args.Error(1)
+}
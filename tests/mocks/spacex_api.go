@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"errors"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
 	"github.com/stretchr/testify/mock"
 	"time"
 )
@@ -33,3 +34,16 @@ type MockSpaceXClientError struct{}
 func (m *MockSpaceXClientError) CheckLaunchConflict(ctx context.Context, launchpadID string, date time.Time) (bool, error) {
 	return false, errors.New("spaceX api error")
 }
+
+// MockSpaceXScheduler serves whole launchpad schedules, like spacex.Client.
+type MockSpaceXScheduler struct {
+	MockSpaceXClient
+}
+
+func (m *MockSpaceXScheduler) LaunchSchedule(ctx context.Context, launchpadID string) (*spacex.Schedule, error) {
+	args := m.Called(ctx, launchpadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*spacex.Schedule), args.Error(1)
+}
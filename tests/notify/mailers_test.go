@@ -0,0 +1,229 @@
+package notify_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEmail = models.Email{
+	To:      "ada@example.com",
+	Subject: "Your trip to Mars is booked",
+	Text:    "Hello Ada,\n\nYour seat is booked.\n",
+	HTML:    "<p>Hello Ada,</p>",
+}
+
+// readParts parses msg and returns its headers and the decoded body of
+// each part by content type.
+func readParts(t *testing.T, msg []byte) (mail.Header, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	bodies := map[string]string{}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+		require.NoError(t, err)
+		bodies[mediaType] = string(body)
+		return m.Header, bodies
+	}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		partType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(quotedprintable.NewReader(p))
+		require.NoError(t, err)
+		bodies[partType] = string(body)
+	}
+	return m.Header, bodies
+}
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg, err := notify.BuildMessage("SpaceTrouble <bookings@example.com>", testEmail, now)
+
+	require.NoError(t, err)
+	header, bodies := readParts(t, msg)
+	assert.Equal(t, "SpaceTrouble <bookings@example.com>", header.Get("From"))
+	assert.Equal(t, "ada@example.com", header.Get("To"))
+	assert.Equal(t, "Your trip to Mars is booked", header.Get("Subject"))
+	assert.Contains(t, header.Get("Message-ID"), "@example.com>")
+	date, err := header.Date()
+	require.NoError(t, err)
+	assert.True(t, date.Equal(now))
+	assert.Equal(t, "Hello Ada,\r\n\r\nYour seat is booked.\r\n", bodies["text/plain"])
+	assert.Equal(t, "<p>Hello Ada,</p>", bodies["text/html"])
+}
+
+func TestBuildMessageTextOnly(t *testing.T) {
+	email := testEmail
+	email.HTML = ""
+	email.Subject = "Départ confirmé"
+
+	msg, err := notify.BuildMessage("bookings@example.com", email, time.Now())
+
+	require.NoError(t, err)
+	header, bodies := readParts(t, msg)
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Départ confirmé", subject)
+	assert.Len(t, bodies, 1)
+	assert.Contains(t, bodies["text/plain"], "Your seat is booked.")
+}
+
+func TestBuildMessageRejectsBadRecipient(t *testing.T) {
+	email := testEmail
+	email.To = "not an address"
+
+	_, err := notify.BuildMessage("bookings@example.com", email, time.Now())
+
+	assert.ErrorContains(t, err, "recipient")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := notify.NewFileMailer(dir, "bookings@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), testEmail))
+	require.NoError(t, mailer.Send(context.Background(), testEmail))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	msg, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	header, bodies := readParts(t, msg)
+	assert.Equal(t, "ada@example.com", header.Get("To"))
+	assert.Equal(t, "<p>Hello Ada,</p>", bodies["text/html"])
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := notify.NewMemoryMailer()
+
+	require.NoError(t, mailer.Send(context.Background(), testEmail))
+	err := mailer.Send(context.Background(), models.Email{To: "nobody"})
+
+	assert.Error(t, err)
+	assert.Equal(t, []models.Email{testEmail}, mailer.Sent())
+}
+
+// smtpSession is the envelope and message one SMTP session delivered.
+type smtpSession struct {
+	from, to string
+	data     []byte
+}
+
+// serveSMTP accepts one SMTP session on a local port, rejecting every
+// recipient when rejectRcpt is set.
+func serveSMTP(t *testing.T, rejectRcpt bool) (string, int, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		var s smtpSession
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch upper := strings.ToUpper(cmd); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				if rejectRcpt {
+					reply("550 no such user")
+					continue
+				}
+				s.to = strings.Trim(cmd[len("RCPT TO:"):], "<> ")
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 go ahead")
+				var data bytes.Buffer
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				s.data = data.Bytes()
+				reply("250 queued")
+			case upper == "QUIT":
+				reply("221 bye")
+				sessions <- s
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, sessions
+}
+
+func TestSMTPMailer(t *testing.T) {
+	host, port, sessions := serveSMTP(t, false)
+	mailer := notify.NewSMTPMailer(host, port, "", "", "SpaceTrouble <bookings@example.com>")
+
+	require.NoError(t, mailer.Send(context.Background(), testEmail))
+
+	s := <-sessions
+	assert.Equal(t, "bookings@example.com", s.from)
+	assert.Equal(t, "ada@example.com", s.to)
+	header, bodies := readParts(t, s.data)
+	assert.Equal(t, "Your trip to Mars is booked", header.Get("Subject"))
+	assert.Equal(t, "<p>Hello Ada,</p>", bodies["text/html"])
+}
+
+func TestSMTPMailerRejectedRecipient(t *testing.T) {
+	host, port, _ := serveSMTP(t, true)
+	mailer := notify.NewSMTPMailer(host, port, "", "", "bookings@example.com")
+
+	err := mailer.Send(context.Background(), testEmail)
+
+	assert.ErrorContains(t, err, "no such user")
+}
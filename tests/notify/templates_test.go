@@ -0,0 +1,86 @@
+package notify_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/notify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBooking() models.Booking {
+	return models.Booking{
+		ID: uuid.MustParse("6f1f7c1e-0b5c-4a55-9d4c-2c3c0e6b0f10"),
+		User: models.User{
+			ID:        uuid.New(),
+			FirstName: "Ada",
+			LastName:  "<Lovelace>",
+			Email:     "ada@example.com",
+		},
+		Flight: models.Flight{
+			LaunchpadID: "5e9e4501f509094ba4566f84",
+			Destination: models.Destination{ID: uuid.New(), Name: "Mars"},
+			LaunchDate:  time.Date(2030, 3, 14, 0, 0, 0, 0, time.UTC),
+		},
+		Status: models.StatusActive,
+	}
+}
+
+func TestRenderEveryKind(t *testing.T) {
+	for _, kind := range notify.Kinds {
+		t.Run(string(kind), func(t *testing.T) {
+			email, err := notify.Render(kind, notify.Data{
+				Booking:   testBooking(),
+				Lead:      "7 days",
+				Checklist: []string{"Upload your passport"},
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, "ada@example.com", email.To)
+			assert.Contains(t, email.Subject, "Mars")
+			assert.NotContains(t, email.Subject, "\n")
+			assert.Contains(t, email.Text, "Hello Ada,")
+			assert.Contains(t, email.Text, "Thursday, 14 March 2030")
+			assert.Contains(t, email.Text, "6f1f7c1e-0b5c-4a55-9d4c-2c3c0e6b0f10")
+			assert.Contains(t, email.HTML, "<html>")
+			assert.Contains(t, email.HTML, "Thursday, 14 March 2030")
+		})
+	}
+}
+
+func TestRenderEscapesHTMLOnly(t *testing.T) {
+	email, err := notify.Render(models.NotifyBookingCreated, notify.Data{Booking: testBooking()})
+
+	require.NoError(t, err)
+	assert.Contains(t, email.Text, "Ada <Lovelace>")
+	assert.Contains(t, email.HTML, "Ada &lt;Lovelace&gt;")
+	assert.NotContains(t, email.HTML, "<Lovelace>")
+}
+
+func TestRenderReminderChecklist(t *testing.T) {
+	email, err := notify.Render(models.NotifyLaunchReminder, notify.Data{
+		Booking:   testBooking(),
+		Lead:      "30 days",
+		Checklist: []string{"Upload your passport", "Complete the medical check"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "30 days to launch: your trip to Mars", email.Subject)
+	assert.Contains(t, email.Text, "  - Upload your passport\n  - Complete the medical check\n")
+	assert.Contains(t, email.HTML, "<li>Complete the medical check</li>")
+
+	email, err = notify.Render(models.NotifyLaunchReminder, notify.Data{Booking: testBooking(), Lead: "24 hours"})
+
+	require.NoError(t, err)
+	assert.NotContains(t, email.Text, "Before you fly")
+	assert.NotContains(t, email.HTML, "<ul>")
+}
+
+func TestRenderUnknownKind(t *testing.T) {
+	_, err := notify.Render("newsletter", notify.Data{Booking: testBooking()})
+
+	assert.ErrorContains(t, err, `no templates for notification "newsletter"`)
+}
@@ -0,0 +1,258 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/notify"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keeps notifications in memory, following the rules of the SQL
+// behind the repository.
+type fakeStore struct {
+	mu            sync.Mutex
+	now           time.Time
+	notifications []*storedNotification
+}
+
+type storedNotification struct {
+	models.Notification
+	status    string
+	lastError string
+	next      time.Time
+}
+
+func (s *fakeStore) EnqueueNotification(_ context.Context, n *models.Notification) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.notifications {
+		if existing.DedupeKey == n.DedupeKey {
+			return false, nil
+		}
+	}
+	stored := &storedNotification{Notification: *n, status: "pending", next: s.now}
+	stored.ID = int64(len(s.notifications) + 1)
+	s.notifications = append(s.notifications, stored)
+	return true, nil
+}
+
+func (s *fakeStore) ClaimNotifications(_ context.Context, limit int, _ time.Duration) ([]models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.Notification
+	for _, n := range s.notifications {
+		if n.status == "pending" && !n.next.After(s.now) && len(claimed) < limit {
+			claimed = append(claimed, n.Notification)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkNotificationSent(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.notifications[id-1]
+	n.status, n.lastError = "sent", ""
+	n.Attempts++
+	return nil
+}
+
+func (s *fakeStore) MarkNotificationFailed(_ context.Context, id int64, reason string, next *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.notifications[id-1]
+	n.Attempts++
+	n.lastError = reason
+	if next == nil {
+		n.status = "failed"
+	} else {
+		n.next = *next
+	}
+	return nil
+}
+
+// flakyMailer fails as many sends as failures says before it works.
+type flakyMailer struct {
+	*notify.MemoryMailer
+	failures int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, email models.Email) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("421 service not available")
+	}
+	return m.MemoryMailer.Send(ctx, email)
+}
+
+func bookingEvent(t *testing.T, eventType models.BookingEventType, booking models.Booking) outbox.CloudEvent {
+	t.Helper()
+	payload, err := json.Marshal(booking)
+	require.NoError(t, err)
+	return outbox.NewCloudEvent(models.OutboxEvent{
+		ID: 1, EventID: uuid.New(), AggregateID: booking.ID, Type: eventType,
+		Payload: payload, OccurredAt: time.Now(),
+	})
+}
+
+func TestSinkQueuesRenderedEmails(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	sink := notify.NewSink(store)
+	booking := testBooking()
+
+	created := bookingEvent(t, models.EventBookingCreated, booking)
+	require.NoError(t, sink.Send(context.Background(), created))
+	require.NoError(t, sink.Send(context.Background(), created), "a redelivered event is not queued twice")
+	require.NoError(t, sink.Send(context.Background(), bookingEvent(t, models.EventBookingCancelled, booking)))
+
+	require.Len(t, store.notifications, 2)
+	first := store.notifications[0]
+	assert.Equal(t, created.ID+":booking_created", first.DedupeKey)
+	assert.Equal(t, booking.ID, first.BookingID)
+	assert.Equal(t, models.NotifyBookingCreated, first.Kind)
+	assert.Equal(t, "ada@example.com", first.Email.To)
+	assert.Equal(t, "Your trip to Mars is booked", first.Email.Subject)
+	assert.Equal(t, models.NotifyBookingCancelled, store.notifications[1].Kind)
+}
+
+func TestSinkQueuesRescheduledEmail(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	sink := notify.NewSink(store)
+	booking := testBooking()
+
+	rescheduled := bookingEvent(t, models.EventBookingRescheduled, booking)
+	require.NoError(t, sink.Send(context.Background(), rescheduled))
+
+	require.Len(t, store.notifications, 1)
+	queued := store.notifications[0]
+	assert.Equal(t, rescheduled.ID+":booking_rescheduled", queued.DedupeKey)
+	assert.Equal(t, models.NotifyBookingRescheduled, queued.Kind)
+	assert.Equal(t, "Your trip to Mars has a new launch date", queued.Email.Subject)
+	assert.Contains(t, queued.Email.Text, "Your launch has moved to Thursday, 14 March 2030.")
+}
+
+func TestSinkSkipsEventsWithoutEmail(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	sink := notify.NewSink(store)
+	booking := testBooking()
+
+	require.NoError(t, sink.Send(context.Background(), bookingEvent(t, models.EventBookingStatusChanged, booking)))
+	booking.User.Email = ""
+	require.NoError(t, sink.Send(context.Background(), bookingEvent(t, models.EventBookingCreated, booking)))
+
+	assert.Empty(t, store.notifications)
+}
+
+func TestWorkerSendsQueuedEmails(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	mailer := notify.NewMemoryMailer()
+	worker := notify.NewWorker(store, mailer)
+	require.NoError(t, notify.NewSink(store).Send(context.Background(), bookingEvent(t, models.EventBookingCreated, testBooking())))
+
+	n, err := worker.SendOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, mailer.Sent(), 1)
+	assert.Equal(t, store.notifications[0].Email, mailer.Sent()[0])
+	assert.Equal(t, "sent", store.notifications[0].status)
+
+	n, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "a sent notification is not sent again")
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{now: now}
+	mailer := &flakyMailer{MemoryMailer: notify.NewMemoryMailer(), failures: 2}
+	worker := notify.NewWorker(store, mailer,
+		notify.WithRetries(5, time.Minute, time.Hour),
+		notify.WithClock(func() time.Time { return store.now }),
+	)
+	_, err := store.EnqueueNotification(context.Background(), &models.Notification{DedupeKey: "k", Email: testEmail})
+	require.NoError(t, err)
+
+	_, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	n := store.notifications[0]
+	assert.Equal(t, "pending", n.status)
+	assert.Equal(t, "421 service not available", n.lastError)
+	assert.Equal(t, now.Add(time.Minute), n.next)
+
+	claimed, err := worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, claimed, "nothing is sent before the retry is due")
+
+	store.now = n.next
+	_, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(3*time.Minute), n.next, "the backoff doubles")
+
+	store.now = n.next
+	_, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sent", n.status)
+	assert.Equal(t, 3, n.Attempts)
+	assert.Len(t, mailer.Sent(), 1)
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	mailer := &flakyMailer{MemoryMailer: notify.NewMemoryMailer(), failures: 10}
+	worker := notify.NewWorker(store, mailer,
+		notify.WithRetries(2, 0, 0),
+		notify.WithClock(func() time.Time { return store.now }),
+	)
+	_, err := store.EnqueueNotification(context.Background(), &models.Notification{DedupeKey: "k", Email: testEmail})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := worker.SendOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "failed", store.notifications[0].status)
+	assert.Equal(t, 2, store.notifications[0].Attempts)
+	assert.Empty(t, mailer.Sent())
+}
+
+// sentLog is a SentRecorder remembering the keys of what was sent.
+type sentLog struct {
+	keys []string
+}
+
+func (l *sentLog) NotificationSent(_ context.Context, n models.Notification) error {
+	l.keys = append(l.keys, n.DedupeKey)
+	return nil
+}
+
+func TestWorkerRecordsOnlySentNotifications(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	mailer := &flakyMailer{MemoryMailer: notify.NewMemoryMailer(), failures: 1}
+	sent := &sentLog{}
+	worker := notify.NewWorker(store, mailer,
+		notify.WithRetries(5, 0, 0),
+		notify.WithClock(func() time.Time { return store.now }),
+		notify.WithSentRecorder(sent),
+	)
+	_, err := store.EnqueueNotification(context.Background(), &models.Notification{DedupeKey: "k", Email: testEmail})
+	require.NoError(t, err)
+
+	_, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, sent.keys, "a failed send is not recorded")
+
+	_, err = worker.SendOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, sent.keys)
+}
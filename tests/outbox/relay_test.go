@@ -0,0 +1,231 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore mimics the outbox table: it only hands out the oldest
+// unpublished event of each booking whose retry is due.
+type fakeStore struct {
+	mu        sync.Mutex
+	now       time.Time
+	events    []*models.OutboxEvent
+	nextAt    map[int64]time.Time
+	published map[int64]bool
+	failures  map[int64]string
+}
+
+func newFakeStore(now time.Time, events ...models.OutboxEvent) *fakeStore {
+	s := &fakeStore{now: now, nextAt: map[int64]time.Time{}, published: map[int64]bool{}, failures: map[int64]string{}}
+	for i := range events {
+		s.events = append(s.events, &events[i])
+	}
+	return s
+}
+
+func (s *fakeStore) ClaimOutbox(_ context.Context, limit int, _ time.Duration) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.OutboxEvent
+	seen := map[uuid.UUID]bool{}
+	for _, e := range s.events {
+		if s.published[e.ID] || seen[e.AggregateID] {
+			continue
+		}
+		seen[e.AggregateID] = true
+		if s.nextAt[e.ID].After(s.now) || len(claimed) == limit {
+			continue
+		}
+		claimed = append(claimed, *e)
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkPublished(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published[id] = true
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(_ context.Context, id int64, next time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.events {
+		if e.ID == id {
+			e.Attempts++
+		}
+	}
+	s.nextAt[id] = next
+	s.failures[id] = reason
+	return nil
+}
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []outbox.CloudEvent
+	fail   func(outbox.CloudEvent) error
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, e outbox.CloudEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		if err := s.fail(e); err != nil {
+			return err
+		}
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *recordingSink) types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var types []string
+	for _, e := range s.events {
+		types = append(types, e.Subject+" "+e.Type)
+	}
+	return types
+}
+
+func outboxEvent(id int64, booking uuid.UUID, eventType models.BookingEventType) models.OutboxEvent {
+	return models.OutboxEvent{
+		ID:          id,
+		EventID:     uuid.New(),
+		AggregateID: booking,
+		Type:        eventType,
+		Payload:     []byte(`{"id":"` + booking.String() + `"}`),
+		OccurredAt:  time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRelayDeliversInOrderPerBooking(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(now,
+		outboxEvent(1, a, models.EventBookingCreated),
+		outboxEvent(2, b, models.EventBookingCreated),
+		outboxEvent(3, a, models.EventBookingCancelled),
+	)
+	sink := &recordingSink{}
+	relay := outbox.NewRelay(store, []outbox.Sink{sink})
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "the cancellation waits for the creation of the same booking")
+
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Equal(t, []string{
+		a.String() + " com.spacetrouble.booking.created",
+		b.String() + " com.spacetrouble.booking.created",
+		a.String() + " com.spacetrouble.booking.cancelled",
+	}, sink.types())
+	assert.Len(t, store.published, 3)
+}
+
+func TestRelayRetriesWithBackoff(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(now,
+		outboxEvent(1, a, models.EventBookingCreated),
+		outboxEvent(2, a, models.EventBookingCancelled),
+		outboxEvent(3, b, models.EventBookingCreated),
+	)
+	failing := true
+	sink := &recordingSink{fail: func(e outbox.CloudEvent) error {
+		if failing && e.Subject == a.String() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	relay := outbox.NewRelay(store, []outbox.Sink{sink},
+		outbox.WithBackoff(time.Second, 5*time.Second),
+		outbox.WithClock(func() time.Time { return store.now }),
+	)
+
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		_, err := relay.RelayOnce(context.Background())
+		require.NoError(t, err)
+		delays = append(delays, store.nextAt[1].Sub(store.now))
+		store.now = store.nextAt[1]
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+	assert.Contains(t, store.failures[1], "recording: connection refused")
+	assert.False(t, store.published[2], "later events for the booking are held back")
+	assert.True(t, store.published[3], "other bookings are not held up")
+
+	failing = false
+	for i := 0; i < 2; i++ {
+		_, err := relay.RelayOnce(context.Background())
+		require.NoError(t, err)
+	}
+	assert.True(t, store.published[1])
+	assert.True(t, store.published[2])
+	assert.Equal(t, []string{
+		b.String() + " com.spacetrouble.booking.created",
+		a.String() + " com.spacetrouble.booking.created",
+		a.String() + " com.spacetrouble.booking.cancelled",
+	}, sink.types())
+}
+
+func TestRelayNeedsEverySink(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(now, outboxEvent(1, uuid.New(), models.EventBookingCreated))
+	ok := &recordingSink{}
+	broken := &recordingSink{fail: func(outbox.CloudEvent) error { return errors.New("503") }}
+	relay := outbox.NewRelay(store, []outbox.Sink{ok, broken}, outbox.WithClock(func() time.Time { return now }))
+
+	_, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, store.published[1])
+	assert.Len(t, ok.events, 1, "the healthy sink still got the event and will get it again")
+}
+
+func TestRelayRun(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now, outboxEvent(1, uuid.New(), models.EventBookingCreated))
+	sink := &recordingSink{}
+	relay := outbox.NewRelay(store, []outbox.Sink{sink}, outbox.WithPollInterval(time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(sink.types()) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestNewCloudEvent(t *testing.T) {
+	booking := uuid.New()
+	e := outboxEvent(1, booking, models.EventBookingCreated)
+
+	ce := outbox.NewCloudEvent(e)
+
+	assert.Equal(t, "1.0", ce.SpecVersion)
+	assert.Equal(t, e.EventID.String(), ce.ID)
+	assert.Equal(t, "/spacetrouble/bookings", ce.Source)
+	assert.Equal(t, "com.spacetrouble.booking.created", ce.Type)
+	assert.Equal(t, booking.String(), ce.Subject)
+	assert.Equal(t, e.OccurredAt, ce.Time)
+	assert.Equal(t, "application/json", ce.DataContentType)
+	assert.JSONEq(t, string(e.Payload), string(ce.Data))
+}
@@ -0,0 +1,175 @@
+package outbox_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	event := outbox.NewCloudEvent(outboxEvent(1, uuid.New(), models.EventBookingCreated))
+
+	t.Run("posts the structured event", func(t *testing.T) {
+		var got outbox.CloudEvent
+		var contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		err := outbox.NewWebhookSink(server.URL, server.Client()).Send(context.Background(), event)
+
+		require.NoError(t, err)
+		assert.Equal(t, "application/cloudevents+json", contentType)
+		assert.Equal(t, event.ID, got.ID)
+		assert.Equal(t, event.Type, got.Type)
+	})
+
+	t.Run("non 2xx is a failure", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := outbox.NewWebhookSink(server.URL, server.Client()).Send(context.Background(), event)
+
+		assert.ErrorContains(t, err, "webhook responded 503")
+	})
+}
+
+func TestWriterSinks(t *testing.T) {
+	first := outbox.NewCloudEvent(outboxEvent(1, uuid.New(), models.EventBookingCreated))
+	second := outbox.NewCloudEvent(outboxEvent(2, uuid.New(), models.EventBookingCancelled))
+
+	t.Run("one json line per event", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := outbox.NewWriterSink("buffer", &buf)
+		require.NoError(t, sink.Send(context.Background(), first))
+		require.NoError(t, sink.Send(context.Background(), second))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		var got outbox.CloudEvent
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+		assert.Equal(t, second.ID, got.ID)
+	})
+
+	t.Run("file sink appends", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.jsonl")
+		for _, e := range []outbox.CloudEvent{first, second} {
+			sink, err := outbox.NewFileSink(path)
+			require.NoError(t, err)
+			assert.Equal(t, "file", sink.Name())
+			require.NoError(t, sink.Send(context.Background(), e))
+		}
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	})
+}
+
+// fakeNATS accepts one connection and speaks the server side of the
+// protocol, reporting every published message.
+func fakeNATS(t *testing.T, reject bool) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	published := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "PUB":
+				var size int
+				fmt.Sscan(fields[len(fields)-1], &size)
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				if reject {
+					fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+					continue
+				}
+				published <- fields[1] + " " + string(payload[:size])
+			case fields[0] == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), published
+}
+
+func TestNATSSink(t *testing.T) {
+	event := outbox.NewCloudEvent(outboxEvent(1, uuid.New(), models.EventBookingCreated))
+
+	t.Run("publishes to the subject", func(t *testing.T) {
+		url, published := fakeNATS(t, false)
+		sink, err := outbox.NewNATSSink(url, "spacetrouble.bookings")
+		require.NoError(t, err)
+		defer sink.Close()
+
+		require.NoError(t, sink.Send(context.Background(), event))
+
+		msg := <-published
+		subject, payload, _ := strings.Cut(msg, " ")
+		assert.Equal(t, "spacetrouble.bookings", subject)
+		var got outbox.CloudEvent
+		require.NoError(t, json.Unmarshal([]byte(payload), &got))
+		assert.Equal(t, event.ID, got.ID)
+	})
+
+	t.Run("server errors fail the send", func(t *testing.T) {
+		url, _ := fakeNATS(t, true)
+		sink, err := outbox.NewNATSSink(url, "spacetrouble.bookings")
+		require.NoError(t, err)
+		defer sink.Close()
+
+		err = sink.Send(context.Background(), event)
+
+		assert.ErrorContains(t, err, "Permissions Violation")
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		sink, err := outbox.NewNATSSink(addr, "spacetrouble.bookings")
+		require.NoError(t, err)
+
+		assert.Error(t, sink.Send(context.Background(), event))
+	})
+}
@@ -0,0 +1,339 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/api"
+	"github.com/chrisdamba/spacetrouble/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return client.NewClient(
+		client.WithBaseURL(server.URL+"/v1"),
+		client.WithAPIKey("test-key"),
+		client.WithRetry(fastRetry),
+	)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, body)
+}
+
+const bookingJSON = `{"id":"123e4567-e89b-12d3-a456-426614174000","user":{"id":"u1","first_name":"John","last_name":"Doe",` +
+	`"gender":"male","birthday":"1990-01-01T00:00:00Z","email":"john@example.com"},"flight":{"id":"f1","launchpad_id":"5e9e4502f5090995de566f86",` +
+	`"destination":{"id":"d1","name":"Mars"},"launch_date":"2031-01-01T00:00:00Z"},"status":"CONFIRMED","created_at":"2030-06-01T12:00:00Z"}`
+
+func TestCreateBooking(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/bookings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "John", body["first_name"])
+		assert.Equal(t, "john@example.com", body["email"])
+		assert.NotContains(t, body, "user_id")
+		writeJSON(w, http.StatusCreated, bookingJSON)
+	})
+
+	booking, err := c.CreateBooking(context.Background(), client.BookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "male",
+		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		Email:         "john@example.com",
+		LaunchpadID:   "5e9e4502f5090995de566f86",
+		DestinationID: "d1",
+		LaunchDate:    time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", booking.ID)
+	assert.Equal(t, client.StatusConfirmed, booking.Status)
+	assert.Equal(t, "Mars", booking.Flight.Destination.Name)
+	assert.Equal(t, "john@example.com", booking.User.Email)
+}
+
+func TestCreateBooking_NotRetried(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeJSON(w, http.StatusServiceUnavailable, `{"error":"try later"}`)
+	})
+
+	_, err := c.CreateBooking(context.Background(), client.BookingRequest{})
+
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+func TestGetBooking(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "abc", r.URL.Query().Get("id"))
+		writeJSON(w, http.StatusOK, bookingJSON)
+	})
+
+	booking, err := c.GetBooking(context.Background(), "abc")
+
+	require.NoError(t, err)
+	assert.Equal(t, "John", booking.User.FirstName)
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+		code     string
+		message  string
+	}{
+		{"not found", 404, `{"error":"booking not found","code":"booking_not_found"}`, client.ErrNotFound, client.CodeBookingNotFound, "booking not found"},
+		{"conflict", 409, `{"error":"launchpad is unavailable","code":"launchpad_unavailable"}`, client.ErrConflict, client.CodeLaunchpadUnavailable, "launchpad is unavailable"},
+		{"validation", 400, `{"error":"Key: 'BookingRequest.Gender' failed"}`, client.ErrBadRequest, "", "Key: 'BookingRequest.Gender' failed"},
+		{"unauthorized", 401, `{"error":"authentication required"}`, client.ErrUnauthorized, "", "authentication required"},
+		{"forbidden", 403, `{"error":"not permitted for this role","code":"forbidden"}`, client.ErrForbidden, client.CodeForbidden, "not permitted for this role"},
+		{"server", 500, `{"error":"boom","code":"internal_error"}`, client.ErrServer, client.CodeInternal, "boom"},
+		{"body without json", 500, `oops`, client.ErrServer, "", "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			})
+
+			_, err := c.GetBooking(context.Background(), "id")
+
+			var apiErr *client.APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.ErrorIs(t, err, tt.sentinel)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, tt.message, apiErr.Message)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("retries transient failures", func(t *testing.T) {
+		var calls int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				writeJSON(w, http.StatusBadGateway, `{"error":"bad gateway"}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, bookingJSON)
+		})
+
+		_, err := c.GetBooking(context.Background(), "id")
+
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		var calls int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, `{"error":"slow down"}`)
+		})
+
+		err := c.CancelBooking(context.Background(), "id")
+
+		assert.ErrorIs(t, err, client.ErrRateLimited)
+		assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			writeJSON(w, http.StatusNotFound, `{"error":"booking not found","code":"booking_not_found"}`)
+		})
+
+		_, err := c.GetBooking(context.Background(), "id")
+
+		assert.ErrorIs(t, err, client.ErrNotFound)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		c := client.NewClient(client.WithBaseURL("http://127.0.0.1:1/v1"), client.WithRetry(client.RetryPolicy{
+			MaxAttempts: 5, BaseDelay: time.Hour,
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := c.Health(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestCancelBooking(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "abc", r.URL.Query().Get("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	assert.NoError(t, c.CancelBooking(context.Background(), "abc"))
+}
+
+func TestRescheduleBooking(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/bookings/reschedule", r.URL.Path)
+		assert.Equal(t, "abc", r.URL.Query().Get("id"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "2031-01-01T00:00:00Z", body["launch_date"])
+		writeJSON(w, http.StatusOK, bookingJSON)
+	})
+
+	booking, err := c.RescheduleBooking(context.Background(), "abc", time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC), booking.Flight.LaunchDate)
+}
+
+func TestListBookings_Iterates(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"bookings":[` + bookingJSON + `,` + bookingJSON + `],"limit":2,"next_cursor":"c1"}`,
+		"c1": `{"bookings":[],"limit":2,"next_cursor":"c2"}`,
+		"c2": `{"bookings":[` + bookingJSON + `],"limit":2,"next_cursor":""}`,
+	}
+	var requested []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "2", q.Get("limit"))
+		assert.Equal(t, "CONFIRMED", q.Get("status"))
+		assert.Equal(t, "2031-01-01T00:00:00Z", q.Get("launch_date_from"))
+		assert.Equal(t, "desc", q.Get("order"))
+		assert.False(t, q.Has("last_name"))
+		requested = append(requested, q.Get("cursor"))
+		writeJSON(w, http.StatusOK, pages[q.Get("cursor")])
+	})
+
+	it := c.ListBookings(context.Background(), client.ListOptions{
+		Limit:      2,
+		Status:     client.StatusConfirmed,
+		LaunchFrom: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		Order:      "desc",
+	})
+	count := 0
+	for it.Next() {
+		assert.Equal(t, "John", it.Booking().User.FirstName)
+		count++
+	}
+
+	require.NoError(t, it.Err())
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"", "c1", "c2"}, requested)
+	assert.False(t, it.Next())
+}
+
+func TestListBookings_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			writeJSON(w, http.StatusOK, `{"bookings":[`+bookingJSON+`],"limit":1,"next_cursor":"c1"}`)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, `{"error":"cursor has expired","code":"cursor_expired"}`)
+	})
+
+	it := c.ListBookings(context.Background(), client.ListOptions{})
+	require.True(t, it.Next())
+	require.False(t, it.Next())
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(it.Err(), &apiErr))
+	assert.Equal(t, client.CodeCursorExpired, apiErr.Code)
+}
+
+func TestHealth(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health", r.URL.Path)
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"status":"healthy","timestamp":%q,"uptime":"1s","go_version":"go1.22"}`, "2030-01-01T00:00:00Z"))
+	})
+
+	health, err := c.Health(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", health.Status)
+}
+
+// clientCodes reads the Code constants declared in pkg/client, so that
+// a code added to the API but not to the client is caught.
+func clientCodes(t *testing.T) map[string]string {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), filepath.Join("..", "..", "..", "pkg", "client"), nil, 0)
+	require.NoError(t, err)
+	codes := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					value := spec.(*ast.ValueSpec)
+					for i, name := range value.Names {
+						if !strings.HasPrefix(name.Name, "Code") || i >= len(value.Values) {
+							continue
+						}
+						lit, ok := value.Values[i].(*ast.BasicLit)
+						require.True(t, ok, "%s is not a string literal", name.Name)
+						code, err := strconv.Unquote(lit.Value)
+						require.NoError(t, err)
+						codes[code] = name.Name
+					}
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func TestCodesMatchServer(t *testing.T) {
+	codes := clientCodes(t)
+	server := make(map[string]bool)
+	for _, code := range api.ErrorCodes() {
+		server[code] = true
+		assert.Contains(t, codes, code, "the API sends %q but the client has no constant for it", code)
+	}
+	for code, name := range codes {
+		assert.True(t, server[code], "client.%s is %q, which the API never sends", name, code)
+	}
+}
@@ -18,6 +18,7 @@ func TestNewConfig(t *testing.T) {
 	require.NotNil(t, cfg)
 
 	assert.Equal(t, ":5000", cfg.Server.Address)
+	assert.Equal(t, ":5001", cfg.Server.GRPCAddress)
 	assert.Equal(t, 15*time.Second, cfg.Server.WriteTimeout)
 	assert.Equal(t, 15*time.Second, cfg.Server.ReadTimeout)
 	assert.Equal(t, 30*time.Second, cfg.Server.IdleTimeout)
@@ -28,23 +29,80 @@ func TestNewConfig(t *testing.T) {
 	assert.Equal(t, "", cfg.Database.Password)
 	assert.Equal(t, 99, cfg.Database.MaxPoolConns)
 	assert.Equal(t, "https://api.spacexdata.com/v4", cfg.SpaceX.BaseURL)
+	assert.Equal(t, 5*time.Minute, cfg.SpaceX.CacheTTL)
+	assert.Equal(t, time.Hour, cfg.SpaceX.CacheStale)
+	assert.Equal(t, 15*time.Second, cfg.SpaceX.Timeout)
+	assert.Equal(t, 3, cfg.SpaceX.MaxAttempts)
+	assert.Equal(t, 200*time.Millisecond, cfg.SpaceX.RetryMinBackoff)
+	assert.Equal(t, 5*time.Second, cfg.SpaceX.RetryMaxBackoff)
+	assert.Equal(t, 5, cfg.SpaceX.BreakerThreshold)
+	assert.Equal(t, 30*time.Second, cfg.SpaceX.BreakerCooldown)
+	assert.Equal(t, 24*time.Hour, cfg.Pagination.CursorTTL)
+	assert.Equal(t, "", cfg.Outbox.Sinks)
+	assert.Equal(t, "outbox.jsonl", cfg.Outbox.FilePath)
+	assert.Equal(t, "spacetrouble.bookings", cfg.Outbox.NATSSubject)
+	assert.Equal(t, time.Second, cfg.Outbox.PollInterval)
+	assert.Equal(t, 100, cfg.Outbox.BatchSize)
+	assert.Equal(t, 10*time.Second, cfg.Webhooks.Timeout)
+	assert.Equal(t, 10, cfg.Webhooks.MaxAttempts)
+	assert.Equal(t, 20, cfg.Webhooks.DisableAfter)
+	assert.Equal(t, "file", cfg.Mail.Transport)
+	assert.Equal(t, "SpaceTrouble <bookings@spacetrouble.local>", cfg.Mail.From)
+	assert.Equal(t, 587, cfg.Mail.SMTPPort)
+	assert.Equal(t, "mail", cfg.Mail.FileDir)
+	assert.Equal(t, 5, cfg.Mail.MaxAttempts)
+	assert.Equal(t, time.Minute, cfg.Reminders.Interval)
+	assert.Equal(t, time.Hour, cfg.Reconcile.Interval)
+	assert.Equal(t, 5*time.Minute, cfg.Reconcile.VerifyInterval)
+	assert.Equal(t, "reject", cfg.SpaceX.DegradedPolicy)
+	assert.Equal(t, 24*time.Hour, cfg.SpaceX.SnapshotMaxAge)
+	assert.Equal(t, 15*time.Minute, cfg.SpaceX.MirrorInterval)
+	assert.Equal(t, time.Hour, cfg.SpaceX.MirrorMaxAge)
 }
 
 func TestNewConfigWithEnvVars(t *testing.T) {
 	os.Clearenv()
 
 	envVars := map[string]string{
-		"SERVER_ADDRESS":       ":8080",
-		"SERVER_WRITE_TIMEOUT": "30s",
-		"SERVER_READ_TIMEOUT":  "30s",
-		"SERVER_IDLE_TIMEOUT":  "60s",
-		"POSTGRES_HOST":        "db.example.com",
-		"POSTGRES_PORT":        "5433",
-		"POSTGRES_DB":          "testdb",
-		"POSTGRES_USER":        "testuser",
-		"POSTGRES_PASSWORD":    "testpass",
-		"MAX_CONNS":            "50",
-		"SPACEX_URL":           "https://api.spacex.com/v5",
+		"SERVER_ADDRESS":           ":8080",
+		"GRPC_ADDRESS":             ":8081",
+		"SERVER_WRITE_TIMEOUT":     "30s",
+		"SERVER_READ_TIMEOUT":      "30s",
+		"SERVER_IDLE_TIMEOUT":      "60s",
+		"POSTGRES_HOST":            "db.example.com",
+		"POSTGRES_PORT":            "5433",
+		"POSTGRES_DB":              "testdb",
+		"POSTGRES_USER":            "testuser",
+		"POSTGRES_PASSWORD":        "testpass",
+		"MAX_CONNS":                "50",
+		"SPACEX_URL":               "https://api.spacex.com/v5",
+		"SPACEX_CACHE_TTL":         "30s",
+		"SPACEX_CACHE_STALE":       "0s",
+		"SPACEX_TIMEOUT":           "5s",
+		"SPACEX_MAX_ATTEMPTS":      "4",
+		"SPACEX_RETRY_MIN_BACKOFF": "100ms",
+		"SPACEX_RETRY_MAX_BACKOFF": "2s",
+		"SPACEX_BREAKER_THRESHOLD": "0",
+		"SPACEX_BREAKER_COOLDOWN":  "1m",
+		"OUTBOX_SINKS":             "stdout,webhook",
+		"OUTBOX_WEBHOOK_URL":       "https://hooks.example.com/bookings",
+		"OUTBOX_POLL_INTERVAL":     "5s",
+		"OUTBOX_BATCH_SIZE":        "20",
+		"WEBHOOK_TIMEOUT":          "3s",
+		"WEBHOOK_MAX_ATTEMPTS":     "5",
+		"MAIL_TRANSPORT":           "smtp",
+		"MAIL_FROM":                "bookings@example.com",
+		"SMTP_HOST":                "smtp.example.com",
+		"SMTP_PORT":                "2525",
+		"SMTP_USERNAME":            "mailer",
+		"SMTP_PASSWORD":            "secret",
+		"REMINDER_INTERVAL":        "30s",
+		"RECONCILE_INTERVAL":       "15m",
+		"VERIFY_INTERVAL":          "1m",
+		"SPACEX_DEGRADED_POLICY":   "snapshot,pending",
+		"SPACEX_SNAPSHOT_MAX_AGE":  "6h",
+		"SPACEX_MIRROR_INTERVAL":   "5m",
+		"SPACEX_MIRROR_MAX_AGE":    "30m",
 	}
 
 	for k, v := range envVars {
@@ -57,6 +115,7 @@ func TestNewConfigWithEnvVars(t *testing.T) {
 
 	// Test environment values
 	assert.Equal(t, ":8080", cfg.Server.Address)
+	assert.Equal(t, ":8081", cfg.Server.GRPCAddress)
 	assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
 	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
 	assert.Equal(t, 60*time.Second, cfg.Server.IdleTimeout)
@@ -67,6 +126,33 @@ func TestNewConfigWithEnvVars(t *testing.T) {
 	assert.Equal(t, "testpass", cfg.Database.Password)
 	assert.Equal(t, 50, cfg.Database.MaxPoolConns)
 	assert.Equal(t, "https://api.spacex.com/v5", cfg.SpaceX.BaseURL)
+	assert.Equal(t, 30*time.Second, cfg.SpaceX.CacheTTL)
+	assert.Equal(t, time.Duration(0), cfg.SpaceX.CacheStale)
+	assert.Equal(t, 5*time.Second, cfg.SpaceX.Timeout)
+	assert.Equal(t, 4, cfg.SpaceX.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, cfg.SpaceX.RetryMinBackoff)
+	assert.Equal(t, 2*time.Second, cfg.SpaceX.RetryMaxBackoff)
+	assert.Equal(t, 0, cfg.SpaceX.BreakerThreshold)
+	assert.Equal(t, time.Minute, cfg.SpaceX.BreakerCooldown)
+	assert.Equal(t, "stdout,webhook", cfg.Outbox.Sinks)
+	assert.Equal(t, "https://hooks.example.com/bookings", cfg.Outbox.WebhookURL)
+	assert.Equal(t, 5*time.Second, cfg.Outbox.PollInterval)
+	assert.Equal(t, 20, cfg.Outbox.BatchSize)
+	assert.Equal(t, 3*time.Second, cfg.Webhooks.Timeout)
+	assert.Equal(t, 5, cfg.Webhooks.MaxAttempts)
+	assert.Equal(t, "smtp", cfg.Mail.Transport)
+	assert.Equal(t, "bookings@example.com", cfg.Mail.From)
+	assert.Equal(t, "smtp.example.com", cfg.Mail.SMTPHost)
+	assert.Equal(t, 2525, cfg.Mail.SMTPPort)
+	assert.Equal(t, "mailer", cfg.Mail.SMTPUsername)
+	assert.Equal(t, "secret", cfg.Mail.SMTPPassword)
+	assert.Equal(t, 30*time.Second, cfg.Reminders.Interval)
+	assert.Equal(t, 15*time.Minute, cfg.Reconcile.Interval)
+	assert.Equal(t, time.Minute, cfg.Reconcile.VerifyInterval)
+	assert.Equal(t, "snapshot,pending", cfg.SpaceX.DegradedPolicy)
+	assert.Equal(t, 6*time.Hour, cfg.SpaceX.SnapshotMaxAge)
+	assert.Equal(t, 5*time.Minute, cfg.SpaceX.MirrorInterval)
+	assert.Equal(t, 30*time.Minute, cfg.SpaceX.MirrorMaxAge)
 }
 
 func TestDatabaseDSN(t *testing.T) {
@@ -112,6 +198,90 @@ func TestInvalidConfigurations(t *testing.T) {
 				"MAX_CONNS": "invalid",
 			},
 		},
+		{
+			name: "Invalid spacex cache ttl",
+			envVars: map[string]string{
+				"SPACEX_CACHE_TTL": "invalid",
+			},
+		},
+		{
+			name: "Invalid spacex max attempts",
+			envVars: map[string]string{
+				"SPACEX_MAX_ATTEMPTS": "invalid",
+			},
+		},
+		{
+			name: "Invalid spacex breaker cooldown",
+			envVars: map[string]string{
+				"SPACEX_BREAKER_COOLDOWN": "invalid",
+			},
+		},
+		{
+			name: "Invalid outbox poll interval",
+			envVars: map[string]string{
+				"OUTBOX_POLL_INTERVAL": "invalid",
+			},
+		},
+		{
+			name: "Invalid outbox batch size",
+			envVars: map[string]string{
+				"OUTBOX_BATCH_SIZE": "invalid",
+			},
+		},
+		{
+			name: "Invalid webhook timeout",
+			envVars: map[string]string{
+				"WEBHOOK_TIMEOUT": "invalid",
+			},
+		},
+		{
+			name: "Invalid webhook disable threshold",
+			envVars: map[string]string{
+				"WEBHOOK_DISABLE_AFTER": "invalid",
+			},
+		},
+		{
+			name: "Invalid smtp port",
+			envVars: map[string]string{
+				"SMTP_PORT": "invalid",
+			},
+		},
+		{
+			name: "Invalid mail max attempts",
+			envVars: map[string]string{
+				"MAIL_MAX_ATTEMPTS": "invalid",
+			},
+		},
+		{
+			name: "Invalid reminder interval",
+			envVars: map[string]string{
+				"REMINDER_INTERVAL": "invalid",
+			},
+		},
+		{
+			name: "Invalid reconcile interval",
+			envVars: map[string]string{
+				"RECONCILE_INTERVAL": "invalid",
+			},
+		},
+		{
+			name: "Invalid verify interval",
+			envVars: map[string]string{
+				"VERIFY_INTERVAL": "invalid",
+			},
+		},
+		{
+			name: "Invalid spacex snapshot max age",
+			envVars: map[string]string{
+				"SPACEX_SNAPSHOT_MAX_AGE": "invalid",
+			},
+		},
+		{
+			name: "Invalid spacex mirror interval",
+			envVars: map[string]string{
+				"SPACEX_MIRROR_INTERVAL": "invalid",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -82,3 +82,28 @@ func TestHealthGet(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthGetDegradedMode(t *testing.T) {
+	mode := health.DegradedMode{
+		SpaceXAvailable: true,
+		Policy:          health.DegradedPolicy{Name: "snapshot", Decisions: 3},
+		Fallbacks:       []health.DegradedPolicy{{Name: "pending", Decisions: 1}, {Name: "reject"}},
+	}
+	handler := health.HealthGet(health.WithDegradedMode(func() health.DegradedMode { return mode }))
+
+	for _, available := range []bool{true, false} {
+		mode.SpaceXAvailable = available
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var response health.HealthResponse
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, &mode, response.DegradedMode)
+		if available {
+			assert.Equal(t, "healthy", response.Status)
+		} else {
+			assert.Equal(t, "degraded", response.Status, "SpaceX being unavailable degrades the service")
+		}
+	}
+}
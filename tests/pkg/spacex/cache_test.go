@@ -0,0 +1,275 @@
+package spacex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource answers schedule lookups, optionally holding each one until
+// release is closed.
+type fakeSource struct {
+	mu       sync.Mutex
+	schedule *spacex.Schedule
+	err      error
+	release  chan struct{}
+	calls    atomic.Int64
+}
+
+func (f *fakeSource) LaunchSchedule(ctx context.Context, id string) (*spacex.Schedule, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.schedule, f.err
+}
+
+func (f *fakeSource) set(schedule *spacex.Schedule, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule, f.err = schedule, err
+}
+
+func schedule(status string) *spacex.Schedule {
+	return &spacex.Schedule{LaunchPad: spacex.LaunchPad{Id: "pad1", Status: status}}
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newCache(source *fakeSource, clk *clock) *spacex.Cache {
+	return spacex.NewCache(source,
+		spacex.WithCacheTTL(time.Minute),
+		spacex.WithStaleWhileRevalidate(10*time.Minute),
+		spacex.WithCacheClock(clk.Now),
+	)
+}
+
+func TestCacheServesFreshSchedules(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active")}
+	cache := newCache(source, &clock{now: time.Now()})
+
+	for i := 0; i < 3; i++ {
+		got, err := cache.LaunchSchedule(context.Background(), "pad1")
+		require.NoError(t, err)
+		assert.Equal(t, "active", got.LaunchPad.Status)
+	}
+
+	assert.EqualValues(t, 1, source.calls.Load())
+	assert.Equal(t, spacex.CacheStats{Hits: 2, Misses: 1, Fetches: 1, Entries: 1}, cache.Stats())
+}
+
+func TestCacheServesStaleWhileRevalidating(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active")}
+	clk := &clock{now: time.Now()}
+	cache := newCache(source, clk)
+
+	_, err := cache.LaunchSchedule(context.Background(), "pad1")
+	require.NoError(t, err)
+
+	source.set(schedule("retired"), nil)
+	clk.Advance(2 * time.Minute)
+	got, err := cache.LaunchSchedule(context.Background(), "pad1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "active", got.LaunchPad.Status, "the stale schedule is served at once")
+	require.Eventually(t, func() bool {
+		got, _ := cache.LaunchSchedule(context.Background(), "pad1")
+		return got.LaunchPad.Status == "retired"
+	}, time.Second, 5*time.Millisecond, "and replaced in the background")
+	assert.EqualValues(t, 2, source.calls.Load())
+	assert.EqualValues(t, 1, cache.Stats().StaleHits)
+}
+
+func TestCacheRefetchesOnceStalePeriodEnds(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active")}
+	clk := &clock{now: time.Now()}
+	cache := newCache(source, clk)
+
+	_, err := cache.LaunchSchedule(context.Background(), "pad1")
+	require.NoError(t, err)
+
+	source.set(schedule("retired"), nil)
+	clk.Advance(11 * time.Minute)
+	got, err := cache.LaunchSchedule(context.Background(), "pad1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "retired", got.LaunchPad.Status)
+	assert.Equal(t, spacex.CacheStats{Misses: 2, Fetches: 2, Entries: 1}, cache.Stats())
+}
+
+func TestCacheCoalescesConcurrentLookups(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active"), release: make(chan struct{})}
+	cache := newCache(source, &clock{now: time.Now()})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := cache.LaunchSchedule(context.Background(), "pad1")
+			assert.NoError(t, err)
+			assert.Equal(t, "active", got.LaunchPad.Status)
+		}()
+	}
+	require.Eventually(t, func() bool { return cache.Stats().Misses == 50 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond) // let the last misses join the lookup
+	close(source.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, source.calls.Load(), "a burst on one launchpad makes one call to SpaceX")
+	assert.EqualValues(t, 1, cache.Stats().Fetches)
+}
+
+func TestCacheKeepsUnknownLaunchpadsButNotFailures(t *testing.T) {
+	source := &fakeSource{err: errors.New("connection reset")}
+	cache := newCache(source, &clock{now: time.Now()})
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.LaunchSchedule(context.Background(), "pad1")
+		assert.ErrorContains(t, err, "connection reset")
+	}
+	assert.EqualValues(t, 2, source.calls.Load(), "failures are not cached")
+	assert.EqualValues(t, 2, cache.Stats().FetchErrors)
+
+	source.set(nil, spacex.ErrNotFound)
+	for i := 0; i < 2; i++ {
+		_, err := cache.LaunchSchedule(context.Background(), "pad1")
+		assert.ErrorIs(t, err, spacex.ErrNotFound)
+	}
+	assert.EqualValues(t, 3, source.calls.Load(), "an unknown launchpad is an answer worth keeping")
+}
+
+func TestCacheLookupOutlivesCancelledCaller(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active"), release: make(chan struct{})}
+	cache := newCache(source, &clock{now: time.Now()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := cache.LaunchSchedule(ctx, "pad1")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return source.calls.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	close(source.release)
+	require.Eventually(t, func() bool {
+		got, err := cache.LaunchSchedule(context.Background(), "pad1")
+		return err == nil && got != nil
+	}, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, 1, source.calls.Load(), "the shared lookup finished and was cached")
+}
+
+func TestCacheCheckLaunchConflict(t *testing.T) {
+	launch := time.Now().UTC().AddDate(0, 1, 0)
+	source := &fakeSource{schedule: &spacex.Schedule{
+		LaunchPad: spacex.LaunchPad{Id: "pad1", Status: "active"},
+		Launches:  []spacex.Launch{{LaunchPadID: "pad1", Date: launch.Unix(), DatePrecision: "day"}},
+	}}
+	cache := newCache(source, &clock{now: time.Now()})
+
+	available, err := cache.CheckLaunchConflict(context.Background(), "pad1", launch)
+	require.NoError(t, err)
+	assert.False(t, available)
+
+	available, err = cache.CheckLaunchConflict(context.Background(), "pad1", launch.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.True(t, available)
+	assert.EqualValues(t, 1, source.calls.Load())
+
+	_, err = cache.CheckLaunchConflict(context.Background(), "", launch)
+	assert.ErrorContains(t, err, "launchpad ID cannot be empty")
+}
+
+func TestCacheLastKnownSchedule(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active")}
+	clk := &clock{now: time.Now()}
+	cache := newCache(source, clk)
+
+	_, _, ok := cache.LastKnownSchedule("pad1")
+	assert.False(t, ok, "nothing is known before the first lookup")
+
+	_, err := cache.LaunchSchedule(context.Background(), "pad1")
+	require.NoError(t, err)
+	fetchedAt := clk.Now()
+	clk.Advance(24 * time.Hour)
+
+	got, seenAt, ok := cache.LastKnownSchedule("pad1")
+	require.True(t, ok, "the schedule is kept past its stale period")
+	assert.Equal(t, "active", got.LaunchPad.Status)
+	assert.Equal(t, fetchedAt, seenAt)
+	assert.EqualValues(t, 1, source.calls.Load(), "SpaceX is not asked")
+}
+
+func TestCacheDropsEntriesPastTheirStalePeriod(t *testing.T) {
+	source := &fakeSource{err: spacex.ErrNotFound}
+	clk := &clock{now: time.Now()}
+	cache := newCache(source, clk)
+
+	for i := 0; i < 100; i++ {
+		_, err := cache.LaunchSchedule(context.Background(), fmt.Sprintf("made-up-%d", i))
+		require.ErrorIs(t, err, spacex.ErrNotFound)
+	}
+	assert.Equal(t, 100, cache.Stats().Entries)
+
+	clk.Advance(11 * time.Minute)
+	_, err := cache.LaunchSchedule(context.Background(), "another")
+	require.ErrorIs(t, err, spacex.ErrNotFound)
+
+	assert.Equal(t, 1, cache.Stats().Entries, "only the entry still within its stale period is left")
+}
+
+func TestCacheRetainsSchedulesForLastKnownSchedule(t *testing.T) {
+	source := &fakeSource{schedule: schedule("active")}
+	clk := &clock{now: time.Now()}
+	cache := spacex.NewCache(source,
+		spacex.WithCacheTTL(time.Minute),
+		spacex.WithStaleWhileRevalidate(10*time.Minute),
+		spacex.WithRetention(24*time.Hour),
+		spacex.WithCacheClock(clk.Now),
+	)
+	_, err := cache.LaunchSchedule(context.Background(), "pad1")
+	require.NoError(t, err)
+	source.set(nil, spacex.ErrNotFound)
+	_, err = cache.LaunchSchedule(context.Background(), "made-up")
+	require.ErrorIs(t, err, spacex.ErrNotFound)
+
+	clk.Advance(time.Hour)
+	_, err = cache.LaunchSchedule(context.Background(), "another")
+	require.ErrorIs(t, err, spacex.ErrNotFound)
+	_, _, ok := cache.LastKnownSchedule("pad1")
+	assert.True(t, ok, "a schedule outlives its stale period until its retention ends")
+	assert.Equal(t, 2, cache.Stats().Entries, "the unknown launchpad is not retained")
+
+	clk.Advance(23 * time.Hour)
+	_, err = cache.LaunchSchedule(context.Background(), "yet-another")
+	require.ErrorIs(t, err, spacex.ErrNotFound)
+	_, _, ok = cache.LastKnownSchedule("pad1")
+	assert.False(t, ok)
+}
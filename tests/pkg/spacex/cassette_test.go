@@ -0,0 +1,175 @@
+package spacex_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Trace", "trace-id")
+		switch r.URL.Path {
+		case "/v4/launchpads/pad1":
+			io.WriteString(w, `{"id":"pad1","status":"active"}`)
+		case "/v4/launches/query":
+			io.WriteString(w, `{"docs":[{"launchpad":"pad1","date_unix":2215467000,"date_precision":"day"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassettes", "pad1.yaml")
+	date := time.Unix(2215467000, 0)
+
+	recorder, err := cassette.New(path, cassette.Record, cassette.WithRedactedHeaders("X-Trace"))
+	require.NoError(t, err)
+	client := spacex.NewClient(spacex.WithHTTPClient(authorizing{recorder}), spacex.WithBaseURL(server.URL+"/v4"))
+
+	available, err := client.CheckLaunchConflict(context.Background(), "pad1", date)
+	require.NoError(t, err)
+	assert.False(t, available, "the recorded response reaches the caller")
+	require.NoError(t, recorder.Save())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.NotContains(t, string(data), "trace-id")
+	assert.Contains(t, string(data), cassette.Redacted)
+
+	interactions := recorder.Interactions()
+	require.Len(t, interactions, 2)
+	assert.Equal(t, []string{cassette.Redacted}, interactions[0].Request.Headers.Values("Authorization"))
+	assert.Equal(t, []string{cassette.Redacted}, interactions[0].Response.Headers.Values("Set-Cookie"))
+	assert.Contains(t, interactions[1].Request.Body, `"launchpad":"pad1"`)
+
+	server.Close()
+	replayer, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+	client = spacex.NewClient(spacex.WithHTTPClient(replayer), spacex.WithBaseURL(server.URL+"/v4"))
+
+	for i := 0; i < 2; i++ {
+		available, err = client.CheckLaunchConflict(context.Background(), "pad1", date)
+		require.NoError(t, err)
+		assert.False(t, available)
+	}
+	assert.EqualValues(t, 2, calls.Load(), "replaying never calls the server")
+}
+
+// authorizing adds a credential to every request, as a proxy might.
+type authorizing struct {
+	next spacex.HTTPClient
+}
+
+func (a authorizing) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer secret")
+	return a.next.Do(req)
+}
+
+func writeCassette(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestCassetteMatchesMethodURLAndBody(t *testing.T) {
+	path := writeCassette(t, `
+interactions:
+  - request:
+      method: POST
+      url: https://api.spacexdata.com/v4/launches/query
+      body: '{"query":{"launchpad":"pad1","upcoming":true}}'
+    response:
+      status: 200
+      body: pad1
+  - request:
+      method: POST
+      url: https://api.spacexdata.com/v4/launches/query
+      body: '{"query":{"launchpad":"pad2","upcoming":true}}'
+    response:
+      status: 200
+      body: pad2
+`)
+	recorder, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+
+	send := func(method, url, body string) (string, error) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := recorder.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(got), nil
+	}
+
+	got, err := send(http.MethodPost, "https://api.spacexdata.com/v4/launches/query", `{ "query": { "upcoming": true, "launchpad": "pad2" } }`)
+	require.NoError(t, err)
+	assert.Equal(t, "pad2", got, "JSON bodies match whatever their key order and spacing")
+
+	got, err = send(http.MethodPost, "https://api.spacexdata.com/v4/launches/query", `{"query":{"launchpad":"pad1","upcoming":true}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "pad1", got)
+
+	_, err = send(http.MethodPost, "https://api.spacexdata.com/v4/launches/query", `{"query":{"launchpad":"pad3","upcoming":true}}`)
+	assert.ErrorIs(t, err, cassette.ErrNoInteraction)
+	_, err = send(http.MethodGet, "https://api.spacexdata.com/v4/launches/query", `{"query":{"launchpad":"pad1","upcoming":true}}`)
+	assert.ErrorIs(t, err, cassette.ErrNoInteraction)
+	_, err = send(http.MethodPost, "https://api.spacexdata.com/v5/launches/query", `{"query":{"launchpad":"pad1","upcoming":true}}`)
+	assert.ErrorIs(t, err, cassette.ErrNoInteraction)
+}
+
+func TestCassetteReplaysInOrder(t *testing.T) {
+	path := writeCassette(t, `
+interactions:
+  - request:
+      method: GET
+      url: https://api.spacexdata.com/v4/launchpads/pad1
+    response:
+      status: 503
+      body: Service Unavailable
+  - request:
+      method: GET
+      url: https://api.spacexdata.com/v4/launchpads/pad1
+    response:
+      status: 200
+      body: '{"id":"pad1","status":"active"}'
+`)
+	recorder, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+	client := spacex.NewClient(
+		spacex.WithHTTPClient(recorder),
+		spacex.WithRetries(2, time.Millisecond, time.Millisecond),
+	)
+
+	launchpad, err := client.GetLaunchPadById(context.Background(), "pad1")
+
+	require.NoError(t, err, "the retry is answered by the next recorded response")
+	assert.Equal(t, "active", launchpad.Status)
+}
+
+func TestCassetteNeedsAFileToReplay(t *testing.T) {
+	_, err := cassette.New(filepath.Join(t.TempDir(), "missing.yaml"), cassette.Replay)
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -30,7 +30,8 @@ func newTestClient(doFunc func(*http.Request) (*http.Response, error)) *spacex.C
 }
 func TestCheckLaunchConflict(t *testing.T) {
 	futureDate := time.Now().Add(24 * time.Hour)
-	baseTime := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	nextYear := time.Now().UTC().AddDate(1, 0, 0)
+	baseTime := time.Date(nextYear.Year(), nextYear.Month(), nextYear.Day(), 12, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name          string
 		launchpadID   string
@@ -378,3 +379,95 @@ func TestClient_GetLaunchPadById(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_Schedules(t *testing.T) {
+	t.Run("groups upcoming launches by launchpad", func(t *testing.T) {
+		var queries []map[string]interface{}
+		client := newTestClient(func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/v4/launchpads":
+				assert.Equal(t, http.MethodGet, req.Method)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`[{"id":"pad1","status":"active"},{"id":"pad2","status":"retired"}]`)),
+				}, nil
+			case "/v4/launches/query":
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+				queries = append(queries, body)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body: io.NopCloser(strings.NewReader(`{"docs":[
+						{"launchpad":"pad1","date_unix":1900000000,"date_precision":"day"},
+						{"launchpad":"pad1","date_unix":1910000000,"date_precision":"month"}
+					]}`)),
+				}, nil
+			}
+			t.Fatalf("unexpected request to %s", req.URL)
+			return nil, nil
+		})
+
+		schedules, err := client.Schedules(context.Background())
+
+		require.NoError(t, err)
+		require.Len(t, schedules, 2)
+		assert.Equal(t, spacex.LaunchPad{Id: "pad1", Status: "active"}, schedules[0].LaunchPad)
+		assert.Len(t, schedules[0].Launches, 2)
+		assert.Equal(t, spacex.LaunchPad{Id: "pad2", Status: "retired"}, schedules[1].LaunchPad)
+		assert.Empty(t, schedules[1].Launches)
+
+		require.Len(t, queries, 1)
+		query := queries[0]["query"].(map[string]interface{})
+		assert.Equal(t, true, query["upcoming"])
+		assert.NotContains(t, query, "launchpad", "one query covers every launchpad")
+	})
+
+	t.Run("fails when launchpads cannot be listed", func(t *testing.T) {
+		client := newTestClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+			}, nil
+		})
+
+		schedules, err := client.Schedules(context.Background())
+
+		assert.ErrorIs(t, err, spacex.ErrBadStatusCode)
+		assert.ErrorContains(t, err, "fetching launchpads")
+		assert.Nil(t, schedules)
+	})
+}
+
+func TestLaunch_Window(t *testing.T) {
+	launchDate := time.Date(2030, 5, 15, 14, 30, 0, 0, time.UTC)
+	dayStart := time.Date(2030, 5, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		precision string
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{precision: "hour", wantEnd: launchDate.Add(time.Hour)},
+		{precision: "day", wantEnd: dayStart.AddDate(0, 0, 1).Add(-time.Second)},
+		{precision: "month", wantEnd: dayStart.AddDate(0, 1, 0).Add(-time.Second)},
+		{precision: "quarter", wantEnd: dayStart.AddDate(0, 3, 0).Add(-time.Second)},
+		{precision: "half", wantEnd: dayStart.AddDate(0, 6, 0).Add(-time.Second)},
+		{precision: "year", wantEnd: dayStart.AddDate(1, 0, 0).Add(-time.Second)},
+		{precision: "week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.precision, func(t *testing.T) {
+			launch := spacex.Launch{Date: launchDate.Unix(), DatePrecision: tt.precision}
+
+			start, end, err := launch.Window()
+
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "invalid date precision")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, dayStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
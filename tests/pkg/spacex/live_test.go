@@ -0,0 +1,75 @@
+package spacex_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordEnv set to 1 makes liveClient call the real SpaceX API and record
+// its responses, replacing the cassette under testdata/cassettes/live:
+//
+//	SPACEX_CASSETTE_RECORD=1 go test ./tests/pkg/spacex/ -run Live
+const recordEnv = "SPACEX_CASSETTE_RECORD"
+
+// liveClient returns a client answered from a cassette recorded against
+// the live API, so that the client is checked against what SpaceX really
+// returns rather than against the shapes the edge-case cassettes assume.
+// The test is skipped while no such cassette has been recorded.
+func liveClient(t *testing.T, name string) *spacex.Client {
+	t.Helper()
+	path := filepath.Join("testdata", "cassettes", "live", name+".yaml")
+
+	if os.Getenv(recordEnv) == "1" {
+		recorder, err := cassette.New(path, cassette.Record)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			if t.Failed() {
+				return
+			}
+			require.NoError(t, recorder.Save())
+		})
+		return spacex.NewClient(spacex.WithHTTPClient(recorder))
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("%s has not been recorded; run with %s=1 to record it from the live API", path, recordEnv)
+	}
+	recorder, err := cassette.New(path, cassette.Replay)
+	require.NoError(t, err)
+	return spacex.NewClient(spacex.WithHTTPClient(recorder))
+}
+
+func TestLiveLaunchpads(t *testing.T) {
+	client := liveClient(t, "launchpads")
+
+	launchpads, err := client.GetLaunchPads(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, launchpads)
+	for _, launchpad := range launchpads {
+		assert.NotEmpty(t, launchpad.Id)
+		assert.NotEmpty(t, launchpad.Status)
+	}
+}
+
+func TestLiveLaunchSchedule(t *testing.T) {
+	const launchpadID = "5e9e4501f509094ba4566f84" // CCSFS SLC 40
+	client := liveClient(t, "schedule")
+
+	schedule, err := client.LaunchSchedule(context.Background(), launchpadID)
+	require.NoError(t, err)
+	assert.Equal(t, launchpadID, schedule.LaunchPad.Id)
+	assert.NotEmpty(t, schedule.LaunchPad.Status)
+	for _, launch := range schedule.Launches {
+		assert.Equal(t, launchpadID, launch.LaunchPadID)
+		assert.NotZero(t, launch.Date)
+		_, _, err := launch.Window()
+		assert.NoError(t, err, "date precision %q", launch.DatePrecision)
+	}
+}
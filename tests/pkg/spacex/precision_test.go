@@ -0,0 +1,113 @@
+package spacex_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The cassettes under testdata/cassettes are written by hand, not
+// recorded: each holds a made-up launch, dated in 2040 at an edge of its
+// precision's window, in the shape of a v4 API response with the fields
+// the client does not read left out or zeroed. They are replayed only.
+// The shapes themselves are checked against the cassettes liveClient
+// records from the real API.
+func replayClient(t *testing.T, name string) *spacex.Client {
+	t.Helper()
+	recorder, err := cassette.New(filepath.Join("testdata", "cassettes", name+".yaml"), cassette.Replay)
+	require.NoError(t, err)
+	return spacex.NewClient(spacex.WithHTTPClient(recorder))
+}
+
+func day(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(12 * time.Hour)
+}
+
+func TestCheckLaunchConflictPrecisionEdges(t *testing.T) {
+	tests := []struct {
+		cassette string
+		date     string
+		want     bool
+	}{
+		// 2040-03-15 23:30 UTC
+		{cassette: "hour", date: "2040-03-14", want: true},
+		{cassette: "hour", date: "2040-03-15", want: false},
+		{cassette: "hour", date: "2040-03-16", want: false}, // the hour runs past midnight
+		{cassette: "hour", date: "2040-03-17", want: true},
+		// 2040-03-15 10:00 UTC
+		{cassette: "day", date: "2040-03-14", want: true},
+		{cassette: "day", date: "2040-03-15", want: false},
+		{cassette: "day", date: "2040-03-16", want: true},
+		// 2040-01-31, a month counted from the 31st
+		{cassette: "month", date: "2040-01-30", want: true},
+		{cassette: "month", date: "2040-01-31", want: false},
+		{cassette: "month", date: "2040-02-29", want: false},
+		{cassette: "month", date: "2040-03-01", want: false}, // February 31st normalises to March 2nd
+		{cassette: "month", date: "2040-03-02", want: true},
+		// 2040-11-20, a quarter across the new year
+		{cassette: "quarter", date: "2040-11-19", want: true},
+		{cassette: "quarter", date: "2041-01-01", want: false},
+		{cassette: "quarter", date: "2041-02-19", want: false},
+		{cassette: "quarter", date: "2041-02-20", want: true},
+		// 2040-07-01
+		{cassette: "half", date: "2040-06-30", want: true},
+		{cassette: "half", date: "2040-12-31", want: false},
+		{cassette: "half", date: "2041-01-01", want: true},
+		// 2040-02-29, a year from a leap day
+		{cassette: "year", date: "2040-02-28", want: true},
+		{cassette: "year", date: "2041-02-28", want: false},
+		{cassette: "year", date: "2041-03-01", want: true},
+		// no launches from a retired launchpad, which is never available
+		{cassette: "retired_launchpad", date: "2040-03-15", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cassette+"/"+tt.date, func(t *testing.T) {
+			client := replayClient(t, tt.cassette)
+
+			available, err := client.CheckLaunchConflict(context.Background(), launchpadOf(t, tt.cassette), day(tt.date))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, available)
+		})
+	}
+}
+
+func TestCheckLaunchConflictPrecisionErrors(t *testing.T) {
+	t.Run("unknown precision", func(t *testing.T) {
+		client := replayClient(t, "unknown_precision")
+
+		_, err := client.CheckLaunchConflict(context.Background(), "5e9e4501f509094ba4566f84", day("2040-03-15"))
+
+		assert.ErrorContains(t, err, "invalid date precision: week")
+	})
+
+	t.Run("unknown launchpad", func(t *testing.T) {
+		client := replayClient(t, "unknown_launchpad")
+
+		_, err := client.CheckLaunchConflict(context.Background(), "000000000000000000000000", day("2040-03-15"))
+
+		assert.ErrorIs(t, err, spacex.ErrNotFound)
+	})
+}
+
+// launchpadOf reads the launchpad a cassette was recorded for from its
+// first request.
+func launchpadOf(t *testing.T, name string) string {
+	t.Helper()
+	recorder, err := cassette.New(filepath.Join("testdata", "cassettes", name+".yaml"), cassette.Replay)
+	require.NoError(t, err)
+	interactions := recorder.Interactions()
+	require.NotEmpty(t, interactions)
+	return filepath.Base(interactions[0].Request.URL)
+}
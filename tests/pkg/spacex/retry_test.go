@@ -0,0 +1,254 @@
+package spacex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scripted answers each request with the next response in turn, repeating
+// the last one once it runs out.
+type scripted struct {
+	responses []func() (*http.Response, error)
+	calls     atomic.Int64
+	bodies    []string
+}
+
+func (s *scripted) Do(req *http.Request) (*http.Response, error) {
+	n := int(s.calls.Add(1))
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(body))
+	}
+	if n > len(s.responses) {
+		n = len(s.responses)
+	}
+	return s.responses[n-1]()
+}
+
+func status(code int, headers ...string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := &http.Response{StatusCode: code, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"id":"pad1","status":"active"}`))}
+		for i := 0; i+1 < len(headers); i += 2 {
+			resp.Header.Set(headers[i], headers[i+1])
+		}
+		return resp, nil
+	}
+}
+
+func failure(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) { return nil, err }
+}
+
+func newRetryingClient(transport spacex.HTTPClient, opts ...spacex.Option) *spacex.Client {
+	return spacex.NewClient(append([]spacex.Option{
+		spacex.WithHTTPClient(transport),
+		spacex.WithBaseURL("https://test.spacex.com/v4"),
+		spacex.WithRetries(3, time.Millisecond, 4*time.Millisecond),
+	}, opts...)...)
+}
+
+func TestClientRetries(t *testing.T) {
+	timeout := fmt.Errorf("Get: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name      string
+		responses []func() (*http.Response, error)
+		wantCalls int64
+		wantErr   error
+	}{
+		{name: "server error then success", responses: []func() (*http.Response, error){status(503), status(200)}, wantCalls: 2},
+		{name: "rate limited then success", responses: []func() (*http.Response, error){status(429), status(200)}, wantCalls: 2},
+		{name: "timeout then success", responses: []func() (*http.Response, error){failure(timeout), status(200)}, wantCalls: 2},
+		{name: "gives up after max attempts", responses: []func() (*http.Response, error){status(500)}, wantCalls: 3, wantErr: spacex.ErrBadStatusCode},
+		{name: "keeps timing out", responses: []func() (*http.Response, error){failure(timeout)}, wantCalls: 3, wantErr: context.DeadlineExceeded},
+		{name: "other transport errors are not retried", responses: []func() (*http.Response, error){failure(errors.New("connection refused"))}, wantCalls: 1},
+		{name: "not found is not retried", responses: []func() (*http.Response, error){status(404)}, wantCalls: 1, wantErr: spacex.ErrNotFound},
+		{name: "client errors are not retried", responses: []func() (*http.Response, error){status(400)}, wantCalls: 1, wantErr: spacex.ErrBadStatusCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &scripted{responses: tt.responses}
+			client := newRetryingClient(transport)
+
+			launchpad, err := client.GetLaunchPadById(context.Background(), "pad1")
+
+			assert.Equal(t, tt.wantCalls, transport.calls.Load())
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.name == "other transport errors are not retried":
+				assert.ErrorContains(t, err, "connection refused")
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "active", launchpad.Status)
+			}
+		})
+	}
+}
+
+func TestClientHonoursRetryAfter(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, retryAfter := range []string{"0", now.Add(-time.Minute).Format(http.TimeFormat)} {
+		transport := &scripted{responses: []func() (*http.Response, error){status(429, "Retry-After", retryAfter), status(200)}}
+		// a backoff this long would time the test out, so the retry can
+		// only have waited as Retry-After asked
+		client := newRetryingClient(transport,
+			spacex.WithRetries(3, time.Hour, time.Hour),
+			spacex.WithClock(func() time.Time { return now }),
+		)
+
+		_, err := client.GetLaunchPadById(context.Background(), "pad1")
+
+		require.NoError(t, err, retryAfter)
+		assert.EqualValues(t, 2, transport.calls.Load())
+	}
+}
+
+func TestClientGivesUpOnLongRetryAfter(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, retryAfter := range []string{"3600", now.Add(time.Hour).Format(http.TimeFormat)} {
+		transport := &scripted{responses: []func() (*http.Response, error){status(429, "Retry-After", retryAfter), status(200)}}
+		client := newRetryingClient(transport,
+			spacex.WithCircuitBreaker(1, time.Hour),
+			spacex.WithClock(func() time.Time { return now }),
+		)
+
+		_, err := client.GetLaunchPadById(context.Background(), "pad1")
+
+		assert.ErrorIs(t, err, spacex.ErrBadStatusCode, retryAfter)
+		assert.EqualValues(t, 1, transport.calls.Load(), "waited for Retry-After %s", retryAfter)
+
+		_, err = client.GetLaunchPadById(context.Background(), "pad1")
+
+		assert.ErrorIs(t, err, spacex.ErrCircuitOpen, "the 429 counts towards the breaker")
+	}
+}
+
+func TestClientResendsBodyOnRetry(t *testing.T) {
+	transport := &scripted{responses: []func() (*http.Response, error){
+		status(502),
+		func() (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"docs":[]}`))}, nil
+		},
+	}}
+	client := newRetryingClient(transport)
+
+	_, err := client.GetUpcomingLaunchesLaunchPad(context.Background(), "pad1")
+
+	require.NoError(t, err)
+	require.Len(t, transport.bodies, 2)
+	assert.NotEmpty(t, transport.bodies[0])
+	assert.Equal(t, transport.bodies[0], transport.bodies[1])
+}
+
+func TestClientStopsRetryingWhenCallerGivesUp(t *testing.T) {
+	transport := &scripted{responses: []func() (*http.Response, error){status(503)}}
+	client := newRetryingClient(transport, spacex.WithRetries(5, time.Hour, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetLaunchPadById(ctx, "pad1")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 1, transport.calls.Load())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	transport := &scripted{responses: []func() (*http.Response, error){status(503)}}
+	client := newRetryingClient(transport,
+		spacex.WithRetries(1, 0, 0),
+		spacex.WithCircuitBreaker(2, time.Minute),
+		spacex.WithClock(func() time.Time { return now }),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetLaunchPadById(ctx, "pad1")
+		assert.ErrorIs(t, err, spacex.ErrBadStatusCode)
+	}
+	_, err := client.GetLaunchPadById(ctx, "pad1")
+	assert.ErrorIs(t, err, spacex.ErrCircuitOpen)
+	assert.EqualValues(t, 2, transport.calls.Load(), "an open breaker does not call SpaceX")
+
+	now = now.Add(time.Minute)
+	_, err = client.GetLaunchPadById(ctx, "pad1")
+	assert.ErrorIs(t, err, spacex.ErrBadStatusCode, "a trial request is let through after the cooldown")
+	_, err = client.GetLaunchPadById(ctx, "pad1")
+	assert.ErrorIs(t, err, spacex.ErrCircuitOpen, "a failed trial opens the breaker again")
+	assert.EqualValues(t, 3, transport.calls.Load())
+
+	transport.responses = append(transport.responses, status(200))
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		_, err = client.GetLaunchPadById(ctx, "pad1")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 6, transport.calls.Load(), "a successful trial closes the breaker")
+}
+
+func TestCircuitBreakerIgnoresCancelledCalls(t *testing.T) {
+	transport := &scripted{responses: []func() (*http.Response, error){failure(context.Canceled)}}
+	client := newRetryingClient(transport, spacex.WithCircuitBreaker(1, time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetLaunchPadById(ctx, "pad1")
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.EqualValues(t, 3, transport.calls.Load())
+}
+
+func TestIsUnavailable(t *testing.T) {
+	unavailable := func(responses ...func() (*http.Response, error)) error {
+		client := newRetryingClient(&scripted{responses: responses}, spacex.WithRetries(1, 0, 0))
+		_, err := client.GetLaunchPadById(context.Background(), "pad1")
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "server error", err: unavailable(status(503)), want: true},
+		{name: "rate limited", err: unavailable(status(429)), want: true},
+		{name: "timeout", err: unavailable(failure(fmt.Errorf("Get: %w", context.DeadlineExceeded))), want: true},
+		{name: "connection refused", err: unavailable(failure(&net.OpError{Op: "dial", Err: errors.New("connection refused")})), want: true},
+		{name: "circuit open", err: fmt.Errorf("checking launchpad: %w", spacex.ErrCircuitOpen), want: true},
+		{name: "client error", err: unavailable(status(400))},
+		{name: "unknown launchpad", err: unavailable(status(404))},
+		{name: "caller gave up", err: fmt.Errorf("Get: %w", context.Canceled)},
+		{name: "no error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, spacex.IsUnavailable(tt.err), "%v", tt.err)
+		})
+	}
+}
+
+func TestClientReportsOpenCircuit(t *testing.T) {
+	transport := &scripted{responses: []func() (*http.Response, error){status(503)}}
+	client := newRetryingClient(transport, spacex.WithRetries(1, 0, 0), spacex.WithCircuitBreaker(1, time.Minute))
+
+	assert.False(t, client.CircuitOpen())
+	_, err := client.GetLaunchPadById(context.Background(), "pad1")
+	require.Error(t, err)
+	assert.True(t, client.CircuitOpen())
+}
@@ -0,0 +1,286 @@
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/reconcile"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keeps bookings and their risks in memory, following the rules
+// of the SQL behind the repository.
+type fakeStore struct {
+	bookings []models.Booking
+	risks    map[uuid.UUID]models.BookingRisk
+}
+
+func (f *fakeStore) FutureBookings(_ context.Context, after time.Time) ([]models.Booking, error) {
+	var future []models.Booking
+	for _, b := range f.bookings {
+		if b.Flight.LaunchDate.After(after) {
+			future = append(future, b)
+		}
+	}
+	return future, nil
+}
+
+func (f *fakeStore) FlagAtRisk(_ context.Context, risk *models.BookingRisk) (bool, error) {
+	b := f.booking(risk.Booking.ID)
+	if f.risks == nil {
+		f.risks = map[uuid.UUID]models.BookingRisk{}
+	}
+	stored, ok := f.risks[b.ID]
+	if !ok {
+		stored = models.BookingRisk{PreviousStatus: b.Status, FlaggedAt: risk.FlaggedAt}
+	}
+	stored.Reason, stored.AlternativeDates = risk.Reason, risk.AlternativeDates
+	f.risks[b.ID] = stored
+
+	if b.Status == models.StatusAtRisk {
+		return false, nil
+	}
+	risk.PreviousStatus = b.Status
+	b.Status = models.StatusAtRisk
+	risk.Booking = *b
+	return true, nil
+}
+
+func (f *fakeStore) ClearRisk(_ context.Context, id uuid.UUID) (*models.Booking, error) {
+	risk, ok := f.risks[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(f.risks, id)
+	b := f.booking(id)
+	b.Status = risk.PreviousStatus
+	restored := *b
+	return &restored, nil
+}
+
+func (f *fakeStore) booking(id uuid.UUID) *models.Booking {
+	for i := range f.bookings {
+		if f.bookings[i].ID == id {
+			return &f.bookings[i]
+		}
+	}
+	return nil
+}
+
+type fakeSchedules struct {
+	schedules map[string]*spacex.Schedule
+	errs      map[string]error
+	lookups   map[string]int
+}
+
+func (f *fakeSchedules) LaunchSchedule(_ context.Context, id string) (*spacex.Schedule, error) {
+	if f.lookups == nil {
+		f.lookups = map[string]int{}
+	}
+	f.lookups[id]++
+	if err := f.errs[id]; err != nil {
+		return nil, err
+	}
+	schedule, ok := f.schedules[id]
+	if !ok {
+		return nil, spacex.ErrNotFound
+	}
+	return schedule, nil
+}
+
+type fakeLeases struct {
+	holder string
+}
+
+func (f *fakeLeases) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if f.holder != "" && f.holder != holder {
+		return false, nil
+	}
+	f.holder = holder
+	return true, nil
+}
+
+func (f *fakeLeases) ReleaseLease(_ context.Context, name, holder string) error {
+	if f.holder == holder {
+		f.holder = ""
+	}
+	return nil
+}
+
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []models.BookingEvent
+}
+
+func (f *fakeEvents) PublishEvent(_ context.Context, event *models.BookingEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, *event)
+	return nil
+}
+
+const (
+	pad    = "5e9e4501f509094ba4566f84"
+	other  = "5e9e4502f509092b78566f87"
+	layout = "2006-01-02 15:04"
+)
+
+var now = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func at(s string) time.Time {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func booking(launchpad, launch string, status models.BookingStatus) models.Booking {
+	return models.Booking{
+		ID:     uuid.New(),
+		Status: status,
+		Flight: models.Flight{LaunchpadID: launchpad, LaunchDate: at(launch)},
+	}
+}
+
+func active(id string, launches ...spacex.Launch) *spacex.Schedule {
+	return &spacex.Schedule{LaunchPad: spacex.LaunchPad{Id: id, Status: "active"}, Launches: launches}
+}
+
+func launch(date, precision string) spacex.Launch {
+	return spacex.Launch{LaunchPadID: pad, Date: at(date).Unix(), DatePrecision: precision}
+}
+
+func newReconciler(store *fakeStore, schedules *fakeSchedules, leases *fakeLeases, events *fakeEvents) *reconcile.Reconciler {
+	return reconcile.NewReconciler(store, schedules, leases,
+		reconcile.WithHolder("a"),
+		reconcile.WithClock(func() time.Time { return now }),
+		reconcile.WithEventPublisher(events),
+	)
+}
+
+func TestReconcilerFlagsNewConflicts(t *testing.T) {
+	clash := booking(pad, "2030-07-10 09:00", models.StatusConfirmed)
+	clear := booking(pad, "2030-07-20 09:00", models.StatusActive)
+	store := &fakeStore{bookings: []models.Booking{clash, clear}}
+	schedules := &fakeSchedules{schedules: map[string]*spacex.Schedule{
+		pad: active(pad, launch("2030-07-10 14:00", "day"), launch("2030-07-11 00:00", "day")),
+	}}
+	events := &fakeEvents{}
+
+	result, err := newReconciler(store, schedules, &fakeLeases{}, events).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ReconcileResult{Checked: 2, Flagged: 1}, result)
+	assert.Equal(t, 1, schedules.lookups[pad], "bookings on one launchpad share a schedule lookup")
+
+	risk := store.risks[clash.ID]
+	assert.Equal(t, "SpaceX has scheduled a launch from launchpad "+pad+" on 2030-07-10 (day precision)", risk.Reason)
+	assert.Equal(t, models.StatusConfirmed, risk.PreviousStatus)
+	assert.Equal(t, []time.Time{at("2030-07-08 09:00"), at("2030-07-09 09:00"), at("2030-07-12 09:00")}, risk.AlternativeDates,
+		"the nearest free days either side, keeping the time of day")
+	assert.Equal(t, models.StatusActive, store.booking(clear.ID).Status)
+
+	require.Len(t, events.events, 1)
+	event := events.events[0]
+	assert.Equal(t, models.EventBookingStatusChanged, event.Type)
+	assert.Equal(t, models.StatusAtRisk, event.Booking.Status)
+	assert.Equal(t, models.StatusConfirmed, event.PreviousStatus)
+}
+
+func TestReconcilerHonoursLaunchPrecision(t *testing.T) {
+	b := booking(pad, "2030-06-10 09:00", models.StatusConfirmed)
+	store := &fakeStore{bookings: []models.Booking{b}}
+	schedules := &fakeSchedules{schedules: map[string]*spacex.Schedule{
+		pad: active(pad, launch("2030-06-03 00:00", "month")),
+	}}
+
+	result, err := newReconciler(store, schedules, &fakeLeases{}, &fakeEvents{}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Flagged)
+	risk := store.risks[b.ID]
+	assert.Contains(t, risk.Reason, "(month precision)")
+	assert.Equal(t, []time.Time{at("2030-06-02 09:00")}, risk.AlternativeDates,
+		"only the day before the launch window is both free and ahead")
+}
+
+func TestReconcilerFlagsUnusableLaunchpads(t *testing.T) {
+	retired := booking(pad, "2030-07-10 09:00", models.StatusActive)
+	gone := booking(other, "2030-07-10 09:00", models.StatusActive)
+	store := &fakeStore{bookings: []models.Booking{retired, gone}}
+	schedules := &fakeSchedules{schedules: map[string]*spacex.Schedule{
+		pad: {LaunchPad: spacex.LaunchPad{Id: pad, Status: "retired"}},
+	}}
+
+	result, err := newReconciler(store, schedules, &fakeLeases{}, &fakeEvents{}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Flagged)
+	assert.Equal(t, "launchpad "+pad+" is retired", store.risks[retired.ID].Reason)
+	assert.Empty(t, store.risks[retired.ID].AlternativeDates)
+	assert.Equal(t, "launchpad "+other+" no longer exists", store.risks[gone.ID].Reason)
+}
+
+func TestReconcilerRestoresClearedBookings(t *testing.T) {
+	b := booking(pad, "2030-07-10 09:00", models.StatusConfirmed)
+	store := &fakeStore{bookings: []models.Booking{b}}
+	schedules := &fakeSchedules{schedules: map[string]*spacex.Schedule{
+		pad: active(pad, launch("2030-07-10 14:00", "day")),
+	}}
+	events := &fakeEvents{}
+	reconciler := newReconciler(store, schedules, &fakeLeases{}, events)
+
+	_, err := reconciler.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	result, err := reconciler.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.ReconcileResult{Checked: 1}, result, "a booking already at risk is not flagged again")
+
+	schedules.schedules[pad] = active(pad, launch("2030-07-14 14:00", "day"))
+	result, err = reconciler.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ReconcileResult{Checked: 1, Cleared: 1}, result)
+	assert.Equal(t, models.StatusConfirmed, store.booking(b.ID).Status)
+	assert.Empty(t, store.risks)
+	require.Len(t, events.events, 2)
+	assert.Equal(t, models.StatusConfirmed, events.events[1].Booking.Status)
+	assert.Equal(t, models.StatusAtRisk, events.events[1].PreviousStatus)
+}
+
+func TestReconcilerSkipsLaunchpadsSpaceXCannotReport(t *testing.T) {
+	unknown := booking(pad, "2030-07-10 09:00", models.StatusActive)
+	clash := booking(other, "2030-07-10 09:00", models.StatusActive)
+	store := &fakeStore{bookings: []models.Booking{unknown, clash}}
+	schedules := &fakeSchedules{
+		schedules: map[string]*spacex.Schedule{other: active(other, launch("2030-07-10 00:00", "day"))},
+		errs:      map[string]error{pad: errors.New("connection reset")},
+	}
+
+	result, err := newReconciler(store, schedules, &fakeLeases{}, &fakeEvents{}).RunOnce(context.Background())
+
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Equal(t, models.ReconcileResult{Checked: 1, Flagged: 1}, result)
+	assert.Equal(t, models.StatusActive, store.booking(unknown.ID).Status, "an outage does not put bookings at risk")
+	assert.Equal(t, models.StatusAtRisk, store.booking(clash.ID).Status)
+}
+
+func TestReconcilerOnlyLeaseHolderRuns(t *testing.T) {
+	store := &fakeStore{bookings: []models.Booking{booking(pad, "2030-07-10 09:00", models.StatusActive)}}
+	schedules := &fakeSchedules{}
+
+	result, err := newReconciler(store, schedules, &fakeLeases{holder: "b"}, &fakeEvents{}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, result)
+	assert.Empty(t, schedules.lookups)
+}
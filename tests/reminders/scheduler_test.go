@@ -0,0 +1,281 @@
+package reminders_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/reminders"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReminders keeps bookings and their recorded reminders in memory,
+// following the rules of the SQL behind the repository, which also skips
+// reminders already queued as notifications.
+type fakeReminders struct {
+	mu            sync.Mutex
+	bookings      []models.Booking
+	recorded      map[string]string
+	notifications *fakeNotifications
+	queries       int
+}
+
+func (f *fakeReminders) DueReminders(_ context.Context, lead string, from, to time.Time, limit int) ([]models.Booking, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries++
+	var due []models.Booking
+	for _, b := range f.bookings {
+		launch := b.Flight.LaunchDate
+		_, sent := f.recorded[b.ID.String()+"/"+lead]
+		queued := f.notifications != nil && f.notifications.has(reminders.NotificationKey(b.ID, lead))
+		if launch.After(from) && !launch.After(to) && b.User.Email != "" && !sent && !queued && len(due) < limit {
+			due = append(due, b)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeReminders) RecordReminder(_ context.Context, bookingID uuid.UUID, lead, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.recorded == nil {
+		f.recorded = map[string]string{}
+	}
+	if _, ok := f.recorded[bookingID.String()+"/"+lead]; !ok {
+		f.recorded[bookingID.String()+"/"+lead] = key
+	}
+	return nil
+}
+
+type fakeNotifications struct {
+	mu     sync.Mutex
+	queued []models.Notification
+	err    error
+}
+
+func (f *fakeNotifications) EnqueueNotification(_ context.Context, n *models.Notification) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, q := range f.queued {
+		if q.DedupeKey == n.DedupeKey {
+			return false, nil
+		}
+	}
+	f.queued = append(f.queued, *n)
+	return true, nil
+}
+
+func (f *fakeNotifications) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, q := range f.queued {
+		if q.DedupeKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeNotifications) ClaimNotifications(context.Context, int, time.Duration) ([]models.Notification, error) {
+	return nil, nil
+}
+
+func (f *fakeNotifications) MarkNotificationSent(context.Context, int64) error { return nil }
+
+func (f *fakeNotifications) MarkNotificationFailed(context.Context, int64, string, *time.Time) error {
+	return nil
+}
+
+// fakeLeases is one lease table shared by every scheduler in a test.
+type fakeLeases struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	holder  string
+	expires time.Time
+}
+
+func (f *fakeLeases) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holder != "" && f.holder != holder && !f.expires.Before(f.now()) {
+		return false, nil
+	}
+	f.holder, f.expires = holder, f.now().Add(ttl)
+	return true, nil
+}
+
+func (f *fakeLeases) ReleaseLease(_ context.Context, name, holder string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holder == holder {
+		f.holder = ""
+	}
+	return nil
+}
+
+var now = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func bookingLaunchingIn(d time.Duration) models.Booking {
+	return models.Booking{
+		ID:     uuid.New(),
+		Status: models.StatusConfirmed,
+		User:   models.User{ID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"},
+		Flight: models.Flight{
+			LaunchpadID: "5e9e4501f509094ba4566f84",
+			Destination: models.Destination{ID: uuid.New(), Name: "Mars"},
+			LaunchDate:  now.Add(d),
+		},
+	}
+}
+
+func newScheduler(store *fakeReminders, notifications *fakeNotifications, leases *fakeLeases, holder string) *reminders.Scheduler {
+	store.notifications = notifications
+	return reminders.NewScheduler(store, notifications, leases,
+		reminders.WithHolder(holder),
+		reminders.WithClock(func() time.Time { return now }),
+	)
+}
+
+func TestSchedulerQueuesDueReminders(t *testing.T) {
+	thirty := bookingLaunchingIn(30*24*time.Hour - time.Hour)
+	week := bookingLaunchingIn(7*24*time.Hour - time.Minute)
+	tomorrow := bookingLaunchingIn(20 * time.Hour)
+	store := &fakeReminders{bookings: []models.Booking{
+		thirty, week, tomorrow,
+		bookingLaunchingIn(45 * 24 * time.Hour), // not due yet
+		bookingLaunchingIn(20 * 24 * time.Hour), // booked too late for its 30 day reminder
+		bookingLaunchingIn(time.Hour),           // too close to launch for its 24 hour reminder
+	}}
+	notifications := &fakeNotifications{}
+	leases := &fakeLeases{now: func() time.Time { return now }}
+
+	n, err := newScheduler(store, notifications, leases, "a").RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	require.Len(t, notifications.queued, 3)
+	assert.Equal(t, "reminder:"+thirty.ID.String()+":30d", notifications.queued[0].DedupeKey)
+	assert.Equal(t, "reminder:"+week.ID.String()+":7d", notifications.queued[1].DedupeKey)
+	assert.Equal(t, "reminder:"+tomorrow.ID.String()+":24h", notifications.queued[2].DedupeKey)
+
+	first := notifications.queued[0]
+	assert.Equal(t, models.NotifyLaunchReminder, first.Kind)
+	assert.Equal(t, thirty.ID, first.BookingID)
+	assert.Equal(t, "30 days to launch: your trip to Mars", first.Email.Subject)
+	assert.Contains(t, first.Email.Text, "Book your pre-flight medical assessment")
+	assert.Equal(t, "24 hours to launch: your trip to Mars", notifications.queued[2].Email.Subject)
+	assert.Empty(t, store.recorded, "reminders are recorded once sent, not when queued")
+}
+
+func TestSchedulerRecordsSentReminders(t *testing.T) {
+	week := bookingLaunchingIn(7*24*time.Hour - time.Minute)
+	tomorrow := bookingLaunchingIn(20 * time.Hour)
+	store := &fakeReminders{bookings: []models.Booking{week, tomorrow}}
+	notifications := &fakeNotifications{}
+	leases := &fakeLeases{now: func() time.Time { return now }}
+	scheduler := newScheduler(store, notifications, leases, "a")
+	_, err := scheduler.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, notifications.queued, 2)
+
+	// only the 7 day reminder is sent; the other is still being retried
+	require.NoError(t, scheduler.NotificationSent(context.Background(), notifications.queued[0]))
+	require.NoError(t, scheduler.NotificationSent(context.Background(), models.Notification{
+		DedupeKey: "created:" + tomorrow.ID.String(), BookingID: tomorrow.ID, Kind: models.NotifyBookingCreated,
+	}))
+
+	assert.Equal(t, map[string]string{
+		week.ID.String() + "/7d": "reminder:" + week.ID.String() + ":7d",
+	}, store.recorded)
+}
+
+func TestSchedulerSendsEachReminderOnce(t *testing.T) {
+	store := &fakeReminders{bookings: []models.Booking{bookingLaunchingIn(20 * time.Hour)}}
+	notifications := &fakeNotifications{}
+	leases := &fakeLeases{now: func() time.Time { return now }}
+	scheduler := newScheduler(store, notifications, leases, "a")
+
+	for i := 0; i < 3; i++ {
+		_, err := scheduler.RunOnce(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, notifications.queued, 1)
+}
+
+func TestSchedulerOnlyLeaseHolderRuns(t *testing.T) {
+	store := &fakeReminders{bookings: []models.Booking{bookingLaunchingIn(20 * time.Hour)}}
+	notifications := &fakeNotifications{}
+	clock := now
+	leases := &fakeLeases{now: func() time.Time { return clock }}
+	a := newScheduler(store, notifications, leases, "a")
+	b := newScheduler(store, notifications, leases, "b")
+
+	n, err := a.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	queries := store.queries
+
+	n, err = b.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, queries, store.queries, "an instance without the lease does not look for reminders")
+
+	clock = clock.Add(10 * time.Minute)
+	_, err = b.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "b", leases.holder, "the lease passes on once it expires")
+	assert.Len(t, notifications.queued, 1)
+}
+
+func TestSchedulerDoesNotRecordUnqueuedReminders(t *testing.T) {
+	store := &fakeReminders{bookings: []models.Booking{bookingLaunchingIn(20 * time.Hour)}}
+	notifications := &fakeNotifications{err: errors.New("connection reset")}
+	leases := &fakeLeases{now: func() time.Time { return now }}
+
+	_, err := newScheduler(store, notifications, leases, "a").RunOnce(context.Background())
+
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Empty(t, store.recorded)
+}
+
+func TestSchedulerReleasesLeaseOnShutdown(t *testing.T) {
+	leases := &fakeLeases{now: func() time.Time { return now }}
+	scheduler := newScheduler(&fakeReminders{}, &fakeNotifications{}, leases, "a")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() { done <- scheduler.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		leases.mu.Lock()
+		defer leases.mu.Unlock()
+		return leases.holder == "a"
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, leases.holder)
+}
+
+func TestChecklist(t *testing.T) {
+	booking := bookingLaunchingIn(20 * time.Hour)
+
+	assert.Equal(t, []string{
+		"Bring the passport you booked with",
+		"Arrive at launchpad 5e9e4501f509094ba4566f84 at least three hours before launch",
+	}, reminders.Checklist(booking, reminders.Leads[2]))
+
+	booking.Status = models.StatusActive
+	items := reminders.Checklist(booking, reminders.Leads[0])
+	assert.Equal(t, "Confirm your booking with our team", items[0])
+	assert.Contains(t, items, "Check your passport is in the name Ada Lovelace and valid for six months after launch")
+}
@@ -3,9 +3,11 @@ package repository_test
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"regexp"
@@ -56,12 +58,12 @@ func TestCreateBooking(t *testing.T) {
 
 	// mock createUserTx
 	userQuery := regexp.QuoteMeta(`
-        INSERT INTO users (id, first_name, last_name, gender, birthday)
-        VALUES ($1, $2, $3, $4, $5)
-        ON CONFLICT (id) DO NOTHING
+        INSERT INTO users (id, first_name, last_name, gender, birthday, email)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+        ON CONFLICT (id) DO UPDATE SET email = COALESCE(EXCLUDED.email, users.email)
     `)
 	mockDb.ExpectExec(userQuery).
-		WithArgs(userID, booking.User.FirstName, booking.User.LastName, booking.User.Gender, booking.User.Birthday).
+		WithArgs(userID, booking.User.FirstName, booking.User.LastName, booking.User.Gender, booking.User.Birthday, booking.User.Email).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 	// mock createFlightTx
@@ -85,6 +87,11 @@ func TestCreateBooking(t *testing.T) {
 		WithArgs(bookingID, userID, flightID, booking.Status, pgxmock.AnyArg()).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
+	// the created event goes into the outbox in the same transaction
+	mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+		WithArgs(pgxmock.AnyArg(), bookingID, models.EventBookingCreated, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
 	// commit transaction
 	mockDb.ExpectCommit()
 
@@ -109,20 +116,124 @@ func TestCreateBooking(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// payloadStatus matches an outbox payload carrying a booking of status.
+type payloadStatus models.BookingStatus
+
+func (p payloadStatus) Match(v interface{}) bool {
+	payload, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	var booking models.Booking
+	return json.Unmarshal(payload, &booking) == nil && booking.Status == models.BookingStatus(p)
+}
+
+func TestCreateBookingStoresTheStatusGiven(t *testing.T) {
+	for given, stored := range map[models.BookingStatus]models.BookingStatus{
+		models.StatusPendingVerification: models.StatusPendingVerification,
+		models.StatusConfirmed:           models.StatusConfirmed,
+		"":                               models.StatusConfirmed,
+	} {
+		t.Run(string(stored)+" from "+string(given), func(t *testing.T) {
+			mockDb, repo := setupMockDB(t)
+			defer mockDb.Close()
+			booking := createMockBookings(1)[0]
+			booking.Status = given
+
+			mockDb.ExpectBegin()
+			mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO flights`)).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO bookings`)).
+				WithArgs(booking.ID, booking.User.ID, booking.Flight.ID, stored, pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+				WithArgs(pgxmock.AnyArg(), booking.ID, models.EventBookingCreated, payloadStatus(stored), pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mockDb.ExpectCommit()
+
+			created, err := repo.CreateBooking(context.Background(), &booking)
+
+			require.NoError(t, err)
+			assert.Equal(t, stored, created.Status)
+			require.NoError(t, mockDb.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateBookings(t *testing.T) {
+	newBooking := func(launchpadID string) *models.Booking {
+		return &models.Booking{
+			ID:   uuid.New(),
+			User: models.User{ID: uuid.New(), FirstName: "Jane", LastName: "Doe", Gender: "female"},
+			Flight: models.Flight{
+				ID:          uuid.New(),
+				LaunchpadID: launchpadID,
+				Destination: models.Destination{ID: uuid.New(), Name: "Mars"},
+				LaunchDate:  time.Now().Add(48 * time.Hour),
+			},
+		}
+	}
+	expectInsert := func(mockDb pgxmock.PgxPoolIface, failBooking bool) {
+		any5 := []interface{}{pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()}
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WithArgs(append(any5, pgxmock.AnyArg())...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO flights`)).WithArgs(any5[:4]...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		if failBooking {
+			mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO bookings`)).WithArgs(any5...).WillReturnError(errors.New("insert failed"))
+			return
+		}
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO bookings`)).WithArgs(any5...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).WithArgs(any5...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+
+	t.Run("all bookings in one transaction", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+		bookings := []*models.Booking{newBooking("LP1"), newBooking("LP2")}
+
+		mockDb.ExpectBegin()
+		expectInsert(mockDb, false)
+		expectInsert(mockDb, false)
+		mockDb.ExpectCommit()
+
+		require.NoError(t, repo.CreateBookings(context.Background(), bookings))
+		for _, b := range bookings {
+			assert.Equal(t, models.StatusConfirmed, b.Status)
+			assert.False(t, b.CreatedAt.IsZero())
+		}
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("one failure rolls back the batch", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		mockDb.ExpectBegin()
+		expectInsert(mockDb, false)
+		expectInsert(mockDb, true)
+		mockDb.ExpectRollback()
+
+		err := repo.CreateBookings(context.Background(), []*models.Booking{newBooking("LP1"), newBooking("LP2")})
+		assert.Error(t, err)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
 func TestGetBookingsPaginated(t *testing.T) {
 	t.Run("successful query without cursor", func(t *testing.T) {
 		mockDb, repo := setupMockDB(t)
 		defer mockDb.Close()
 
 		limit := 2
-		bookings := createMockBookings(2)
+		bookings := createMockBookings(3)
 
 		rows := createMockRows(bookings)
 
 		expectedQuery := `
             SELECT 
                 B.id, B.status, B.created_at,
-                U.id, U.first_name, U.last_name, U.gender, U.birthday,
+                U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
                 F.id, F.launchpad_id, F.launch_date,
                 D.id, D.name
             FROM bookings B
@@ -133,15 +244,16 @@ func TestGetBookingsPaginated(t *testing.T) {
             LIMIT $1`
 
 		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
-			WithArgs(limit).
+			WithArgs(limit + 1).
 			WillReturnRows(rows)
 
-		result, cursor, err := repo.GetBookingsPaginated(context.Background(), "", limit)
+		page, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "", "", limit)
 
 		require.NoError(t, err)
-		require.Len(t, result, 2)
-		assert.NotEmpty(t, cursor)
-		verifyBookings(t, bookings, result)
+		require.Len(t, page.Bookings, 2)
+		assert.NotEmpty(t, page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
+		verifyBookings(t, bookings[:2], page.Bookings)
 	})
 
 	t.Run("successful query with cursor", func(t *testing.T) {
@@ -159,7 +271,7 @@ func TestGetBookingsPaginated(t *testing.T) {
 		expectedQuery := `
             SELECT 
                 B.id, B.status, B.created_at,
-                U.id, U.first_name, U.last_name, U.gender, U.birthday,
+                U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
                 F.id, F.launchpad_id, F.launch_date,
                 D.id, D.name
             FROM bookings B
@@ -171,15 +283,164 @@ func TestGetBookingsPaginated(t *testing.T) {
             LIMIT $3`
 
 		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
-			WithArgs(pgxmock.AnyArg(), cursorID, limit).
+			WithArgs(pgxmock.AnyArg(), cursorID, limit+1).
 			WillReturnRows(rows)
 
-		result, nextCursor, err := repo.GetBookingsPaginated(context.Background(), cursor, limit)
+		page, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, cursor, "", limit)
+
+		require.NoError(t, err)
+		require.Len(t, page.Bookings, 2)
+		// the page exactly fills limit but nothing follows it
+		assert.Empty(t, page.NextCursor)
+		assert.NotEmpty(t, page.PrevCursor)
+		verifyBookings(t, bookings, page.Bookings)
+	})
+
+	t.Run("filtered by user", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		limit := 2
+		userID := uuid.New()
+		bookings := createMockBookings(1)
+
+		expectedQuery := `
+            SELECT 
+                B.id, B.status, B.created_at,
+                U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
+                F.id, F.launchpad_id, F.launch_date,
+                D.id, D.name
+            FROM bookings B
+            JOIN users U ON U.id = B.user_id
+            JOIN flights F ON F.id = B.flight_id
+            JOIN destinations D ON D.id = F.destination_id
+            WHERE B.user_id = $1
+            ORDER BY B.created_at, B.id
+            LIMIT $2`
+
+		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+			WithArgs(userID.String(), limit+1).
+			WillReturnRows(createMockRows(bookings))
+
+		filter := models.BookingFilter{UserID: userID.String()}
+		page, err := repo.GetBookingsPaginated(context.Background(), filter, "", "", limit)
 
 		require.NoError(t, err)
-		require.Len(t, result, 2)
-		assert.NotEmpty(t, nextCursor)
-		verifyBookings(t, bookings, result)
+		verifyBookings(t, bookings, page.Bookings)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("filtered and sorted by launch date descending", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		limit := 1
+		bookings := createMockBookings(2)
+		destinationID := uuid.New().String()
+		from := time.Date(2030, 11, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		cursorID := uuid.New()
+		cursor := utils.EncodeCursor(utils.Cursor{
+			SortKey:   "launch_date",
+			Direction: "desc",
+			Value:     to,
+			ID:        cursorID,
+		})
+
+		expectedQuery := `
+            SELECT 
+                B.id, B.status, B.created_at,
+                U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
+                F.id, F.launchpad_id, F.launch_date,
+                D.id, D.name
+            FROM bookings B
+            JOIN users U ON U.id = B.user_id
+            JOIN flights F ON F.id = B.flight_id
+            JOIN destinations D ON D.id = F.destination_id
+            WHERE (F.launch_date, B.id) < ($1, $2) AND B.status = $3 AND F.destination_id = $4
+            AND LOWER(U.last_name) = LOWER($5) AND F.launch_date >= $6 AND F.launch_date < $7
+            ORDER BY F.launch_date DESC, B.id DESC
+            LIMIT $8`
+
+		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+			WithArgs(pgxmock.AnyArg(), cursorID, models.StatusConfirmed, destinationID, "Doe", from, to, limit+1).
+			WillReturnRows(createMockRows(bookings))
+
+		filter := models.BookingFilter{
+			Status:        models.StatusConfirmed,
+			DestinationID: destinationID,
+			LastName:      "Doe",
+			LaunchFrom:    from,
+			LaunchTo:      to,
+			Sort:          models.BookingSort{Field: models.SortByLaunchDate, Direction: models.SortDesc},
+		}
+		page, err := repo.GetBookingsPaginated(context.Background(), filter, cursor, "", limit)
+
+		require.NoError(t, err)
+		verifyBookings(t, bookings[:1], page.Bookings)
+		next, err := utils.DecodeCursor(page.NextCursor)
+		require.NoError(t, err)
+		assert.Equal(t, "launch_date", next.SortKey)
+		assert.Equal(t, "desc", next.Direction)
+		assert.True(t, bookings[0].Flight.LaunchDate.Equal(next.Value))
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("cursor from a different sort order", func(t *testing.T) {
+		_, repo := setupMockDB(t)
+
+		cursor := utils.EncodeCursor(utils.Cursor{SortKey: "created_at", Direction: "asc", Value: time.Now(), ID: uuid.New()})
+		filter := models.BookingFilter{Sort: models.BookingSort{Field: models.SortByLaunchDate}}
+
+		_, err := repo.GetBookingsPaginated(context.Background(), filter, cursor, "", 10)
+		assert.Error(t, err)
+	})
+
+	t.Run("paging backward from a before cursor", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		limit := 2
+		// rows arrive newest first when walking backwards
+		bookings := createMockBookings(3)
+		reversed := []models.Booking{bookings[2], bookings[1], bookings[0]}
+		cursorID := uuid.New()
+		cursor := utils.EncodeCursor(utils.Cursor{SortKey: "created_at", Direction: "asc", Value: time.Now().Add(time.Hour * 10), ID: cursorID})
+
+		expectedQuery := `
+            SELECT 
+                B.id, B.status, B.created_at,
+                U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
+                F.id, F.launchpad_id, F.launch_date,
+                D.id, D.name
+            FROM bookings B
+            JOIN users U ON U.id = B.user_id
+            JOIN flights F ON F.id = B.flight_id
+            JOIN destinations D ON D.id = F.destination_id
+            WHERE (B.created_at, B.id) < ($1, $2)
+            ORDER BY B.created_at DESC, B.id DESC
+            LIMIT $3`
+
+		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+			WithArgs(pgxmock.AnyArg(), cursorID, limit+1).
+			WillReturnRows(createMockRows(reversed))
+
+		page, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "", cursor, limit)
+
+		require.NoError(t, err)
+		verifyBookings(t, []models.Booking{bookings[1], bookings[2]}, page.Bookings)
+		assert.NotEmpty(t, page.NextCursor)
+		prev, err := utils.DecodeCursor(page.PrevCursor)
+		require.NoError(t, err)
+		assert.Equal(t, bookings[1].ID, prev.ID)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("after and before together", func(t *testing.T) {
+		_, repo := setupMockDB(t)
+
+		_, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "a", "b", 10)
+		assert.Error(t, err)
 	})
 
 	t.Run("empty result", func(t *testing.T) {
@@ -189,14 +450,14 @@ func TestGetBookingsPaginated(t *testing.T) {
 		limit := 2
 		rows := pgxmock.NewRows([]string{
 			"id", "status", "created_at",
-			"user_id", "first_name", "last_name", "gender", "birthday",
+			"user_id", "first_name", "last_name", "gender", "birthday", "email",
 			"flight_id", "launchpad_id", "launch_date",
 			"destination_id", "destination_name",
 		})
 		expectedQuery := `
 			SELECT 
 				B.id, B.status, B.created_at,
-				U.id, U.first_name, U.last_name, U.gender, U.birthday,
+				U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
 				F.id, F.launchpad_id, F.launch_date,
 				D.id, D.name
 			FROM bookings B
@@ -207,14 +468,15 @@ func TestGetBookingsPaginated(t *testing.T) {
 			LIMIT $1`
 
 		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
-			WithArgs(limit).
+			WithArgs(limit + 1).
 			WillReturnRows(rows)
 
-		result, cursor, err := repo.GetBookingsPaginated(context.Background(), "", limit)
+		page, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "", "", limit)
 
 		require.NoError(t, err)
-		assert.Empty(t, result)
-		assert.Empty(t, cursor)
+		assert.Empty(t, page.Bookings)
+		assert.Empty(t, page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
 	})
 
 	t.Run("invalid cursor format", func(t *testing.T) {
@@ -222,7 +484,7 @@ func TestGetBookingsPaginated(t *testing.T) {
 
 		invalidCursor := base64.StdEncoding.EncodeToString([]byte("invalid"))
 
-		_, _, err := repo.GetBookingsPaginated(context.Background(), invalidCursor, 10)
+		_, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, invalidCursor, "", 10)
 		assert.Error(t, err)
 	})
 
@@ -231,10 +493,10 @@ func TestGetBookingsPaginated(t *testing.T) {
 		defer mockDb.Close()
 
 		mockDb.ExpectQuery(formatQueryForRegex(`SELECT.*FROM bookings.*`)).
-			WithArgs(10).
+			WithArgs(11).
 			WillReturnError(fmt.Errorf("database error"))
 
-		_, _, err := repo.GetBookingsPaginated(context.Background(), "", 10)
+		_, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "", "", 10)
 		assert.Error(t, err)
 	})
 
@@ -245,14 +507,94 @@ func TestGetBookingsPaginated(t *testing.T) {
 		rows := pgxmock.NewRows([]string{"id"}).AddRow("invalid") // This will cause a scan error
 
 		mockDb.ExpectQuery(formatQueryForRegex(`SELECT.*FROM bookings.*`)).
-			WithArgs(10).
+			WithArgs(11).
 			WillReturnRows(rows)
 
-		_, _, err := repo.GetBookingsPaginated(context.Background(), "", 10)
+		_, err := repo.GetBookingsPaginated(context.Background(), models.BookingFilter{}, "", "", 10)
 		assert.Error(t, err)
 	})
 }
 
+func TestCountBookings(t *testing.T) {
+	mockDb, repo := setupMockDB(t)
+	defer mockDb.Close()
+
+	expectedQuery := `
+        SELECT COUNT(*)
+        FROM bookings B
+        JOIN users U ON U.id = B.user_id
+        JOIN flights F ON F.id = B.flight_id
+        WHERE B.status = $1`
+
+	mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+		WithArgs(models.StatusCancelled).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := repo.CountBookings(context.Background(), models.BookingFilter{Status: models.StatusCancelled})
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestStreamBookings(t *testing.T) {
+	expectedQuery := `
+        SELECT 
+            B.id, B.status, B.created_at,
+            U.id, U.first_name, U.last_name, U.gender, U.birthday, COALESCE(U.email, ''),
+            F.id, F.launchpad_id, F.launch_date,
+            D.id, D.name
+        FROM bookings B
+        JOIN users U ON U.id = B.user_id
+        JOIN flights F ON F.id = B.flight_id
+        JOIN destinations D ON D.id = F.destination_id
+        WHERE F.launchpad_id = $1
+        ORDER BY F.launch_date DESC, B.id DESC`
+	filter := models.BookingFilter{
+		LaunchpadID: "pad-1",
+		Sort:        models.BookingSort{Field: models.SortByLaunchDate, Direction: models.SortDesc},
+	}
+
+	t.Run("streams every matching row", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		bookings := createMockBookings(3)
+		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+			WithArgs("pad-1").
+			WillReturnRows(createMockRows(bookings))
+
+		var streamed []models.Booking
+		err := repo.StreamBookings(context.Background(), filter, func(b models.Booking) error {
+			streamed = append(streamed, b)
+			return nil
+		})
+
+		require.NoError(t, err)
+		verifyBookings(t, bookings, streamed)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("stops when the callback fails", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		mockDb.ExpectQuery(formatQueryForRegex(expectedQuery)).
+			WithArgs("pad-1").
+			WillReturnRows(createMockRows(createMockBookings(3)))
+
+		calls := 0
+		stop := errors.New("client went away")
+		err := repo.StreamBookings(context.Background(), filter, func(b models.Booking) error {
+			calls++
+			return stop
+		})
+
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
 func TestGetBookingByID(t *testing.T) {
 	t.Run("successful retrieval", func(t *testing.T) {
 		mockDb, repo := setupMockDB(t)
@@ -398,7 +740,33 @@ func TestGetDestinationById(t *testing.T) {
 	})
 }
 
+func TestGetDestinationsByIDs(t *testing.T) {
+	mockDb, repo := setupMockDB(t)
+	defer mockDb.Close()
+
+	mars, moon := uuid.New(), uuid.New()
+	ids := []string{mars.String(), moon.String(), uuid.NewString()}
+	mockDb.ExpectQuery(`SELECT id, name FROM destinations WHERE id = ANY\(\$1::uuid\[\]\)`).
+		WithArgs(ids).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).
+			AddRow(mars, "Mars").
+			AddRow(moon, "Moon"))
+
+	result, err := repo.GetDestinationsByIDs(context.Background(), ids)
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.Destination{{ID: mars, Name: "Mars"}, {ID: moon, Name: "Moon"}}, result)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
 func TestDeleteBooking(t *testing.T) {
+	selectQuery := `SELECT .+ FROM bookings B .+ WHERE B.id = \$1 FOR UPDATE OF B`
+	bookingRows := func(id string) *pgxmock.Rows {
+		booking := createMockBookings(1)[0]
+		booking.ID = uuid.MustParse(id)
+		return createMockRows([]models.Booking{booking})
+	}
+
 	t.Run("successful deletion", func(t *testing.T) {
 		mockDb, repo := setupMockDB(t)
 		defer mockDb.Close()
@@ -406,9 +774,15 @@ func TestDeleteBooking(t *testing.T) {
 		bookingID := uuid.New().String()
 
 		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnRows(bookingRows(bookingID))
 		mockDb.ExpectExec("DELETE FROM bookings WHERE id = \\$1").
 			WithArgs(bookingID).
 			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), uuid.MustParse(bookingID), models.EventBookingCancelled, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 		mockDb.ExpectCommit()
 
 		err := repo.DeleteBooking(context.Background(), bookingID)
@@ -424,9 +798,9 @@ func TestDeleteBooking(t *testing.T) {
 		bookingID := uuid.New().String()
 
 		mockDb.ExpectBegin()
-		mockDb.ExpectExec("DELETE FROM bookings WHERE id = \\$1").
+		mockDb.ExpectQuery(selectQuery).
 			WithArgs(bookingID).
-			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+			WillReturnError(pgx.ErrNoRows)
 		mockDb.ExpectRollback()
 
 		err := repo.DeleteBooking(context.Background(), bookingID)
@@ -443,6 +817,9 @@ func TestDeleteBooking(t *testing.T) {
 		bookingID := uuid.New().String()
 
 		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnRows(bookingRows(bookingID))
 		mockDb.ExpectExec("DELETE FROM bookings WHERE id = \\$1").
 			WithArgs(bookingID).
 			WillReturnError(errors.New("database error"))
@@ -454,6 +831,113 @@ func TestDeleteBooking(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to delete booking")
 		assert.NoError(t, mockDb.ExpectationsWereMet())
 	})
+
+	t.Run("outbox failure rolls back the delete", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		bookingID := uuid.New().String()
+
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnRows(bookingRows(bookingID))
+		mockDb.ExpectExec("DELETE FROM bookings WHERE id = \\$1").
+			WithArgs(bookingID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("outbox unavailable"))
+		mockDb.ExpectRollback()
+
+		err := repo.DeleteBooking(context.Background(), bookingID)
+
+		assert.ErrorContains(t, err, "failed to write outbox event")
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestRescheduleBooking(t *testing.T) {
+	selectQuery := `SELECT .+ FROM bookings B .+ WHERE B.id = \$1 FOR UPDATE OF B`
+	booking := createMockBookings(1)[0]
+	bookingID := booking.ID.String()
+	launchDate := booking.Flight.LaunchDate.AddDate(0, 0, 14)
+
+	t.Run("moves the flight and records the event", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(regexp.QuoteMeta(`UPDATE flights SET launch_date = $2 WHERE id = $1`)).
+			WithArgs(booking.Flight.ID, launchDate).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`UPDATE bookings SET status = $2 WHERE id = $1`)).
+			WithArgs(bookingID, models.StatusPendingVerification).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`DELETE FROM booking_risks WHERE booking_id = $1`)).
+			WithArgs(bookingID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), booking.ID, models.EventBookingRescheduled, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		result, err := repo.RescheduleBooking(context.Background(), bookingID, launchDate, models.StatusPendingVerification)
+
+		require.NoError(t, err)
+		assert.Equal(t, launchDate, result.Flight.LaunchDate)
+		assert.Equal(t, models.StatusPendingVerification, result.Status)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("booking not found", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnError(pgx.ErrNoRows)
+		mockDb.ExpectRollback()
+
+		result, err := repo.RescheduleBooking(context.Background(), bookingID, launchDate, models.StatusConfirmed)
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrBookingNotFound, err)
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("outbox failure rolls back the move", func(t *testing.T) {
+		mockDb, repo := setupMockDB(t)
+		defer mockDb.Close()
+
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectQuery).
+			WithArgs(bookingID).
+			WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(regexp.QuoteMeta(`UPDATE flights SET launch_date = $2 WHERE id = $1`)).
+			WithArgs(booking.Flight.ID, launchDate).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`UPDATE bookings SET status = $2 WHERE id = $1`)).
+			WithArgs(bookingID, models.StatusConfirmed).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`DELETE FROM booking_risks WHERE booking_id = $1`)).
+			WithArgs(bookingID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("outbox unavailable"))
+		mockDb.ExpectRollback()
+
+		result, err := repo.RescheduleBooking(context.Background(), bookingID, launchDate, models.StatusConfirmed)
+
+		assert.Nil(t, result)
+		assert.ErrorContains(t, err, "failed to write outbox event")
+		assert.NoError(t, mockDb.ExpectationsWereMet())
+	})
 }
 
 func TestBookingRepository_GetFlights(t *testing.T) {
@@ -947,7 +1431,7 @@ func createMockBookings(count int) []models.Booking {
 func createMockRows(bookings []models.Booking) *pgxmock.Rows {
 	rows := pgxmock.NewRows([]string{
 		"id", "status", "created_at",
-		"user_id", "first_name", "last_name", "gender", "birthday",
+		"user_id", "first_name", "last_name", "gender", "birthday", "email",
 		"flight_id", "launchpad_id", "launch_date",
 		"destination_id", "destination_name",
 	})
@@ -955,7 +1439,7 @@ func createMockRows(bookings []models.Booking) *pgxmock.Rows {
 	for _, b := range bookings {
 		rows.AddRow(
 			b.ID, b.Status, b.CreatedAt,
-			b.User.ID, b.User.FirstName, b.User.LastName, b.User.Gender, b.User.Birthday,
+			b.User.ID, b.User.FirstName, b.User.LastName, b.User.Gender, b.User.Birthday, b.User.Email,
 			b.Flight.ID, b.Flight.LaunchpadID, b.Flight.LaunchDate,
 			b.Flight.Destination.ID, b.Flight.Destination.Name,
 		)
@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishEvent(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewEventRepository(mockDb)
+
+	event := &models.BookingEvent{
+		Type:       models.EventBookingCreated,
+		Booking:    models.Booking{ID: uuid.New(), Status: models.StatusActive},
+		OccurredAt: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	mockDb.ExpectQuery(`INSERT INTO booking_events \(type, booking_id, payload, occurred_at\)`).
+		WithArgs(models.EventBookingCreated, event.Booking.ID, pgxmock.AnyArg(), event.OccurredAt).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(42)))
+
+	require.NoError(t, repo.PublishEvent(context.Background(), event))
+
+	assert.Equal(t, int64(42), event.ID)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestEventsSince(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewEventRepository(mockDb)
+
+	bookingID := uuid.New()
+	payload, err := json.Marshal(models.BookingEvent{
+		Type:           models.EventBookingCancelled,
+		Booking:        models.Booking{ID: bookingID, Status: models.StatusCancelled},
+		PreviousStatus: models.StatusActive,
+	})
+	require.NoError(t, err)
+	occurred := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockDb.ExpectQuery(`SELECT id, payload, occurred_at FROM booking_events\s+WHERE id > \$1 ORDER BY id LIMIT \$2`).
+		WithArgs(int64(10), 100).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "payload", "occurred_at"}).AddRow(int64(11), payload, occurred))
+
+	events, err := repo.EventsSince(context.Background(), 10, 100)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(11), events[0].ID)
+	assert.Equal(t, occurred, events[0].OccurredAt)
+	assert.Equal(t, bookingID, events[0].Booking.ID)
+	assert.Equal(t, models.StatusActive, events[0].PreviousStatus)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLaunchpadRepo(t *testing.T) (pgxmock.PgxPoolIface, *repository.LaunchpadRepository) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockDb.Close)
+	return mockDb, repository.NewLaunchpadRepository(mockDb)
+}
+
+var closureColumns = []string{"launchpad_id", "reason", "closed_at"}
+
+func TestCloseLaunchpad(t *testing.T) {
+	mockDb, repo := setupLaunchpadRepo(t)
+	firstClosed := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := firstClosed.Add(time.Hour)
+
+	mockDb.ExpectQuery(`INSERT INTO launchpad_closures .+ON CONFLICT \(launchpad_id\) DO UPDATE SET reason = EXCLUDED.reason`).
+		WithArgs("pad1", "storm damage", now).
+		WillReturnRows(pgxmock.NewRows(closureColumns).AddRow("pad1", "storm damage", firstClosed))
+
+	closure, err := repo.CloseLaunchpad(context.Background(), &models.LaunchpadClosure{LaunchpadID: "pad1", Reason: "storm damage", ClosedAt: now})
+
+	require.NoError(t, err)
+	assert.Equal(t, firstClosed, closure.ClosedAt, "closing again keeps the first closing time")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestReopenLaunchpad(t *testing.T) {
+	t.Run("reopened", func(t *testing.T) {
+		mockDb, repo := setupLaunchpadRepo(t)
+		mockDb.ExpectExec(`DELETE FROM launchpad_closures WHERE launchpad_id = \$1`).
+			WithArgs("pad1").
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		require.NoError(t, repo.ReopenLaunchpad(context.Background(), "pad1"))
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("not closed", func(t *testing.T) {
+		mockDb, repo := setupLaunchpadRepo(t)
+		mockDb.ExpectExec(`DELETE FROM launchpad_closures`).
+			WithArgs("pad1").
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		assert.ErrorIs(t, repo.ReopenLaunchpad(context.Background(), "pad1"), models.ErrLaunchpadNotClosed)
+	})
+}
+
+func TestListLaunchpadClosures(t *testing.T) {
+	mockDb, repo := setupLaunchpadRepo(t)
+	closedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDb.ExpectQuery(`SELECT launchpad_id, reason, closed_at FROM launchpad_closures ORDER BY closed_at`).
+		WillReturnRows(pgxmock.NewRows(closureColumns).
+			AddRow("pad1", "", closedAt).
+			AddRow("pad2", "storm damage", closedAt.Add(time.Hour)))
+
+	closures, err := repo.ListLaunchpadClosures(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.LaunchpadClosure{
+		{LaunchpadID: "pad1", ClosedAt: closedAt},
+		{LaunchpadID: "pad2", Reason: "storm damage", ClosedAt: closedAt.Add(time.Hour)},
+	}, closures)
+}
+
+func TestLaunchpadClosure(t *testing.T) {
+	t.Run("closed", func(t *testing.T) {
+		mockDb, repo := setupLaunchpadRepo(t)
+		mockDb.ExpectQuery(`FROM launchpad_closures WHERE launchpad_id = \$1`).
+			WithArgs("pad1").
+			WillReturnRows(pgxmock.NewRows(closureColumns).AddRow("pad1", "storm damage", time.Now()))
+
+		closure, err := repo.LaunchpadClosure(context.Background(), "pad1")
+
+		require.NoError(t, err)
+		require.NotNil(t, closure)
+		assert.Equal(t, "storm damage", closure.Reason)
+	})
+
+	t.Run("open", func(t *testing.T) {
+		mockDb, repo := setupLaunchpadRepo(t)
+		mockDb.ExpectQuery(`FROM launchpad_closures`).WithArgs("pad1").WillReturnError(pgx.ErrNoRows)
+
+		closure, err := repo.LaunchpadClosure(context.Background(), "pad1")
+
+		require.NoError(t, err)
+		assert.Nil(t, closure)
+	})
+}
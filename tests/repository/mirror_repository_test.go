@@ -0,0 +1,156 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceMirror(t *testing.T) {
+	syncedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	launchDate := time.Date(2030, 3, 10, 15, 0, 0, 0, time.UTC)
+	dayStart := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	upsertLaunchpad := `INSERT INTO spacex_launchpads .+ON CONFLICT \(id\) DO UPDATE`
+	insertLaunch := `INSERT INTO spacex_launches`
+
+	t.Run("replaces launchpads and launches", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewMirrorRepository(mockDb)
+
+		schedules := []spacex.Schedule{
+			{
+				LaunchPad: spacex.LaunchPad{Id: "pad1", Status: "active"},
+				Launches:  []spacex.Launch{{LaunchPadID: "pad1", Date: launchDate.Unix(), DatePrecision: "day"}},
+			},
+			{LaunchPad: spacex.LaunchPad{Id: "pad2", Status: "retired"}},
+		}
+		mockDb.ExpectBegin()
+		mockDb.ExpectExec(`DELETE FROM spacex_launchpads WHERE NOT \(id = ANY\(\$1\)\)`).
+			WithArgs([]string{"pad1", "pad2"}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockDb.ExpectExec(`DELETE FROM spacex_launches`).
+			WillReturnResult(pgxmock.NewResult("DELETE", 4))
+		mockDb.ExpectExec(upsertLaunchpad).
+			WithArgs("pad1", "active", syncedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectExec(insertLaunch).
+			WithArgs("pad1", launchDate.Unix(), "day", dayStart, dayStart.AddDate(0, 0, 1).Add(-time.Second)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectExec(upsertLaunchpad).
+			WithArgs("pad2", "retired", syncedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		err = repo.ReplaceMirror(context.Background(), schedules, syncedAt)
+
+		require.NoError(t, err)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the previous copy on an unreadable launch", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewMirrorRepository(mockDb)
+
+		schedules := []spacex.Schedule{{
+			LaunchPad: spacex.LaunchPad{Id: "pad1", Status: "active"},
+			Launches:  []spacex.Launch{{LaunchPadID: "pad1", Date: launchDate.Unix(), DatePrecision: "week"}},
+		}}
+		mockDb.ExpectBegin()
+		mockDb.ExpectExec(`DELETE FROM spacex_launchpads`).
+			WithArgs([]string{"pad1"}).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDb.ExpectExec(`DELETE FROM spacex_launches`).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDb.ExpectExec(upsertLaunchpad).
+			WithArgs("pad1", "active", syncedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectRollback()
+
+		err = repo.ReplaceMirror(context.Background(), schedules, syncedAt)
+
+		assert.ErrorContains(t, err, "invalid date precision: week")
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestLaunchpadDay(t *testing.T) {
+	day := time.Date(2030, 3, 10, 0, 0, 0, 0, time.UTC)
+	syncedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := `SELECT P.status, P.synced_at, EXISTS \(.+FROM spacex_launches L.+FROM spacex_launchpads P\s+WHERE P.id = \$1`
+
+	t.Run("reports a mirrored launchpad", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewMirrorRepository(mockDb)
+
+		mockDb.ExpectQuery(query).
+			WithArgs("pad1", day).
+			WillReturnRows(pgxmock.NewRows([]string{"status", "synced_at", "exists"}).AddRow("active", syncedAt, true))
+
+		result, err := repo.LaunchpadDay(context.Background(), "pad1", day)
+
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, "active", result.Status)
+		assert.Equal(t, syncedAt, result.SyncedAt)
+		assert.True(t, result.Taken)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("returns nil for an unknown launchpad", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewMirrorRepository(mockDb)
+
+		mockDb.ExpectQuery(query).WithArgs("nope", day).WillReturnError(pgx.ErrNoRows)
+
+		result, err := repo.LaunchpadDay(context.Background(), "nope", day)
+
+		require.NoError(t, err)
+		assert.Nil(t, result)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestLastMirrorSync(t *testing.T) {
+	syncedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value *time.Time
+		want  time.Time
+	}{
+		{name: "synced", value: &syncedAt, want: syncedAt},
+		{name: "never synced", value: nil, want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDb, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockDb.Close()
+			repo := repository.NewMirrorRepository(mockDb)
+
+			mockDb.ExpectQuery(`SELECT MAX\(synced_at\) FROM spacex_launchpads`).
+				WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(tt.value))
+
+			got, err := repo.LastMirrorSync(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			require.NoError(t, mockDb.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueueNotification(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewNotificationRepository(mockDb)
+
+	n := &models.Notification{
+		DedupeKey: "evt:booking_created",
+		BookingID: uuid.New(),
+		Kind:      models.NotifyBookingCreated,
+		Email:     models.Email{To: "ada@example.com", Subject: "Booked", Text: "text", HTML: "<p>html</p>"},
+	}
+	query := `INSERT INTO notifications .+ON CONFLICT \(dedupe_key\) DO NOTHING`
+	args := []interface{}{n.DedupeKey, n.BookingID, "booking_created", "ada@example.com", "Booked", "text", "<p>html</p>"}
+	mockDb.ExpectExec(query).WithArgs(args...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDb.ExpectExec(query).WithArgs(args...).WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	queued, err := repo.EnqueueNotification(context.Background(), n)
+	require.NoError(t, err)
+	assert.True(t, queued)
+
+	queued, err = repo.EnqueueNotification(context.Background(), n)
+	require.NoError(t, err)
+	assert.False(t, queued, "the same dedupe key is only queued once")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestClaimNotifications(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewNotificationRepository(mockDb)
+
+	columns := []string{"id", "dedupe_key", "booking_id", "kind", "recipient", "subject", "text_body", "html_body", "attempts"}
+	mockDb.ExpectQuery(`UPDATE notifications SET locked_until = now\(\) \+ \$2::interval\s+WHERE id IN \(.+FOR UPDATE SKIP LOCKED\s+\)\s+RETURNING`).
+		WithArgs(20, 5*time.Minute).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(9), "b", uuid.New(), models.NotifyBookingCancelled, "b@example.com", "Cancelled", "t", "", 1).
+			AddRow(int64(4), "a", uuid.New(), models.NotifyBookingCreated, "a@example.com", "Booked", "t", "<p>h</p>", 0))
+
+	notifications, err := repo.ClaimNotifications(context.Background(), 20, 5*time.Minute)
+
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, int64(4), notifications[0].ID, "claimed notifications come back in id order")
+	assert.Equal(t, "a@example.com", notifications[0].Email.To)
+	assert.Equal(t, "<p>h</p>", notifications[0].Email.HTML)
+	assert.Equal(t, 1, notifications[1].Attempts)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestClaimNotificationsError(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewNotificationRepository(mockDb)
+
+	mockDb.ExpectQuery(`UPDATE notifications`).WithArgs(20, time.Minute).WillReturnError(errors.New("connection reset"))
+
+	_, err = repo.ClaimNotifications(context.Background(), 20, time.Minute)
+
+	assert.ErrorContains(t, err, "failed to claim notifications")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestMarkNotification(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewNotificationRepository(mockDb)
+
+	next := time.Date(2030, 1, 1, 12, 5, 0, 0, time.UTC)
+	mockDb.ExpectExec(`UPDATE notifications\s+SET status = 'sent'`).WithArgs(int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDb.ExpectExec(`UPDATE notifications\s+SET attempts = attempts \+ 1`).WithArgs(int64(2), "421 busy", &next).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDb.ExpectExec(`UPDATE notifications\s+SET attempts = attempts \+ 1`).WithArgs(int64(3), "550 no such user", (*time.Time)(nil)).
+		WillReturnError(errors.New("connection reset"))
+
+	require.NoError(t, repo.MarkNotificationSent(context.Background(), 1))
+	require.NoError(t, repo.MarkNotificationFailed(context.Background(), 2, "421 busy", &next))
+	err = repo.MarkNotificationFailed(context.Background(), 3, "550 no such user", nil)
+
+	assert.ErrorContains(t, err, "failed to record notification failure")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimOutbox(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewOutboxRepository(mockDb)
+
+	occurred := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "event_id", "aggregate_id", "type", "payload", "occurred_at", "attempts"}
+	mockDb.ExpectQuery(`UPDATE outbox SET locked_until = now\(\) \+ \$2::interval\s+WHERE id IN \(.+FOR UPDATE SKIP LOCKED\s+\)\s+RETURNING`).
+		WithArgs(10, 30*time.Second).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(7), uuid.New(), uuid.New(), models.EventBookingCancelled, []byte(`{}`), occurred, 2).
+			AddRow(int64(3), uuid.New(), uuid.New(), models.EventBookingCreated, []byte(`{}`), occurred, 0))
+
+	events, err := repo.ClaimOutbox(context.Background(), 10, 30*time.Second)
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(3), events[0].ID, "claimed events come back in id order")
+	assert.Equal(t, int64(7), events[1].ID)
+	assert.Equal(t, 2, events[1].Attempts)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestClaimOutboxError(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewOutboxRepository(mockDb)
+
+	mockDb.ExpectQuery(`UPDATE outbox`).WithArgs(10, time.Minute).WillReturnError(errors.New("connection reset"))
+
+	_, err = repo.ClaimOutbox(context.Background(), 10, time.Minute)
+
+	assert.ErrorContains(t, err, "failed to claim outbox events")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestMarkOutbox(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewOutboxRepository(mockDb)
+
+	next := time.Date(2030, 1, 1, 12, 0, 30, 0, time.UTC)
+	mockDb.ExpectExec(`UPDATE outbox SET published_at = now\(\)`).
+		WithArgs(int64(3)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDb.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, next_attempt_at = \$2, last_error = \$3`).
+		WithArgs(int64(7), next, "webhook: 503").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.MarkPublished(context.Background(), 3))
+	require.NoError(t, repo.MarkFailed(context.Background(), 7, next, "webhook: 503"))
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
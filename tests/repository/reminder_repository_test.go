@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDueReminders(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewReminderRepository(mockDb)
+
+	bookings := createMockBookings(2)
+	for i := range bookings {
+		bookings[i].User.Email = "user@example.com"
+	}
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+	mockDb.ExpectQuery(`FROM bookings B.+WHERE F.launch_date > \$1 AND F.launch_date <= \$2\s+AND U.email IS NOT NULL\s+AND NOT EXISTS \(\s+SELECT 1 FROM booking_reminders R WHERE R.booking_id = B.id AND R.lead = \$3::text\s+\)\s+AND NOT EXISTS \(\s+SELECT 1 FROM notifications N WHERE N.dedupe_key = 'reminder:' \|\| B.id::text \|\| ':' \|\| \$3::text\s+\)\s+ORDER BY F.launch_date, B.id\s+LIMIT \$4`).
+		WithArgs(from, to, "24h", 100).
+		WillReturnRows(createMockRows(bookings))
+
+	due, err := repo.DueReminders(context.Background(), "24h", from, to, 100)
+
+	require.NoError(t, err)
+	verifyBookings(t, bookings, due)
+	assert.Equal(t, "user@example.com", due[0].User.Email)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestDueRemindersError(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewReminderRepository(mockDb)
+
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDb.ExpectQuery(`FROM bookings B`).WithArgs(at, at, "7d", 100).WillReturnError(errors.New("connection reset"))
+
+	_, err = repo.DueReminders(context.Background(), "7d", at, at, 100)
+
+	assert.ErrorContains(t, err, "failed to find due reminders")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestRecordReminder(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewReminderRepository(mockDb)
+
+	bookingID := uuid.New()
+	mockDb.ExpectExec(`INSERT INTO booking_reminders \(booking_id, lead, notification_key\).+ON CONFLICT \(booking_id, lead\) DO NOTHING`).
+		WithArgs(bookingID, "30d", "reminder:x:30d").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	require.NoError(t, repo.RecordReminder(context.Background(), bookingID, "30d", "reminder:x:30d"))
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestAcquireLease(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewLeaseRepository(mockDb)
+
+	query := `INSERT INTO leases \(name, holder, expires_at\).+ON CONFLICT \(name\) DO UPDATE.+WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now\(\)\s+RETURNING holder`
+	mockDb.ExpectQuery(query).WithArgs("job", "a", time.Minute).
+		WillReturnRows(pgxmock.NewRows([]string{"holder"}).AddRow("a"))
+	mockDb.ExpectQuery(query).WithArgs("job", "b", time.Minute).WillReturnError(pgx.ErrNoRows)
+	mockDb.ExpectQuery(query).WithArgs("job", "c", time.Minute).WillReturnError(errors.New("connection reset"))
+
+	held, err := repo.AcquireLease(context.Background(), "job", "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	held, err = repo.AcquireLease(context.Background(), "job", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held, "the lease is held by someone else")
+
+	_, err = repo.AcquireLease(context.Background(), "job", "c", time.Minute)
+	assert.ErrorContains(t, err, "failed to acquire lease job")
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestReleaseLease(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewLeaseRepository(mockDb)
+
+	mockDb.ExpectExec(`DELETE FROM leases WHERE name = \$1 AND holder = \$2`).WithArgs("job", "a").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	require.NoError(t, repo.ReleaseLease(context.Background(), "job", "a"))
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
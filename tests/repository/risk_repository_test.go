@@ -0,0 +1,316 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFutureBookings(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewRiskRepository(mockDb)
+
+	bookings := createMockBookings(3)
+	after := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDb.ExpectQuery(`FROM bookings B.+WHERE F.launch_date > \$1 AND B.status <> \$2\s+ORDER BY F.launchpad_id, F.launch_date, B.id`).
+		WithArgs(after, models.StatusCancelled).
+		WillReturnRows(createMockRows(bookings))
+
+	future, err := repo.FutureBookings(context.Background(), after)
+
+	require.NoError(t, err)
+	verifyBookings(t, bookings, future)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestFlagAtRisk(t *testing.T) {
+	selectBooking := `FROM bookings B.+WHERE B.id = \$1 FOR UPDATE OF B`
+	upsertRisk := `INSERT INTO booking_risks .+ON CONFLICT \(booking_id\) DO UPDATE SET reason = EXCLUDED.reason, alternative_dates = EXCLUDED.alternative_dates`
+	flaggedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	alternatives := []time.Time{flaggedAt.AddDate(0, 0, 30)}
+
+	t.Run("flags a booking", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectBooking).WithArgs(booking.ID).WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(upsertRisk).
+			WithArgs(booking.ID, "launchpad LP0 is retired", models.StatusConfirmed, alternatives, flaggedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectExec(`UPDATE bookings SET status = \$2 WHERE id = \$1`).
+			WithArgs(booking.ID, models.StatusAtRisk).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), booking.ID, models.EventBookingStatusChanged, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		risk := &models.BookingRisk{
+			Booking:          models.Booking{ID: booking.ID},
+			Reason:           "launchpad LP0 is retired",
+			AlternativeDates: alternatives,
+			FlaggedAt:        flaggedAt,
+		}
+		flagged, err := repo.FlagAtRisk(context.Background(), risk)
+
+		require.NoError(t, err)
+		assert.True(t, flagged)
+		assert.Equal(t, models.StatusConfirmed, risk.PreviousStatus)
+		assert.Equal(t, models.StatusAtRisk, risk.Booking.Status)
+		assert.Equal(t, booking.User.FirstName, risk.Booking.User.FirstName)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("refreshes a booking already at risk", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		booking.Status = models.StatusAtRisk
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectBooking).WithArgs(booking.ID).WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(upsertRisk).
+			WithArgs(booking.ID, "launchpad LP0 is retired", models.StatusAtRisk, alternatives, flaggedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		flagged, err := repo.FlagAtRisk(context.Background(), &models.BookingRisk{
+			Booking:          booking,
+			Reason:           "launchpad LP0 is retired",
+			AlternativeDates: alternatives,
+			FlaggedAt:        flaggedAt,
+		})
+
+		require.NoError(t, err)
+		assert.False(t, flagged, "no new status change is recorded")
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("skips a booking cancelled meanwhile", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectBooking).WithArgs(booking.ID).WillReturnError(pgx.ErrNoRows)
+		mockDb.ExpectRollback()
+
+		flagged, err := repo.FlagAtRisk(context.Background(), &models.BookingRisk{Booking: booking})
+
+		require.NoError(t, err)
+		assert.False(t, flagged)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestPendingBookings(t *testing.T) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDb.Close()
+	repo := repository.NewRiskRepository(mockDb)
+
+	bookings := createMockBookings(2)
+	after := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDb.ExpectQuery(`FROM bookings B.+WHERE F.launch_date > \$1 AND B.status = \$2\s+ORDER BY F.launch_date, B.id`).
+		WithArgs(after, models.StatusPendingVerification).
+		WillReturnRows(createMockRows(bookings))
+
+	pending, err := repo.PendingBookings(context.Background(), after)
+
+	require.NoError(t, err)
+	verifyBookings(t, bookings, pending)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestConfirmBooking(t *testing.T) {
+	selectBooking := `FROM bookings B.+WHERE B.id = \$1 FOR UPDATE OF B`
+
+	t.Run("confirms a pending booking", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		booking.Status = models.StatusPendingVerification
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectBooking).WithArgs(booking.ID).WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(`UPDATE bookings SET status = \$2 WHERE id = \$1`).
+			WithArgs(booking.ID, models.StatusConfirmed).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), booking.ID, models.EventBookingStatusChanged, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		confirmed, err := repo.ConfirmBooking(context.Background(), booking.ID)
+
+		require.NoError(t, err)
+		require.NotNil(t, confirmed)
+		assert.Equal(t, models.StatusConfirmed, confirmed.Status)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("leaves a booking no longer pending", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		booking.Status = models.StatusCancelled
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(selectBooking).WithArgs(booking.ID).WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectRollback()
+
+		confirmed, err := repo.ConfirmBooking(context.Background(), booking.ID)
+
+		require.NoError(t, err)
+		assert.Nil(t, confirmed)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestClearRisk(t *testing.T) {
+	deleteRisk := `DELETE FROM booking_risks WHERE booking_id = \$1 RETURNING previous_status`
+
+	t.Run("restores the previous status", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		booking.Status = models.StatusAtRisk
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(deleteRisk).WithArgs(booking.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"previous_status"}).AddRow(models.StatusActive))
+		mockDb.ExpectQuery(`FROM bookings B.+WHERE B.id = \$1 FOR UPDATE OF B`).WithArgs(booking.ID).
+			WillReturnRows(createMockRows([]models.Booking{booking}))
+		mockDb.ExpectExec(`UPDATE bookings SET status = \$2 WHERE id = \$1`).
+			WithArgs(booking.ID, models.StatusActive).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDb.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+			WithArgs(pgxmock.AnyArg(), booking.ID, models.EventBookingStatusChanged, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDb.ExpectCommit()
+
+		restored, err := repo.ClearRisk(context.Background(), booking.ID)
+
+		require.NoError(t, err)
+		require.NotNil(t, restored)
+		assert.Equal(t, models.StatusActive, restored.Status)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("booking not at risk", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		booking := createMockBookings(1)[0]
+		mockDb.ExpectBegin()
+		mockDb.ExpectQuery(deleteRisk).WithArgs(booking.ID).WillReturnError(pgx.ErrNoRows)
+		mockDb.ExpectRollback()
+
+		restored, err := repo.ClearRisk(context.Background(), booking.ID)
+
+		require.NoError(t, err)
+		assert.Nil(t, restored)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
+
+func TestAtRiskBookings(t *testing.T) {
+	columns := []string{
+		"id", "status", "created_at",
+		"user_id", "first_name", "last_name", "gender", "birthday", "email",
+		"flight_id", "launchpad_id", "launch_date",
+		"destination_id", "destination_name",
+		"reason", "previous_status", "alternative_dates", "flagged_at",
+	}
+	flaggedAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("lists a customer's at-risk bookings", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		b := createMockBookings(1)[0]
+		b.Status = models.StatusAtRisk
+		alternatives := []time.Time{flaggedAt.AddDate(0, 0, 3)}
+		mockDb.ExpectQuery(`JOIN booking_risks R ON R.booking_id = B.id\s+WHERE B.status = \$1 AND B.user_id = \$2 ORDER BY F.launch_date, B.id`).
+			WithArgs(models.StatusAtRisk, b.User.ID.String()).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(
+				b.ID, b.Status, b.CreatedAt,
+				b.User.ID, b.User.FirstName, b.User.LastName, b.User.Gender, b.User.Birthday, b.User.Email,
+				b.Flight.ID, b.Flight.LaunchpadID, b.Flight.LaunchDate,
+				b.Flight.Destination.ID, b.Flight.Destination.Name,
+				"launchpad LP0 is retired", models.StatusConfirmed, alternatives, flaggedAt,
+			))
+
+		risks, err := repo.AtRiskBookings(context.Background(), b.User.ID.String())
+
+		require.NoError(t, err)
+		require.Len(t, risks, 1)
+		verifyBookings(t, []models.Booking{b}, []models.Booking{risks[0].Booking})
+		assert.Equal(t, "launchpad LP0 is retired", risks[0].Reason)
+		assert.Equal(t, models.StatusConfirmed, risks[0].PreviousStatus)
+		assert.Equal(t, alternatives, risks[0].AlternativeDates)
+		assert.Equal(t, flaggedAt, risks[0].FlaggedAt)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("lists everyone's with no user", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		mockDb.ExpectQuery(`WHERE B.status = \$1 ORDER BY`).
+			WithArgs(models.StatusAtRisk).
+			WillReturnRows(pgxmock.NewRows(columns))
+
+		risks, err := repo.AtRiskBookings(context.Background(), "")
+
+		require.NoError(t, err)
+		assert.NotNil(t, risks)
+		assert.Empty(t, risks)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mockDb, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		defer mockDb.Close()
+		repo := repository.NewRiskRepository(mockDb)
+
+		mockDb.ExpectQuery(`JOIN booking_risks`).WithArgs(models.StatusAtRisk).WillReturnError(errors.New("connection reset"))
+
+		_, err = repo.AtRiskBookings(context.Background(), "")
+
+		assert.ErrorContains(t, err, "failed to get at-risk bookings")
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+}
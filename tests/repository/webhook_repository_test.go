@@ -0,0 +1,211 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupWebhookRepo(t *testing.T) (pgxmock.PgxPoolIface, *repository.WebhookRepository) {
+	mockDb, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockDb.Close)
+	return mockDb, repository.NewWebhookRepository(mockDb)
+}
+
+var webhookColumns = []string{"id", "owner_id", "url", "event_types", "active", "consecutive_failures", "disabled_at", "created_at"}
+
+func TestCreateWebhook(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+
+	mockDb.ExpectExec(`INSERT INTO webhook_subscriptions`).
+		WithArgs(pgxmock.AnyArg(), (*uuid.UUID)(nil), "https://a.example.com", "whsec_x", []string{"booking.created"}, true, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	w, err := repo.CreateWebhook(context.Background(), &models.WebhookSubscription{
+		URL: "https://a.example.com", Secret: "whsec_x", EventTypes: []models.BookingEventType{models.EventBookingCreated},
+	})
+
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, w.ID)
+	assert.True(t, w.Active)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestGetWebhook(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		id, owner := uuid.New(), uuid.New()
+		disabled := time.Now()
+		mockDb.ExpectQuery(`SELECT .+ FROM webhook_subscriptions\s+WHERE id = \$1`).
+			WithArgs(id.String()).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(id, &owner, "https://a.example.com", []string{"booking.cancelled"}, false, 20, &disabled, time.Now()))
+
+		w, err := repo.GetWebhook(context.Background(), id.String())
+
+		require.NoError(t, err)
+		assert.Equal(t, owner, w.OwnerID)
+		assert.Equal(t, []models.BookingEventType{models.EventBookingCancelled}, w.EventTypes)
+		assert.False(t, w.Active)
+		assert.Equal(t, 20, w.ConsecutiveFailures)
+		require.NotNil(t, w.DisabledAt)
+		assert.Empty(t, w.Secret, "the secret is never read back")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		mockDb.ExpectQuery(`SELECT .+ FROM webhook_subscriptions`).WithArgs("x").WillReturnError(pgx.ErrNoRows)
+
+		_, err := repo.GetWebhook(context.Background(), "x")
+
+		assert.ErrorIs(t, err, models.ErrWebhookNotFound)
+	})
+}
+
+func TestListWebhooks(t *testing.T) {
+	t.Run("by owner", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		owner := uuid.New()
+		mockDb.ExpectQuery(`FROM webhook_subscriptions\s+WHERE owner_id = \$1 ORDER BY created_at, id`).
+			WithArgs(owner).
+			WillReturnRows(pgxmock.NewRows(webhookColumns).
+				AddRow(uuid.New(), &owner, "https://a.example.com", []string{}, true, 0, nil, time.Now()))
+
+		webhooks, err := repo.ListWebhooks(context.Background(), owner)
+
+		require.NoError(t, err)
+		assert.Len(t, webhooks, 1)
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("everyone's", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		mockDb.ExpectQuery(`FROM webhook_subscriptions\s+ORDER BY created_at, id`).
+			WithArgs().
+			WillReturnRows(pgxmock.NewRows(webhookColumns))
+
+		webhooks, err := repo.ListWebhooks(context.Background(), uuid.Nil)
+
+		require.NoError(t, err)
+		assert.NotNil(t, webhooks)
+		assert.Empty(t, webhooks)
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+	mockDb.ExpectExec(`DELETE FROM webhook_subscriptions WHERE id = \$1`).WithArgs("a").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mockDb.ExpectExec(`DELETE FROM webhook_subscriptions WHERE id = \$1`).WithArgs("b").WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	assert.NoError(t, repo.DeleteWebhook(context.Background(), "a"))
+	assert.ErrorIs(t, repo.DeleteWebhook(context.Background(), "b"), models.ErrWebhookNotFound)
+}
+
+func TestEnableWebhook(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+	mockDb.ExpectExec(`UPDATE webhook_subscriptions\s+SET active = true, consecutive_failures = 0, disabled_at = NULL\s+WHERE id = \$1`).
+		WithArgs("a").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDb.ExpectExec(`UPDATE webhook_subscriptions`).WithArgs("b").WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	assert.NoError(t, repo.EnableWebhook(context.Background(), "a"))
+	assert.ErrorIs(t, repo.EnableWebhook(context.Background(), "b"), models.ErrWebhookNotFound)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestListDeliveries(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+	status, lastError := 503, "endpoint responded 503"
+	next, delivered := time.Now().Add(time.Minute), time.Now()
+	mockDb.ExpectQuery(`FROM webhook_deliveries\s+WHERE subscription_id = \$1\s+ORDER BY id DESC\s+LIMIT \$2`).
+		WithArgs("sub", 50).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "subscription_id", "event_id", "event_type", "status", "attempts",
+			"response_status", "last_error", "next_attempt_at", "delivered_at", "created_at"}).
+			AddRow(int64(2), uuid.New(), uuid.New(), models.EventBookingCancelled, models.DeliveryPending, 1, &status, &lastError, next, nil, time.Now()).
+			AddRow(int64(1), uuid.New(), uuid.New(), models.EventBookingCreated, models.DeliveryDelivered, 1, nil, nil, time.Now(), &delivered, time.Now()))
+
+	deliveries, err := repo.ListDeliveries(context.Background(), "sub", 50)
+
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 503, deliveries[0].ResponseStatus)
+	assert.Equal(t, lastError, deliveries[0].LastError)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.Nil(t, deliveries[1].NextAttemptAt, "only pending deliveries have a next attempt")
+	assert.NotNil(t, deliveries[1].DeliveredAt)
+}
+
+func TestEnqueueDeliveries(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+	eventID, owner := uuid.New(), uuid.New()
+	mockDb.ExpectExec(`INSERT INTO webhook_deliveries .+ FROM webhook_subscriptions\s+WHERE active.+ON CONFLICT \(subscription_id, event_id\) DO NOTHING`).
+		WithArgs(eventID, "booking.created", []byte(`{}`), owner).
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+	n, err := repo.EnqueueDeliveries(context.Background(), eventID, models.EventBookingCreated, owner, []byte(`{}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, mockDb.ExpectationsWereMet())
+}
+
+func TestClaimDeliveries(t *testing.T) {
+	mockDb, repo := setupWebhookRepo(t)
+	mockDb.ExpectQuery(`WITH claimed AS \(\s+UPDATE webhook_deliveries SET locked_until .+FOR UPDATE OF d SKIP LOCKED`).
+		WithArgs(50, time.Minute).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "subscription_id", "event_id", "event_type", "payload", "attempts", "created_at", "url", "secret"}).
+			AddRow(int64(4), uuid.New(), uuid.New(), models.EventBookingCreated, []byte(`{}`), 2, time.Now(), "https://a.example.com", "whsec_x"))
+
+	deliveries, err := repo.ClaimDeliveries(context.Background(), 50, time.Minute)
+
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "https://a.example.com", deliveries[0].URL)
+	assert.Equal(t, "whsec_x", deliveries[0].Secret)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+}
+
+func TestMarkDeliveryOutcome(t *testing.T) {
+	t.Run("delivered", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		mockDb.ExpectExec(`SET status = 'delivered'.+UPDATE webhook_subscriptions SET consecutive_failures = 0`).
+			WithArgs(int64(4), 204).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		require.NoError(t, repo.MarkDelivered(context.Background(), 4, 204))
+		require.NoError(t, mockDb.ExpectationsWereMet())
+	})
+
+	t.Run("failed and disabled", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		next := time.Now().Add(time.Minute)
+		mockDb.ExpectQuery(`UPDATE webhook_deliveries.+consecutive_failures = consecutive_failures \+ 1.+RETURNING NOT active`).
+			WithArgs(int64(4), 500, "endpoint responded 500", &next, 20).
+			WillReturnRows(pgxmock.NewRows([]string{"disabled"}).AddRow(true))
+
+		disabled, err := repo.MarkDeliveryFailed(context.Background(), 4, 500, "endpoint responded 500", &next, 20)
+
+		require.NoError(t, err)
+		assert.True(t, disabled)
+	})
+
+	t.Run("subscription deleted meanwhile", func(t *testing.T) {
+		mockDb, repo := setupWebhookRepo(t)
+		mockDb.ExpectQuery(`UPDATE webhook_deliveries`).
+			WithArgs(int64(4), 0, "timeout", pgxmock.AnyArg(), 20).
+			WillReturnError(pgx.ErrNoRows)
+
+		disabled, err := repo.MarkDeliveryFailed(context.Background(), 4, 0, "timeout", nil, 20)
+
+		require.NoError(t, err)
+		assert.False(t, disabled)
+	})
+}
@@ -0,0 +1,183 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	padA = "5e9e4502f5090995de566f86"
+	padB = "5e9e4501f509094ba4566f84"
+)
+
+func batchRequest(launchpadID string, destinationID uuid.UUID, launchDate time.Time) models.BookingRequest {
+	return models.BookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "male",
+		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   launchpadID,
+		DestinationID: destinationID.String(),
+		LaunchDate:    launchDate,
+	}
+}
+
+func TestCreateBookingsBatch(t *testing.T) {
+	mars := &models.Destination{ID: uuid.New(), Name: "Mars"}
+	moon := &models.Destination{ID: uuid.New(), Name: "Moon"}
+	launchDate := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+
+	stubChecks := func(repo *mocks.MockBookingRepository) {
+		repo.On("GetDestinationById", mock.Anything, mars.ID.String()).Return(mars, nil).Maybe()
+		repo.On("GetDestinationById", mock.Anything, moon.ID.String()).Return(moon, nil).Maybe()
+		repo.On("GetFlights", mock.Anything, mock.Anything).Return([]models.Flight{}, nil).Maybe()
+		repo.On("IsLaunchPadWeekAvailable", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	}
+
+	t.Run("atomic batch stores all rows in one call", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := agentContext()
+
+		stubChecks(mockRepo)
+		mockSpaceX.On("CheckLaunchConflict", ctx, mock.Anything, launchDate).Return(true, nil)
+		mockRepo.On("CreateBookings", ctx, mock.MatchedBy(func(b []*models.Booking) bool { return len(b) == 2 })).Return(nil)
+
+		res, err := svc.CreateBookingsBatch(ctx, []models.BookingRequest{
+			batchRequest(padA, mars.ID, launchDate),
+			batchRequest(padB, moon.ID, launchDate),
+		}, models.BatchAtomic)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Created)
+		assert.Equal(t, 0, res.Failed)
+		for i, row := range res.Results {
+			assert.Equal(t, i, row.Index)
+			assert.Equal(t, models.RowCreated, row.Status)
+			assert.NotNil(t, row.Booking)
+		}
+		mockRepo.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("atomic batch publishes an event per stored row", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithEventPublisher(store))
+		ctx := agentContext()
+
+		stubChecks(mockRepo)
+		mockSpaceX.On("CheckLaunchConflict", ctx, mock.Anything, launchDate).Return(true, nil)
+		mockRepo.On("CreateBookings", ctx, mock.Anything).Return(nil)
+
+		res, err := svc.CreateBookingsBatch(ctx, []models.BookingRequest{
+			batchRequest(padA, mars.ID, launchDate),
+			batchRequest(padB, moon.ID, launchDate),
+		}, models.BatchAtomic)
+		require.NoError(t, err)
+
+		recorded, _ := store.EventsSince(ctx, 0, 10)
+		require.Len(t, recorded, 2)
+		for i, event := range recorded {
+			assert.Equal(t, models.EventBookingCreated, event.Type)
+			assert.Equal(t, res.Results[i].Booking.ID, event.Booking.ID)
+		}
+	})
+
+	t.Run("atomic batch aborts on a conflict within the batch", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := agentContext()
+
+		stubChecks(mockRepo)
+		mockSpaceX.On("CheckLaunchConflict", ctx, padA, launchDate).Return(true, nil).Once()
+
+		res, err := svc.CreateBookingsBatch(ctx, []models.BookingRequest{
+			batchRequest(padA, mars.ID, launchDate),
+			batchRequest(padA, moon.ID, launchDate),
+		}, models.BatchAtomic)
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, res.Created)
+		assert.Equal(t, 2, res.Failed)
+		assert.ErrorIs(t, res.Results[0].Err, models.ErrBatchAborted)
+		assert.ErrorIs(t, res.Results[1].Err, models.ErrBatchConflict)
+		assert.ErrorIs(t, res.Results[1].Err, models.ErrLaunchPadUnavailable)
+		assert.Nil(t, res.Results[0].Booking)
+		mockRepo.AssertNotCalled(t, "CreateBookings", mock.Anything, mock.Anything)
+		mockSpaceX.AssertExpectations(t)
+	})
+
+	t.Run("best effort batch keeps the valid rows", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := agentContext()
+
+		invalid := batchRequest(padA, mars.ID, launchDate)
+		invalid.FirstName = ""
+
+		stubChecks(mockRepo)
+		mockSpaceX.On("CheckLaunchConflict", ctx, padA, launchDate).Return(true, nil)
+		mockRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).
+			Return(&models.Booking{ID: uuid.New()}, nil).Once()
+
+		res, err := svc.CreateBookingsBatch(ctx, []models.BookingRequest{
+			batchRequest(padA, mars.ID, launchDate),
+			invalid,
+		}, models.BatchBestEffort)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.Created)
+		assert.Equal(t, 1, res.Failed)
+		assert.Equal(t, models.RowCreated, res.Results[0].Status)
+		assert.Equal(t, models.RowFailed, res.Results[1].Status)
+		assert.ErrorIs(t, res.Results[1].Err, models.ErrInvalidRequest)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("schedules are fetched once per launchpad", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXScheduler)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := agentContext()
+
+		stubChecks(mockRepo)
+		schedule := &spacex.Schedule{
+			LaunchPad: spacex.LaunchPad{Id: padA, Status: "active"},
+			Launches:  []spacex.Launch{{LaunchPadID: padA, Date: launchDate.Add(14 * 24 * time.Hour).Unix(), DatePrecision: "day"}},
+		}
+		mockSpaceX.On("LaunchSchedule", ctx, padA).Return(schedule, nil).Once()
+		mockRepo.On("CreateBookings", ctx, mock.Anything).Return(nil)
+
+		res, err := svc.CreateBookingsBatch(ctx, []models.BookingRequest{
+			batchRequest(padA, mars.ID, launchDate),
+			batchRequest(padA, mars.ID, launchDate.Add(7*24*time.Hour)),
+		}, models.BatchAtomic)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.Created)
+		mockSpaceX.AssertExpectations(t)
+		mockSpaceX.AssertNotCalled(t, "CheckLaunchConflict", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown mode is rejected", func(t *testing.T) {
+		svc := service.NewBookingService(new(mocks.MockBookingRepository), new(mocks.MockSpaceXClient))
+
+		_, err := svc.CreateBookingsBatch(agentContext(), nil, models.BatchMode("sometimes"))
+
+		assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	})
+}
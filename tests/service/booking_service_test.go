@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
 	"github.com/chrisdamba/spacetrouble/internal/service"
 	"github.com/chrisdamba/spacetrouble/tests/mocks"
 	"github.com/chrisdamba/spacetrouble/tests/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
 )
@@ -24,7 +26,7 @@ func TestCreateBooking(t *testing.T) {
 		Gender:        "Male",
 		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
 		LaunchpadID:   "pad-1",
-		DestinationID: validDestinationID,
+		DestinationID: validDestinationID.String(),
 		LaunchDate:    validLaunchDate,
 	}
 
@@ -37,7 +39,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -83,7 +85,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(nil, assert.AnError)
 
@@ -99,7 +101,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		differentDestID := uuid.New()
 		existingFlight := []models.Flight{{
@@ -124,7 +126,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -142,7 +144,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -163,7 +165,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -188,7 +190,7 @@ func TestCreateBooking(t *testing.T) {
 			Gender:        "Male",
 			Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
 			LaunchpadID:   "pad-1",
-			DestinationID: validDestinationID,
+			DestinationID: validDestinationID.String(),
 			LaunchDate:    validLaunchDate,
 		}
 		validDestination := &models.Destination{
@@ -199,7 +201,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := &mocks.MockSpaceXClientUnavailable{}
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -222,7 +224,7 @@ func TestCreateBooking(t *testing.T) {
 			Gender:        "Male",
 			Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
 			LaunchpadID:   "pad-1",
-			DestinationID: validDestinationID,
+			DestinationID: validDestinationID.String(),
 			LaunchDate:    validLaunchDate,
 		}
 		validDestination := &models.Destination{
@@ -233,7 +235,7 @@ func TestCreateBooking(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := &mocks.MockSpaceXClientError{}
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetDestinationById", ctx, validDestinationID.String()).Return(validDestination, nil)
 		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
@@ -255,7 +257,7 @@ func TestDeleteBooking(t *testing.T) {
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
 		bookingID := uuid.New().String()
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockBooking := &models.Booking{
 			ID:     uuid.MustParse(bookingID),
@@ -276,7 +278,7 @@ func TestDeleteBooking(t *testing.T) {
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
-		err := svc.DeleteBooking(context.Background(), "invalid-uuid")
+		err := svc.DeleteBooking(agentContext(), "invalid-uuid")
 
 		assert.Error(t, err)
 		assert.Equal(t, models.ErrInvalidUUID, err)
@@ -289,7 +291,7 @@ func TestDeleteBooking(t *testing.T) {
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
 		bookingID := uuid.New().String()
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockRepo.On("GetBookingByID", ctx, bookingID).Return(nil, models.ErrBookingNotFound)
 
@@ -300,13 +302,31 @@ func TestDeleteBooking(t *testing.T) {
 		mockRepo.AssertNotCalled(t, "DeleteBooking")
 	})
 
+	t.Run("at-risk booking can be deleted", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+
+		bookingID := uuid.New().String()
+		ctx := agentContext()
+
+		mockRepo.On("GetBookingByID", ctx, bookingID).Return(&models.Booking{
+			ID:     uuid.MustParse(bookingID),
+			Status: models.StatusAtRisk,
+		}, nil)
+		mockRepo.On("DeleteBooking", ctx, bookingID).Return(nil)
+
+		assert.NoError(t, svc.DeleteBooking(ctx, bookingID))
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("cannot delete cancelled booking", func(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
 		bookingID := uuid.New().String()
-		ctx := context.Background()
+		ctx := agentContext()
 
 		mockBooking := &models.Booking{
 			ID:     uuid.MustParse(bookingID),
@@ -323,21 +343,147 @@ func TestDeleteBooking(t *testing.T) {
 	})
 }
 
+func TestRescheduleBooking(t *testing.T) {
+	destination := models.Destination{ID: uuid.New(), Name: "Mars"}
+	// a Monday, so the day after is in the same ISO week
+	launch := time.Date(2030, 3, 11, 9, 0, 0, 0, time.UTC)
+	bookingWith := func(status models.BookingStatus) *models.Booking {
+		return &models.Booking{
+			ID:     uuid.New(),
+			Status: status,
+			Flight: models.Flight{ID: uuid.New(), LaunchpadID: "pad-1", Destination: destination, LaunchDate: launch},
+		}
+	}
+
+	t.Run("moves the booking and publishes the event", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithEventPublisher(store))
+		ctx := agentContext()
+		booking := bookingWith(models.StatusAtRisk)
+		newDate := launch.AddDate(0, 0, 14)
+		moved := *booking
+		moved.Flight.LaunchDate = newDate
+		moved.Status = models.StatusConfirmed
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+		mockRepo.On("GetFlights", ctx, map[string]interface{}{"launchpad_id": "pad-1", "launch_date": newDate}).Return([]models.Flight{}, nil)
+		mockRepo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destination.ID.String(), newDate).Return(true, nil)
+		mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", newDate).Return(true, nil)
+		mockRepo.On("RescheduleBooking", ctx, booking.ID.String(), newDate, models.StatusConfirmed).Return(&moved, nil)
+
+		result, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: newDate})
+
+		require.NoError(t, err)
+		assert.Equal(t, newDate, result.Flight.LaunchDate)
+		recorded, err := store.EventsSince(ctx, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, recorded, 1)
+		assert.Equal(t, models.EventBookingRescheduled, recorded[0].Type)
+		assert.Equal(t, models.StatusAtRisk, recorded[0].PreviousStatus)
+		mockRepo.AssertExpectations(t)
+		mockSpaceX.AssertExpectations(t)
+	})
+
+	t.Run("a move within the week does not clash with itself", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := agentContext()
+		booking := bookingWith(models.StatusConfirmed)
+		newDate := launch.AddDate(0, 0, 1)
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+		mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", newDate).Return(true, nil)
+		mockRepo.On("RescheduleBooking", ctx, booking.ID.String(), newDate, models.StatusConfirmed).Return(booking, nil)
+
+		_, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: newDate})
+
+		require.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "IsLaunchPadWeekAvailable", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("the same date is rejected", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := agentContext()
+		booking := bookingWith(models.StatusConfirmed)
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+
+		_, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: launch})
+
+		assert.ErrorIs(t, err, models.ErrInvalidRequest)
+		mockRepo.AssertNotCalled(t, "RescheduleBooking", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("a date SpaceX has reserved is rejected", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithEventPublisher(store))
+		ctx := agentContext()
+		booking := bookingWith(models.StatusConfirmed)
+		newDate := launch.AddDate(0, 0, 14)
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+		mockRepo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destination.ID.String(), newDate).Return(true, nil)
+		mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", newDate).Return(false, nil)
+
+		_, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: newDate})
+
+		assert.ErrorIs(t, err, models.ErrLaunchPadUnavailable)
+		mockRepo.AssertNotCalled(t, "RescheduleBooking", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		recorded, _ := store.EventsSince(ctx, 0, 10)
+		assert.Empty(t, recorded)
+	})
+
+	t.Run("cancelled bookings cannot be moved", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := agentContext()
+		booking := bookingWith(models.StatusCancelled)
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+
+		_, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: launch.AddDate(0, 0, 14)})
+
+		assert.ErrorContains(t, err, "cannot reschedule booking with status")
+	})
+
+	t.Run("customers cannot move other customers' bookings", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := customerContext(uuid.New())
+		booking := bookingWith(models.StatusConfirmed)
+		booking.User.ID = uuid.New()
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(booking, nil)
+
+		_, err := svc.RescheduleBooking(ctx, booking.ID.String(), &models.RescheduleRequest{LaunchDate: launch.AddDate(0, 0, 14)})
+
+		assert.ErrorIs(t, err, models.ErrBookingNotFound)
+	})
+}
+
 func TestAllBookings(t *testing.T) {
 	t.Run("successful retrieval", func(t *testing.T) {
 		mockRepo := new(mocks.MockBookingRepository)
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
-		ctx := context.Background()
+		ctx := agentContext()
 		cursor := "some-cursor"
 		limit := 10
 
 		mockBookings := utils.CreateMockBookings(2)
 		nextCursor := "next-cursor"
 
-		mockRepo.On("GetBookingsPaginated", ctx, cursor, limit).
-			Return(mockBookings, nextCursor, nil)
+		mockRepo.On("GetBookingsPaginated", ctx, models.BookingFilter{}, cursor, "", limit).
+			Return(models.BookingPage{Bookings: mockBookings, NextCursor: nextCursor}, nil)
 
 		getReq := models.GetBookingsRequest{
 			Limit: limit,
@@ -350,6 +496,35 @@ func TestAllBookings(t *testing.T) {
 		assert.Len(t, response.Bookings, 2)
 		assert.Equal(t, limit, response.Limit)
 		assert.Equal(t, nextCursor, response.Cursor)
+		assert.Equal(t, nextCursor, response.NextCursor)
+		assert.Nil(t, response.TotalCount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("paging backward with total count", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+
+		ctx := agentContext()
+		filter := models.BookingFilter{Status: models.StatusConfirmed}
+
+		mockRepo.On("GetBookingsPaginated", ctx, filter, "", "before-cursor", 5).
+			Return(models.BookingPage{Bookings: utils.CreateMockBookings(5), NextCursor: "next", PrevCursor: "prev"}, nil)
+		mockRepo.On("CountBookings", ctx, filter).Return(42, nil)
+
+		response, err := svc.AllBookings(ctx, models.GetBookingsRequest{
+			Limit:        5,
+			Before:       "before-cursor",
+			Filter:       filter,
+			IncludeTotal: true,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "next", response.NextCursor)
+		assert.Equal(t, "prev", response.PrevCursor)
+		require.NotNil(t, response.TotalCount)
+		assert.Equal(t, 42, *response.TotalCount)
 		mockRepo.AssertExpectations(t)
 	})
 
@@ -358,11 +533,11 @@ func TestAllBookings(t *testing.T) {
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
-		ctx := context.Background()
+		ctx := agentContext()
 
 		// Return empty slice instead of nil for first argument
-		mockRepo.On("GetBookingsPaginated", ctx, "", 10).
-			Return([]models.Booking{}, "", errors.New("database error"))
+		mockRepo.On("GetBookingsPaginated", ctx, models.BookingFilter{}, "", "", 10).
+			Return(models.BookingPage{}, errors.New("database error"))
 
 		getReq := models.GetBookingsRequest{
 			Limit: 10,
@@ -381,11 +556,11 @@ func TestAllBookings(t *testing.T) {
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
-		ctx := context.Background()
+		ctx := agentContext()
 
 		// Service should convert negative limit to 10 before calling repository
-		mockRepo.On("GetBookingsPaginated", ctx, "", 10).
-			Return([]models.Booking{}, "", nil)
+		mockRepo.On("GetBookingsPaginated", ctx, models.BookingFilter{}, "", "", 10).
+			Return(models.BookingPage{}, nil)
 
 		getReq := models.GetBookingsRequest{
 			Limit: -5,
@@ -404,10 +579,10 @@ func TestAllBookings(t *testing.T) {
 		mockSpaceX := new(mocks.MockSpaceXClient)
 		svc := service.NewBookingService(mockRepo, mockSpaceX)
 
-		ctx := context.Background()
+		ctx := agentContext()
 
-		mockRepo.On("GetBookingsPaginated", ctx, "", 10).
-			Return([]models.Booking{}, "", nil)
+		mockRepo.On("GetBookingsPaginated", ctx, models.BookingFilter{}, "", "", 10).
+			Return(models.BookingPage{}, nil)
 
 		getReq := models.GetBookingsRequest{
 			Limit: 0,
@@ -421,3 +596,165 @@ func TestAllBookings(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func agentContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Role: auth.RoleAgent})
+}
+
+func customerContext(userID uuid.UUID) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID, Role: auth.RoleCustomer})
+}
+
+func TestBookingAuthorization(t *testing.T) {
+	customerID := uuid.New()
+
+	t.Run("unauthenticated requests are rejected", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+
+		_, err := svc.AllBookings(context.Background(), models.GetBookingsRequest{})
+		assert.Equal(t, models.ErrUnauthenticated, err)
+
+		_, err = svc.CreateBooking(context.Background(), utils.CreateMockBookingRequest())
+		assert.Equal(t, models.ErrUnauthenticated, err)
+		mockRepo.AssertNotCalled(t, "GetBookingsPaginated")
+	})
+
+	t.Run("customer list is scoped to own bookings", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := customerContext(customerID)
+
+		filter := models.BookingFilter{UserID: customerID.String()}
+		mockRepo.On("GetBookingsPaginated", ctx, filter, "", "", 10).
+			Return(models.BookingPage{}, nil)
+
+		_, err := svc.AllBookings(ctx, models.GetBookingsRequest{})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("customer cannot get another customer's booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := customerContext(customerID)
+
+		other := utils.CreateMockBooking(uuid.Nil)
+		mockRepo.On("GetBookingByID", ctx, other.ID.String()).Return(other, nil)
+
+		booking, err := svc.GetBooking(ctx, other.ID.String())
+
+		assert.Nil(t, booking)
+		assert.Equal(t, models.ErrBookingNotFound, err)
+	})
+
+	t.Run("customer can get own booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := customerContext(customerID)
+
+		own := utils.CreateMockBooking(uuid.Nil)
+		own.User.ID = customerID
+		mockRepo.On("GetBookingByID", ctx, own.ID.String()).Return(own, nil)
+
+		booking, err := svc.GetBooking(ctx, own.ID.String())
+
+		assert.NoError(t, err)
+		assert.Equal(t, own.ID, booking.ID)
+	})
+
+	t.Run("customer cannot cancel another customer's booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := customerContext(customerID)
+
+		other := utils.CreateMockBooking(uuid.Nil)
+		mockRepo.On("GetBookingByID", ctx, other.ID.String()).Return(other, nil)
+
+		err := svc.DeleteBooking(ctx, other.ID.String())
+
+		assert.Equal(t, models.ErrBookingNotFound, err)
+		mockRepo.AssertNotCalled(t, "DeleteBooking")
+	})
+
+	t.Run("customer cannot book for another user", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+
+		request := utils.CreateMockBookingRequest()
+		request.UserID = uuid.New().String()
+
+		booking, err := svc.CreateBooking(customerContext(customerID), request)
+
+		assert.Nil(t, booking)
+		assert.Equal(t, models.ErrForbidden, err)
+		mockRepo.AssertNotCalled(t, "CreateBooking")
+	})
+
+	t.Run("customer bookings are created for the customer", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		svc := service.NewBookingService(mockRepo, mockSpaceX)
+		ctx := customerContext(customerID)
+
+		request := utils.CreateMockBookingRequest()
+		destination := &models.Destination{ID: uuid.MustParse(request.DestinationID), Name: "Mars"}
+
+		mockRepo.On("GetDestinationById", ctx, request.DestinationID).Return(destination, nil)
+		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+		mockRepo.On("IsLaunchPadWeekAvailable", ctx, request.LaunchpadID, request.DestinationID, request.LaunchDate).Return(true, nil)
+		mockSpaceX.On("CheckLaunchConflict", ctx, request.LaunchpadID, request.LaunchDate).Return(true, nil)
+		mockRepo.On("CreateBooking", ctx, mock.MatchedBy(func(b *models.Booking) bool {
+			return b.User.ID == customerID
+		})).Return(utils.CreateMockBooking(uuid.Nil), nil)
+
+		_, err := svc.CreateBooking(ctx, request)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestExportBookings(t *testing.T) {
+	t.Run("customers only export their own bookings", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		customerID := uuid.New()
+		ctx := customerContext(customerID)
+
+		want := models.BookingFilter{UserID: customerID.String(), Status: models.StatusActive}
+		mockRepo.On("StreamBookings", ctx, want, mock.Anything).Return([]models.Booking{{ID: uuid.New()}}, nil)
+
+		rows := 0
+		err := svc.ExportBookings(ctx, models.BookingFilter{UserID: uuid.NewString(), Status: models.StatusActive}, func(models.Booking) error {
+			rows++
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, rows)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("agents export any booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient))
+		ctx := agentContext()
+
+		mockRepo.On("StreamBookings", ctx, models.BookingFilter{}, mock.Anything).Return(nil, nil)
+
+		err := svc.ExportBookings(ctx, models.BookingFilter{}, func(models.Booking) error { return nil })
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := service.NewBookingService(new(mocks.MockBookingRepository), new(mocks.MockSpaceXClient))
+
+		err := svc.ExportBookings(context.Background(), models.BookingFilter{}, func(models.Booking) error { return nil })
+
+		assert.ErrorIs(t, err, models.ErrUnauthenticated)
+	})
+}
@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSnapshots struct {
+	schedule *spacex.Schedule
+	seenAt   time.Time
+}
+
+func (f *fakeSnapshots) LastKnownSchedule(launchpadID string) (*spacex.Schedule, time.Time, bool) {
+	if f.schedule == nil {
+		return nil, time.Time{}, false
+	}
+	return f.schedule, f.seenAt, true
+}
+
+func TestParseDegradedPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []models.DegradedPolicy
+		wantErr bool
+	}{
+		{value: "", want: []models.DegradedPolicy{models.DegradedReject}},
+		{value: "reject", want: []models.DegradedPolicy{models.DegradedReject}},
+		{value: "pending", want: []models.DegradedPolicy{models.DegradedPending, models.DegradedReject}},
+		{value: "snapshot, pending", want: []models.DegradedPolicy{models.DegradedSnapshot, models.DegradedPending, models.DegradedReject}},
+		{value: "snapshot,reject,pending", want: []models.DegradedPolicy{models.DegradedSnapshot, models.DegradedReject}},
+		{value: "queue", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := service.ParseDegradedPolicy(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateBookingWhileSpaceXIsUnavailable(t *testing.T) {
+	destinationID := uuid.New()
+	launchDate := time.Now().Add(48 * time.Hour)
+	request := &models.BookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "Male",
+		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "pad-1",
+		DestinationID: destinationID.String(),
+		LaunchDate:    launchDate,
+	}
+	free := &spacex.Schedule{LaunchPad: spacex.LaunchPad{Id: "pad-1", Status: "active"}}
+	taken := &spacex.Schedule{
+		LaunchPad: spacex.LaunchPad{Id: "pad-1", Status: "active"},
+		Launches:  []spacex.Launch{{LaunchPadID: "pad-1", Date: launchDate.Unix(), DatePrecision: "day"}},
+	}
+
+	tests := []struct {
+		name       string
+		policy     string
+		snapshots  *fakeSnapshots
+		wantStatus models.BookingStatus
+		wantErr    error
+		decided    models.DegradedPolicy
+	}{
+		{name: "rejected by default", policy: "", wantErr: models.ErrSpaceXUnavailable, decided: models.DegradedReject},
+		{name: "accepted as pending", policy: "pending", wantStatus: models.StatusPendingVerification, decided: models.DegradedPending},
+		{name: "accepted on a free snapshot", policy: "snapshot",
+			snapshots: &fakeSnapshots{schedule: free, seenAt: time.Now().Add(-time.Hour)}, wantStatus: models.StatusConfirmed, decided: models.DegradedSnapshot},
+		{name: "turned down on a taken snapshot", policy: "snapshot,pending",
+			snapshots: &fakeSnapshots{schedule: taken, seenAt: time.Now().Add(-time.Hour)}, wantErr: models.ErrLaunchPadUnavailable, decided: models.DegradedSnapshot},
+		{name: "too old a snapshot falls back", policy: "snapshot,pending",
+			snapshots: &fakeSnapshots{schedule: taken, seenAt: time.Now().Add(-48 * time.Hour)}, wantStatus: models.StatusPendingVerification, decided: models.DegradedPending},
+		{name: "no snapshot falls back to rejecting", policy: "snapshot",
+			snapshots: &fakeSnapshots{}, wantErr: models.ErrSpaceXUnavailable, decided: models.DegradedReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := service.ParseDegradedPolicy(tt.policy)
+			require.NoError(t, err)
+			mockRepo := new(mocks.MockBookingRepository)
+			mockSpaceX := new(mocks.MockSpaceXClient)
+			var snapshots service.ScheduleSnapshots
+			if tt.snapshots != nil {
+				snapshots = tt.snapshots
+			}
+			svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithDegradedMode(policies, snapshots, 24*time.Hour))
+			ctx := agentContext()
+
+			mockRepo.On("GetDestinationById", ctx, destinationID.String()).Return(&models.Destination{ID: destinationID, Name: "Mars"}, nil)
+			mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+			mockRepo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destinationID.String(), launchDate).Return(true, nil)
+			mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", launchDate).
+				Return(false, fmt.Errorf("checking launchpad: %w", spacex.ErrCircuitOpen))
+			var stored *models.Booking
+			if tt.wantErr == nil {
+				mockRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).
+					Run(func(args mock.Arguments) { stored = args.Get(1).(*models.Booking) }).
+					Return(&models.Booking{}, nil)
+			}
+
+			booking, err := svc.CreateBooking(ctx, request)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, booking)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, stored)
+				assert.Equal(t, tt.wantStatus, stored.Status)
+			}
+			report := svc.DegradedMode()
+			assert.Equal(t, policies[0], report.Policy)
+			assert.Equal(t, policies[1:], report.Fallbacks)
+			assert.EqualValues(t, 1, report.Decisions[tt.decided])
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDestinationService(t *testing.T) {
+	t.Run("admin creates destination", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewDestinationService(mockRepo)
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{Role: auth.RoleAdmin})
+
+		created := &models.Destination{ID: uuid.New(), Name: "Callisto"}
+		mockRepo.On("CreateDestination", ctx, mock.MatchedBy(func(d *models.Destination) bool {
+			return d.Name == "Callisto"
+		})).Return(created, nil)
+
+		dest, err := svc.CreateDestination(ctx, &models.DestinationRequest{Name: "Callisto"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, created, dest)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("agent cannot create destination", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewDestinationService(mockRepo)
+
+		dest, err := svc.CreateDestination(agentContext(), &models.DestinationRequest{Name: "Callisto"})
+
+		assert.Nil(t, dest)
+		assert.Equal(t, models.ErrForbidden, err)
+		mockRepo.AssertNotCalled(t, "CreateDestination")
+	})
+
+	t.Run("customer lists destinations", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewDestinationService(mockRepo)
+		ctx := customerContext(uuid.New())
+
+		mockRepo.On("GetDestinations", ctx).Return([]models.Destination{{ID: uuid.New(), Name: "Mars"}}, nil)
+
+		destinations, err := svc.AllDestinations(ctx)
+
+		assert.NoError(t, err)
+		assert.Len(t, destinations, 1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("looks up destinations by id", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewDestinationService(mockRepo)
+		ctx := customerContext(uuid.New())
+		ids := []string{uuid.NewString(), uuid.NewString()}
+
+		mockRepo.On("GetDestinationsByIDs", ctx, ids).Return([]models.Destination{{Name: "Mars"}}, nil)
+
+		destinations, err := svc.DestinationsByIDs(ctx, ids)
+
+		assert.NoError(t, err)
+		assert.Len(t, destinations, 1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects malformed ids before querying", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewDestinationService(mockRepo)
+
+		_, err := svc.DestinationsByIDs(customerContext(uuid.New()), []string{"mars"})
+
+		assert.ErrorIs(t, err, models.ErrInvalidUUID)
+		mockRepo.AssertNotCalled(t, "GetDestinationsByIDs")
+	})
+}
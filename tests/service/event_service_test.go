@@ -0,0 +1,229 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/events"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func bookingFor(userID uuid.UUID) models.Booking {
+	return models.Booking{ID: uuid.New(), User: models.User{ID: userID}, Status: models.StatusActive}
+}
+
+// eventLog numbers and keeps events the way the event store does, and
+// hands each to its broker as the Postgres listener would.
+type eventLog struct {
+	mu     sync.Mutex
+	events []models.BookingEvent
+	broker *events.Broker
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{broker: events.NewBroker()}
+}
+
+func (l *eventLog) PublishEvent(ctx context.Context, event *models.BookingEvent) error {
+	l.mu.Lock()
+	event.ID = int64(len(l.events) + 1)
+	l.events = append(l.events, *event)
+	l.mu.Unlock()
+	l.broker.Publish(*event)
+	return nil
+}
+
+func (l *eventLog) EventsSince(ctx context.Context, afterID int64, limit int) ([]models.BookingEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var since []models.BookingEvent
+	for _, e := range l.events {
+		if e.ID > afterID && len(since) < limit {
+			since = append(since, e)
+		}
+	}
+	return since, nil
+}
+
+func recordEvent(t *testing.T, store *eventLog, booking models.Booking) int64 {
+	t.Helper()
+	event := &models.BookingEvent{Type: models.EventBookingCreated, Booking: booking}
+	require.NoError(t, store.PublishEvent(context.Background(), event))
+	return event.ID
+}
+
+func next(t *testing.T, ch <-chan models.BookingEvent) models.BookingEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "stream closed")
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return models.BookingEvent{}
+	}
+}
+
+func TestEventService(t *testing.T) {
+	t.Run("replays after the last event id, then streams", func(t *testing.T) {
+		store := newEventLog()
+		svc := service.NewEventService(store.broker, store)
+		ctx, cancel := context.WithCancel(agentContext())
+		defer cancel()
+
+		first := recordEvent(t, store, bookingFor(uuid.New()))
+		second := recordEvent(t, store, bookingFor(uuid.New()))
+
+		stream, err := svc.Subscribe(ctx, first)
+		require.NoError(t, err)
+		assert.Equal(t, second, next(t, stream).ID)
+
+		third := recordEvent(t, store, bookingFor(uuid.New()))
+		assert.Equal(t, third, next(t, stream).ID)
+	})
+
+	t.Run("starts with live events without a last event id", func(t *testing.T) {
+		store := newEventLog()
+		svc := service.NewEventService(store.broker, store)
+		ctx, cancel := context.WithCancel(agentContext())
+		defer cancel()
+		recordEvent(t, store, bookingFor(uuid.New()))
+
+		stream, err := svc.Subscribe(ctx, 0)
+		require.NoError(t, err)
+
+		live := recordEvent(t, store, bookingFor(uuid.New()))
+		assert.Equal(t, live, next(t, stream).ID)
+	})
+
+	t.Run("customers only see their own bookings", func(t *testing.T) {
+		store := newEventLog()
+		svc := service.NewEventService(store.broker, store)
+		customer := uuid.New()
+		ctx, cancel := context.WithCancel(customerContext(customer))
+		defer cancel()
+
+		stream, err := svc.Subscribe(ctx, 0)
+		require.NoError(t, err)
+		recordEvent(t, store, bookingFor(uuid.New()))
+		own := recordEvent(t, store, bookingFor(customer))
+
+		assert.Equal(t, own, next(t, stream).ID)
+	})
+
+	t.Run("closes the stream when the context ends", func(t *testing.T) {
+		store := newEventLog()
+		svc := service.NewEventService(store.broker, store)
+		ctx, cancel := context.WithCancel(agentContext())
+
+		stream, err := svc.Subscribe(ctx, 0)
+		require.NoError(t, err)
+		cancel()
+
+		_, ok := <-stream
+		assert.False(t, ok)
+	})
+
+	t.Run("requires a principal", func(t *testing.T) {
+		store := newEventLog()
+		svc := service.NewEventService(store.broker, store)
+
+		_, err := svc.Subscribe(context.Background(), 0)
+
+		assert.ErrorIs(t, err, models.ErrUnauthenticated)
+	})
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) PublishEvent(ctx context.Context, event *models.BookingEvent) error {
+	return errors.New("event store down")
+}
+
+func TestBookingEvents(t *testing.T) {
+	t.Run("cancelling publishes the cancelled booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient), service.WithEventPublisher(store))
+		ctx := agentContext()
+		booking := bookingFor(uuid.New())
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(&booking, nil)
+		mockRepo.On("DeleteBooking", ctx, booking.ID.String()).Return(nil)
+
+		require.NoError(t, svc.DeleteBooking(ctx, booking.ID.String()))
+
+		recorded, err := store.EventsSince(ctx, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, recorded, 1)
+		assert.Equal(t, models.EventBookingCancelled, recorded[0].Type)
+		assert.Equal(t, models.StatusCancelled, recorded[0].Booking.Status)
+		assert.Equal(t, models.StatusActive, recorded[0].PreviousStatus)
+		assert.False(t, recorded[0].OccurredAt.IsZero())
+	})
+
+	t.Run("a failed delete publishes nothing", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient), service.WithEventPublisher(store))
+		ctx := agentContext()
+		booking := bookingFor(uuid.New())
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(&booking, nil)
+		mockRepo.On("DeleteBooking", ctx, booking.ID.String()).Return(models.ErrBookingNotFound)
+
+		assert.ErrorIs(t, svc.DeleteBooking(ctx, booking.ID.String()), models.ErrBookingNotFound)
+
+		recorded, _ := store.EventsSince(ctx, 0, 10)
+		assert.Empty(t, recorded)
+	})
+
+	t.Run("publish failures do not fail the change", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		svc := service.NewBookingService(mockRepo, new(mocks.MockSpaceXClient), service.WithEventPublisher(failingPublisher{}))
+		ctx := agentContext()
+		booking := bookingFor(uuid.New())
+
+		mockRepo.On("GetBookingByID", ctx, booking.ID.String()).Return(&booking, nil)
+		mockRepo.On("DeleteBooking", ctx, booking.ID.String()).Return(nil)
+
+		assert.NoError(t, svc.DeleteBooking(ctx, booking.ID.String()))
+	})
+
+	t.Run("creating publishes the stored booking", func(t *testing.T) {
+		mockRepo := new(mocks.MockBookingRepository)
+		mockSpaceX := new(mocks.MockSpaceXClient)
+		store := newEventLog()
+		svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithEventPublisher(store))
+		ctx := agentContext()
+		destination := &models.Destination{ID: uuid.New(), Name: "Mars"}
+		launch := time.Now().Add(48 * time.Hour)
+
+		mockRepo.On("GetDestinationById", ctx, destination.ID.String()).Return(destination, nil)
+		mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+		mockRepo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destination.ID.String(), launch).Return(true, nil)
+		mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", launch).Return(true, nil)
+		stored := bookingFor(uuid.New())
+		mockRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).Return(&stored, nil)
+
+		_, err := svc.CreateBooking(ctx, &models.BookingRequest{
+			FirstName: "John", LastName: "Doe", Gender: "male", LaunchpadID: "pad-1",
+			DestinationID: destination.ID.String(), LaunchDate: launch,
+		})
+		require.NoError(t, err)
+
+		recorded, _ := store.EventsSince(ctx, 0, 10)
+		require.Len(t, recorded, 1)
+		assert.Equal(t, models.EventBookingCreated, recorded[0].Type)
+		assert.Equal(t, stored.ID, recorded[0].Booking.ID)
+	})
+}
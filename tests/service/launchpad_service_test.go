@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/auth"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func adminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Role: auth.RoleAdmin})
+}
+
+func TestLaunchpadService(t *testing.T) {
+	const launchpadID = "5e9e4501f509094ba4566f84"
+
+	t.Run("admin closes a launchpad", func(t *testing.T) {
+		repo := new(mocks.MockLaunchpadRepository)
+		svc := service.NewLaunchpadService(repo)
+		ctx := adminContext()
+
+		closed := &models.LaunchpadClosure{LaunchpadID: launchpadID, Reason: "pad refurbishment", ClosedAt: time.Now()}
+		repo.On("CloseLaunchpad", ctx, mock.MatchedBy(func(c *models.LaunchpadClosure) bool {
+			return c.LaunchpadID == launchpadID && c.Reason == "pad refurbishment" && !c.ClosedAt.IsZero()
+		})).Return(closed, nil)
+
+		closure, err := svc.CloseLaunchpad(ctx, &models.LaunchpadClosureRequest{LaunchpadID: launchpadID, Reason: "pad refurbishment"})
+
+		require.NoError(t, err)
+		assert.Equal(t, closed, closure)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("admin reopens and lists launchpads", func(t *testing.T) {
+		repo := new(mocks.MockLaunchpadRepository)
+		svc := service.NewLaunchpadService(repo)
+		ctx := adminContext()
+
+		repo.On("ReopenLaunchpad", ctx, launchpadID).Return(models.ErrLaunchpadNotClosed).Once()
+		repo.On("ListLaunchpadClosures", ctx).Return([]models.LaunchpadClosure{{LaunchpadID: launchpadID}}, nil)
+
+		assert.ErrorIs(t, svc.ReopenLaunchpad(ctx, launchpadID), models.ErrLaunchpadNotClosed)
+		closures, err := svc.LaunchpadClosures(ctx)
+		require.NoError(t, err)
+		assert.Len(t, closures, 1)
+		repo.AssertExpectations(t)
+	})
+
+	for name, ctx := range map[string]context.Context{
+		"agent":    agentContext(),
+		"customer": customerContext(uuid.New()),
+	} {
+		t.Run(name+" cannot manage launchpads", func(t *testing.T) {
+			repo := new(mocks.MockLaunchpadRepository)
+			svc := service.NewLaunchpadService(repo)
+
+			_, err := svc.CloseLaunchpad(ctx, &models.LaunchpadClosureRequest{LaunchpadID: launchpadID})
+			assert.Equal(t, models.ErrForbidden, err)
+			assert.Equal(t, models.ErrForbidden, svc.ReopenLaunchpad(ctx, launchpadID))
+			_, err = svc.LaunchpadClosures(ctx)
+			assert.Equal(t, models.ErrForbidden, err)
+			repo.AssertNotCalled(t, "CloseLaunchpad", mock.Anything, mock.Anything)
+			repo.AssertNotCalled(t, "ReopenLaunchpad", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateBookingOnClosedLaunchpad(t *testing.T) {
+	destinationID := uuid.New()
+	request := &models.BookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "male",
+		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "pad-1",
+		DestinationID: destinationID.String(),
+		LaunchDate:    time.Now().Add(24 * time.Hour),
+	}
+
+	t.Run("is turned down", func(t *testing.T) {
+		repo := new(mocks.MockBookingRepository)
+		spaceX := new(mocks.MockSpaceXClient)
+		closures := new(mocks.MockLaunchpadRepository)
+		svc := service.NewBookingService(repo, spaceX, service.WithLaunchpadClosures(closures))
+		ctx := agentContext()
+
+		repo.On("GetDestinationById", ctx, destinationID.String()).Return(&models.Destination{ID: destinationID}, nil)
+		closures.On("LaunchpadClosure", ctx, "pad-1").Return(&models.LaunchpadClosure{LaunchpadID: "pad-1"}, nil)
+
+		booking, err := svc.CreateBooking(ctx, request)
+
+		assert.Nil(t, booking)
+		assert.ErrorIs(t, err, models.ErrLaunchPadUnavailable)
+		spaceX.AssertNotCalled(t, "CheckLaunchConflict", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "CreateBooking", mock.Anything, mock.Anything)
+	})
+
+	t.Run("goes ahead once reopened", func(t *testing.T) {
+		repo := new(mocks.MockBookingRepository)
+		spaceX := new(mocks.MockSpaceXClient)
+		closures := new(mocks.MockLaunchpadRepository)
+		svc := service.NewBookingService(repo, spaceX, service.WithLaunchpadClosures(closures))
+		ctx := agentContext()
+
+		repo.On("GetDestinationById", ctx, destinationID.String()).Return(&models.Destination{ID: destinationID}, nil)
+		closures.On("LaunchpadClosure", ctx, "pad-1").Return(nil, nil)
+		repo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+		repo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destinationID.String(), request.LaunchDate).Return(true, nil)
+		spaceX.On("CheckLaunchConflict", ctx, "pad-1", request.LaunchDate).Return(true, nil)
+		repo.On("CreateBooking", ctx, mock.Anything).Return(&models.Booking{ID: uuid.New()}, nil)
+
+		_, err := svc.CreateBooking(ctx, request)
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+		closures.AssertExpectations(t)
+	})
+}
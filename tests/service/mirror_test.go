@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMirror struct {
+	day      *models.LaunchpadDay
+	err      error
+	syncedAt time.Time
+}
+
+func (f *fakeMirror) LaunchpadDay(_ context.Context, launchpadID string, day time.Time) (*models.LaunchpadDay, error) {
+	return f.day, f.err
+}
+
+func (f *fakeMirror) LastMirrorSync(_ context.Context) (time.Time, error) {
+	return f.syncedAt, nil
+}
+
+func TestCreateBookingAgainstTheMirror(t *testing.T) {
+	destinationID := uuid.New()
+	launchDate := time.Now().Add(48 * time.Hour)
+	request := &models.BookingRequest{
+		FirstName:     "John",
+		LastName:      "Doe",
+		Gender:        "Male",
+		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "pad-1",
+		DestinationID: destinationID.String(),
+		LaunchDate:    launchDate,
+	}
+	fresh := time.Now().Add(-10 * time.Minute)
+	stale := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name     string
+		mirror   *fakeMirror
+		live     bool
+		wantErr  error
+		wantLive bool
+	}{
+		{name: "free on a fresh mirror",
+			mirror: &fakeMirror{day: &models.LaunchpadDay{Status: "active", SyncedAt: fresh}}},
+		{name: "taken on a fresh mirror",
+			mirror:  &fakeMirror{day: &models.LaunchpadDay{Status: "active", Taken: true, SyncedAt: fresh}},
+			wantErr: models.ErrLaunchPadUnavailable},
+		{name: "inactive on a fresh mirror",
+			mirror:  &fakeMirror{day: &models.LaunchpadDay{Status: "retired", SyncedAt: fresh}},
+			wantErr: models.ErrLaunchPadUnavailable},
+		{name: "unknown to a fresh mirror",
+			mirror:  &fakeMirror{syncedAt: fresh},
+			wantErr: spacex.ErrNotFound},
+		{name: "stale mirror asks SpaceX",
+			mirror: &fakeMirror{day: &models.LaunchpadDay{Status: "active", Taken: true, SyncedAt: stale}},
+			live:   true, wantLive: true},
+		{name: "never synced mirror asks SpaceX",
+			mirror: &fakeMirror{},
+			live:   true, wantLive: true},
+		{name: "unreadable mirror asks SpaceX",
+			mirror: &fakeMirror{err: errors.New("connection refused")},
+			live:   false, wantLive: true, wantErr: models.ErrLaunchPadUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockBookingRepository)
+			mockSpaceX := new(mocks.MockSpaceXClient)
+			svc := service.NewBookingService(mockRepo, mockSpaceX, service.WithLaunchMirror(tt.mirror, time.Hour))
+			ctx := agentContext()
+
+			mockRepo.On("GetDestinationById", ctx, destinationID.String()).Return(&models.Destination{ID: destinationID, Name: "Mars"}, nil)
+			mockRepo.On("GetFlights", ctx, mock.Anything).Return([]models.Flight{}, nil)
+			mockRepo.On("IsLaunchPadWeekAvailable", ctx, "pad-1", destinationID.String(), launchDate).Return(true, nil)
+			if tt.wantLive {
+				mockSpaceX.On("CheckLaunchConflict", ctx, "pad-1", launchDate).Return(tt.live, nil)
+			}
+			if tt.wantErr == nil {
+				mockRepo.On("CreateBooking", ctx, mock.AnythingOfType("*models.Booking")).Return(&models.Booking{}, nil)
+			}
+
+			_, err := svc.CreateBooking(ctx, request)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+			mockSpaceX.AssertExpectations(t)
+			if !tt.wantLive {
+				mockSpaceX.AssertNotCalled(t, "CheckLaunchConflict", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiskService(t *testing.T) {
+	t.Run("customer sees only their own bookings", func(t *testing.T) {
+		repo := new(mocks.MockRiskRepository)
+		svc := service.NewRiskService(repo)
+		userID := uuid.New()
+		ctx := customerContext(userID)
+
+		risks := []models.BookingRisk{{Reason: "SpaceX has scheduled a launch"}}
+		repo.On("AtRiskBookings", ctx, userID.String()).Return(risks, nil)
+
+		report, err := svc.AtRiskBookings(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, risks, report.Bookings)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("agent sees every booking", func(t *testing.T) {
+		repo := new(mocks.MockRiskRepository)
+		svc := service.NewRiskService(repo)
+		ctx := agentContext()
+
+		repo.On("AtRiskBookings", ctx, "").Return([]models.BookingRisk{}, nil)
+
+		report, err := svc.AtRiskBookings(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, report.Bookings)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		repo := new(mocks.MockRiskRepository)
+		svc := service.NewRiskService(repo)
+
+		_, err := svc.AtRiskBookings(context.Background())
+
+		assert.ErrorIs(t, err, models.ErrUnauthenticated)
+		repo.AssertNotCalled(t, "AtRiskBookings")
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mocks.MockRiskRepository)
+		svc := service.NewRiskService(repo)
+		ctx := agentContext()
+
+		repo.On("AtRiskBookings", ctx, "").Return(nil, errors.New("connection reset"))
+
+		_, err := svc.AtRiskBookings(ctx)
+
+		assert.ErrorContains(t, err, "error fetching at-risk bookings")
+	})
+}
@@ -0,0 +1,226 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/ports"
+	"github.com/chrisdamba/spacetrouble/internal/service"
+	"github.com/chrisdamba/spacetrouble/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// hostsResolver resolves the hosts in it and no others.
+type hostsResolver map[string]string
+
+func (r hostsResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addr, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return []netip.Addr{netip.MustParseAddr(addr)}, nil
+}
+
+func newWebhookService(repo *mocks.MockWebhookRepository) ports.WebhookService {
+	return service.NewWebhookService(repo, service.WithResolver(hostsResolver{
+		"partner.example.com":  "93.184.216.34",
+		"internal.example.com": "10.0.0.7",
+	}))
+}
+
+func TestWebhookService(t *testing.T) {
+	t.Run("customer subscription is scoped to the customer", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		userID := uuid.New()
+		ctx := customerContext(userID)
+
+		var stored *models.WebhookSubscription
+		repo.On("CreateWebhook", ctx, mock.Anything).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.WebhookSubscription) }).
+			Return(&models.WebhookSubscription{ID: uuid.New()}, nil)
+
+		_, err := svc.CreateWebhook(ctx, &models.WebhookRequest{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []models.BookingEventType{models.EventBookingCancelled, models.EventBookingCancelled},
+		})
+
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, userID, stored.OwnerID)
+		assert.Equal(t, "https://partner.example.com/hooks", stored.URL)
+		assert.Equal(t, []models.BookingEventType{models.EventBookingCancelled}, stored.EventTypes)
+		assert.True(t, strings.HasPrefix(stored.Secret, "whsec_"))
+		assert.Len(t, stored.Secret, len("whsec_")+64)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("agent subscription receives every booking", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := agentContext()
+
+		repo.On("CreateWebhook", ctx, mock.MatchedBy(func(w *models.WebhookSubscription) bool {
+			return w.OwnerID == uuid.Nil && len(w.EventTypes) == 0
+		})).Return(&models.WebhookSubscription{ID: uuid.New()}, nil)
+
+		_, err := svc.CreateWebhook(ctx, &models.WebhookRequest{URL: "https://partner.example.com/hooks"})
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects endpoints that are not public", func(t *testing.T) {
+		for _, url := range []string{
+			"http://169.254.169.254/latest/meta-data/",
+			"https://internal.example.com/hooks",
+			"https://unknown.example.com/hooks",
+		} {
+			repo := new(mocks.MockWebhookRepository)
+			svc := newWebhookService(repo)
+
+			_, err := svc.CreateWebhook(customerContext(uuid.New()), &models.WebhookRequest{URL: url})
+
+			assert.ErrorIs(t, err, models.ErrWebhookDestination, url)
+			repo.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("lists only the customer's subscriptions", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		userID := uuid.New()
+		ctx := customerContext(userID)
+		repo.On("ListWebhooks", ctx, userID).Return([]models.WebhookSubscription{{ID: uuid.New(), OwnerID: userID}}, nil)
+
+		webhooks, err := svc.ListWebhooks(ctx)
+
+		require.NoError(t, err)
+		assert.Len(t, webhooks, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("agents list every subscription", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := agentContext()
+		repo.On("ListWebhooks", ctx, uuid.Nil).Return([]models.WebhookSubscription{}, nil)
+
+		_, err := svc.ListWebhooks(ctx)
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("another customer's subscription is not found", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := customerContext(uuid.New())
+		id := uuid.NewString()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{OwnerID: uuid.New()}, nil)
+
+		err := svc.DeleteWebhook(ctx, id)
+
+		assert.ErrorIs(t, err, models.ErrWebhookNotFound)
+		repo.AssertNotCalled(t, "DeleteWebhook", mock.Anything, mock.Anything)
+	})
+
+	t.Run("deletes an owned subscription", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		userID := uuid.New()
+		ctx := customerContext(userID)
+		id := uuid.NewString()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{OwnerID: userID}, nil)
+		repo.On("DeleteWebhook", ctx, id).Return(nil)
+
+		require.NoError(t, svc.DeleteWebhook(ctx, id))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("re-enables a disabled subscription", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		userID := uuid.New()
+		ctx := customerContext(userID)
+		id := uuid.NewString()
+		disabledAt := time.Now()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{
+			OwnerID: userID, URL: "https://partner.example.com/hooks", ConsecutiveFailures: 20, DisabledAt: &disabledAt,
+		}, nil)
+		repo.On("EnableWebhook", ctx, id).Return(nil)
+
+		webhook, err := svc.EnableWebhook(ctx, id)
+
+		require.NoError(t, err)
+		assert.True(t, webhook.Active)
+		assert.Zero(t, webhook.ConsecutiveFailures)
+		assert.Nil(t, webhook.DisabledAt)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("does not re-enable an endpoint that moved inside", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := agentContext()
+		id := uuid.NewString()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{URL: "https://internal.example.com/hooks"}, nil)
+
+		_, err := svc.EnableWebhook(ctx, id)
+
+		assert.ErrorIs(t, err, models.ErrWebhookDestination)
+		repo.AssertNotCalled(t, "EnableWebhook", mock.Anything, mock.Anything)
+	})
+
+	t.Run("another customer's subscription cannot be re-enabled", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := customerContext(uuid.New())
+		id := uuid.NewString()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{OwnerID: uuid.New(), URL: "https://partner.example.com/hooks"}, nil)
+
+		_, err := svc.EnableWebhook(ctx, id)
+
+		assert.ErrorIs(t, err, models.ErrWebhookNotFound)
+		repo.AssertNotCalled(t, "EnableWebhook", mock.Anything, mock.Anything)
+	})
+
+	t.Run("delivery log", func(t *testing.T) {
+		repo := new(mocks.MockWebhookRepository)
+		svc := newWebhookService(repo)
+		ctx := agentContext()
+		id := uuid.NewString()
+		repo.On("GetWebhook", ctx, id).Return(&models.WebhookSubscription{OwnerID: uuid.New()}, nil)
+		repo.On("ListDeliveries", ctx, id, 20).Return([]models.WebhookDelivery{{ID: 1, Status: models.DeliveryDelivered}}, nil)
+
+		deliveries, err := svc.WebhookDeliveries(ctx, id, 20)
+
+		require.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		svc := newWebhookService(new(mocks.MockWebhookRepository))
+
+		_, err := svc.GetWebhook(agentContext(), "not-a-uuid")
+
+		assert.ErrorIs(t, err, models.ErrInvalidUUID)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		svc := newWebhookService(new(mocks.MockWebhookRepository))
+
+		_, err := svc.ListWebhooks(context.Background())
+
+		assert.ErrorIs(t, err, models.ErrUnauthenticated)
+	})
+}
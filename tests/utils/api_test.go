@@ -2,6 +2,7 @@ package utils_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
 	"github.com/chrisdamba/spacetrouble/internal/utils"
@@ -12,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -252,6 +254,30 @@ func TestAllowedContentTypes(t *testing.T) {
 			allowedTypes: []string{"application/json"},
 			wantStatus:   http.StatusUnsupportedMediaType,
 		},
+		{
+			name:         "charset parameter",
+			contentType:  "application/json; charset=utf-8",
+			allowedTypes: []string{"application/json"},
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "media type is case insensitive",
+			contentType:  "Application/XML",
+			allowedTypes: []string{"application/json", "application/xml"},
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "malformed content type",
+			contentType:  "application/json; charset",
+			allowedTypes: []string{"application/json"},
+			wantStatus:   http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "missing content type",
+			contentType:  "",
+			allowedTypes: []string{"application/json"},
+			wantStatus:   http.StatusUnsupportedMediaType,
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,6 +297,18 @@ func TestAllowedContentTypes(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, w.Code)
 		})
 	}
+
+	t.Run("requests without a body skip the check", func(t *testing.T) {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		w := httptest.NewRecorder()
+
+		utils.AllowedContentTypes(handler, "application/json")(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
 }
 
 func normalizeXML(xmlStr string) string {
@@ -291,3 +329,36 @@ func normalizeXML(xmlStr string) string {
 
 	return string(normalized)
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := utils.Cursor{
+		SortKey:   "launch_date",
+		Direction: "desc",
+		Value:     time.Date(2030, 5, 1, 10, 30, 0, 123, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := utils.DecodeCursor(utils.EncodeCursor(c))
+
+	require.NoError(t, err)
+	assert.Equal(t, c.SortKey, decoded.SortKey)
+	assert.Equal(t, c.Direction, decoded.Direction)
+	assert.True(t, c.Value.Equal(decoded.Value))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	id := uuid.New()
+	legacy := base64.StdEncoding.EncodeToString([]byte("2030-01-01T00:00:00Z," + id.String()))
+
+	decoded, err := utils.DecodeCursor(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "created_at", decoded.SortKey)
+	assert.Equal(t, "asc", decoded.Direction)
+	assert.Equal(t, id, decoded.ID)
+
+	for _, invalid := range []string{"%%%", base64.StdEncoding.EncodeToString([]byte("a,b,c"))} {
+		_, err := utils.DecodeCursor(invalid)
+		assert.Error(t, err)
+	}
+}
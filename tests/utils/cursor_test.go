@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSigningKeys(t *testing.T) {
+	keys, err := utils.ParseSigningKeys("k2:second, k1:first")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].Kid)
+	assert.Equal(t, []byte("first"), keys[1].Secret)
+
+	_, err = utils.ParseSigningKeys("missing-secret")
+	assert.Error(t, err)
+
+	keys, err = utils.ParseSigningKeys("")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestCursorSigner(t *testing.T) {
+	key := utils.SigningKey{Kid: "k1", Secret: []byte("secret")}
+	signer, err := utils.NewCursorSigner([]utils.SigningKey{key}, time.Minute)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		token := signer.Sign("inner", "fp")
+		inner, err := signer.Verify(token, "fp")
+		require.NoError(t, err)
+		assert.Equal(t, "inner", inner)
+	})
+
+	t.Run("empty cursor stays empty", func(t *testing.T) {
+		assert.Empty(t, signer.Sign("", "fp"))
+	})
+
+	t.Run("different fingerprint", func(t *testing.T) {
+		_, err := signer.Verify(signer.Sign("inner", "fp"), "other")
+		assert.Equal(t, utils.ErrCursorMismatch, err)
+	})
+
+	t.Run("forged with another secret", func(t *testing.T) {
+		forger, err := utils.NewCursorSigner([]utils.SigningKey{{Kid: "k1", Secret: []byte("guess")}}, time.Minute)
+		require.NoError(t, err)
+		_, err = signer.Verify(forger.Sign("inner", "fp"), "fp")
+		assert.Equal(t, utils.ErrCursorInvalid, err)
+	})
+
+	t.Run("not a signed cursor", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "abc.def", "!!.!!"} {
+			_, err := signer.Verify(token, "fp")
+			assert.Equal(t, utils.ErrCursorInvalid, err, token)
+		}
+	})
+
+	t.Run("requires a key", func(t *testing.T) {
+		_, err := utils.NewCursorSigner(nil, time.Minute)
+		assert.Error(t, err)
+	})
+}
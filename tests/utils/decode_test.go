@@ -0,0 +1,103 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBody(t *testing.T) {
+	launch := time.Date(2031, 5, 1, 9, 30, 0, 0, time.UTC)
+	want := models.BookingRequest{
+		FirstName:     "Ada",
+		LastName:      "Lovelace",
+		Gender:        "female",
+		Birthday:      time.Date(1990, 12, 10, 0, 0, 0, 0, time.UTC),
+		LaunchpadID:   "5e9e4502f5090995de566f86",
+		DestinationID: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		LaunchDate:    launch,
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json with charset",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"first_name":"Ada","last_name":"Lovelace","gender":"female","birthday":"1990-12-10T00:00:00Z","launchpad_id":"5e9e4502f5090995de566f86","destination_id":"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11","launch_date":"2031-05-01T09:30:00Z"}`,
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body: `<booking>
+				<first_name>Ada</first_name><last_name>Lovelace</last_name><gender>female</gender>
+				<birthday>1990-12-10T00:00:00Z</birthday><launchpad_id>5e9e4502f5090995de566f86</launchpad_id>
+				<destination_id>a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11</destination_id><launch_date>2031-05-01T09:30:00Z</launch_date>
+			</booking>`,
+		},
+		{
+			name:        "text/xml with charset",
+			contentType: "text/xml; charset=UTF-8",
+			body:        `<booking><first_name>Ada</first_name><last_name>Lovelace</last_name><gender>female</gender><birthday>1990-12-10T00:00:00Z</birthday><launchpad_id>5e9e4502f5090995de566f86</launchpad_id><destination_id>a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11</destination_id><launch_date>2031-05-01T09:30:00Z</launch_date></booking>`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "first_name=Ada&last_name=Lovelace&gender=female&birthday=1990-12-10&launchpad_id=5e9e4502f5090995de566f86&destination_id=a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11&launch_date=2031-05-01T09%3A30%3A00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			var got models.BookingRequest
+			require.NoError(t, utils.DecodeBody(req, &got))
+			assert.Equal(t, want.FirstName, got.FirstName)
+			assert.Equal(t, want.LastName, got.LastName)
+			assert.Equal(t, want.Gender, got.Gender)
+			assert.True(t, want.Birthday.Equal(got.Birthday))
+			assert.Equal(t, want.LaunchpadID, got.LaunchpadID)
+			assert.Equal(t, want.DestinationID, got.DestinationID)
+			assert.True(t, want.LaunchDate.Equal(got.LaunchDate))
+		})
+	}
+}
+
+func TestDecodeBody_Errors(t *testing.T) {
+	t.Run("unsupported media type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello"))
+		req.Header.Set("Content-Type", "text/plain")
+
+		var got models.BookingRequest
+		assert.ErrorIs(t, utils.DecodeBody(req, &got), utils.ErrUnsupportedMediaType)
+	})
+
+	t.Run("bad form date", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("birthday=yesterday"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var got models.BookingRequest
+		err := utils.DecodeBody(req, &got)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "birthday")
+	})
+
+	t.Run("no content type reads json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"name":"Mars"}`))
+
+		var got models.DestinationRequest
+		require.NoError(t, utils.DecodeBody(req, &got))
+		assert.Equal(t, "Mars", got.Name)
+	})
+}
@@ -77,8 +77,8 @@ func CreateMockBookingRequest() *models.BookingRequest {
 		Gender:        "other",
 		Birthday:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
 		LaunchpadID:   "5e9e4502f5090995de566f86",
-		DestinationID: uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), // Mars
-		LaunchDate:    time.Now().AddDate(0, 1, 0),                            // One month from now
+		DestinationID: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", // Mars
+		LaunchDate:    time.Now().AddDate(0, 1, 0),            // One month from now
 	}
 }
 
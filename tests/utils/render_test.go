@@ -0,0 +1,156 @@
+package utils_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrisdamba/spacetrouble/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type testTable []testResponse
+
+func (t testTable) Table() ([]string, [][]string) {
+	records := make([][]string, len(t))
+	for i, r := range t {
+		records[i] = []string{r.Name, "x"}
+	}
+	return []string{"name", "extra"}, records
+}
+
+func render(t *testing.T, accept string, statusCode int, res interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	utils.RenderResponse(req, w, statusCode, res)
+	return w
+}
+
+func TestRenderResponse_Negotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		res         interface{}
+		wantStatus  int
+		wantContent string
+	}{
+		{"no accept header defaults to json", "", testResponse{}, http.StatusOK, "application/json"},
+		{"highest q wins", "application/json;q=0.5, application/yaml", testResponse{}, http.StatusOK, "application/yaml"},
+		{"equal q keeps client order", "application/xml, application/json", testResponse{}, http.StatusOK, "application/xml"},
+		{"specific range overrides wildcard", "*/*;q=0.9, application/json;q=0.1", testResponse{}, http.StatusOK, "application/xml"},
+		{"q=0 excludes a type", "application/json;q=0, application/*;q=0.5", testResponse{}, http.StatusOK, "application/xml"},
+		{"wildcard picks server preference", "*/*", testResponse{}, http.StatusOK, "application/json"},
+		{"params besides q are ignored", "application/msgpack; charset=utf-8", testResponse{}, http.StatusOK, "application/msgpack"},
+		{"csv for list responses", "text/csv", testTable{{Name: "a"}}, http.StatusOK, "text/csv"},
+		{"csv not offered for single values", "text/csv", testResponse{}, http.StatusNotAcceptable, "application/json"},
+		{"csv preferred but unavailable falls to next", "text/csv, application/yaml;q=0.5", testResponse{}, http.StatusOK, "application/yaml"},
+		{"nothing acceptable", "text/html", testResponse{}, http.StatusNotAcceptable, "application/json"},
+		{"errors keep their status", "text/html", utils.NewBadRequest("bad"), http.StatusBadRequest, "application/json"},
+		{"malformed range ignored", "application/yaml;q=abc, application/xml", testResponse{}, http.StatusOK, "application/xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := http.StatusOK
+			if ae, isErr := tt.res.(utils.ApiError); isErr {
+				status = ae.StatusCode
+			}
+			w := render(t, tt.accept, status, tt.res)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantContent, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+		})
+	}
+}
+
+func TestRenderResponse_NotAcceptableBody(t *testing.T) {
+	w := render(t, "text/html", http.StatusOK, testResponse{Name: "test"})
+
+	var ae utils.ApiError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ae))
+	assert.Equal(t, "not_acceptable", ae.Code)
+}
+
+func TestRenderResponse_EmptyBodyWithoutMatch(t *testing.T) {
+	w := render(t, "text/html", http.StatusNoContent, nil)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRenderResponse_Formats(t *testing.T) {
+	res := testResponse{Name: "test", Value: 123}
+
+	t.Run("yaml uses json field names", func(t *testing.T) {
+		w := render(t, "application/yaml", http.StatusOK, res)
+
+		var got map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, map[string]interface{}{"name": "test", "value": 123}, got)
+	})
+
+	t.Run("msgpack uses json field names", func(t *testing.T) {
+		w := render(t, "application/msgpack", http.StatusOK, res)
+
+		var got map[string]interface{}
+		require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, "test", got["name"])
+		assert.EqualValues(t, 123, got["value"])
+	})
+
+	t.Run("csv writes header and rows", func(t *testing.T) {
+		w := render(t, "text/csv", http.StatusOK, testTable{{Name: "a,b"}, {Name: "c"}})
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"name", "extra"}, {"a,b", "x"}, {"c", "x"}}, records)
+	})
+
+	t.Run("csv cells cannot be read as formulas", func(t *testing.T) {
+		w := render(t, "text/csv", http.StatusOK, testTable{{Name: "=1+1"}, {Name: "@SUM(A1)"}})
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"name", "extra"}, {"'=1+1", "x"}, {"'@SUM(A1)", "x"}}, records)
+	})
+
+	t.Run("yaml errors", func(t *testing.T) {
+		w := render(t, "application/yaml", http.StatusBadRequest, utils.ApiError{Msg: "bad", Code: "invalid_request"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "code: invalid_request\nerror: bad\n", w.Body.String())
+	})
+}
+
+type upperRenderer struct{}
+
+func (upperRenderer) CanRender(res interface{}) bool {
+	_, ok := res.(testResponse)
+	return ok
+}
+
+func (upperRenderer) Render(w http.ResponseWriter, statusCode int, res interface{}) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(strings.ToUpper(res.(testResponse).Name)))
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	utils.RegisterRenderer("text/plain", upperRenderer{})
+
+	w := render(t, "text/plain", http.StatusOK, testResponse{Name: "shout"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "SHOUT", w.Body.String())
+}
@@ -0,0 +1,194 @@
+package verify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/verify"
+	"github.com/chrisdamba/spacetrouble/pkg/spacex"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keeps bookings in memory, following the rules of the SQL
+// behind the repository.
+type fakeStore struct {
+	bookings []models.Booking
+	risks    map[uuid.UUID]models.BookingRisk
+}
+
+func (f *fakeStore) PendingBookings(_ context.Context, after time.Time) ([]models.Booking, error) {
+	var pending []models.Booking
+	for _, b := range f.bookings {
+		if b.Status == models.StatusPendingVerification && b.Flight.LaunchDate.After(after) {
+			pending = append(pending, b)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeStore) ConfirmBooking(_ context.Context, id uuid.UUID) (*models.Booking, error) {
+	b := f.booking(id)
+	if b.Status != models.StatusPendingVerification {
+		return nil, nil
+	}
+	b.Status = models.StatusConfirmed
+	confirmed := *b
+	return &confirmed, nil
+}
+
+func (f *fakeStore) FlagAtRisk(_ context.Context, risk *models.BookingRisk) (bool, error) {
+	b := f.booking(risk.Booking.ID)
+	if f.risks == nil {
+		f.risks = map[uuid.UUID]models.BookingRisk{}
+	}
+	risk.PreviousStatus = b.Status
+	f.risks[b.ID] = *risk
+	b.Status = models.StatusAtRisk
+	risk.Booking = *b
+	return true, nil
+}
+
+func (f *fakeStore) booking(id uuid.UUID) *models.Booking {
+	for i := range f.bookings {
+		if f.bookings[i].ID == id {
+			return &f.bookings[i]
+		}
+	}
+	return nil
+}
+
+// fakeSpaceX answers conflict checks per launchpad.
+type fakeSpaceX struct {
+	taken map[string]bool
+	errs  map[string]error
+	calls int
+}
+
+func (f *fakeSpaceX) CheckLaunchConflict(_ context.Context, launchpadID string, _ time.Time) (bool, error) {
+	f.calls++
+	if err := f.errs[launchpadID]; err != nil {
+		return false, err
+	}
+	return !f.taken[launchpadID], nil
+}
+
+type fakeLeases struct {
+	holder string
+}
+
+func (f *fakeLeases) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if f.holder != "" && f.holder != holder {
+		return false, nil
+	}
+	f.holder = holder
+	return true, nil
+}
+
+func (f *fakeLeases) ReleaseLease(_ context.Context, name, holder string) error {
+	if f.holder == holder {
+		f.holder = ""
+	}
+	return nil
+}
+
+type fakeEvents struct {
+	events []models.BookingEvent
+}
+
+func (f *fakeEvents) PublishEvent(_ context.Context, event *models.BookingEvent) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+var now = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func pending(launchpad string, days int) models.Booking {
+	return models.Booking{
+		ID:     uuid.New(),
+		Status: models.StatusPendingVerification,
+		Flight: models.Flight{LaunchpadID: launchpad, LaunchDate: now.AddDate(0, 0, days)},
+	}
+}
+
+func newVerifier(store *fakeStore, spaceX *fakeSpaceX, leases *fakeLeases, events *fakeEvents) *verify.Verifier {
+	return verify.NewVerifier(store, spaceX, leases,
+		verify.WithHolder("a"),
+		verify.WithClock(func() time.Time { return now }),
+		verify.WithEventPublisher(events),
+	)
+}
+
+func TestVerifierConfirmsOrFlagsPendingBookings(t *testing.T) {
+	free := pending("free", 10)
+	taken := pending("taken", 10)
+	gone := pending("gone", 10)
+	store := &fakeStore{bookings: []models.Booking{free, taken, gone}}
+	spaceX := &fakeSpaceX{
+		taken: map[string]bool{"taken": true},
+		errs:  map[string]error{"gone": fmt.Errorf("checking launchpad: %w", spacex.ErrNotFound)},
+	}
+	events := &fakeEvents{}
+
+	result, err := newVerifier(store, spaceX, &fakeLeases{}, events).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, models.VerifyResult{Checked: 3, Confirmed: 1, Flagged: 2}, result)
+	assert.Equal(t, models.StatusConfirmed, store.booking(free.ID).Status)
+	assert.Equal(t, models.StatusAtRisk, store.booking(taken.ID).Status)
+	assert.Equal(t, "SpaceX reports launchpad taken unavailable on 2030-06-11", store.risks[taken.ID].Reason)
+	assert.Equal(t, models.StatusPendingVerification, store.risks[taken.ID].PreviousStatus)
+	assert.Equal(t, "launchpad gone no longer exists", store.risks[gone.ID].Reason)
+
+	require.Len(t, events.events, 3)
+	for _, event := range events.events {
+		assert.Equal(t, models.EventBookingStatusChanged, event.Type)
+		assert.Equal(t, models.StatusPendingVerification, event.PreviousStatus)
+	}
+	assert.Equal(t, models.StatusConfirmed, events.events[0].Booking.Status)
+}
+
+func TestVerifierWaitsForSpaceX(t *testing.T) {
+	first := pending("pad", 5)
+	second := pending("pad", 6)
+	store := &fakeStore{bookings: []models.Booking{first, second}}
+	spaceX := &fakeSpaceX{errs: map[string]error{"pad": fmt.Errorf("checking launchpad: %w", spacex.ErrCircuitOpen)}}
+
+	result, err := newVerifier(store, spaceX, &fakeLeases{}, &fakeEvents{}).RunOnce(context.Background())
+
+	assert.ErrorIs(t, err, spacex.ErrCircuitOpen)
+	assert.Zero(t, result)
+	assert.Equal(t, 1, spaceX.calls, "the run stops at the first booking SpaceX cannot be asked about")
+	assert.Equal(t, models.StatusPendingVerification, store.booking(first.ID).Status)
+	assert.Equal(t, models.StatusPendingVerification, store.booking(second.ID).Status)
+}
+
+func TestVerifierSkipsBookingsItCannotCheck(t *testing.T) {
+	broken := pending("broken", 5)
+	free := pending("free", 6)
+	store := &fakeStore{bookings: []models.Booking{broken, free}}
+	spaceX := &fakeSpaceX{errs: map[string]error{"broken": errors.New("invalid date precision: week")}}
+
+	result, err := newVerifier(store, spaceX, &fakeLeases{}, &fakeEvents{}).RunOnce(context.Background())
+
+	assert.ErrorContains(t, err, "invalid date precision")
+	assert.Equal(t, models.VerifyResult{Checked: 1, Confirmed: 1}, result)
+	assert.Equal(t, models.StatusPendingVerification, store.booking(broken.ID).Status)
+	assert.Equal(t, models.StatusConfirmed, store.booking(free.ID).Status)
+}
+
+func TestVerifierOnlyLeaseHolderRuns(t *testing.T) {
+	store := &fakeStore{bookings: []models.Booking{pending("pad", 5)}}
+	spaceX := &fakeSpaceX{}
+
+	result, err := newVerifier(store, spaceX, &fakeLeases{holder: "b"}, &fakeEvents{}).RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Zero(t, result)
+	assert.Zero(t, spaceX.calls)
+}
@@ -0,0 +1,97 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver answers lookups from a map; hosts missing from it do not
+// resolve.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	out := make([]netip.Addr, len(addrs))
+	for i, a := range addrs {
+		out[i] = netip.MustParseAddr(a)
+	}
+	return out, nil
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"255.255.255.255":        false,
+		"224.0.0.1":              false,
+		"198.18.0.1":             false,
+		"::1":                    false,
+		"::":                     false,
+		"fe80::1":                false,
+		"fd00:ec2::254":          false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+		"64:ff9b::a9fe:a9fe":     false,
+	} {
+		assert.Equal(t, public, webhooks.PublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckDestination(t *testing.T) {
+	resolver := fakeResolver{
+		"partner.example.com":  {"93.184.216.34"},
+		"localhost":            {"127.0.0.1", "::1"},
+		"metadata.example.com": {"169.254.169.254"},
+		"split.example.com":    {"93.184.216.34", "10.0.0.5"},
+	}
+
+	assert.NoError(t, webhooks.CheckDestination(context.Background(), resolver, "https://partner.example.com/hooks"))
+	assert.NoError(t, webhooks.CheckDestination(context.Background(), resolver, "http://93.184.216.34:8080/hooks"))
+
+	for _, url := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:5432",
+		"http://[::1]/hooks",
+		"http://localhost/hooks",
+		"https://metadata.example.com/hooks",
+		"https://split.example.com/hooks",
+		"https://unknown.example.com/hooks",
+		"ftp://partner.example.com",
+		"https:///hooks",
+	} {
+		err := webhooks.CheckDestination(context.Background(), resolver, url)
+		assert.ErrorIs(t, err, models.ErrWebhookDestination, url)
+	}
+}
+
+func TestHTTPClientRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	t.Cleanup(server.Close)
+
+	_, err := webhooks.NewHTTPClient(time.Second).Post(server.URL, "application/json", nil)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, models.ErrWebhookDestination)
+	assert.False(t, called)
+}
@@ -0,0 +1,340 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/chrisdamba/spacetrouble/internal"
+	"github.com/chrisdamba/spacetrouble/internal/outbox"
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore keeps subscriptions and deliveries in memory, following the
+// rules of the SQL behind the repository.
+type fakeStore struct {
+	mu            sync.Mutex
+	now           time.Time
+	subscriptions map[uuid.UUID]*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+}
+
+func newFakeStore(now time.Time, subs ...*models.WebhookSubscription) *fakeStore {
+	s := &fakeStore{now: now, subscriptions: map[uuid.UUID]*models.WebhookSubscription{}}
+	for _, sub := range subs {
+		s.subscriptions[sub.ID] = sub
+	}
+	return s
+}
+
+func (s *fakeStore) EnqueueDeliveries(_ context.Context, eventID uuid.UUID, eventType models.BookingEventType, ownerID uuid.UUID, payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sub := range s.subscriptions {
+		if !sub.Active || !sub.Accepts(eventType) || (sub.OwnerID != uuid.Nil && sub.OwnerID != ownerID) {
+			continue
+		}
+		duplicate := false
+		for _, d := range s.deliveries {
+			duplicate = duplicate || (d.SubscriptionID == sub.ID && d.EventID == eventID)
+		}
+		if duplicate {
+			continue
+		}
+		next := s.now
+		s.deliveries = append(s.deliveries, &models.WebhookDelivery{
+			ID: int64(len(s.deliveries) + 1), SubscriptionID: sub.ID, EventID: eventID, EventType: eventType,
+			Status: models.DeliveryPending, NextAttemptAt: &next, Payload: payload,
+		})
+		n++
+	}
+	return n, nil
+}
+
+func (s *fakeStore) ClaimDeliveries(_ context.Context, limit int, _ time.Duration) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.WebhookDelivery
+	for _, d := range s.deliveries {
+		sub := s.subscriptions[d.SubscriptionID]
+		if d.Status != models.DeliveryPending || !sub.Active || d.NextAttemptAt.After(s.now) || len(claimed) == limit {
+			continue
+		}
+		c := *d
+		c.URL, c.Secret = sub.URL, sub.Secret
+		claimed = append(claimed, c)
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkDelivered(_ context.Context, id int64, responseStatus int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Attempts++
+	d.Status, d.ResponseStatus, d.LastError = models.DeliveryDelivered, responseStatus, ""
+	s.subscriptions[d.SubscriptionID].ConsecutiveFailures = 0
+	return nil
+}
+
+func (s *fakeStore) MarkDeliveryFailed(_ context.Context, id int64, responseStatus int, reason string, next *time.Time, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Attempts++
+	d.ResponseStatus, d.LastError = responseStatus, reason
+	if next == nil {
+		d.Status = models.DeliveryFailed
+	} else {
+		d.NextAttemptAt = next
+	}
+	sub := s.subscriptions[d.SubscriptionID]
+	sub.ConsecutiveFailures++
+	if sub.Active && sub.ConsecutiveFailures >= disableAfter {
+		sub.Active = false
+		return true, nil
+	}
+	return false, nil
+}
+
+// receiver is an httptest endpoint that verifies signatures and answers
+// with the next queued status.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	received []outbox.CloudEvent
+	headers  []http.Header
+	verifyAt func() time.Time
+}
+
+func newReceiver(t *testing.T, secret string, verifyAt func() time.Time, statuses ...int) *receiver {
+	r := &receiver{secret: secret, statuses: statuses, verifyAt: verifyAt}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		if err := webhooks.Verify(r.secret, req.Header.Get(webhooks.SignatureHeader), body, 5*time.Minute, r.verifyAt()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if status < 300 {
+			var event outbox.CloudEvent
+			require.NoError(t, json.Unmarshal(body, &event))
+			r.received = append(r.received, event)
+			r.headers = append(r.headers, req.Header.Clone())
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func subscription(url string, owner uuid.UUID, types ...models.BookingEventType) *models.WebhookSubscription {
+	return &models.WebhookSubscription{ID: uuid.New(), OwnerID: owner, URL: url, Secret: "whsec_" + uuid.NewString(), EventTypes: types, Active: true}
+}
+
+func cloudEvent(t *testing.T, eventType models.BookingEventType, owner uuid.UUID) outbox.CloudEvent {
+	t.Helper()
+	payload, err := json.Marshal(models.Booking{ID: uuid.New(), User: models.User{ID: owner}})
+	require.NoError(t, err)
+	return outbox.NewCloudEvent(models.OutboxEvent{EventID: uuid.New(), Type: eventType, Payload: payload, OccurredAt: time.Now()})
+}
+
+func TestFanoutFiltersSubscriptions(t *testing.T) {
+	customer := uuid.New()
+	all := subscription("https://a.example.com", uuid.Nil)
+	cancellations := subscription("https://b.example.com", uuid.Nil, models.EventBookingCancelled)
+	own := subscription("https://c.example.com", customer)
+	other := subscription("https://d.example.com", uuid.New())
+	store := newFakeStore(time.Now(), all, cancellations, own, other)
+	fanout := webhooks.NewFanout(store)
+
+	event := cloudEvent(t, models.EventBookingCreated, customer)
+	require.NoError(t, fanout.Send(context.Background(), event))
+	require.NoError(t, fanout.Send(context.Background(), event), "the outbox may deliver twice")
+
+	var got []uuid.UUID
+	for _, d := range store.deliveries {
+		got = append(got, d.SubscriptionID)
+		assert.Equal(t, models.EventBookingCreated, d.EventType)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{all.ID, own.ID}, got)
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now)
+	rcv := newReceiver(t, "", func() time.Time { return now })
+	sub := subscription(rcv.URL, uuid.Nil)
+	rcv.secret = sub.Secret
+	store.subscriptions[sub.ID] = sub
+
+	event := cloudEvent(t, models.EventBookingCancelled, uuid.New())
+	require.NoError(t, webhooks.NewFanout(store).Send(context.Background(), event))
+
+	dispatcher := webhooks.NewDispatcher(store, webhooks.WithHTTPClient(rcv.Client()), webhooks.WithClock(func() time.Time { return now }))
+	n, err := dispatcher.DispatchOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, rcv.received, 1)
+	assert.Equal(t, event.ID, rcv.received[0].ID)
+	assert.Equal(t, "com.spacetrouble.booking.cancelled", rcv.received[0].Type)
+	assert.Equal(t, "application/cloudevents+json", rcv.headers[0].Get("Content-Type"))
+	assert.Equal(t, strconv.FormatInt(store.deliveries[0].ID, 10), rcv.headers[0].Get(webhooks.DeliveryHeader))
+	assert.Equal(t, models.DeliveryDelivered, store.deliveries[0].Status)
+	assert.Equal(t, http.StatusNoContent, store.deliveries[0].ResponseStatus)
+
+	n, err = dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "delivered events are not sent again")
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	start := time.Now()
+	store := newFakeStore(start)
+	rcv := newReceiver(t, "", func() time.Time { return store.now }, 500, 503, 200)
+	sub := subscription(rcv.URL, uuid.Nil)
+	rcv.secret = sub.Secret
+	store.subscriptions[sub.ID] = sub
+	require.NoError(t, webhooks.NewFanout(store).Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+
+	dispatcher := webhooks.NewDispatcher(store,
+		webhooks.WithHTTPClient(rcv.Client()),
+		webhooks.WithRetries(5, time.Second, time.Minute),
+		webhooks.WithClock(func() time.Time { return store.now }),
+	)
+	delivery := store.deliveries[0]
+
+	_, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, 500, delivery.ResponseStatus)
+	assert.Equal(t, start.Add(time.Second), *delivery.NextAttemptAt)
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "not due yet")
+
+	store.now = *delivery.NextAttemptAt
+	_, err = dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, store.now.Add(2*time.Second), *delivery.NextAttemptAt)
+	assert.Equal(t, 2, sub.ConsecutiveFailures)
+
+	store.now = *delivery.NextAttemptAt
+	_, err = dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Zero(t, sub.ConsecutiveFailures, "a success resets the failure run")
+	assert.Len(t, rcv.received, 1)
+}
+
+func TestDispatcherGivesUpAndDisables(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now)
+	rcv := newReceiver(t, "", func() time.Time { return store.now }, 500, 500, 500, 500)
+	sub := subscription(rcv.URL, uuid.Nil)
+	rcv.secret = sub.Secret
+	store.subscriptions[sub.ID] = sub
+	fanout := webhooks.NewFanout(store)
+	require.NoError(t, fanout.Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+	require.NoError(t, fanout.Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+
+	dispatcher := webhooks.NewDispatcher(store,
+		webhooks.WithHTTPClient(rcv.Client()),
+		webhooks.WithRetries(2, time.Second, time.Second),
+		webhooks.WithDisableAfter(3),
+		webhooks.WithClock(func() time.Time { return store.now }),
+	)
+
+	for i := 0; i < 3; i++ {
+		_, err := dispatcher.DispatchOnce(context.Background())
+		require.NoError(t, err)
+		store.now = store.now.Add(time.Second)
+	}
+
+	assert.Equal(t, models.DeliveryFailed, store.deliveries[0].Status, "gave up after max attempts")
+	assert.Equal(t, 2, store.deliveries[0].Attempts)
+	assert.Contains(t, store.deliveries[0].LastError, "endpoint responded 500")
+	assert.False(t, sub.Active, "disabled after three failures in a row")
+	assert.Equal(t, models.DeliveryPending, store.deliveries[1].Status, "kept for when the endpoint is back")
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "disabled subscriptions get nothing")
+}
+
+func TestDispatcherUnreachableEndpoint(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now)
+	rcv := newReceiver(t, "", time.Now)
+	sub := subscription(rcv.URL, uuid.Nil)
+	store.subscriptions[sub.ID] = sub
+	rcv.Close()
+	require.NoError(t, webhooks.NewFanout(store).Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+
+	dispatcher := webhooks.NewDispatcher(store, webhooks.WithHTTPClient(rcv.Client()), webhooks.WithClock(func() time.Time { return now }))
+	_, err := dispatcher.DispatchOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.deliveries[0].Attempts)
+	assert.Zero(t, store.deliveries[0].ResponseStatus)
+	assert.NotEmpty(t, store.deliveries[0].LastError)
+}
+
+func TestDispatcherKeepsOnlyTheResponseStatus(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"AccessKeyId":"ASIA...","SecretAccessKey":"..."}`, http.StatusInternalServerError)
+	}))
+	t.Cleanup(endpoint.Close)
+	sub := subscription(endpoint.URL, uuid.Nil)
+	store.subscriptions[sub.ID] = sub
+	require.NoError(t, webhooks.NewFanout(store).Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+
+	dispatcher := webhooks.NewDispatcher(store, webhooks.WithHTTPClient(endpoint.Client()), webhooks.WithClock(func() time.Time { return now }))
+	_, err := dispatcher.DispatchOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, store.deliveries[0].ResponseStatus)
+	assert.Equal(t, "endpoint responded 500", store.deliveries[0].LastError)
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(now)
+	rcv := newReceiver(t, "", time.Now)
+	sub := subscription(rcv.URL, uuid.Nil)
+	store.subscriptions[sub.ID] = sub
+	require.NoError(t, webhooks.NewFanout(store).Send(context.Background(), cloudEvent(t, models.EventBookingCreated, uuid.New())))
+
+	// the default client, as in production, where the receiver on the
+	// loopback interface stands for anything inside the network
+	dispatcher := webhooks.NewDispatcher(store, webhooks.WithClock(func() time.Time { return now }))
+	_, err := dispatcher.DispatchOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, rcv.received)
+	assert.Equal(t, 1, store.deliveries[0].Attempts)
+	assert.Contains(t, store.deliveries[0].LastError, "not a public address")
+}
@@ -0,0 +1,48 @@
+package webhooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrisdamba/spacetrouble/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":"evt"}`)
+	signedAt := time.Unix(1893456000, 0)
+	header := webhooks.Sign(secret, signedAt, body)
+
+	t.Run("round trip", func(t *testing.T) {
+		assert.Regexp(t, `^t=1893456000,v1=[0-9a-f]{64}$`, header)
+		assert.NoError(t, webhooks.Verify(secret, header, body, 5*time.Minute, signedAt.Add(time.Minute)))
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		err := webhooks.Verify(secret, header, []byte(`{"id":"other"}`), 5*time.Minute, signedAt)
+		assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		err := webhooks.Verify("whsec_other", header, body, 5*time.Minute, signedAt)
+		assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+	})
+
+	t.Run("replayed later", func(t *testing.T) {
+		err := webhooks.Verify(secret, header, body, 5*time.Minute, signedAt.Add(time.Hour))
+		assert.ErrorIs(t, err, webhooks.ErrSignatureExpired)
+	})
+
+	t.Run("timestamp cannot be swapped", func(t *testing.T) {
+		forged := "t=1893459600" + header[len("t=1893456000"):]
+		err := webhooks.Verify(secret, forged, body, 5*time.Minute, signedAt.Add(time.Hour))
+		assert.ErrorIs(t, err, webhooks.ErrInvalidSignature)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, h := range []string{"", "v1=abc", "t=now,v1=00", "t=1893456000", "t=1893456000,v1=zz"} {
+			assert.ErrorIs(t, webhooks.Verify(secret, h, body, time.Minute, signedAt), webhooks.ErrMalformedHeader, h)
+		}
+	})
+}